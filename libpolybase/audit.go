@@ -0,0 +1,240 @@
+package libpolybase
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (pb *PB) ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error) {
+	conditions := []string{"academic_year = ?"}
+	args := []any{pb.year}
+
+	if filter.EntityType != nil {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, string(*filter.EntityType))
+	}
+
+	if filter.EntityID != nil {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, *filter.EntityID)
+	}
+
+	if filter.Actor != nil {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, *filter.Actor)
+	}
+
+	if filter.Action != nil {
+		conditions = append(conditions, "action = ?")
+		args = append(args, string(*filter.Action))
+	}
+
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	query := `SELECT id, actor, action, entity_type, entity_id, before, after, created_at, reverted_by, merged_from
+    FROM audit_events`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := pb.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []AuditEvent
+	for rows.Next() {
+		event, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// Changes returns the top-level fields that differ between the before and
+// after snapshots of the event.
+func (e AuditEvent) Changes() (map[string]AuditChange, error) {
+	before := make(map[string]json.RawMessage)
+	after := make(map[string]json.RawMessage)
+	if len(e.Before) > 0 {
+		if err := json.Unmarshal(e.Before, &before); err != nil {
+			return nil, fmt.Errorf("decode before snapshot: %w", err)
+		}
+	}
+	if len(e.After) > 0 {
+		if err := json.Unmarshal(e.After, &after); err != nil {
+			return nil, fmt.Errorf("decode after snapshot: %w", err)
+		}
+	}
+
+	changes := make(map[string]AuditChange)
+	for field, value := range before {
+		if !bytes.Equal(value, after[field]) {
+			changes[field] = AuditChange{Before: value, After: after[field]}
+		}
+	}
+	for field, value := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = AuditChange{After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+func (pb *PB) getAuditEvent(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (AuditEvent, error) {
+	row := querier.QueryRowContext(ctx, `
+    SELECT id, actor, action, entity_type, entity_id, before, after, created_at, reverted_by, merged_from
+    FROM audit_events
+    WHERE academic_year = ? AND id = ?`, pb.year, id)
+	event, err := scanAuditEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return AuditEvent{}, notFound("change not found")
+	}
+	return event, err
+}
+
+func scanAuditEvent(row interface{ Scan(...any) error }) (AuditEvent, error) {
+	var e AuditEvent
+	var action, entityType string
+	var before, after, mergedFrom sql.NullString
+	var revertedBy sql.NullInt64
+	if err := row.Scan(&e.ID, &e.Actor, &action, &entityType, &e.EntityID,
+		&before, &after, &e.CreatedAt, &revertedBy, &mergedFrom); err != nil {
+		return AuditEvent{}, fmt.Errorf("scan audit event: %w", err)
+	}
+	if revertedBy.Valid {
+		id := int(revertedBy.Int64)
+		e.RevertedBy = &id
+	}
+	e.MergedFrom = mergedFrom.String
+	e.Action = AuditAction(action)
+	e.EntityType = EntityType(entityType)
+	if before.Valid {
+		e.Before = json.RawMessage(before.String)
+	}
+	if after.Valid {
+		e.After = json.RawMessage(after.String)
+	}
+	return e, nil
+}
+
+// audit records a mutation in the audit log and returns the ID of the event.
+// It must be called in the transaction of the mutation, a nil snapshot is
+// stored as NULL.
+func (pb *PB) audit(ctx context.Context, tx *sql.Tx, user string, action AuditAction, entityType EntityType, entityID string, before any, after any) (int, error) {
+	beforeJSON, err := snapshot(before)
+	if err != nil {
+		return 0, fmt.Errorf("encode before snapshot: %w", err)
+	}
+	afterJSON, err := snapshot(after)
+	if err != nil {
+		return 0, fmt.Errorf("encode after snapshot: %w", err)
+	}
+
+	createdAt := time.Now().UTC()
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO audit_events (academic_year, actor, action, entity_type, entity_id, before, after, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, user, string(action), string(entityType), entityID, beforeJSON, afterJSON, createdAt)
+	if err != nil {
+		return 0, fmt.Errorf("record audit event: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("get audit event id: %w", err)
+	}
+
+	if recorder, ok := ctx.Value(changesKey{}).(*ChangeRecorder); ok {
+		event := AuditEvent{ID: int(id), Actor: user, Action: action, EntityType: entityType, EntityID: entityID, CreatedAt: createdAt}
+		if beforeJSON.Valid {
+			event.Before = json.RawMessage(beforeJSON.String)
+		}
+		if afterJSON.Valid {
+			event.After = json.RawMessage(afterJSON.String)
+		}
+		recorder.events = append(recorder.events, event)
+	}
+	return int(id), nil
+}
+
+type changesKey struct{}
+
+// ChangeRecorder keeps the audit events written by the mutations called with
+// the context returned by RecordChanges, so a caller can tell which change a
+// mutation made instead of looking it up in the audit log.
+type ChangeRecorder struct {
+	events []AuditEvent
+}
+
+// RecordChanges returns a context recording the audit events written under it.
+// The events of a mutation that fails are recorded as well, and must be ignored
+// as they are rolled back.
+func RecordChanges(ctx context.Context) (context.Context, *ChangeRecorder) {
+	recorder := &ChangeRecorder{}
+	return context.WithValue(ctx, changesKey{}, recorder), recorder
+}
+
+// CourseChange returns the first event recorded on a course, the one a
+// mutation writing several events records for the course it was called on.
+func (c *ChangeRecorder) CourseChange(id CourseID) (AuditEvent, bool) {
+	return c.change(EntityCourse, id.ID())
+}
+
+// PackChange returns the first event recorded on a pack.
+func (c *ChangeRecorder) PackChange(id int) (AuditEvent, bool) {
+	return c.change(EntityPack, packEntityID(id))
+}
+
+func (c *ChangeRecorder) change(entityType EntityType, entityID string) (AuditEvent, bool) {
+	for _, event := range c.events {
+		if event.EntityType == entityType && event.EntityID == entityID {
+			return event, true
+		}
+	}
+	return AuditEvent{}, false
+}
+
+func snapshot(v any) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func packEntityID(id int) string {
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,283 @@
+package libpolybase
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Kind is a kind of course of the catalogue.
+type Kind struct {
+	// Name is stored on the courses.
+	Name string `toml:"name"`
+	// Label is displayed in place of the name, which is used when empty.
+	Label string `toml:"label"`
+	// Colour is a CSS colour for the cards of the kind. When empty the
+	// stylesheet decides.
+	Colour string `toml:"colour"`
+	// Order sorts the courses of a same code by kind, lowest first.
+	Order int `toml:"order"`
+}
+
+// colourRegexp accepts CSS colours such as #a0c4ff, teal or hsl(337, 100%, 89%)
+// and nothing that could escape a style attribute.
+var colourRegexp = regexp.MustCompile(`^[#a-zA-Z0-9(),.%/ -]*$`)
+
+// DisplayName returns the label of the kind, or its name when it has none.
+func (k Kind) DisplayName() string {
+	if k.Label != "" {
+		return k.Label
+	}
+	return k.Name
+}
+
+// Template is a set of courses created together for a new code, such as the
+// lectures, tutorials and lab work of a usual UE.
+type Template struct {
+	Name  string `toml:"name" json:"name"`
+	Label string `toml:"label" json:"label"`
+	// Courses lists the kinds of the template along with their number of
+	// parts.
+	Courses []TemplateCourse `toml:"courses" json:"courses"`
+}
+
+type TemplateCourse struct {
+	Kind  string `toml:"kind" json:"kind"`
+	Parts int    `toml:"parts" json:"parts"`
+}
+
+// DisplayName returns the label of the template, or its name when it has
+// none.
+func (t Template) DisplayName() string {
+	if t.Label != "" {
+		return t.Label
+	}
+	return t.Name
+}
+
+// Expand lists the courses of the template, every part of every kind, each
+// a copy of base.
+func (t Template) Expand(base Course) []Course {
+	var courses []Course
+	for _, tc := range t.Courses {
+		for part := 1; part <= tc.Parts; part++ {
+			course := base
+			course.Kind = tc.Kind
+			course.Part = part
+			course.Parts = tc.Parts
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
+
+// Catalogue holds the rules courses must follow. Kinds and semesters are
+// listed in the order they are offered in.
+type Catalogue struct {
+	// CodePatterns are the regular expressions a course code must match one
+	// of. Their first group captures the level of study.
+	CodePatterns []string `toml:"code_patterns"`
+	// Levels maps the values captured by the code patterns to levels, 1 to 5
+	// mapping to L1 to M2 when empty. A captured value missing from it is
+	// read as a level name, and gives LevelOther when it is not one.
+	Levels    map[string]Level `toml:"levels"`
+	Kinds     []Kind           `toml:"kinds"`
+	Semesters []string         `toml:"semesters"`
+	// Templates are the sets of courses offered when creating a new code.
+	Templates []Template `toml:"templates"`
+
+	patterns []*regexp.Regexp
+}
+
+// DefaultCatalogue returns the rules of the computer science department.
+func DefaultCatalogue() Catalogue {
+	return Catalogue{
+		CodePatterns: []string{`^[LMU]{2}(\d)IN\d{3}$`},
+		Levels:       defaultLevels(),
+		Kinds: []Kind{
+			{Name: "TD", Order: 4},
+			{Name: "Cours", Order: 3},
+			{Name: "Memento", Order: 1},
+			{Name: "TME", Order: 2},
+		},
+		Semesters: []string{"S1", "S2"},
+		Templates: []Template{
+			{
+				Name:  "standard",
+				Label: "UE standard",
+				Courses: []TemplateCourse{
+					{Kind: "Cours", Parts: 2},
+					{Kind: "TD", Parts: 1},
+					{Kind: "TME", Parts: 1},
+				},
+			},
+		},
+	}
+}
+
+// Compile checks the catalogue and prepares its code patterns. It must be
+// called before the catalogue is used.
+func (c *Catalogue) Compile() error {
+	if len(c.CodePatterns) == 0 {
+		return fmt.Errorf("catalogue.code_patterns cannot be empty")
+	}
+	c.patterns = make([]*regexp.Regexp, len(c.CodePatterns))
+	for i, pattern := range c.CodePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("catalogue.code_patterns: %w", err)
+		}
+		if re.NumSubexp() < 1 {
+			return fmt.Errorf("catalogue.code_patterns: %s has no group capturing the level", pattern)
+		}
+		c.patterns[i] = re
+	}
+
+	if len(c.Levels) == 0 {
+		c.Levels = defaultLevels()
+	}
+	for value, level := range c.Levels {
+		if _, err := ParseLevel(string(level)); err != nil {
+			return fmt.Errorf("catalogue.levels: invalid level %q for %q", level, value)
+		}
+	}
+
+	if len(c.Kinds) == 0 {
+		return fmt.Errorf("catalogue.kinds cannot be empty")
+	}
+	seen := make(map[string]bool)
+	for _, kind := range c.Kinds {
+		if !kindRegexp.MatchString(kind.Name) {
+			return fmt.Errorf("catalogue.kinds: invalid name %q, it must only contain letters", kind.Name)
+		}
+		if !colourRegexp.MatchString(kind.Colour) {
+			return fmt.Errorf("catalogue.kinds: invalid colour %q for %s", kind.Colour, kind.Name)
+		}
+		if seen[kind.Name] {
+			return fmt.Errorf("catalogue.kinds: %s is listed twice", kind.Name)
+		}
+		seen[kind.Name] = true
+	}
+
+	if len(c.Semesters) == 0 {
+		return fmt.Errorf("catalogue.semesters cannot be empty")
+	}
+	for _, semester := range c.Semesters {
+		if strings.TrimSpace(semester) == "" {
+			return fmt.Errorf("catalogue.semesters cannot hold an empty semester")
+		}
+	}
+
+	templates := make(map[string]bool)
+	for _, template := range c.Templates {
+		if strings.TrimSpace(template.Name) == "" {
+			return fmt.Errorf("catalogue.templates cannot hold a template without a name")
+		}
+		if templates[template.Name] {
+			return fmt.Errorf("catalogue.templates: %s is listed twice", template.Name)
+		}
+		templates[template.Name] = true
+		if len(template.Courses) == 0 {
+			return fmt.Errorf("catalogue.templates: %s has no course", template.Name)
+		}
+		for _, course := range template.Courses {
+			if !seen[course.Kind] {
+				return fmt.Errorf("catalogue.templates: unknown kind %q in %s", course.Kind, template.Name)
+			}
+			if course.Parts <= 0 || course.Parts >= 1000 {
+				return fmt.Errorf("catalogue.templates: parts of %s in %s must be in 1-1000", course.Kind, template.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Template returns the template with the given name.
+func (c Catalogue) Template(name string) (Template, error) {
+	i := slices.IndexFunc(c.Templates, func(t Template) bool { return t.Name == name })
+	if i < 0 {
+		return Template{}, notFound("no template named %s", name)
+	}
+	return c.Templates[i], nil
+}
+
+// Level returns the level of study captured from a course code.
+func (c Catalogue) Level(code string) (Level, error) {
+	for _, re := range c.patterns {
+		res := re.FindStringSubmatch(code)
+		if res == nil {
+			continue
+		}
+		if level, ok := c.Levels[res[1]]; ok {
+			return level, nil
+		}
+		if level, err := ParseLevel(res[1]); err == nil {
+			return level, nil
+		}
+		return LevelOther, nil
+	}
+	return "", invalid("code", "invalid course id")
+}
+
+// Kind returns the kind with the given name.
+func (c Catalogue) Kind(name string) (Kind, bool) {
+	i := slices.IndexFunc(c.Kinds, func(k Kind) bool { return k.Name == name })
+	if i < 0 {
+		return Kind{}, false
+	}
+	return c.Kinds[i], true
+}
+
+// KindOrder returns the sort rank of a kind, unknown kinds coming last.
+func (c Catalogue) KindOrder(name string) int {
+	if kind, ok := c.Kind(name); ok {
+		return kind.Order
+	}
+	return math.MaxInt
+}
+
+func (c Catalogue) validateKind(kind string) error {
+	if _, ok := c.Kind(kind); ok {
+		return nil
+	}
+	names := make([]string, len(c.Kinds))
+	for i, k := range c.Kinds {
+		names[i] = k.Name
+	}
+	return invalid("kind", "KIND must be %s", oneOf(names))
+}
+
+func (c Catalogue) validateSemester(semester string) error {
+	if slices.Contains(c.Semesters, semester) {
+		return nil
+	}
+	return invalid("semester", "SEMESTER must be %s", oneOf(c.Semesters))
+}
+
+func oneOf(values []string) string {
+	if len(values) == 2 {
+		return fmt.Sprintf("either %s or %s", values[0], values[1])
+	}
+	return "one of: " + strings.Join(values, ", ")
+}
+
+func defaultLevels() map[string]Level {
+	return map[string]Level{
+		"1": LevelL1,
+		"2": LevelL2,
+		"3": LevelL3,
+		"4": LevelM1,
+		"5": LevelM2,
+	}
+}
+
+var defaultCatalogue = func() Catalogue {
+	c := DefaultCatalogue()
+	if err := c.Compile(); err != nil {
+		panic(err)
+	}
+	return c
+}()
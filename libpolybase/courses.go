@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"log"
 	"regexp"
-	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-var codeRegexp = regexp.MustCompile(`^[LMU]{2}(\d)IN\d{3}$`)
+var (
+	codeRegexp = regexp.MustCompile(`^[A-Z0-9{}-]+$`)
+	kindRegexp = regexp.MustCompile(`^[a-zA-Z]+$`)
+)
 
 func (pb *PB) CreateCourse(ctx context.Context, user string, course Course) (Course, error) {
 	tx, err := pb.db.BeginTx(ctx, nil)
@@ -24,7 +28,115 @@ func (pb *PB) CreateCourse(ctx context.Context, user string, course Course) (Cou
 		}
 	}()
 
-	course, err = validateCourse(course)
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	course, err = pb.createCourse(ctx, tx, user, course)
+	if err != nil {
+		return Course{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	updatedCourse, err := pb.GetCourse(ctx, CourseID{course.Code, course.Kind, course.Part})
+	if err != nil {
+		return Course{}, fmt.Errorf("get updated course: %w", err)
+	}
+
+	details := fmt.Sprintf("created course %s", course.ID())
+	if err := pb.logAction(user, "CREATE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return updatedCourse, nil
+}
+
+// CreateCourses creates all the courses or none of them. The error of a
+// refused course names it.
+func (pb *PB) CreateCourses(ctx context.Context, user string, courses []Course) ([]Course, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	ids := make([]CourseID, len(courses))
+	for i, course := range courses {
+		created, err := pb.createCourse(ctx, tx, user, course)
+		if err != nil {
+			return nil, fmt.Errorf("course %s: %w", course.ID(), err)
+		}
+		ids[i] = created.CID()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	for _, id := range ids {
+		details := fmt.Sprintf("created course %s", id.ID())
+		if err := pb.logAction(user, "CREATE", details); err != nil {
+			log.Printf("Warning: failed to log action: %v", err)
+		}
+	}
+
+	return pb.getParts(ctx, ids)
+}
+
+func (pb *PB) CloneCourses(ctx context.Context, user string, opts CloneOptions) ([]Course, error) {
+	if opts.Part != 0 && opts.Kind == "" {
+		return nil, invalid("kind", "KIND is required to clone a single part")
+	}
+
+	source := pb
+	if opts.Year != 0 {
+		source = pb.forYear(opts.Year)
+	}
+	filter := CourseFilter{ShowHidden: true, Code: &opts.Code}
+	if opts.Kind != "" {
+		filter.Kinds = []string{opts.Kind}
+	}
+	if opts.Part != 0 {
+		filter.Part = &opts.Part
+	}
+	page, err := source.ListCourses(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Items) == 0 {
+		return nil, notFound("no course %s to clone in %s", strings.TrimSpace(strings.Join([]string{opts.Code, opts.Kind}, " ")), source.year)
+	}
+
+	copies := make([]Course, len(page.Items))
+	for i, course := range page.Items {
+		if opts.ToCode != "" {
+			course.Code = opts.ToCode
+		}
+		if opts.ToSemester != "" {
+			course.Semester = opts.ToSemester
+		}
+		course.Quantity = 0
+		copies[i] = course
+	}
+
+	return pb.CreateCourses(ctx, user, copies)
+}
+
+// createCourse adds a course in the transaction. A trashed course with the
+// same ID must be restored or purged first.
+func (pb *PB) createCourse(ctx context.Context, tx *sql.Tx, user string, course Course) (Course, error) {
+	course, err := pb.validateCourse(course)
 	if err != nil {
 		return Course{}, err
 	}
@@ -34,42 +146,48 @@ func (pb *PB) CreateCourse(ctx context.Context, user string, course Course) (Cou
 		return Course{}, fmt.Errorf("failed to check course existence: %w", err)
 	}
 	if exists {
-		return Course{}, fmt.Errorf("course already exists")
+		return Course{}, alreadyExists("course already exists")
 	}
 
-	_, err = ValidateCourseID(NewCourseID(course.Code, course.Kind, course.Part))
+	trashed, err := pb.trashed(ctx, course.CID(), tx)
 	if err != nil {
-		return Course{}, fmt.Errorf("invalid course id")
+		return Course{}, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if trashed {
+		return Course{}, alreadyExists("course %s already exists in the trash", course.ID())
 	}
 
-	course.Shown = true
-	if _, err := tx.ExecContext(ctx, `
-    INSERT INTO courses (code, kind, part, parts, name, quantity, total, shown, semester)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		course.Code, course.Kind, course.Part, course.Parts, course.Name,
-		course.Quantity, course.Total, course.Shown, course.Semester); err != nil {
-		return Course{}, fmt.Errorf("create course: %w", err)
+	_, err = pb.validateCourseID(NewCourseID(course.Code, course.Kind, course.Part))
+	if err != nil {
+		return Course{}, invalid("code", "invalid course id")
+	}
+
+	if err := pb.insertCourse(ctx, tx, user, course); err != nil {
+		return Course{}, err
 	}
 
 	if err := pb.setParts(ctx, CourseID{course.Code, course.Kind, course.Part}, tx); err != nil {
 		return Course{}, fmt.Errorf("set parts: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	// The price history starts with the price the course is created with
+	if course.Price != 0 {
+		id := course.CID()
+		if _, err := pb.insertPriceChange(ctx, tx, user, PriceChange{Course: &id, Price: &course.Price, EffectiveFrom: today()}); err != nil {
+			return Course{}, err
+		}
 	}
 
-	updatedCourse, err := pb.GetCourse(ctx, CourseID{course.Code, course.Kind, course.Part})
+	created, err := pb.getCourse(ctx, course.CID(), tx)
 	if err != nil {
-		return Course{}, fmt.Errorf("get updated course: %w", err)
+		return Course{}, fmt.Errorf("get created course: %w", err)
 	}
 
-	details := fmt.Sprintf("created course %s", course.ID())
-	if err := pb.logAction(user, "CREATE", details); err != nil {
-		log.Printf("Warning: failed to log action: %v", err)
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityCourse, course.ID(), nil, created); err != nil {
+		return Course{}, err
 	}
 
-	return updatedCourse, nil
+	return created, nil
 }
 
 func (pb *PB) UpdateCourse(ctx context.Context, user string, id CourseID, partial PartialCourse) (Course, error) {
@@ -83,11 +201,20 @@ func (pb *PB) UpdateCourse(ctx context.Context, user string, id CourseID, partia
 		}
 	}()
 
-	id, err = ValidateCourseID(id)
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	id, err = pb.validateCourseID(id)
 	if err != nil {
 		return Course{}, err
 	}
 
+	current, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get current course: %w", err)
+	}
+
 	course, err := pb.mergeCourse(ctx, id, partial, tx)
 	if err != nil {
 		return Course{}, err
@@ -99,18 +226,50 @@ func (pb *PB) UpdateCourse(ctx context.Context, user string, id CourseID, partia
 	}
 
 	if !exists {
-		return Course{}, fmt.Errorf("course does not exists")
+		return Course{}, notFound("course does not exists")
 	}
 
-	if _, err := tx.ExecContext(ctx, `
+	if course.CID() != id {
+		taken, err := pb.exists(ctx, course.CID(), tx)
+		if err != nil {
+			return Course{}, fmt.Errorf("failed to check course existence: %w", err)
+		}
+		if taken {
+			return Course{}, alreadyExists("course %s already exists", course.ID())
+		}
+
+		trashed, err := pb.trashed(ctx, course.CID(), tx)
+		if err != nil {
+			return Course{}, fmt.Errorf("failed to check trash: %w", err)
+		}
+		if trashed {
+			return Course{}, alreadyExists("course %s already exists in the trash", course.ID())
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
     UPDATE courses 
-    SET code = ?, kind = ?, part = ?, parts = ?, name = ?, quantity = ?, total = ?, shown = ?, semester = ?
-    WHERE code = ? AND kind = ? AND part = ?`,
+    SET code = ?, kind = ?, part = ?, parts = ?, name = ?, quantity = ?, total = ?, status = ?, shown = ?, semester = ?,
+      pages = ?, price = ?, print_cost = ?, teacher = ?, edition = ?, edition_date = ?, description = ?,
+      revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND revision = ?`,
 		course.Code, course.Kind, course.Part, course.Parts,
-		course.Name, course.Quantity, course.Total, course.Shown, course.Semester,
-		id.Code, id.Kind, id.Part,
-	); err != nil {
+		course.Name, course.Quantity, course.Total, course.Status, course.Shown, course.Semester,
+		course.Pages, course.Price, course.PrintCost, course.Teacher,
+		course.Edition, course.EditionDate, course.Description,
+		pb.year, id.Code, id.Kind, id.Part, course.Revision,
+	)
+	if err != nil {
+		return Course{}, fmt.Errorf("update course: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
 		return Course{}, fmt.Errorf("update course: %w", err)
+	} else if updated == 0 {
+		current, err := pb.getCourse(ctx, id, tx)
+		if err != nil {
+			return Course{}, fmt.Errorf("get current course: %w", err)
+		}
+		return Course{}, &RevisionConflict{Expected: course.Revision, Current: current.Revision}
 	}
 
 	if err := pb.setParts(ctx, CourseID{course.Code, course.Kind, course.Part}, tx); err != nil {
@@ -133,17 +292,37 @@ func (pb *PB) UpdateCourse(ctx context.Context, user string, id CourseID, partia
 			newID.Part = *partial.Part
 		}
 
-		_, err = tx.ExecContext(ctx, `
-            UPDATE pack_courses 
-            SET course_code = ?, course_kind = ?, course_part = ?
-            WHERE course_code = ? AND course_kind = ? AND course_part = ?`,
-			newID.Code, newID.Kind, newID.Part,
-			id.Code, id.Kind, id.Part)
-		if err != nil {
-			return Course{}, fmt.Errorf("update pack course references: %w", err)
+		if err := pb.renameCourseReferences(ctx, id, newID, tx); err != nil {
+			return Course{}, err
+		}
+	}
+
+	if partial.Tags != nil {
+		if err := pb.setCourseTags(ctx, tx, course.CID(), course.Tags); err != nil {
+			return Course{}, err
+		}
+	}
+
+	if course.Price != current.Price {
+		id := course.CID()
+		if _, err := pb.insertPriceChange(ctx, tx, user, PriceChange{Course: &id, Price: &course.Price, EffectiveFrom: today()}); err != nil {
+			return Course{}, err
 		}
 	}
 
+	if err := pb.recordMovement(ctx, tx, user, course.CID(), course.Quantity-current.Quantity, course.Quantity, ReasonCorrection, nil); err != nil {
+		return Course{}, err
+	}
+
+	updated, err := pb.getCourse(ctx, course.CID(), tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get updated course: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionUpdate, EntityCourse, course.ID(), current, updated); err != nil {
+		return Course{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Course{}, fmt.Errorf("commit transaction: %w", err)
 	}
@@ -172,48 +351,24 @@ func (pb *PB) DeleteCourse(ctx context.Context, user string, id CourseID) error
 		}
 	}()
 
-	exists, err := pb.exists(ctx, id, tx)
-	if err != nil {
-		return fmt.Errorf("failed to check course existence: %w", err)
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return err
 	}
 
-	if !exists {
-		return fmt.Errorf("course does not exists")
-	}
-
-	var maxPart int
-	err = tx.QueryRowContext(ctx, `
-        SELECT COALESCE(MAX(part), 0)
-        FROM courses 
-        WHERE code = ? AND kind = ? AND part != ?`,
-		id.Code, id.Kind, id.Part).Scan(&maxPart)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("get max part: %w", err)
+	current, err := pb.getCourse(ctx, id, tx)
+	if _, ok := err.(*CourseNotFound); ok {
+		return notFound("course does not exists")
 	}
-
-	_, err = tx.ExecContext(ctx, `
-        DELETE FROM pack_courses 
-        WHERE course_code = ? AND course_kind = ? AND course_part = ?`,
-		id.Code, id.Kind, id.Part)
 	if err != nil {
-		return fmt.Errorf("remove course from packs: %w", err)
+		return fmt.Errorf("failed to check course existence: %w", err)
 	}
 
-	_, err = tx.ExecContext(ctx, `
-        DELETE FROM courses
-        WHERE code = ? AND KIND = ? AND part = ?`,
-		id.Code, id.Kind, id.Part)
-	if err != nil {
-		return fmt.Errorf("delete course: %w", err)
+	if err := pb.trashCourse(ctx, user, id, tx); err != nil {
+		return err
 	}
 
-	_, err = tx.ExecContext(ctx, `
-        UPDATE courses
-        SET parts = ?
-        WHERE code = ? AND kind = ?`,
-		maxPart, id.Code, id.Kind)
-	if err != nil {
-		return fmt.Errorf("update parts: %w", err)
+	if _, err := pb.audit(ctx, tx, user, ActionDelete, EntityCourse, id.ID(), current, nil); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -229,7 +384,7 @@ func (pb *PB) DeleteCourse(ctx context.Context, user string, id CourseID) error
 }
 
 func (pb *PB) GetCourse(ctx context.Context, id CourseID) (Course, error) {
-	id, err := ValidateCourseID(id)
+	id, err := pb.validateCourseID(id)
 	if err != nil {
 		return Course{}, err
 	}
@@ -238,12 +393,17 @@ func (pb *PB) GetCourse(ctx context.Context, id CourseID) (Course, error) {
 	var shown int
 
 	err = pb.db.QueryRowContext(ctx, `
-    SELECT code, kind, part, parts, name, quantity, total, shown, semester
+    SELECT code, kind, part, parts, name, quantity, total, status, shown, semester,
+      pages, `+priceColumn("courses")+`, print_cost, teacher, edition, edition_date, description, revision,
+      `+courseTagsColumn("courses")+", "+hasMasterColumn("courses")+`
     FROM courses
-    WHERE code = ? AND kind = ? AND part = ?`,
-		id.Code, id.Kind, id.Part).Scan(
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		pb.year, id.Code, id.Kind, id.Part).Scan(
 		&course.Code, &course.Kind, &course.Part, &course.Parts,
-		&course.Name, &course.Quantity, &course.Total, &shown, &course.Semester)
+		&course.Name, &course.Quantity, &course.Total, &course.Status, &shown, &course.Semester,
+		&course.Pages, &course.Price, &course.PrintCost, &course.Teacher,
+		&course.Edition, &course.EditionDate, &course.Description,
+		&course.Revision, (*tagList)(&course.Tags), &course.HasMaster)
 
 	if err == sql.ErrNoRows {
 		return Course{}, &CourseNotFound{}
@@ -255,99 +415,225 @@ func (pb *PB) GetCourse(ctx context.Context, id CourseID) (Course, error) {
 
 	course.Shown = shown == 1
 
-	course.Year, err = GetYear(course.Code)
+	course.Level, err = pb.catalogue.Level(course.Code)
 
 	return course, err
 }
 
-func (pb *PB) ListCourse(ctx context.Context, showHidden bool, filterSemester *string, filterCode *string, filterKind *string, filterPart *int) ([]Course, error) {
-	var courses []Course
-	var conditions []string
-	var args []any
+// ListCourses lists the live courses matching the filter, a page at a time
+// when the filter has a limit.
+func (pb *PB) ListCourses(ctx context.Context, filter CourseFilter) (Page[Course], error) {
+	conditions := []string{"academic_year = ?", "deleted_at IS NULL"}
+	args := []any{pb.year}
 
-	if !showHidden {
+	if !filter.ShowHidden {
 		conditions = append(conditions, "shown = 1")
 	}
 
-	if filterSemester != nil {
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + likeEscaper.Replace(search) + "%"
+		conditions = append(conditions, `(name LIKE ? ESCAPE '\' OR code LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern)
+	}
+
+	if filter.Semester != nil {
 		conditions = append(conditions, "semester = ?")
-		args = append(args, *filterSemester)
+		args = append(args, *filter.Semester)
 	}
 
-	if filterCode != nil {
+	if filter.Code != nil {
 		conditions = append(conditions, "code = ?")
-		args = append(args, *filterCode)
+		args = append(args, *filter.Code)
 	}
 
-	if filterKind != nil {
-		conditions = append(conditions, "kind = ?")
-		args = append(args, *filterKind)
+	if filter.Part != nil {
+		conditions = append(conditions, "part = ?")
+		args = append(args, *filter.Part)
 	}
 
-	if filterPart != nil {
-		conditions = append(conditions, "part = ?")
-		args = append(args, *filterPart)
+	if len(filter.Kinds) > 0 {
+		conditions = append(conditions, "kind IN (?"+strings.Repeat(", ?", len(filter.Kinds)-1)+")")
+		for _, kind := range filter.Kinds {
+			args = append(args, kind)
+		}
+	}
+
+	if len(filter.Statuses) > 0 {
+		conditions = append(conditions, "status IN (?"+strings.Repeat(", ?", len(filter.Statuses)-1)+")")
+		for _, status := range filter.Statuses {
+			status, err := ParseStatus(string(status))
+			if err != nil {
+				return Page[Course]{}, err
+			}
+			args = append(args, status)
+		}
 	}
 
-	query := `SELECT code, kind, part, parts, name, quantity, total, shown, semester FROM courses`
-	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+	if filter.Level != "" {
+		level, err := ParseLevel(string(filter.Level))
+		if err != nil {
+			return Page[Course]{}, err
+		}
+		codes, err := pb.codesAtLevel(ctx, level)
+		if err != nil {
+			return Page[Course]{}, err
+		}
+		if len(codes) == 0 {
+			return Page[Course]{}, nil
+		}
+		conditions = append(conditions, "code IN (?"+strings.Repeat(", ?", len(codes)-1)+")")
+		args = append(args, codes...)
+	}
+
+	switch filter.Stock {
+	case "":
+	case StockLow:
+		conditions = append(conditions, "quantity * 100 <= total * ?")
+		args = append(args, LowStockPercent)
+	case StockOut:
+		conditions = append(conditions, "quantity = 0")
+	default:
+		return Page[Course]{}, invalid("stock", "stock must be either low or out")
+	}
+
+	if filter.PackID != nil {
+		conditions = append(conditions, `EXISTS (
+      SELECT 1 FROM pack_courses pc
+      WHERE pc.pack_id = ? AND pc.academic_year = courses.academic_year AND pc.course_code = code AND pc.course_kind = kind AND pc.course_part = part
+    )`)
+		args = append(args, *filter.PackID)
+	}
+
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, `EXISTS (
+      SELECT 1 FROM course_tags t
+      WHERE t.academic_year = courses.academic_year AND t.course_code = code AND t.course_kind = kind AND t.course_part = part AND t.tag = ?
+    )`)
+		args = append(args, strings.Join(strings.Fields(tag), " "))
+	}
+
+	if filter.MissingMaster {
+		conditions = append(conditions, "NOT "+hasMasterColumn("courses"))
+	}
+
+	order, err := courseOrder(filter.Sort, filter.Desc)
+	if err != nil {
+		return Page[Course]{}, err
+	}
+
+	offset, err := decodeCursor(filter.Cursor)
+	if err != nil {
+		return Page[Course]{}, err
+	}
+
+	query := `SELECT code, kind, part, parts, name, quantity, total, status, shown, semester,
+    pages, ` + priceColumn("courses") + `, print_cost, teacher, edition, edition_date, description, revision,
+    ` + courseTagsColumn("courses") + ", " + hasMasterColumn("courses") + ` FROM courses`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY " + order
+	if filter.Limit > 0 {
+		// One more row tells whether there is a next page
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, filter.Limit+1, offset)
+	} else if offset > 0 {
+		query += " LIMIT -1 OFFSET ?"
+		args = append(args, offset)
 	}
-	query += " ORDER BY semester DESC, code ASC, kind ASC, part ASC"
 
 	rows, err := pb.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list courses: %w", err)
+		return Page[Course]{}, fmt.Errorf("list courses: %w", err)
 	}
 	defer rows.Close()
 
+	var page Page[Course]
 	for rows.Next() {
 		var c Course
 
 		if err := rows.Scan(&c.Code, &c.Kind, &c.Part, &c.Parts, &c.Name,
-			&c.Quantity, &c.Total, &c.Shown, &c.Semester); err != nil {
-			return nil, fmt.Errorf("scan course: %w", err)
+			&c.Quantity, &c.Total, &c.Status, &c.Shown, &c.Semester,
+			&c.Pages, &c.Price, &c.PrintCost, &c.Teacher, &c.Edition, &c.EditionDate, &c.Description,
+			&c.Revision, (*tagList)(&c.Tags), &c.HasMaster); err != nil {
+			return Page[Course]{}, fmt.Errorf("scan course: %w", err)
 		}
 
 		var errIn error
-		c.Year, errIn = GetYear(c.Code)
+		c.Level, errIn = pb.catalogue.Level(c.Code)
 		if errIn != nil {
 			err = errors.Join(err, errIn, fmt.Errorf("invalid course %s (%s)", c.Name, c.Code))
 		} else {
-			courses = append(courses, c)
+			page.Items = append(page.Items, c)
 		}
 	}
 	if err != nil {
-		return nil, err
+		return Page[Course]{}, err
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate courses: %w", err)
+		return Page[Course]{}, fmt.Errorf("iterate courses: %w", err)
 	}
 
-	return courses, nil
+	if filter.Limit > 0 && len(page.Items) > filter.Limit {
+		page.Items = page.Items[:filter.Limit]
+		page.NextCursor = encodeCursor(offset + filter.Limit)
+	}
+
+	return page, nil
 }
 
 func (pb *PB) UpdateCourseQuantity(ctx context.Context, user string, id CourseID, delta int) (Course, error) {
-	id, err := ValidateCourseID(id)
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
 	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
 		return Course{}, err
 	}
 
-	current, err := pb.GetCourse(ctx, id)
+	current, err := pb.getCourse(ctx, id, tx)
 	if err != nil {
 		return Course{}, fmt.Errorf("failed to get current course: %w", err)
 	}
 
 	newQuantity := clampQuantity(current.Quantity+delta, current.Total)
 
-	if _, err = pb.db.ExecContext(ctx, ` UPDATE courses 
-    SET quantity = ?
-    WHERE code = ? AND kind = ? AND part = ?`,
-		newQuantity, id.Code, id.Kind, id.Part); err != nil {
+	if _, err = tx.ExecContext(ctx, ` UPDATE courses 
+    SET quantity = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		newQuantity, pb.year, id.Code, id.Kind, id.Part); err != nil {
 		return Course{}, fmt.Errorf("update quantity: %w", err)
 	}
 
+	applied := newQuantity - current.Quantity
+	if err := pb.recordMovement(ctx, tx, user, id, applied, newQuantity, quantityReason(applied), nil); err != nil {
+		return Course{}, err
+	}
+
+	if applied != 0 {
+		updated, err := pb.getCourse(ctx, id, tx)
+		if err != nil {
+			return Course{}, fmt.Errorf("get updated course: %w", err)
+		}
+		if _, err := pb.audit(ctx, tx, user, ActionQuantity, EntityCourse, id.ID(), current, updated); err != nil {
+			return Course{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
 	details := fmt.Sprintf("updated quantity of course %s", id.ID())
 	if err := pb.logAction(user, "UPDATE QUANTITY", details); err != nil {
 		log.Printf("Warning: failed to log action: %v", err)
@@ -356,32 +642,144 @@ func (pb *PB) UpdateCourseQuantity(ctx context.Context, user string, id CourseID
 	return pb.GetCourse(ctx, id)
 }
 
-func (pb *PB) UpdateCourseShown(ctx context.Context, user string, id CourseID, shown bool) (Course, error) {
-	id, err := ValidateCourseID(id)
+// insertCourse adds a live course, its quantity recorded as a restock.
+func (pb *PB) insertCourse(ctx context.Context, tx *sql.Tx, user string, course Course) error {
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO courses (academic_year, code, kind, part, parts, name, quantity, total, status, shown, semester,
+      pages, price, print_cost, teacher, edition, edition_date, description)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, course.Code, course.Kind, course.Part, course.Parts, course.Name,
+		course.Quantity, course.Total, course.Status, course.Shown, course.Semester,
+		course.Pages, course.Price, course.PrintCost, course.Teacher,
+		course.Edition, course.EditionDate, course.Description); err != nil {
+		return fmt.Errorf("create course: %w", err)
+	}
+
+	if err := pb.setCourseTags(ctx, tx, course.CID(), course.Tags); err != nil {
+		return err
+	}
+
+	return pb.recordMovement(ctx, tx, user, course.CID(), course.Quantity, course.Quantity, ReasonRestock, nil)
+}
+
+// trashCourse moves a course to the trash. Its pack memberships are kept so
+// that restoring it puts it back in its packs.
+func (pb *PB) trashCourse(ctx context.Context, user string, id CourseID, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET deleted_at = ?, deleted_by = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		time.Now().UTC(), user, pb.year, id.Code, id.Kind, id.Part)
 	if err != nil {
-		return Course{}, err
+		return fmt.Errorf("delete course: %w", err)
 	}
 
-	shownInt := 0
-	if shown {
-		shownInt = 1
+	if err := pb.setParts(ctx, id, tx); err != nil {
+		return fmt.Errorf("update parts: %w", err)
 	}
-	_, err = pb.db.ExecContext(ctx, `
-    UPDATE courses 
-    SET shown = ?
-    WHERE code = ? AND kind = ? AND part = ?`,
-		shownInt, id.Code, id.Kind, id.Part,
-	)
+	return nil
+}
+
+// renameCourseReferences points the references of a course, its stock
+// movements and its audit events to its new ID.
+func (pb *PB) renameCourseReferences(ctx context.Context, from CourseID, to CourseID, tx *sql.Tx) error {
+	if err := pb.moveCourseReferences(ctx, from, to, tx); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+    UPDATE stock_movements
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
 	if err != nil {
-		return Course{}, fmt.Errorf("update shown: %w", err)
+		return fmt.Errorf("update stock movement references: %w", err)
 	}
 
-	details := fmt.Sprintf("updated visibility of course %s", id.ID())
-	if err := pb.logAction(user, "UPDATE VISIBILITY", details); err != nil {
-		log.Printf("Warning: failed to log action: %v", err)
+	_, err = tx.ExecContext(ctx, `
+    UPDATE audit_events
+    SET entity_id = ?
+    WHERE academic_year = ? AND entity_type = ? AND entity_id = ?`,
+		to.ID(), pb.year, string(EntityCourse), from.ID())
+	if err != nil {
+		return fmt.Errorf("update audit event references: %w", err)
+	}
+	return nil
+}
+
+// moveCourseReferences points the pack memberships, editions, tags, notes,
+// master files and price history of a course to another ID, leaving its
+// history behind. A course taking the references of another it is merged with
+// keeps a single copy of their common tags.
+func (pb *PB) moveCourseReferences(ctx context.Context, from CourseID, to CourseID, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE pack_courses 
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update pack course references: %w", err)
 	}
 
-	return pb.GetCourse(ctx, id)
+	_, err = tx.ExecContext(ctx, `
+    UPDATE course_editions
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update edition references: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE OR IGNORE course_tags
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update tag references: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM course_tags
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("remove duplicate tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE notes
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update note references: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE master_files
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update master file references: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE price_changes
+    SET course_code = ?, course_kind = ?, course_part = ?
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		to.Code, to.Kind, to.Part,
+		pb.year, from.Code, from.Kind, from.Part)
+	if err != nil {
+		return fmt.Errorf("update price references: %w", err)
+	}
+	return nil
 }
 
 func (pb *PB) setParts(ctx context.Context, courseID CourseID, tx *sql.Tx) error {
@@ -389,8 +787,8 @@ func (pb *PB) setParts(ctx context.Context, courseID CourseID, tx *sql.Tx) error
 	err := tx.QueryRowContext(ctx, `
     SELECT COALESCE(MAX(part), 0)
     FROM courses 
-    WHERE code = ? AND kind = ?`,
-		courseID.Code, courseID.Kind).Scan(&maxPart)
+    WHERE academic_year = ? AND code = ? AND kind = ? AND deleted_at IS NULL`,
+		pb.year, courseID.Code, courseID.Kind).Scan(&maxPart)
 	if err != nil {
 		return fmt.Errorf("get max part: %w", err)
 	}
@@ -398,31 +796,22 @@ func (pb *PB) setParts(ctx context.Context, courseID CourseID, tx *sql.Tx) error
 	_, err = tx.ExecContext(ctx, `
     UPDATE courses
     SET parts = ?
-    WHERE code = ? AND kind = ?`,
-		maxPart, courseID.Code, courseID.Kind)
+    WHERE academic_year = ? AND code = ? AND kind = ? AND deleted_at IS NULL`,
+		maxPart, pb.year, courseID.Code, courseID.Kind)
 	if err != nil {
 		return fmt.Errorf("update parts: %w", err)
 	}
 	return nil
 }
 
-func GetYear(code string) (int, error) {
-	res := codeRegexp.FindStringSubmatch(code)
-	if len(res) != 2 {
-		return 0, fmt.Errorf("invalid course id")
-	}
-	return strconv.Atoi(res[1])
-
-}
-
-func validateCourse(course Course) (Course, error) {
+func (pb *PB) validateCourse(course Course) (Course, error) {
 	// Validate Code
 	course.Code = strings.TrimSpace(course.Code)
 	if course.Code == "" {
-		return Course{}, fmt.Errorf("CODE cannot be empty")
+		return Course{}, invalid("code", "CODE cannot be empty")
 	}
 	var err error
-	course.Year, err = GetYear(course.Code)
+	course.Level, err = pb.catalogue.Level(course.Code)
 	if err != nil {
 		return Course{}, err
 	}
@@ -430,18 +819,15 @@ func validateCourse(course Course) (Course, error) {
 	// Validate Kind
 	course.Kind = strings.TrimSpace(course.Kind)
 	if course.Kind == "" {
-		return Course{}, fmt.Errorf("KIND cannot be empty")
+		return Course{}, invalid("kind", "KIND cannot be empty")
 	}
-	switch course.Kind {
-	case "TD", "Cours", "Memento", "TME":
-		// valid
-	default:
-		return Course{}, fmt.Errorf("KIND must be one of: TD, Cours, Memento, TME")
+	if err := pb.catalogue.validateKind(course.Kind); err != nil {
+		return Course{}, err
 	}
 
 	// Validate Part
 	if course.Part <= 0 || course.Part >= 1000 {
-		return Course{}, fmt.Errorf("PART must be in 1-1000")
+		return Course{}, invalid("part", "PART must be in 1-1000")
 	}
 
 	// Validate Name
@@ -454,11 +840,26 @@ func validateCourse(course Course) (Course, error) {
 
 	// Validate Semester
 	course.Semester = strings.TrimSpace(course.Semester)
-	if course.Semester != "S1" && course.Semester != "S2" {
-		return Course{}, fmt.Errorf("SEMESTER must be either S1 or S2")
+	if err := pb.catalogue.validateSemester(course.Semester); err != nil {
+		return Course{}, err
 	}
 
-	return course, nil
+	// Validate Status, new courses being available unless told otherwise
+	if course.Status == "" {
+		course.Status = StatusAvailable
+	}
+	course.Status, err = ParseStatus(string(course.Status))
+	if err != nil {
+		return Course{}, err
+	}
+	course.Shown = course.Status.Visible()
+
+	course.Tags, err = validateTags(course.Tags)
+	if err != nil {
+		return Course{}, err
+	}
+
+	return validateCourseDetails(course)
 }
 
 func (pb *PB) mergeCourse(ctx context.Context, id CourseID, partial PartialCourse, tx *sql.Tx) (Course, error) {
@@ -469,9 +870,18 @@ func (pb *PB) mergeCourse(ctx context.Context, id CourseID, partial PartialCours
 		partial.Name == nil &&
 		partial.Quantity == nil &&
 		partial.Total == nil &&
+		partial.Status == nil &&
 		partial.Shown == nil &&
-		partial.Semester == nil {
-		return Course{}, fmt.Errorf("at least one field must be updated")
+		partial.Semester == nil &&
+		partial.Pages == nil &&
+		partial.Price == nil &&
+		partial.PrintCost == nil &&
+		partial.Teacher == nil &&
+		partial.Edition == nil &&
+		partial.EditionDate == nil &&
+		partial.Description == nil &&
+		partial.Tags == nil {
+		return Course{}, invalid("", "at least one field must be updated")
 	}
 
 	current, err := pb.getCourse(ctx, id, tx)
@@ -479,16 +889,30 @@ func (pb *PB) mergeCourse(ctx context.Context, id CourseID, partial PartialCours
 		return Course{}, fmt.Errorf("get current course: %w", err)
 	}
 
+	if partial.Revision != nil && *partial.Revision != current.Revision {
+		return Course{}, &RevisionConflict{Expected: *partial.Revision, Current: current.Revision}
+	}
+
 	course := Course{
-		Code:     current.Code,
-		Kind:     current.Kind,
-		Part:     current.Part,
-		Parts:    current.Parts,
-		Name:     current.Name,
-		Quantity: current.Quantity,
-		Total:    current.Total,
-		Shown:    current.Shown,
-		Semester: current.Semester,
+		Code:        current.Code,
+		Kind:        current.Kind,
+		Part:        current.Part,
+		Parts:       current.Parts,
+		Name:        current.Name,
+		Quantity:    current.Quantity,
+		Total:       current.Total,
+		Status:      current.Status,
+		Shown:       current.Shown,
+		Semester:    current.Semester,
+		Pages:       current.Pages,
+		Price:       current.Price,
+		PrintCost:   current.PrintCost,
+		Teacher:     current.Teacher,
+		Edition:     current.Edition,
+		EditionDate: current.EditionDate,
+		Description: current.Description,
+		Tags:        current.Tags,
+		Revision:    current.Revision,
 	}
 
 	if partial.Code != nil {
@@ -512,12 +936,82 @@ func (pb *PB) mergeCourse(ctx context.Context, id CourseID, partial PartialCours
 	if partial.Total != nil {
 		course.Total = *partial.Total
 	}
-	if partial.Shown != nil {
-		course.Shown = *partial.Shown
+	switch {
+	case partial.Status != nil:
+		course.Status, err = ParseStatus(string(*partial.Status))
+		if err != nil {
+			return Course{}, err
+		}
+	case partial.Shown != nil:
+		course.Status = shownStatus(current.Status, *partial.Shown)
+	}
+	if err := checkTransition(current.Status, course.Status); err != nil {
+		return Course{}, err
 	}
 	if partial.Semester != nil {
 		course.Semester = *partial.Semester
 	}
+	if partial.Pages != nil {
+		course.Pages = *partial.Pages
+	}
+	if partial.Price != nil {
+		course.Price = *partial.Price
+	}
+	if partial.PrintCost != nil {
+		course.PrintCost = *partial.PrintCost
+	}
+	if partial.Teacher != nil {
+		course.Teacher = *partial.Teacher
+	}
+	if partial.Edition != nil {
+		course.Edition = *partial.Edition
+	}
+	if partial.EditionDate != nil {
+		course.EditionDate = *partial.EditionDate
+	}
+	if partial.Description != nil {
+		course.Description = *partial.Description
+	}
+	if partial.Tags != nil {
+		course.Tags = *partial.Tags
+	}
+
+	return pb.validateCourse(course)
+}
+
+// validateCourseDetails checks the optional details of a course.
+func validateCourseDetails(course Course) (Course, error) {
+	if course.Pages < 0 {
+		return Course{}, invalid("pages", "page count cannot be negative")
+	}
+	if course.Price < 0 {
+		return Course{}, invalid("price", "price cannot be negative")
+	}
+	if course.PrintCost < 0 {
+		return Course{}, invalid("print_cost", "print cost cannot be negative")
+	}
+
+	course.Teacher = strings.TrimSpace(course.Teacher)
+	if utf8.RuneCountInString(course.Teacher) > 100 {
+		return Course{}, invalid("teacher", "teacher cannot exceed 100 characters")
+	}
+
+	course.Edition = strings.TrimSpace(course.Edition)
+	if utf8.RuneCountInString(course.Edition) > 100 {
+		return Course{}, invalid("edition", "edition cannot exceed 100 characters")
+	}
 
-	return validateCourse(course)
+	course.EditionDate = strings.TrimSpace(course.EditionDate)
+	if course.EditionDate != "" {
+		if _, err := time.Parse(time.DateOnly, course.EditionDate); err != nil {
+			return Course{}, invalid("edition_date", "edition date must be written as YYYY-MM-DD")
+		}
+	}
+
+	course.Description = strings.TrimSpace(course.Description)
+	if utf8.RuneCountInString(course.Description) > 2000 {
+		return Course{}, invalid("description", "description cannot exceed 2000 characters")
+	}
+
+	return course, nil
 }
@@ -0,0 +1,545 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// initialEditionLabel names the edition created for the stock a course had
+// before its first edition was added, when the course has no edition label.
+const initialEditionLabel = "initial"
+
+func (pb *PB) ListEditions(ctx context.Context, id CourseID) ([]Edition, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := pb.exists(ctx, id, pb.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return nil, notFound("course %s does not exist", id.ID())
+	}
+
+	return pb.listEditions(ctx, id, pb.db)
+}
+
+func (pb *PB) AddEdition(ctx context.Context, user string, id CourseID, edition Edition) (Edition, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	edition, err = validateEdition(edition)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Edition{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Edition{}, err
+	}
+
+	course, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Edition{}, fmt.Errorf("get course: %w", err)
+	}
+
+	newQuantity := course.Quantity + edition.Quantity
+	if newQuantity > course.Total {
+		return Edition{}, invalid("quantity", "quantity (%d) cannot exceed total (%d)", newQuantity, course.Total)
+	}
+
+	editions, err := pb.listEditions(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	// The stock on the shelf before the first edition becomes an edition of
+	// its own, so that it is distributed first
+	if len(liveEditions(editions)) == 0 && course.Quantity > 0 {
+		label := course.Edition
+		if label == "" {
+			label = initialEditionLabel
+		}
+		initialID, err := pb.insertEdition(ctx, tx, user, id, Edition{Label: label, Date: course.EditionDate, Quantity: course.Quantity})
+		if err != nil {
+			return Edition{}, err
+		}
+		initial, err := pb.getEdition(ctx, initialID, tx)
+		if err != nil {
+			return Edition{}, err
+		}
+		if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityEdition, editionEntityID(initialID), nil, initial); err != nil {
+			return Edition{}, err
+		}
+	}
+
+	editionID, err := pb.insertEdition(ctx, tx, user, id, edition)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET quantity = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		newQuantity, pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return Edition{}, fmt.Errorf("update quantity: %w", err)
+	}
+
+	if err := pb.insertMovement(ctx, tx, user, id, edition.Quantity, newQuantity, ReasonRestock, nil); err != nil {
+		return Edition{}, err
+	}
+
+	if err := pb.setCurrentEdition(ctx, tx, id, &editionID); err != nil {
+		return Edition{}, err
+	}
+
+	created, err := pb.getEdition(ctx, editionID, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityEdition, editionEntityID(editionID), nil, created); err != nil {
+		return Edition{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Edition{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("added edition %s to course %s", created.Label, id.ID())
+	if err := pb.logAction(user, "CREATE EDITION", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return created, nil
+}
+
+func (pb *PB) UpdateEdition(ctx context.Context, user string, id int, partial PartialEdition) (Edition, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Edition{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Edition{}, err
+	}
+
+	current, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	edition := current
+	if partial.Label != nil {
+		edition.Label = *partial.Label
+	}
+	if partial.Date != nil {
+		edition.Date = *partial.Date
+	}
+	if partial.Notes != nil {
+		edition.Notes = *partial.Notes
+	}
+
+	edition, err = validateEdition(edition)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE course_editions
+    SET label = ?, date = ?, notes = ?
+    WHERE academic_year = ? AND id = ?`,
+		edition.Label, edition.Date, edition.Notes, pb.year, id); err != nil {
+		return Edition{}, fmt.Errorf("update edition: %w", err)
+	}
+
+	if edition.Current {
+		if err := pb.setCurrentEdition(ctx, tx, edition.Course, &id); err != nil {
+			return Edition{}, err
+		}
+	}
+
+	updated, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionUpdate, EntityEdition, editionEntityID(id), current, updated); err != nil {
+		return Edition{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Edition{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("updated edition %s of course %s", updated.Label, updated.Course.ID())
+	if err := pb.logAction(user, "UPDATE EDITION", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return updated, nil
+}
+
+func (pb *PB) SetCurrentEdition(ctx context.Context, user string, id int) (Edition, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Edition{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Edition{}, err
+	}
+
+	current, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+	if current.RetiredAt != nil {
+		return Edition{}, conflict("edition %s is retired", current.Label)
+	}
+	if current.Current {
+		return current, nil
+	}
+
+	if err := pb.setCurrentEdition(ctx, tx, current.Course, &id); err != nil {
+		return Edition{}, err
+	}
+
+	updated, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionUpdate, EntityEdition, editionEntityID(id), current, updated); err != nil {
+		return Edition{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Edition{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("made edition %s current for course %s", updated.Label, updated.Course.ID())
+	if err := pb.logAction(user, "UPDATE EDITION", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return updated, nil
+}
+
+// RetireEdition removes the remaining copies of an edition from the course
+// stock. When the edition was the current one, the newest edition left
+// becomes current.
+func (pb *PB) RetireEdition(ctx context.Context, user string, id int) (Edition, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Edition{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Edition{}, err
+	}
+
+	current, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+	if current.RetiredAt != nil {
+		return Edition{}, conflict("edition %s is already retired", current.Label)
+	}
+
+	course, err := pb.getCourse(ctx, current.Course, tx)
+	if err != nil {
+		return Edition{}, fmt.Errorf("get course: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE course_editions
+    SET quantity = 0, retired_at = ?, retired_by = ?
+    WHERE academic_year = ? AND id = ?`,
+		time.Now().UTC(), user, pb.year, id); err != nil {
+		return Edition{}, fmt.Errorf("retire edition: %w", err)
+	}
+
+	newQuantity := max(course.Quantity-current.Quantity, 0)
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET quantity = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		newQuantity, pb.year, course.Code, course.Kind, course.Part); err != nil {
+		return Edition{}, fmt.Errorf("update quantity: %w", err)
+	}
+
+	if err := pb.insertMovement(ctx, tx, user, course.CID(), newQuantity-course.Quantity, newQuantity, ReasonWriteOff, nil); err != nil {
+		return Edition{}, err
+	}
+
+	if current.Current {
+		editions, err := pb.listEditions(ctx, course.CID(), tx)
+		if err != nil {
+			return Edition{}, err
+		}
+		var next *int
+		if live := liveEditions(editions); len(live) > 0 {
+			next = &live[len(live)-1].ID
+		}
+		if err := pb.setCurrentEdition(ctx, tx, course.CID(), next); err != nil {
+			return Edition{}, err
+		}
+	}
+
+	retired, err := pb.getEdition(ctx, id, tx)
+	if err != nil {
+		return Edition{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionRetire, EntityEdition, editionEntityID(id), current, retired); err != nil {
+		return Edition{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Edition{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("retired edition %s of course %s", retired.Label, retired.Course.ID())
+	if err := pb.logAction(user, "RETIRE EDITION", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return retired, nil
+}
+
+// allocateEditions spreads a change of the quantity of a course over its live
+// editions: copies are taken from the oldest editions first and added to the
+// current one. Courses without editions are left alone.
+func (pb *PB) allocateEditions(ctx context.Context, tx *sql.Tx, id CourseID, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	editions, err := pb.listEditions(ctx, id, tx)
+	if err != nil {
+		return err
+	}
+	live := liveEditions(editions)
+	if len(live) == 0 {
+		return nil
+	}
+
+	changes := make(map[int]int)
+	if delta > 0 {
+		target := live[len(live)-1]
+		for _, edition := range live {
+			if edition.Current {
+				target = edition
+			}
+		}
+		changes[target.ID] = delta
+	} else {
+		remaining := -delta
+		for _, edition := range live {
+			if remaining == 0 {
+				break
+			}
+			taken := min(edition.Quantity, remaining)
+			if taken > 0 {
+				changes[edition.ID] = -taken
+				remaining -= taken
+			}
+		}
+	}
+
+	for editionID, change := range changes {
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE course_editions
+      SET quantity = quantity + ?
+      WHERE academic_year = ? AND id = ?`,
+			change, pb.year, editionID); err != nil {
+			return fmt.Errorf("update edition quantity: %w", err)
+		}
+	}
+	return nil
+}
+
+// setCurrentEdition points a course to its current edition, copying the label
+// and date of the edition to the course. A nil edition clears the pointer and
+// keeps the labels of the course.
+func (pb *PB) setCurrentEdition(ctx context.Context, tx *sql.Tx, course CourseID, editionID *int) error {
+	if editionID == nil {
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE courses
+      SET current_edition = NULL, revision = revision + 1
+      WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+			pb.year, course.Code, course.Kind, course.Part); err != nil {
+			return fmt.Errorf("set current edition: %w", err)
+		}
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET current_edition = e.id, edition = e.label, edition_date = e.date, revision = revision + 1
+    FROM course_editions e
+    WHERE e.id = ? AND courses.academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		*editionID, pb.year, course.Code, course.Kind, course.Part); err != nil {
+		return fmt.Errorf("set current edition: %w", err)
+	}
+	return nil
+}
+
+func (pb *PB) insertEdition(ctx context.Context, tx *sql.Tx, user string, course CourseID, edition Edition) (int, error) {
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO course_editions (academic_year, course_code, course_kind, course_part, label, date, notes, quantity, created_at, created_by)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, course.Code, course.Kind, course.Part,
+		edition.Label, edition.Date, edition.Notes, edition.Quantity, time.Now().UTC(), user)
+	if err != nil {
+		return 0, fmt.Errorf("create edition: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("get edition id: %w", err)
+	}
+	return int(id), nil
+}
+
+const editionColumns = `e.id, e.course_code, e.course_kind, e.course_part, e.label, e.date, e.notes, e.quantity,
+    COALESCE(c.current_edition = e.id, 0), e.created_at, e.created_by, e.retired_at, e.retired_by
+    FROM course_editions e
+    LEFT JOIN courses c ON c.academic_year = e.academic_year AND c.code = e.course_code AND c.kind = e.course_kind AND c.part = e.course_part`
+
+func (pb *PB) getEdition(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (Edition, error) {
+	row := querier.QueryRowContext(ctx, "SELECT "+editionColumns+`
+    WHERE e.academic_year = ? AND e.id = ? AND c.deleted_at IS NULL`,
+		pb.year, id)
+	edition, err := scanEdition(row)
+	if err == sql.ErrNoRows {
+		return Edition{}, notFound("edition %d does not exist", id)
+	}
+	if err != nil {
+		return Edition{}, fmt.Errorf("get edition: %w", err)
+	}
+	return edition, nil
+}
+
+func (pb *PB) listEditions(ctx context.Context, course CourseID, querier interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) ([]Edition, error) {
+	rows, err := querier.QueryContext(ctx, "SELECT "+editionColumns+`
+    WHERE e.academic_year = ? AND e.course_code = ? AND e.course_kind = ? AND e.course_part = ?
+    ORDER BY e.id`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return nil, fmt.Errorf("list editions: %w", err)
+	}
+	defer rows.Close()
+
+	var editions []Edition
+	for rows.Next() {
+		edition, err := scanEdition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan edition: %w", err)
+		}
+		editions = append(editions, edition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate editions: %w", err)
+	}
+	return editions, nil
+}
+
+func scanEdition(row interface{ Scan(...any) error }) (Edition, error) {
+	var e Edition
+	var retiredAt sql.NullTime
+	var retiredBy sql.NullString
+	if err := row.Scan(&e.ID, &e.Course.Code, &e.Course.Kind, &e.Course.Part,
+		&e.Label, &e.Date, &e.Notes, &e.Quantity, &e.Current,
+		&e.CreatedAt, &e.CreatedBy, &retiredAt, &retiredBy); err != nil {
+		return Edition{}, err
+	}
+	if retiredAt.Valid {
+		e.RetiredAt = &retiredAt.Time
+	}
+	e.RetiredBy = retiredBy.String
+	return e, nil
+}
+
+// liveEditions keeps the editions that are not retired, in order.
+func liveEditions(editions []Edition) []Edition {
+	var live []Edition
+	for _, edition := range editions {
+		if edition.RetiredAt == nil {
+			live = append(live, edition)
+		}
+	}
+	return live
+}
+
+func validateEdition(edition Edition) (Edition, error) {
+	edition.Label = strings.TrimSpace(edition.Label)
+	if edition.Label == "" {
+		return Edition{}, invalid("label", "label cannot be empty")
+	}
+	if utf8.RuneCountInString(edition.Label) > 100 {
+		return Edition{}, invalid("label", "label cannot exceed 100 characters")
+	}
+
+	edition.Date = strings.TrimSpace(edition.Date)
+	if edition.Date != "" {
+		if _, err := time.Parse(time.DateOnly, edition.Date); err != nil {
+			return Edition{}, invalid("date", "date must be written as YYYY-MM-DD")
+		}
+	}
+
+	edition.Notes = strings.TrimSpace(edition.Notes)
+	if utf8.RuneCountInString(edition.Notes) > 2000 {
+		return Edition{}, invalid("notes", "notes cannot exceed 2000 characters")
+	}
+
+	if edition.Quantity < 0 {
+		return Edition{}, invalid("quantity", "quantity cannot be negative")
+	}
+
+	return edition, nil
+}
+
+func editionEntityID(id int) string {
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,86 @@
+package libpolybase
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the Polybase operations match one of these with
+// errors.Is, or a *ValidationError with errors.As, while keeping a detailed
+// message.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrConflict      = errors.New("conflict")
+	ErrForbidden     = errors.New("forbidden")
+)
+
+// ValidationError reports an invalid input. Field names the offending input
+// when there is one.
+type ValidationError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+// kindError classifies an error under one of the sentinel errors without
+// changing its message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+func notFound(format string, args ...any) error {
+	return &kindError{kind: ErrNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+func alreadyExists(format string, args ...any) error {
+	return &kindError{kind: ErrAlreadyExists, msg: fmt.Sprintf(format, args...)}
+}
+
+func conflict(format string, args ...any) error {
+	return &kindError{kind: ErrConflict, msg: fmt.Sprintf(format, args...)}
+}
+
+func forbidden(format string, args ...any) error {
+	return &kindError{kind: ErrForbidden, msg: fmt.Sprintf(format, args...)}
+}
+
+func invalid(field string, format string, args ...any) error {
+	return &ValidationError{Field: field, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *CourseNotFound) Error() string {
+	return "course not found"
+}
+
+func (e *CourseNotFound) Unwrap() error {
+	return ErrNotFound
+}
+
+func (e *RevisionConflict) Error() string {
+	return fmt.Sprintf("modified by someone else: expected revision %d, current revision is %d", e.Expected, e.Current)
+}
+
+func (e *RevisionConflict) Unwrap() error {
+	return ErrConflict
+}
+
+func (e *RevertConflict) Error() string {
+	return fmt.Sprintf("change %d cannot be reverted: %s", e.ChangeID, e.Reason)
+}
+
+func (e *RevertConflict) Unwrap() error {
+	return ErrConflict
+}
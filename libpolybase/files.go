@@ -0,0 +1,277 @@
+package libpolybase
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// pdfMagic starts every PDF document.
+const pdfMagic = "%PDF-"
+
+var errNoStorage = errors.New("file storage is not configured")
+
+func (pb *PB) PutMasterFile(ctx context.Context, user string, id CourseID, name string, content io.Reader) (MasterFile, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return MasterFile{}, err
+	}
+
+	name, err = validateFileName(name)
+	if err != nil {
+		return MasterFile{}, err
+	}
+
+	// The content is stored first: when the upload fails afterwards, the
+	// file left in the store is reused by the next upload of the same
+	// content
+	checksum, size, err := pb.storeFile(content)
+	if err != nil {
+		return MasterFile{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return MasterFile{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return MasterFile{}, err
+	}
+
+	exists, err := pb.exists(ctx, id, tx)
+	if err != nil {
+		return MasterFile{}, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return MasterFile{}, notFound("course %s does not exist", id.ID())
+	}
+
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO master_files (academic_year, course_code, course_kind, course_part, name, checksum, size, uploaded_at, uploaded_by)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, id.Code, id.Kind, id.Part, name, checksum, size, time.Now().UTC(), user)
+	if err != nil {
+		return MasterFile{}, fmt.Errorf("record master file: %w", err)
+	}
+
+	fileID, err := result.LastInsertId()
+	if err != nil {
+		return MasterFile{}, fmt.Errorf("get master file id: %w", err)
+	}
+
+	file, err := pb.getMasterFile(ctx, int(fileID), tx)
+	if err != nil {
+		return MasterFile{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionUpload, EntityCourse, id.ID(), nil, file); err != nil {
+		return MasterFile{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return MasterFile{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("uploaded master file %s (%s) for course %s", name, checksum[:12], id.ID())
+	if err := pb.logAction(user, "UPLOAD", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return file, nil
+}
+
+func (pb *PB) GetMasterFile(ctx context.Context, id CourseID) (MasterFile, error) {
+	files, err := pb.ListMasterFiles(ctx, id)
+	if err != nil {
+		return MasterFile{}, err
+	}
+	if len(files) == 0 {
+		return MasterFile{}, notFound("course %s has no master file", id.ID())
+	}
+	return files[0], nil
+}
+
+func (pb *PB) ListMasterFiles(ctx context.Context, id CourseID) ([]MasterFile, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := pb.exists(ctx, id, pb.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return nil, notFound("course %s does not exist", id.ID())
+	}
+
+	rows, err := pb.db.QueryContext(ctx, "SELECT "+masterFileColumns+`
+    WHERE m.academic_year = ? AND m.course_code = ? AND m.course_kind = ? AND m.course_part = ?
+    ORDER BY m.id DESC`,
+		pb.year, id.Code, id.Kind, id.Part)
+	if err != nil {
+		return nil, fmt.Errorf("list master files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []MasterFile
+	for rows.Next() {
+		file, err := scanMasterFile(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan master file: %w", err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate master files: %w", err)
+	}
+	return files, nil
+}
+
+func (pb *PB) OpenMasterFile(ctx context.Context, fileID int) (MasterFile, io.ReadCloser, error) {
+	if pb.storage == "" {
+		return MasterFile{}, nil, errNoStorage
+	}
+
+	file, err := pb.getMasterFile(ctx, fileID, pb.db)
+	if err != nil {
+		return MasterFile{}, nil, err
+	}
+
+	content, err := os.Open(pb.filePath(file.Checksum))
+	if err != nil {
+		return MasterFile{}, nil, fmt.Errorf("open master file %s: %w", file.Checksum, err)
+	}
+	return file, content, nil
+}
+
+// storeFile copies a PDF document to the file store and returns its checksum
+// and size. The document is written to a temporary file, then moved to its
+// place unless the store already holds the same content.
+func (pb *PB) storeFile(content io.Reader) (string, int64, error) {
+	if pb.storage == "" {
+		return "", 0, errNoStorage
+	}
+
+	reader := bufio.NewReader(content)
+	head, err := reader.Peek(len(pdfMagic))
+	if err != nil && err != io.EOF {
+		return "", 0, fmt.Errorf("read file: %w", err)
+	}
+	if string(head) != pdfMagic {
+		return "", 0, invalid("file", "master files must be PDF documents")
+	}
+
+	if err := os.MkdirAll(pb.storage, 0o750); err != nil {
+		return "", 0, fmt.Errorf("create file store: %w", err)
+	}
+	tmp, err := os.CreateTemp(pb.storage, ".upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), reader)
+	if err != nil {
+		tmp.Close()
+		return "", 0, fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("write file: %w", err)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	path := pb.filePath(checksum)
+	if _, err := os.Stat(path); err == nil {
+		return checksum, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", 0, fmt.Errorf("create file store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("store file: %w", err)
+	}
+	return checksum, size, nil
+}
+
+// filePath places the files in subdirectories named after the first two
+// characters of their checksum.
+func (pb *PB) filePath(checksum string) string {
+	return filepath.Join(pb.storage, checksum[:2], checksum)
+}
+
+// masterFileColumns selects the master files of the live courses.
+var masterFileColumns = `m.id, m.course_code, m.course_kind, m.course_part, m.name, m.checksum, m.size,
+    m.id = (` + currentMasterFile("m") + `), m.uploaded_at, m.uploaded_by
+    FROM master_files m
+    JOIN courses c ON c.academic_year = m.academic_year AND c.code = m.course_code AND c.kind = m.course_kind AND c.part = m.course_part
+      AND c.deleted_at IS NULL`
+
+// currentMasterFile selects the ID of the current master file of the course
+// of a row of the master_files table.
+func currentMasterFile(table string) string {
+	return `SELECT MAX(cur.id) FROM master_files cur
+      WHERE cur.academic_year = ` + table + `.academic_year AND cur.course_code = ` + table + `.course_code
+        AND cur.course_kind = ` + table + `.course_kind AND cur.course_part = ` + table + `.course_part`
+}
+
+// hasMasterColumn tells whether the course of a row of the courses table has
+// a master file.
+func hasMasterColumn(table string) string {
+	return `EXISTS (SELECT 1 FROM master_files m
+      WHERE m.academic_year = ` + table + `.academic_year AND m.course_code = ` + table + `.code
+        AND m.course_kind = ` + table + `.kind AND m.course_part = ` + table + `.part)`
+}
+
+func (pb *PB) getMasterFile(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (MasterFile, error) {
+	row := querier.QueryRowContext(ctx, "SELECT "+masterFileColumns+`
+    WHERE m.academic_year = ? AND m.id = ?`,
+		pb.year, id)
+	file, err := scanMasterFile(row)
+	if err == sql.ErrNoRows {
+		return MasterFile{}, notFound("master file %d does not exist", id)
+	}
+	if err != nil {
+		return MasterFile{}, fmt.Errorf("get master file: %w", err)
+	}
+	return file, nil
+}
+
+func scanMasterFile(row interface{ Scan(...any) error }) (MasterFile, error) {
+	var f MasterFile
+	err := row.Scan(&f.ID, &f.Course.Code, &f.Course.Kind, &f.Course.Part,
+		&f.Name, &f.Checksum, &f.Size, &f.Current, &f.UploadedAt, &f.UploadedBy)
+	return f, err
+}
+
+// validateFileName keeps the base name of an uploaded file.
+func validateFileName(name string) (string, error) {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "", invalid("name", "file name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		return "", invalid("name", "file name cannot exceed 255 characters")
+	}
+	return name, nil
+}
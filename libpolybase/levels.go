@@ -0,0 +1,42 @@
+package libpolybase
+
+import (
+	"slices"
+	"strings"
+)
+
+var levels = []Level{LevelL1, LevelL2, LevelL3, LevelM1, LevelM2, LevelOther}
+
+// Levels returns every level in order.
+func Levels() []Level {
+	return slices.Clone(levels)
+}
+
+// ParseLevel reads a level name, ignoring case.
+func ParseLevel(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	for _, level := range levels {
+		if strings.EqualFold(s, string(level)) {
+			return level, nil
+		}
+	}
+	return "", invalid("level", "level must be one of L1, L2, L3, M1, M2 or other")
+}
+
+// Compare orders levels, returning a negative number when l comes before
+// other. Unknown levels come after LevelOther.
+func (l Level) Compare(other Level) int {
+	return levelRank(l) - levelRank(other)
+}
+
+func levelRank(l Level) int {
+	if i := slices.Index(levels, l); i >= 0 {
+		return i
+	}
+	return len(levels)
+}
+
+// GetLevel returns the level of a course code under the default catalogue.
+func GetLevel(code string) (Level, error) {
+	return defaultCatalogue.Level(code)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pack_courses;
+DROP TABLE IF EXISTS packs;
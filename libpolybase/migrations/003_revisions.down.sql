@@ -0,0 +1,2 @@
+ALTER TABLE packs DROP COLUMN revision;
+ALTER TABLE courses DROP COLUMN revision;
@@ -0,0 +1,2 @@
+ALTER TABLE courses ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE packs ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_code TEXT NOT NULL,
+    course_kind TEXT NOT NULL,
+    course_part INTEGER NOT NULL,
+    delta INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    actor TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    pack_id INTEGER,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_movements_course
+    ON stock_movements (course_code, course_kind, course_part, created_at);
+
+CREATE INDEX IF NOT EXISTS stock_movements_created_at
+    ON stock_movements (created_at);
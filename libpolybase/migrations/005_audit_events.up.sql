@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    before TEXT,
+    after TEXT,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_entity
+    ON audit_events (entity_type, entity_id, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_events_actor
+    ON audit_events (actor, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_events_created_at
+    ON audit_events (created_at);
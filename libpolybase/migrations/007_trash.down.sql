@@ -0,0 +1,16 @@
+DELETE FROM pack_courses WHERE pack_id IN (SELECT id FROM packs WHERE deleted_at IS NOT NULL);
+DELETE FROM packs WHERE deleted_at IS NOT NULL;
+DELETE FROM pack_courses WHERE EXISTS (
+    SELECT 1 FROM courses c
+    WHERE c.code = pack_courses.course_code
+      AND c.kind = pack_courses.course_kind
+      AND c.part = pack_courses.course_part
+      AND c.deleted_at IS NOT NULL
+);
+DELETE FROM courses WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE packs DROP COLUMN deleted_by;
+ALTER TABLE packs DROP COLUMN deleted_at;
+
+ALTER TABLE courses DROP COLUMN deleted_by;
+ALTER TABLE courses DROP COLUMN deleted_at;
@@ -0,0 +1,5 @@
+ALTER TABLE courses ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE courses ADD COLUMN deleted_by TEXT;
+
+ALTER TABLE packs ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE packs ADD COLUMN deleted_by TEXT;
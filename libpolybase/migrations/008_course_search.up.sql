@@ -0,0 +1,31 @@
+-- Full-text index of the catalogue, accent and case insensitive. Kept in sync
+-- with courses by the triggers below, trashed courses are filtered at query
+-- time.
+CREATE VIRTUAL TABLE IF NOT EXISTS course_search USING fts5(
+    code,
+    name,
+    kind,
+    part UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS course_search_insert AFTER INSERT ON courses BEGIN
+    INSERT INTO course_search (code, name, kind, part)
+    VALUES (new.code, new.name, new.kind, new.part);
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_delete AFTER DELETE ON courses BEGIN
+    DELETE FROM course_search
+    WHERE code = old.code AND kind = old.kind AND part = old.part;
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_update AFTER UPDATE OF code, kind, part, name ON courses BEGIN
+    DELETE FROM course_search
+    WHERE code = old.code AND kind = old.kind AND part = old.part;
+    INSERT INTO course_search (code, name, kind, part)
+    VALUES (new.code, new.name, new.kind, new.part);
+END;
+
+INSERT INTO course_search (code, name, kind, part)
+SELECT code, name, kind, part FROM courses;
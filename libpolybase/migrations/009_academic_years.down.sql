@@ -0,0 +1,110 @@
+-- Only the latest academic year is kept, the older ones cannot share the
+-- course IDs
+DROP TABLE course_search;
+
+CREATE TABLE latest_year AS
+SELECT MAX(academic_year) AS year FROM (
+    SELECT academic_year FROM courses UNION SELECT academic_year FROM packs
+);
+
+CREATE TABLE courses_old (
+    code TEXT,
+    kind TEXT,
+    part INTEGER DEFAULT 1,
+    parts INTEGER DEFAULT 1,
+    name TEXT,
+    quantity INTEGER,
+    total INTEGER,
+    shown INTEGER DEFAULT 1,
+    semester TEXT,
+    revision INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    deleted_by TEXT,
+    PRIMARY KEY (code, kind, part)
+);
+
+INSERT INTO courses_old (code, kind, part, parts, name, quantity, total, shown, semester, revision, deleted_at, deleted_by)
+SELECT code, kind, part, parts, name, quantity, total, shown, semester, revision, deleted_at, deleted_by
+FROM courses
+WHERE academic_year = (SELECT year FROM latest_year);
+
+CREATE TABLE packs_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    revision INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    deleted_by TEXT
+);
+
+INSERT INTO packs_old (id, name, revision, deleted_at, deleted_by)
+SELECT id, name, revision, deleted_at, deleted_by
+FROM packs
+WHERE academic_year = (SELECT year FROM latest_year);
+
+CREATE TABLE pack_courses_old AS
+SELECT pack_id, course_code, course_kind, course_part
+FROM pack_courses
+WHERE academic_year = (SELECT year FROM latest_year);
+
+DROP TABLE pack_courses;
+DROP TABLE packs;
+DROP TABLE courses;
+
+ALTER TABLE courses_old RENAME TO courses;
+ALTER TABLE packs_old RENAME TO packs;
+
+CREATE TABLE pack_courses (
+    pack_id INTEGER,
+    course_code TEXT,
+    course_kind TEXT,
+    course_part INTEGER,
+    FOREIGN KEY (pack_id) REFERENCES packs(id) ON DELETE CASCADE,
+    FOREIGN KEY (course_code, course_kind, course_part)
+        REFERENCES courses(code, kind, part) ON UPDATE CASCADE,
+    PRIMARY KEY (pack_id, course_code, course_kind, course_part)
+);
+
+INSERT INTO pack_courses (pack_id, course_code, course_kind, course_part)
+SELECT pack_id, course_code, course_kind, course_part
+FROM pack_courses_old
+WHERE pack_id IN (SELECT id FROM packs);
+
+DROP TABLE pack_courses_old;
+DROP TABLE latest_year;
+
+DROP INDEX IF EXISTS stock_movements_academic_year;
+ALTER TABLE stock_movements DROP COLUMN academic_year;
+
+DROP INDEX IF EXISTS audit_events_academic_year;
+ALTER TABLE audit_events DROP COLUMN academic_year;
+
+DROP TABLE IF EXISTS academic_years;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS course_search USING fts5(
+    code,
+    name,
+    kind,
+    part UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS course_search_insert AFTER INSERT ON courses BEGIN
+    INSERT INTO course_search (code, name, kind, part)
+    VALUES (new.code, new.name, new.kind, new.part);
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_delete AFTER DELETE ON courses BEGIN
+    DELETE FROM course_search
+    WHERE code = old.code AND kind = old.kind AND part = old.part;
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_update AFTER UPDATE OF code, kind, part, name ON courses BEGIN
+    DELETE FROM course_search
+    WHERE code = old.code AND kind = old.kind AND part = old.part;
+    INSERT INTO course_search (code, name, kind, part)
+    VALUES (new.code, new.name, new.kind, new.part);
+END;
+
+INSERT INTO course_search (code, name, kind, part)
+SELECT code, name, kind, part FROM courses;
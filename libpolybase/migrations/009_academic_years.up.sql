@@ -0,0 +1,119 @@
+-- Courses, packs and the stock history belong to an academic year, named after
+-- the calendar year it starts in. Rows created before belong to the current
+-- academic year, which starts in July.
+CREATE TABLE IF NOT EXISTS academic_years (
+    year INTEGER PRIMARY KEY,
+    read_only INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO academic_years (year)
+VALUES (CAST(strftime('%Y', 'now') AS INTEGER) - (CAST(strftime('%m', 'now') AS INTEGER) < 7));
+
+-- The same course can now exist once per year, so its primary key and the
+-- pack memberships referencing it take the year
+CREATE TABLE courses_new (
+    academic_year INTEGER NOT NULL,
+    code TEXT,
+    kind TEXT,
+    part INTEGER DEFAULT 1,
+    parts INTEGER DEFAULT 1,
+    name TEXT,
+    quantity INTEGER,
+    total INTEGER,
+    shown INTEGER DEFAULT 1,
+    semester TEXT,
+    revision INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    deleted_by TEXT,
+    PRIMARY KEY (academic_year, code, kind, part)
+);
+
+INSERT INTO courses_new (academic_year, code, kind, part, parts, name, quantity, total, shown, semester, revision, deleted_at, deleted_by)
+SELECT (SELECT year FROM academic_years), code, kind, part, parts, name, quantity, total, shown, semester, revision, deleted_at, deleted_by
+FROM courses;
+
+CREATE TABLE packs_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    revision INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    deleted_by TEXT
+);
+
+INSERT INTO packs_new (id, academic_year, name, revision, deleted_at, deleted_by)
+SELECT id, (SELECT year FROM academic_years), name, revision, deleted_at, deleted_by
+FROM packs;
+
+CREATE TABLE pack_courses_old AS
+SELECT pack_id, course_code, course_kind, course_part FROM pack_courses;
+
+DROP TABLE pack_courses;
+DROP TABLE packs;
+DROP TABLE courses;
+
+ALTER TABLE courses_new RENAME TO courses;
+ALTER TABLE packs_new RENAME TO packs;
+
+CREATE TABLE pack_courses (
+    pack_id INTEGER,
+    academic_year INTEGER NOT NULL,
+    course_code TEXT,
+    course_kind TEXT,
+    course_part INTEGER,
+    FOREIGN KEY (pack_id) REFERENCES packs(id) ON DELETE CASCADE,
+    FOREIGN KEY (academic_year, course_code, course_kind, course_part)
+        REFERENCES courses(academic_year, code, kind, part) ON UPDATE CASCADE,
+    PRIMARY KEY (pack_id, course_code, course_kind, course_part)
+);
+
+INSERT INTO pack_courses (pack_id, academic_year, course_code, course_kind, course_part)
+SELECT pack_id, (SELECT year FROM academic_years), course_code, course_kind, course_part
+FROM pack_courses_old;
+
+DROP TABLE pack_courses_old;
+
+ALTER TABLE stock_movements ADD COLUMN academic_year INTEGER;
+UPDATE stock_movements SET academic_year = (SELECT year FROM academic_years);
+
+CREATE INDEX IF NOT EXISTS stock_movements_academic_year
+    ON stock_movements (academic_year, created_at);
+
+ALTER TABLE audit_events ADD COLUMN academic_year INTEGER;
+UPDATE audit_events SET academic_year = (SELECT year FROM academic_years);
+
+CREATE INDEX IF NOT EXISTS audit_events_academic_year
+    ON audit_events (academic_year, created_at);
+
+-- The search index follows, its triggers went away with the old table
+DROP TABLE course_search;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS course_search USING fts5(
+    code,
+    name,
+    kind,
+    part UNINDEXED,
+    academic_year UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS course_search_insert AFTER INSERT ON courses BEGIN
+    INSERT INTO course_search (code, name, kind, part, academic_year)
+    VALUES (new.code, new.name, new.kind, new.part, new.academic_year);
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_delete AFTER DELETE ON courses BEGIN
+    DELETE FROM course_search
+    WHERE academic_year = old.academic_year AND code = old.code AND kind = old.kind AND part = old.part;
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_update AFTER UPDATE OF code, kind, part, name ON courses BEGIN
+    DELETE FROM course_search
+    WHERE academic_year = old.academic_year AND code = old.code AND kind = old.kind AND part = old.part;
+    INSERT INTO course_search (code, name, kind, part, academic_year)
+    VALUES (new.code, new.name, new.kind, new.part, new.academic_year);
+END;
+
+INSERT INTO course_search (code, name, kind, part, academic_year)
+SELECT code, name, kind, part, academic_year FROM courses;
@@ -0,0 +1,7 @@
+ALTER TABLE courses DROP COLUMN description;
+ALTER TABLE courses DROP COLUMN edition_date;
+ALTER TABLE courses DROP COLUMN edition;
+ALTER TABLE courses DROP COLUMN teacher;
+ALTER TABLE courses DROP COLUMN print_cost;
+ALTER TABLE courses DROP COLUMN price;
+ALTER TABLE courses DROP COLUMN pages;
@@ -0,0 +1,7 @@
+ALTER TABLE courses ADD COLUMN pages INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE courses ADD COLUMN price INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE courses ADD COLUMN print_cost INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE courses ADD COLUMN teacher TEXT NOT NULL DEFAULT '';
+ALTER TABLE courses ADD COLUMN edition TEXT NOT NULL DEFAULT '';
+ALTER TABLE courses ADD COLUMN edition_date TEXT NOT NULL DEFAULT '';
+ALTER TABLE courses ADD COLUMN description TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+ALTER TABLE courses DROP COLUMN current_edition;
+
+DROP INDEX IF EXISTS course_editions_course;
+DROP TABLE IF EXISTS course_editions;
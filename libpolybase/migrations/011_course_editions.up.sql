@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS course_editions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year INTEGER NOT NULL,
+    course_code TEXT NOT NULL,
+    course_kind TEXT NOT NULL,
+    course_part INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    date TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    created_by TEXT NOT NULL,
+    retired_at TIMESTAMP,
+    retired_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS course_editions_course
+    ON course_editions (academic_year, course_code, course_kind, course_part);
+
+ALTER TABLE courses ADD COLUMN current_edition INTEGER;
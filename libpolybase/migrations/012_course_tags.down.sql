@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS course_tags_tag;
+DROP TABLE IF EXISTS course_tags;
@@ -0,0 +1,12 @@
+-- Free-form tags grouping courses the schema does not model, such as an
+-- option or a double degree. Tags compare regardless of case.
+CREATE TABLE IF NOT EXISTS course_tags (
+    academic_year INTEGER NOT NULL,
+    course_code TEXT NOT NULL,
+    course_kind TEXT NOT NULL,
+    course_part INTEGER NOT NULL,
+    tag TEXT NOT NULL COLLATE NOCASE,
+    PRIMARY KEY (academic_year, course_code, course_kind, course_part, tag)
+);
+
+CREATE INDEX IF NOT EXISTS course_tags_tag ON course_tags (academic_year, tag);
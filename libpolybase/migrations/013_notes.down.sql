@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS notes_pack;
+DROP INDEX IF EXISTS notes_course;
+DROP TABLE IF EXISTS notes;
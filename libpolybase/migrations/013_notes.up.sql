@@ -0,0 +1,18 @@
+-- Notes are left by volunteers on either a course or a pack of a year
+CREATE TABLE IF NOT EXISTS notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year INTEGER NOT NULL,
+    course_code TEXT,
+    course_kind TEXT,
+    course_part INTEGER,
+    pack_id INTEGER REFERENCES packs(id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP,
+    CHECK ((course_code IS NULL) != (pack_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS notes_course
+    ON notes (academic_year, course_code, course_kind, course_part);
+CREATE INDEX IF NOT EXISTS notes_pack ON notes (pack_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS master_files_course;
+DROP TABLE IF EXISTS master_files;
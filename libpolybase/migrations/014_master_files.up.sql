@@ -0,0 +1,17 @@
+-- Uploads of the source document of a course, the newest being the current
+-- master. The content lives in the file store under its checksum.
+CREATE TABLE IF NOT EXISTS master_files (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year INTEGER NOT NULL,
+    course_code TEXT NOT NULL,
+    course_kind TEXT NOT NULL,
+    course_part INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    uploaded_at TIMESTAMP NOT NULL,
+    uploaded_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS master_files_course
+    ON master_files (academic_year, course_code, course_kind, course_part);
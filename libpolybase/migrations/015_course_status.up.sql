@@ -0,0 +1,6 @@
+-- The shown flag is kept, written from the status: only the available and out
+-- of stock courses are public. Hidden courses start as drafts.
+ALTER TABLE courses ADD COLUMN status TEXT NOT NULL DEFAULT 'available'
+    CHECK (status IN ('draft', 'awaiting_print', 'available', 'out_of_stock', 'discontinued'));
+
+UPDATE courses SET status = 'draft' WHERE shown = 0;
@@ -0,0 +1,3 @@
+ALTER TABLE packs DROP COLUMN level;
+ALTER TABLE packs DROP COLUMN semester;
+ALTER TABLE packs DROP COLUMN shown;
@@ -0,0 +1,5 @@
+-- Packs stay hidden from the public until published. The semester and level
+-- are empty for the packs spanning several of them.
+ALTER TABLE packs ADD COLUMN shown INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE packs ADD COLUMN semester TEXT NOT NULL DEFAULT '';
+ALTER TABLE packs ADD COLUMN level TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS price_changes_pack;
+DROP INDEX IF EXISTS price_changes_course;
+DROP TABLE IF EXISTS price_changes;
@@ -0,0 +1,24 @@
+-- Price history of the courses and the packs, each entry being in effect from
+-- its date until the next one. Course entries hold the price of the course,
+-- pack entries the fixed price of the pack, NULL for a price computed from its
+-- courses less the discount percent. The price column of the courses is the
+-- price of the courses without any entry in effect.
+CREATE TABLE IF NOT EXISTS price_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    academic_year INTEGER NOT NULL,
+    course_code TEXT,
+    course_kind TEXT,
+    course_part INTEGER,
+    pack_id INTEGER,
+    price INTEGER,
+    discount INTEGER NOT NULL DEFAULT 0,
+    effective_from TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    created_by TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS price_changes_course
+    ON price_changes (academic_year, course_code, course_kind, course_part, effective_from);
+
+CREATE INDEX IF NOT EXISTS price_changes_pack
+    ON price_changes (pack_id, effective_from);
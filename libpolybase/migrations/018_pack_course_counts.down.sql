@@ -0,0 +1 @@
+ALTER TABLE pack_courses DROP COLUMN count;
@@ -0,0 +1,3 @@
+-- A pack may hold several copies of a course, such as one sheet for each
+-- practical group.
+ALTER TABLE pack_courses ADD COLUMN count INTEGER NOT NULL DEFAULT 1 CHECK (count > 0);
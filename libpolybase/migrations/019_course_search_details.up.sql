@@ -0,0 +1,38 @@
+-- The search index covers the teacher and the description of the courses as
+-- well. FTS5 tables cannot gain a column, so it is rebuilt.
+DROP TRIGGER IF EXISTS course_search_insert;
+DROP TRIGGER IF EXISTS course_search_delete;
+DROP TRIGGER IF EXISTS course_search_update;
+DROP TABLE course_search;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS course_search USING fts5(
+    code,
+    name,
+    kind,
+    teacher,
+    description,
+    part UNINDEXED,
+    academic_year UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS course_search_insert AFTER INSERT ON courses BEGIN
+    INSERT INTO course_search (code, name, kind, teacher, description, part, academic_year)
+    VALUES (new.code, new.name, new.kind, new.teacher, new.description, new.part, new.academic_year);
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_delete AFTER DELETE ON courses BEGIN
+    DELETE FROM course_search
+    WHERE academic_year = old.academic_year AND code = old.code AND kind = old.kind AND part = old.part;
+END;
+
+CREATE TRIGGER IF NOT EXISTS course_search_update AFTER UPDATE OF code, kind, part, name, teacher, description ON courses BEGIN
+    DELETE FROM course_search
+    WHERE academic_year = old.academic_year AND code = old.code AND kind = old.kind AND part = old.part;
+    INSERT INTO course_search (code, name, kind, teacher, description, part, academic_year)
+    VALUES (new.code, new.name, new.kind, new.teacher, new.description, new.part, new.academic_year);
+END;
+
+INSERT INTO course_search (code, name, kind, teacher, description, part, academic_year)
+SELECT code, name, kind, teacher, description, part, academic_year FROM courses;
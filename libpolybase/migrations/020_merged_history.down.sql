@@ -0,0 +1,2 @@
+ALTER TABLE audit_events DROP COLUMN merged_from;
+ALTER TABLE stock_movements DROP COLUMN merged_from;
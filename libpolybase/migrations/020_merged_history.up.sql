@@ -0,0 +1,5 @@
+-- The stock movements and audit events of a merged part go to the course it is
+-- merged into, keeping the ID the part had at the time, so that the parts
+-- renumbered after it do not take over its history.
+ALTER TABLE stock_movements ADD COLUMN merged_from TEXT;
+ALTER TABLE audit_events ADD COLUMN merged_from TEXT;
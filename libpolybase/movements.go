@@ -0,0 +1,123 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func (pb *PB) ListMovements(ctx context.Context, filter MovementFilter) ([]Movement, error) {
+	conditions := []string{"academic_year = ?"}
+	args := []any{pb.year}
+
+	if filter.Course != nil {
+		conditions = append(conditions, "course_code = ? AND course_kind = ? AND course_part = ?")
+		args = append(args, filter.Course.Code, filter.Course.Kind, filter.Course.Part)
+	}
+
+	if filter.Code != nil {
+		conditions = append(conditions, "course_code = ?")
+		args = append(args, *filter.Code)
+	}
+
+	if filter.PackID != nil {
+		conditions = append(conditions, "pack_id = ?")
+		args = append(args, *filter.PackID)
+	}
+
+	if filter.Actor != nil {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, *filter.Actor)
+	}
+
+	if filter.Reason != nil {
+		conditions = append(conditions, "reason = ?")
+		args = append(args, string(*filter.Reason))
+	}
+
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since.UTC())
+	}
+
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until.UTC())
+	}
+
+	query := `SELECT id, course_code, course_kind, course_part, delta, quantity, actor, reason, pack_id, created_at, merged_from
+    FROM stock_movements`
+	query += " WHERE " + strings.Join(conditions, " AND ")
+	query += " ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := pb.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list movements: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []Movement
+	for rows.Next() {
+		var m Movement
+		var reason string
+		var packID sql.NullInt64
+		var mergedFrom sql.NullString
+		if err := rows.Scan(&m.ID, &m.Course.Code, &m.Course.Kind, &m.Course.Part,
+			&m.Delta, &m.Quantity, &m.Actor, &reason, &packID, &m.CreatedAt, &mergedFrom); err != nil {
+			return nil, fmt.Errorf("scan movement: %w", err)
+		}
+		m.Reason = MovementReason(reason)
+		if packID.Valid {
+			id := int(packID.Int64)
+			m.PackID = &id
+		}
+		m.MergedFrom = mergedFrom.String
+		movements = append(movements, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+// recordMovement appends an entry to the stock ledger and spreads the change
+// over the editions of the course. It must be called in the transaction that
+// changes the quantity of the course.
+func (pb *PB) recordMovement(ctx context.Context, tx *sql.Tx, user string, id CourseID, delta int, quantity int, reason MovementReason, packID *int) error {
+	if err := pb.insertMovement(ctx, tx, user, id, delta, quantity, reason, packID); err != nil {
+		return err
+	}
+	return pb.allocateEditions(ctx, tx, id, delta)
+}
+
+// insertMovement appends an entry to the stock ledger, leaving the editions
+// of the course alone.
+func (pb *PB) insertMovement(ctx context.Context, tx *sql.Tx, user string, id CourseID, delta int, quantity int, reason MovementReason, packID *int) error {
+	if delta == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+    INSERT INTO stock_movements (academic_year, course_code, course_kind, course_part, delta, quantity, actor, reason, pack_id, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, id.Code, id.Kind, id.Part, delta, quantity, user, string(reason), packID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("record movement: %w", err)
+	}
+	return nil
+}
+
+func quantityReason(delta int) MovementReason {
+	if delta < 0 {
+		return ReasonDistribution
+	}
+	return ReasonRestock
+}
@@ -0,0 +1,303 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func (pb *PB) ListCourseNotes(ctx context.Context, id CourseID) ([]Note, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := pb.exists(ctx, id, pb.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return nil, notFound("course %s does not exist", id.ID())
+	}
+
+	return pb.listNotes(ctx, `
+    WHERE n.academic_year = ? AND n.course_code = ? AND n.course_kind = ? AND n.course_part = ?`,
+		pb.year, id.Code, id.Kind, id.Part)
+}
+
+func (pb *PB) ListPackNotes(ctx context.Context, id int) ([]Note, error) {
+	if _, err := pb.getPack(ctx, id, pb.db); err != nil {
+		return nil, err
+	}
+
+	return pb.listNotes(ctx, `
+    WHERE n.academic_year = ? AND n.pack_id = ?`,
+		pb.year, id)
+}
+
+func (pb *PB) AddCourseNote(ctx context.Context, user string, id CourseID, body string) (Note, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Note{}, err
+	}
+	return pb.addNote(ctx, user, body, &id, nil)
+}
+
+func (pb *PB) AddPackNote(ctx context.Context, user string, id int, body string) (Note, error) {
+	return pb.addNote(ctx, user, body, nil, &id)
+}
+
+func (pb *PB) GetNote(ctx context.Context, id int) (Note, error) {
+	return pb.getNote(ctx, id, pb.db)
+}
+
+func (pb *PB) UpdateNote(ctx context.Context, user string, id int, body string) (Note, error) {
+	body, err := validateNote(body)
+	if err != nil {
+		return Note{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Note{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Note{}, err
+	}
+
+	current, err := pb.getNote(ctx, id, tx)
+	if err != nil {
+		return Note{}, err
+	}
+	if current.Author != user {
+		return Note{}, forbidden("note %d can only be edited by %s", id, current.Author)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE notes
+    SET body = ?, updated_at = ?
+    WHERE academic_year = ? AND id = ?`,
+		body, time.Now().UTC(), pb.year, id); err != nil {
+		return Note{}, fmt.Errorf("update note: %w", err)
+	}
+
+	updated, err := pb.getNote(ctx, id, tx)
+	if err != nil {
+		return Note{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Note{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("updated note %d on %s", id, noteSubject(updated))
+	if err := pb.logAction(user, "UPDATE NOTE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return updated, nil
+}
+
+func (pb *PB) DeleteNote(ctx context.Context, user string, id int) error {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return err
+	}
+
+	current, err := pb.getNote(ctx, id, tx)
+	if err != nil {
+		return err
+	}
+	if current.Author != user {
+		return forbidden("note %d can only be deleted by %s", id, current.Author)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM notes WHERE academic_year = ? AND id = ?", pb.year, id); err != nil {
+		return fmt.Errorf("delete note: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("deleted note %d on %s", id, noteSubject(current))
+	if err := pb.logAction(user, "DELETE NOTE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return nil
+}
+
+// addNote leaves a note on either a course or a pack.
+func (pb *PB) addNote(ctx context.Context, user string, body string, course *CourseID, packID *int) (Note, error) {
+	body, err := validateNote(body)
+	if err != nil {
+		return Note{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Note{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Note{}, err
+	}
+
+	var code, kind, part any
+	if course != nil {
+		exists, err := pb.exists(ctx, *course, tx)
+		if err != nil {
+			return Note{}, fmt.Errorf("failed to check course existence: %w", err)
+		}
+		if !exists {
+			return Note{}, notFound("course %s does not exist", course.ID())
+		}
+		code, kind, part = course.Code, course.Kind, course.Part
+	} else if _, err := pb.getPack(ctx, *packID, tx); err != nil {
+		return Note{}, err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO notes (academic_year, course_code, course_kind, course_part, pack_id, author, body, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, code, kind, part, packID, user, body, time.Now().UTC())
+	if err != nil {
+		return Note{}, fmt.Errorf("create note: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Note{}, fmt.Errorf("get note id: %w", err)
+	}
+
+	created, err := pb.getNote(ctx, int(id), tx)
+	if err != nil {
+		return Note{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Note{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("added note %d on %s", id, noteSubject(created))
+	if err := pb.logAction(user, "CREATE NOTE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return created, nil
+}
+
+// noteColumns selects the notes whose course or pack is not in the trash.
+const noteColumns = `n.id, n.course_code, n.course_kind, n.course_part, n.pack_id,
+    n.author, n.body, n.created_at, n.updated_at
+    FROM notes n
+    LEFT JOIN courses c ON c.academic_year = n.academic_year AND c.code = n.course_code AND c.kind = n.course_kind AND c.part = n.course_part
+    LEFT JOIN packs p ON p.id = n.pack_id`
+
+const liveNote = `(c.code IS NOT NULL AND c.deleted_at IS NULL OR p.id IS NOT NULL AND p.deleted_at IS NULL)`
+
+func (pb *PB) getNote(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (Note, error) {
+	row := querier.QueryRowContext(ctx, "SELECT "+noteColumns+`
+    WHERE n.academic_year = ? AND n.id = ? AND `+liveNote,
+		pb.year, id)
+	note, err := scanNote(row)
+	if err == sql.ErrNoRows {
+		return Note{}, notFound("note %d does not exist", id)
+	}
+	if err != nil {
+		return Note{}, fmt.Errorf("get note: %w", err)
+	}
+	return note, nil
+}
+
+// listNotes lists the notes matching a WHERE clause, newest first.
+func (pb *PB) listNotes(ctx context.Context, where string, args ...any) ([]Note, error) {
+	rows, err := pb.db.QueryContext(ctx, "SELECT "+noteColumns+where+`
+    ORDER BY n.created_at DESC, n.id DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate notes: %w", err)
+	}
+	return notes, nil
+}
+
+func scanNote(row interface{ Scan(...any) error }) (Note, error) {
+	var n Note
+	var code, kind sql.NullString
+	var part, packID sql.NullInt64
+	var updatedAt sql.NullTime
+	if err := row.Scan(&n.ID, &code, &kind, &part, &packID,
+		&n.Author, &n.Body, &n.CreatedAt, &updatedAt); err != nil {
+		return Note{}, err
+	}
+	if code.Valid {
+		id := NewCourseID(code.String, kind.String, int(part.Int64))
+		n.Course = &id
+	}
+	if packID.Valid {
+		id := int(packID.Int64)
+		n.PackID = &id
+	}
+	if updatedAt.Valid {
+		n.UpdatedAt = &updatedAt.Time
+	}
+	return n, nil
+}
+
+// noteSubject names the course or pack of a note for the log.
+func noteSubject(note Note) string {
+	if note.Course != nil {
+		return "course " + note.Course.ID()
+	}
+	return fmt.Sprintf("pack %d", *note.PackID)
+}
+
+func validateNote(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", invalid("body", "note cannot be empty")
+	}
+	if utf8.RuneCountInString(body) > 2000 {
+		return "", invalid("body", "note cannot exceed 2000 characters")
+	}
+	return body, nil
+}
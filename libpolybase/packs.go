@@ -1,14 +1,19 @@
 package libpolybase
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"slices"
 	"strings"
+	"time"
 )
 
-func (pb *PB) CreatePack(ctx context.Context, user string, name string, courses []CourseID) (Pack, error) {
+func (pb *PB) CreatePack(ctx context.Context, user string, name string, courses []PackCourse) (Pack, error) {
 	if err := validatePack(name, courses); err != nil {
 		return Pack{}, err
 	}
@@ -23,19 +28,23 @@ func (pb *PB) CreatePack(ctx context.Context, user string, name string, courses
 		}
 	}()
 
-	for _, courseID := range courses {
-		exists, err := pb.exists(ctx, courseID, tx)
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Pack{}, err
+	}
+
+	for _, course := range courses {
+		exists, err := pb.exists(ctx, course.CourseID, tx)
 		if err != nil {
 			return Pack{}, fmt.Errorf("check course existence: %w", err)
 		}
 		if !exists {
-			return Pack{}, fmt.Errorf("course %s does not exist", courseID.ID())
+			return Pack{}, invalid("courses", "course %s does not exist", course.ID())
 		}
 	}
 
 	result, err := tx.ExecContext(ctx, `
-    INSERT INTO packs (name) VALUES (?)`,
-		strings.TrimSpace(name))
+    INSERT INTO packs (academic_year, name) VALUES (?, ?)`,
+		pb.year, strings.TrimSpace(name))
 	if err != nil {
 		return Pack{}, fmt.Errorf("create pack: %w", err)
 	}
@@ -45,14 +54,17 @@ func (pb *PB) CreatePack(ctx context.Context, user string, name string, courses
 		return Pack{}, fmt.Errorf("get pack id: %w", err)
 	}
 
-	for _, courseID := range courses {
-		_, err = tx.ExecContext(ctx, `
-      INSERT INTO pack_courses (pack_id, course_code, course_kind, course_part)
-      VALUES (?, ?, ?, ?)`,
-			packID, courseID.Code, courseID.Kind, courseID.Part)
-		if err != nil {
-			return Pack{}, fmt.Errorf("add course to pack: %w", err)
-		}
+	if err := pb.addPackCourses(ctx, int(packID), courses, tx); err != nil {
+		return Pack{}, err
+	}
+
+	created, err := pb.getPack(ctx, int(packID), tx)
+	if err != nil {
+		return Pack{}, fmt.Errorf("get created pack: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityPack, packEntityID(int(packID)), nil, created); err != nil {
+		return Pack{}, err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -67,8 +79,8 @@ func (pb *PB) CreatePack(ctx context.Context, user string, name string, courses
 }
 
 func (pb *PB) UpdatePack(ctx context.Context, user string, id int, partial PartialPack) (Pack, error) {
-	if partial.Name == nil && partial.Courses == nil {
-		return Pack{}, fmt.Errorf("at least one field must be updated")
+	if partial.Name == nil && partial.Shown == nil && partial.Semester == nil && partial.Level == nil && partial.Courses == nil && partial.Pricing == nil {
+		return Pack{}, invalid("", "at least one field must be updated")
 	}
 
 	tx, err := pb.db.BeginTx(ctx, nil)
@@ -81,57 +93,111 @@ func (pb *PB) UpdatePack(ctx context.Context, user string, id int, partial Parti
 		}
 	}()
 
-	var exists bool
-	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM PACKS WHERE id = ?)", id).Scan(&exists)
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Pack{}, err
+	}
+
+	current, err := pb.getPack(ctx, id, tx)
 	if err != nil {
-		return Pack{}, fmt.Errorf("check pack existence: %w", err)
+		return Pack{}, err
 	}
+	revision := current.Revision
 
-	if !exists {
-		return Pack{}, fmt.Errorf("pack not found")
+	if partial.Revision != nil && *partial.Revision != revision {
+		return Pack{}, &RevisionConflict{Expected: *partial.Revision, Current: revision}
 	}
 
 	if partial.Name != nil {
 		if strings.TrimSpace(*partial.Name) == "" {
-			return Pack{}, fmt.Errorf("pack name cannot be empty")
+			return Pack{}, invalid("name", "pack name cannot be empty")
 		}
-		_, err = tx.ExecContext(ctx, "UPDATE packs SET name = ? WHERE id = ?",
-			strings.TrimSpace(*partial.Name), id)
+		_, err = tx.ExecContext(ctx, "UPDATE packs SET name = ? WHERE academic_year = ? AND id = ?",
+			strings.TrimSpace(*partial.Name), pb.year, id)
 		if err != nil {
 			return Pack{}, fmt.Errorf("update pack name: %w", err)
 		}
 	}
 
+	if partial.Shown != nil || partial.Semester != nil || partial.Level != nil {
+		shown, semester, level := current.Shown, current.Semester, current.Level
+		if partial.Shown != nil {
+			shown = *partial.Shown
+		}
+		if partial.Semester != nil {
+			semester = *partial.Semester
+		}
+		if partial.Level != nil {
+			level = *partial.Level
+		}
+		semester, level, err = pb.validatePackGroup(semester, level)
+		if err != nil {
+			return Pack{}, err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE packs SET shown = ?, semester = ?, level = ? WHERE academic_year = ? AND id = ?",
+			shown, semester, level, pb.year, id)
+		if err != nil {
+			return Pack{}, fmt.Errorf("update pack publication: %w", err)
+		}
+	}
+
+	if partial.Pricing != nil && !samePricing(*partial.Pricing, current.Pricing) {
+		if err := validatePricing(*partial.Pricing); err != nil {
+			return Pack{}, err
+		}
+		pricing := *partial.Pricing
+		if _, err := pb.insertPriceChange(ctx, tx, user, PriceChange{PackID: &id, Price: pricing.Fixed, Discount: pricing.Discount, EffectiveFrom: today()}); err != nil {
+			return Pack{}, err
+		}
+	}
+
 	if partial.Courses != nil {
-		if len(*partial.Courses) == 0 {
-			return Pack{}, fmt.Errorf("pack must contain at least one course")
+		if err := validatePackCourses(*partial.Courses); err != nil {
+			return Pack{}, err
 		}
 
-		for _, courseID := range *partial.Courses {
-			exists, err := pb.exists(ctx, courseID, tx)
+		for _, course := range *partial.Courses {
+			exists, err := pb.exists(ctx, course.CourseID, tx)
 			if err != nil {
 				return Pack{}, fmt.Errorf("check course existence: %w", err)
 			}
 			if !exists {
-				return Pack{}, fmt.Errorf("course %s does not exist", courseID.ID())
+				return Pack{}, invalid("courses", "course %s does not exist", course.ID())
 			}
 		}
 
-		_, err = tx.ExecContext(ctx, "DELETE FROM pack_courses WHERE pack_id = ?", id)
-		if err != nil {
-			return Pack{}, fmt.Errorf("remove existing courses: %w", err)
+		if err := pb.clearPackCourses(ctx, id, tx); err != nil {
+			return Pack{}, err
 		}
-		for _, courseID := range *partial.Courses {
-			_, err = tx.ExecContext(ctx, `
-          INSERT INTO pack_courses (pack_id, course_code, course_kind, course_part)
-          VALUES (?, ?, ?, ?)`,
-				id, courseID.Code, courseID.Kind, courseID.Part)
-			if err != nil {
-				return Pack{}, fmt.Errorf("add course to pack: %w", err)
-			}
+		if err := pb.addPackCourses(ctx, id, *partial.Courses, tx); err != nil {
+			return Pack{}, err
 		}
 	}
 
+	result, err := tx.ExecContext(ctx, `
+    UPDATE packs SET revision = revision + 1 WHERE academic_year = ? AND id = ? AND revision = ?`,
+		pb.year, id, revision)
+	if err != nil {
+		return Pack{}, fmt.Errorf("update pack revision: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return Pack{}, fmt.Errorf("update pack revision: %w", err)
+	} else if updated == 0 {
+		latest, err := pb.getPack(ctx, id, tx)
+		if err != nil {
+			return Pack{}, fmt.Errorf("get current pack: %w", err)
+		}
+		return Pack{}, &RevisionConflict{Expected: revision, Current: latest.Revision}
+	}
+
+	updated, err := pb.getPack(ctx, id, tx)
+	if err != nil {
+		return Pack{}, fmt.Errorf("get updated pack: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionUpdate, EntityPack, packEntityID(id), current, updated); err != nil {
+		return Pack{}, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return Pack{}, fmt.Errorf("commit transaction: %w", err)
 	}
@@ -155,25 +221,21 @@ func (pb *PB) DeletePack(ctx context.Context, user string, id int) error {
 		}
 	}()
 
-	var exists bool
-	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM packs WHERE id = ?)", id).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("check pack existence: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("pack not found")
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return err
 	}
 
-	// Delete course associations first
-	_, err = tx.ExecContext(ctx, "DELETE FROM pack_courses WHERE pack_id = ?", id)
+	current, err := pb.getPack(ctx, id, tx)
 	if err != nil {
-		return fmt.Errorf("delete pack courses: %w", err)
+		return err
 	}
 
-	// Delete pack
-	_, err = tx.ExecContext(ctx, "DELETE FROM packs WHERE id = ?", id)
-	if err != nil {
-		return fmt.Errorf("delete pack: %w", err)
+	if err := pb.trashPack(ctx, user, id, tx); err != nil {
+		return err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionDelete, EntityPack, packEntityID(id), current, nil); err != nil {
+		return err
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -189,101 +251,169 @@ func (pb *PB) DeletePack(ctx context.Context, user string, id int) error {
 }
 
 func (pb *PB) GetPack(ctx context.Context, id int) (Pack, error) {
+	return pb.getPack(ctx, id, pb.db)
+}
+
+func (pb *PB) getPack(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) (Pack, error) {
 	var pack Pack
-	err := pb.db.QueryRowContext(ctx, `
-    SELECT id, name
+	var fixed sql.NullInt64
+	err := querier.QueryRowContext(ctx, `
+    SELECT packs.id, name, shown, semester, level, pricing.price, COALESCE(pricing.discount, 0), revision
     FROM packs
-    WHERE id = ?`, id).Scan(&pack.ID, &pack.Name)
+    `+packPricingJoin("packs")+`
+    WHERE packs.academic_year = ? AND packs.id = ? AND deleted_at IS NULL`, pb.year, id).
+		Scan(&pack.ID, &pack.Name, &pack.Shown, &pack.Semester, &pack.Level, &fixed, &pack.Pricing.Discount, &pack.Revision)
 	if err == sql.ErrNoRows {
-		return Pack{}, fmt.Errorf("pack not found")
+		return Pack{}, notFound("pack not found")
 	}
 	if err != nil {
 		return Pack{}, fmt.Errorf("get pack: %w", err)
 	}
+	pack.Pricing.Fixed = fixedPrice(fixed)
 
-	rows, err := pb.db.QueryContext(ctx, `
-    SELECT c.code, c.kind, c.part, c.parts, c.name, c.quantity, c.total, c.shown, c.semester
+	rows, err := querier.QueryContext(ctx, `
+    SELECT c.code, c.kind, c.part, pc.count, c.quantity, c.shown, `+priceColumn("c")+`
     FROM courses c
-    JOIN pack_courses pc ON c.code = pc.course_code
+    JOIN pack_courses pc ON c.academic_year = pc.academic_year
+      AND c.code = pc.course_code
       AND c.kind = pc.course_kind
       AND c.part = pc.course_part
-    WHERE pc.pack_id = ?
-    ORDER BY c.code,
-    CASE c.kind 
-        WHEN 'Memento' THEN 1
-        WHEN 'TME' THEN 2
-        WHEN 'Cours' THEN 3
-        WHEN 'TD' THEN 4
-        ELSE 5
-    END,
-    c.part`, id)
+    WHERE pc.pack_id = ? AND c.deleted_at IS NULL
+    ORDER BY c.code, c.part`, id)
 	if err != nil {
 		return Pack{}, fmt.Errorf("get pack courses: %w", err)
 	}
 	defer rows.Close()
 
+	var members []packMember
 	for rows.Next() {
-		var course Course
-		if err := rows.Scan(
-			&course.Code, &course.Kind, &course.Part, &course.Parts,
-			&course.Name, &course.Quantity, &course.Total, &course.Shown,
-			&course.Semester); err != nil {
+		var member packMember
+		if err := rows.Scan(&member.Code, &member.Kind, &member.Part, &member.Count,
+			&member.quantity, &member.shown, &member.price); err != nil {
 			return Pack{}, fmt.Errorf("scan course: %w", err)
 		}
-		pack.Courses = append(pack.Courses, CourseID{
-			Code: course.Code,
-			Kind: course.Kind,
-			Part: course.Part,
-		})
+		members = append(members, member)
 	}
 
 	if err = rows.Err(); err != nil {
 		return Pack{}, fmt.Errorf("iterate courses: %w", err)
 	}
 
+	pb.setPackMembers(&pack, members)
 	return pack, nil
 }
 
+// packMember is a course of a pack along with the stock and the price the
+// availability and the price of the pack are computed from.
+type packMember struct {
+	PackCourse
+	quantity int
+	shown    bool
+	price    Price
+}
+
+// setPackMembers fills the courses, the availability and the price of a pack
+// from the rows read along with it.
+func (pb *PB) setPackMembers(pack *Pack, members []packMember) {
+	// Within a code, courses are ordered by kind as the catalogue says
+	slices.SortStableFunc(members, func(a, b packMember) int {
+		return cmp.Or(
+			strings.Compare(a.Code, b.Code),
+			cmp.Compare(pb.catalogue.KindOrder(a.Kind), pb.catalogue.KindOrder(b.Kind)),
+		)
+	})
+
+	pack.Courses = nil
+	pack.Availability = PackAvailability{}
+	prices := make([]Price, len(members))
+	for i, member := range members {
+		pack.Courses = append(pack.Courses, member.PackCourse)
+		prices[i] = member.price * Price(member.Count)
+		complete := max(member.quantity, 0) / member.Count
+		if i == 0 || complete < pack.Availability.Complete {
+			limiting := member.CourseID
+			pack.Availability.Complete = complete
+			pack.Availability.Limiting = &limiting
+		}
+		if member.quantity < member.Count {
+			pack.Availability.OutOfStock = append(pack.Availability.OutOfStock, member.CourseID)
+		}
+		if !member.shown {
+			pack.Availability.Hidden = append(pack.Availability.Hidden, member.CourseID)
+		}
+	}
+	pack.Price = pack.Pricing.price(prices)
+}
+
 func (pb *PB) ListPacks(ctx context.Context) ([]Pack, error) {
-	// Get packs ordered by ID
+	return pb.listPacks(ctx, false)
+}
+
+func (pb *PB) ListShownPacks(ctx context.Context) ([]Pack, error) {
+	return pb.listPacks(ctx, true)
+}
+
+func (pb *PB) listPacks(ctx context.Context, shownOnly bool) ([]Pack, error) {
+	// Get packs ordered by ID, along with the stock of their courses
 	rows, err := pb.db.QueryContext(ctx, `
-        SELECT id, name, course_code, course_kind, course_part
+        SELECT packs.id, name, packs.shown, semester, level, pricing.price, COALESCE(pricing.discount, 0), revision,
+          pack_courses.course_code, pack_courses.course_kind, pack_courses.course_part, pack_courses.count, quantity, course_shown, course_price
         FROM packs 
-        LEFT JOIN pack_courses ON packs.id = pack_courses.pack_id
-        ORDER BY packs.id, course_code, course_kind, course_part`)
+        `+packPricingJoin("packs")+`
+        LEFT JOIN (
+          SELECT pc.*, c.quantity, c.shown AS course_shown, `+priceColumn("c")+` AS course_price FROM pack_courses pc
+          JOIN courses c ON c.academic_year = pc.academic_year
+            AND c.code = pc.course_code
+            AND c.kind = pc.course_kind
+            AND c.part = pc.course_part
+          WHERE c.deleted_at IS NULL
+        ) AS pack_courses ON packs.id = pack_courses.pack_id
+        WHERE packs.academic_year = ? AND packs.deleted_at IS NULL AND (packs.shown = 1 OR NOT ?)
+        ORDER BY packs.id, pack_courses.course_code, pack_courses.course_kind, pack_courses.course_part`, pb.year, shownOnly)
 	if err != nil {
 		return nil, fmt.Errorf("list packs: %w", err)
 	}
 	defer rows.Close()
 
 	var packs []Pack
-	var currentPack *Pack
+	var members [][]packMember
 
 	for rows.Next() {
-		var id int
-		var name string
+		var pack Pack
+		var fixed sql.NullInt64
 		var code, kind sql.NullString
-		var part sql.NullInt64
+		var part, count, quantity, price sql.NullInt64
+		var shown sql.NullBool
 
-		if err := rows.Scan(&id, &name, &code, &kind, &part); err != nil {
+		if err := rows.Scan(&pack.ID, &pack.Name, &pack.Shown, &pack.Semester, &pack.Level, &fixed, &pack.Pricing.Discount, &pack.Revision,
+			&code, &kind, &part, &count, &quantity, &shown, &price); err != nil {
 			return nil, fmt.Errorf("scan pack: %w", err)
 		}
+		pack.Pricing.Fixed = fixedPrice(fixed)
 
 		// Start new pack if ID changes
-		if currentPack == nil || currentPack.ID != id {
-			packs = append(packs, Pack{
-				ID:   id,
-				Name: name,
-			})
-			currentPack = &packs[len(packs)-1]
+		if len(packs) == 0 || packs[len(packs)-1].ID != pack.ID {
+			packs = append(packs, pack)
+			members = append(members, nil)
 		}
 
 		// Add course if one exists for this row
 		if code.Valid && kind.Valid && part.Valid {
-			currentPack.Courses = append(currentPack.Courses, CourseID{
-				Code: code.String,
-				Kind: kind.String,
-				Part: int(part.Int64),
+			members[len(members)-1] = append(members[len(members)-1], packMember{
+				PackCourse: PackCourse{
+					CourseID: CourseID{
+						Code: code.String,
+						Kind: kind.String,
+						Part: int(part.Int64),
+					},
+					Count: int(count.Int64),
+				},
+				quantity: int(quantity.Int64),
+				shown:    shown.Bool,
+				price:    Price(price.Int64),
 			})
 		}
 	}
@@ -292,111 +422,287 @@ func (pb *PB) ListPacks(ctx context.Context) ([]Pack, error) {
 		return nil, fmt.Errorf("iterate packs: %w", err)
 	}
 
+	for i := range packs {
+		pb.setPackMembers(&packs[i], members[i])
+	}
+
 	return packs, nil
 }
 
-func (pb *PB) UpdatePackQuantity(ctx context.Context, user string, id int, delta int) (Pack, error) {
+var packQuantityPolicies = []PackQuantityPolicy{PolicyStrict, PolicyClamp, PolicySkip}
+
+// PackQuantityPolicies returns every pack quantity policy, the default first.
+func PackQuantityPolicies() []PackQuantityPolicy {
+	return slices.Clone(packQuantityPolicies)
+}
+
+// ParsePackQuantityPolicy reads a policy name, ignoring case. An empty name
+// is the strict policy.
+func ParsePackQuantityPolicy(s string) (PackQuantityPolicy, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return PolicyStrict, nil
+	}
+	if policy := PackQuantityPolicy(s); slices.Contains(packQuantityPolicies, policy) {
+		return policy, nil
+	}
+	return "", invalid("policy", "policy must be one of strict, clamp or skip")
+}
+
+// copies returns the copies of a course delta packs take, count copies going
+// in each, saturating rather than overflowing.
+func copies(delta int, count int) int {
+	if count > 1 && delta > math.MaxInt/count {
+		return math.MaxInt
+	}
+	if count > 1 && delta < -math.MaxInt/count {
+		return -math.MaxInt
+	}
+	return delta * count
+}
+
+// packQuantity works out how much of delta a course at quantity out of total
+// takes under policy.
+func packQuantity(id CourseID, quantity int, total int, delta int, policy PackQuantityPolicy) CourseQuantityResult {
+	applied := delta
+	if delta < 0 && -delta > quantity {
+		applied = -quantity
+	} else if delta > 0 && delta > total-quantity {
+		applied = max(total-quantity, 0)
+	}
+	if applied != delta && policy == PolicySkip {
+		applied = 0
+	}
+
+	shortfall := delta - applied
+	if shortfall < 0 {
+		shortfall = -shortfall
+	}
+	return CourseQuantityResult{Course: id, Applied: applied, Quantity: quantity + applied, Shortfall: shortfall}
+}
+
+func (pb *PB) UpdatePackQuantity(ctx context.Context, user string, id int, delta int, policy PackQuantityPolicy) (PackQuantityResult, error) {
+	policy, err := ParsePackQuantityPolicy(string(policy))
+	if err != nil {
+		return PackQuantityResult{}, err
+	}
+	// Keep the opposite of the delta, and so the shortfalls, within an int
+	delta = max(delta, -math.MaxInt)
+
 	tx, err := pb.db.BeginTx(ctx, nil)
 	if err != nil {
-		return Pack{}, fmt.Errorf("begin transaction: %w", err)
+		return PackQuantityResult{}, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
 			log.Printf("failed to rollback transaction: %v", err)
 		}
 	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return PackQuantityResult{}, err
+	}
 	rows, err := tx.QueryContext(ctx, `
-    SELECT c.code, c.kind, c.part, c.quantity, c.total
+    SELECT c.code, c.kind, c.part, pc.count, c.quantity, c.total
     FROM courses c
-    JOIN pack_courses pc ON c.code = pc.course_code
+    JOIN pack_courses pc ON c.academic_year = pc.academic_year
+      AND c.code = pc.course_code
       AND c.kind = pc.course_kind
       AND c.part = pc.course_part
-    WHERE pc.pack_id = ?`, id)
+    JOIN packs p ON p.id = pc.pack_id
+    WHERE p.academic_year = ? AND pc.pack_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL
+    ORDER BY c.code, c.kind, c.part`, pb.year, id)
 	if err != nil {
-		return Pack{}, fmt.Errorf("get pack courses: %w", err)
+		return PackQuantityResult{}, fmt.Errorf("get pack courses: %w", err)
 	}
 	defer rows.Close()
 
-	type courseUpdate struct {
-		id       CourseID
-		quantity int
-		total    int
-		delta    int // Store per-course delta
-	}
-	var coursesToUpdate []courseUpdate
-
-	// First pass: validate all updates and compute per-course deltas
+	// First pass: work out what each course takes, failing on the first
+	// shortfall under the strict policy
+	result := PackQuantityResult{Policy: policy, Delta: delta, Courses: []CourseQuantityResult{}}
 	for rows.Next() {
-		var code, kind string
-		var part, quantity, total int
-		if err := rows.Scan(&code, &kind, &part, &quantity, &total); err != nil {
-			return Pack{}, fmt.Errorf("scan course: %w", err)
+		var course CourseID
+		var count, quantity, total int
+		if err := rows.Scan(&course.Code, &course.Kind, &course.Part, &count, &quantity, &total); err != nil {
+			return PackQuantityResult{}, fmt.Errorf("scan course: %w", err)
 		}
 
-		// Calculate adjusted delta for this course
-		courseDelta := delta
-		if delta < 0 {
-			// If reducing would go below 0, adjust delta to hit exactly 0
-			if quantity+delta < 0 {
-				courseDelta = -quantity
+		update := packQuantity(course, quantity, total, copies(delta, count), policy)
+		if update.Shortfall > 0 && policy == PolicyStrict {
+			if delta < 0 {
+				return PackQuantityResult{}, invalid("quantity", "quantity would go below zero for course %s", course.ID())
 			}
-		} else if quantity+delta > total {
-			// Check upper bound
-			return Pack{}, fmt.Errorf("quantity would exceed total for course %s/%s/%d", code, kind, part)
+			return PackQuantityResult{}, invalid("quantity", "quantity would exceed total for course %s", course.ID())
 		}
-
-		coursesToUpdate = append(coursesToUpdate, courseUpdate{
-			id:       CourseID{Code: code, Kind: kind, Part: part},
-			quantity: quantity,
-			total:    total,
-			delta:    courseDelta,
-		})
+		result.Courses = append(result.Courses, update)
 	}
 	if err = rows.Err(); err != nil {
-		return Pack{}, fmt.Errorf("iterate courses: %w", err)
+		return PackQuantityResult{}, fmt.Errorf("iterate courses: %w", err)
 	}
-	if len(coursesToUpdate) == 0 {
-		return pb.GetPack(ctx, id)
+	if len(result.Courses) == 0 {
+		result.Pack, err = pb.GetPack(ctx, id)
+		if err != nil {
+			return PackQuantityResult{}, err
+		}
+		return result, nil
 	}
 
 	// Second pass: apply the updates
-	for _, course := range coursesToUpdate {
+	before := make(map[string]int)
+	after := make(map[string]int)
+	for _, course := range result.Courses {
+		if course.Applied == 0 {
+			continue
+		}
+		before[course.Course.ID()] = course.Quantity - course.Applied
+		after[course.Course.ID()] = course.Quantity
+
 		_, err = tx.ExecContext(ctx, `
       UPDATE courses
-      SET quantity = quantity + ?
-      WHERE code = ? AND kind = ? AND part = ?`,
-			course.delta, course.id.Code, course.id.Kind, course.id.Part)
+      SET quantity = quantity + ?, revision = revision + 1
+      WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+			course.Applied, pb.year, course.Course.Code, course.Course.Kind, course.Course.Part)
 		if err != nil {
-			return Pack{}, fmt.Errorf("update course quantity: %w", err)
+			return PackQuantityResult{}, fmt.Errorf("update course quantity: %w", err)
+		}
+
+		if err := pb.recordMovement(ctx, tx, user, course.Course, course.Applied, course.Quantity, ReasonPack, &id); err != nil {
+			return PackQuantityResult{}, err
+		}
+	}
+
+	if len(after) > 0 {
+		if _, err := pb.audit(ctx, tx, user, ActionQuantity, EntityPack, packEntityID(id), before, after); err != nil {
+			return PackQuantityResult{}, err
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return Pack{}, fmt.Errorf("commit transaction: %w", err)
+		return PackQuantityResult{}, fmt.Errorf("commit transaction: %w", err)
 	}
-	details := fmt.Sprintf("updated quantities for pack %d by %d", id, delta)
+	details := fmt.Sprintf("updated quantities for pack %d by %d (%s)", id, delta, policy)
 	if err := pb.logAction(user, "UPDATE PACK QUANTITY", details); err != nil {
 		log.Printf("Warning: failed to log action: %v", err)
 	}
-	return pb.GetPack(ctx, id)
+
+	result.Pack, err = pb.GetPack(ctx, id)
+	if err != nil {
+		return PackQuantityResult{}, err
+	}
+	return result, nil
+}
+
+// trashPack moves a pack to the trash, along with its course memberships.
+func (pb *PB) trashPack(ctx context.Context, user string, id int, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE packs
+    SET deleted_at = ?, deleted_by = ?, revision = revision + 1
+    WHERE academic_year = ? AND id = ? AND deleted_at IS NULL`,
+		time.Now().UTC(), user, pb.year, id)
+	if err != nil {
+		return fmt.Errorf("delete pack: %w", err)
+	}
+	return nil
+}
+
+// clearPackCourses removes the courses of a pack before they are replaced,
+// keeping the memberships of the courses in the trash.
+func (pb *PB) clearPackCourses(ctx context.Context, id int, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    DELETE FROM pack_courses
+    WHERE pack_id = ? AND NOT EXISTS (
+      SELECT 1 FROM courses c
+      WHERE c.academic_year = pack_courses.academic_year
+        AND c.code = pack_courses.course_code
+        AND c.kind = pack_courses.course_kind
+        AND c.part = pack_courses.course_part
+        AND c.deleted_at IS NOT NULL
+    )`, id)
+	if err != nil {
+		return fmt.Errorf("remove existing courses: %w", err)
+	}
+	return nil
+}
+
+// UnmarshalJSON reads a course of a pack, as a single copy when the count is
+// missing: the snapshots of the packs recorded before they could hold several
+// copies of a course have none.
+func (c *PackCourse) UnmarshalJSON(data []byte) error {
+	type packCourse PackCourse
+	course := packCourse{Count: 1}
+	if err := json.Unmarshal(data, &course); err != nil {
+		return err
+	}
+	*c = PackCourse(course)
+	return nil
 }
 
-func validatePack(name string, courses []CourseID) error {
+func validatePack(name string, courses []PackCourse) error {
 	if strings.TrimSpace(name) == "" {
-		return fmt.Errorf("pack name cannot be empty")
+		return invalid("name", "pack name cannot be empty")
 	}
+	return validatePackCourses(courses)
+}
 
+// validatePackCourses checks the courses of a pack, each being listed once
+// with the number of its copies.
+func validatePackCourses(courses []PackCourse) error {
 	if len(courses) == 0 {
-		return fmt.Errorf("pack must contain at least one course")
+		return invalid("courses", "pack must contain at least one course")
 	}
 
 	seen := make(map[string]bool)
-	for _, id := range courses {
-		if seen[id.ID()] {
-			return fmt.Errorf("duplicate course in pack: %s", id.ID())
+	for _, course := range courses {
+		if seen[course.ID()] {
+			return invalid("courses", "duplicate course in pack: %s", course.ID())
+		}
+		seen[course.ID()] = true
+		if course.Count < 1 {
+			return invalid("courses", "pack must contain at least one copy of course %s", course.ID())
 		}
-		seen[id.ID()] = true
 	}
 
 	return nil
 }
+
+// addPackCourses adds courses to a pack, with the number of their copies.
+func (pb *PB) addPackCourses(ctx context.Context, id int, courses []PackCourse, tx *sql.Tx) error {
+	for _, course := range courses {
+		_, err := tx.ExecContext(ctx, `
+      INSERT INTO pack_courses (pack_id, academic_year, course_code, course_kind, course_part, count)
+      VALUES (?, ?, ?, ?, ?, ?)`,
+			id, pb.year, course.Code, course.Kind, course.Part, course.Count)
+		if err != nil {
+			return fmt.Errorf("add course to pack: %w", err)
+		}
+	}
+	return nil
+}
+
+// validatePackGroup checks the semester and level of a pack, either being
+// empty when the pack spans several of them.
+func (pb *PB) validatePackGroup(semester string, level Level) (string, Level, error) {
+	semester = strings.TrimSpace(semester)
+	if semester != "" {
+		if err := pb.catalogue.validateSemester(semester); err != nil {
+			return "", "", err
+		}
+	}
+	if strings.TrimSpace(string(level)) == "" {
+		return semester, "", nil
+	}
+	level, err := ParseLevel(string(level))
+	if err != nil {
+		return "", "", err
+	}
+	return semester, level, nil
+}
+
+// fixedPrice reads the fixed price of a pricing, NULL for a computed price.
+func fixedPrice(price sql.NullInt64) *Price {
+	if !price.Valid {
+		return nil
+	}
+	fixed := Price(price.Int64)
+	return &fixed
+}
@@ -0,0 +1,519 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+)
+
+func (pb *PB) InsertPart(ctx context.Context, user string, course Course) (Course, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	course, err = pb.validateCourse(course)
+	if err != nil {
+		return Course{}, err
+	}
+
+	parts, err := pb.partNumbers(ctx, tx, course.Code, course.Kind)
+	if err != nil {
+		return Course{}, err
+	}
+	last := 0
+	if len(parts) > 0 {
+		last = parts[len(parts)-1]
+	}
+	if course.Part > last+1 {
+		return Course{}, invalid("part", "PART must be in 1-%d", last+1)
+	}
+
+	// Only a part appended after the last one can clash with the trash, the
+	// others take the place of a live part
+	trashed, err := pb.trashed(ctx, course.CID(), tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if trashed {
+		return Course{}, alreadyExists("course %s already exists in the trash", course.ID())
+	}
+
+	moves := map[int]int{}
+	for _, part := range parts {
+		if part >= course.Part {
+			moves[part] = part + 1
+		}
+	}
+	if err := pb.moveParts(ctx, tx, user, course.Code, course.Kind, moves); err != nil {
+		return Course{}, err
+	}
+
+	if err := pb.insertCourse(ctx, tx, user, course); err != nil {
+		return Course{}, err
+	}
+
+	if err := pb.setParts(ctx, course.CID(), tx); err != nil {
+		return Course{}, fmt.Errorf("set parts: %w", err)
+	}
+
+	created, err := pb.getCourse(ctx, course.CID(), tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get created course: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityCourse, course.ID(), nil, created); err != nil {
+		return Course{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("inserted course %s", course.ID())
+	if err := pb.logAction(user, "INSERT PART", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.GetCourse(ctx, course.CID())
+}
+
+func (pb *PB) SplitCourse(ctx context.Context, user string, id CourseID, quantity int) ([]Course, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	current, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return nil, fmt.Errorf("get course: %w", err)
+	}
+
+	if quantity < 0 || quantity > current.Quantity {
+		return nil, invalid("quantity", "quantity must be in 0-%d", current.Quantity)
+	}
+
+	newID := NewCourseID(id.Code, id.Kind, id.Part+1)
+	trashed, err := pb.trashed(ctx, newID, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if trashed {
+		return nil, alreadyExists("course %s already exists in the trash", newID.ID())
+	}
+
+	parts, err := pb.partNumbers(ctx, tx, id.Code, id.Kind)
+	if err != nil {
+		return nil, err
+	}
+	moves := map[int]int{}
+	for _, part := range parts {
+		if part > id.Part {
+			moves[part] = part + 1
+		}
+	}
+	if err := pb.moveParts(ctx, tx, user, id.Code, id.Kind, moves); err != nil {
+		return nil, err
+	}
+
+	// The new part starts empty and the copies are then transferred, so that
+	// they come out of the editions of the course
+	split := current
+	split.Part = newID.Part
+	split.Quantity = 0
+	if err := pb.insertCourse(ctx, tx, user, split); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET quantity = quantity - ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		quantity, pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return nil, fmt.Errorf("update quantity: %w", err)
+	}
+	if err := pb.recordMovement(ctx, tx, user, id, -quantity, current.Quantity-quantity, ReasonTransfer, nil); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET quantity = ?
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		quantity, pb.year, newID.Code, newID.Kind, newID.Part); err != nil {
+		return nil, fmt.Errorf("update quantity: %w", err)
+	}
+	if err := pb.recordMovement(ctx, tx, user, newID, quantity, quantity, ReasonTransfer, nil); err != nil {
+		return nil, err
+	}
+
+	// Packs selling the course now sell both parts
+	if _, err := tx.ExecContext(ctx, `
+    INSERT INTO pack_courses (pack_id, academic_year, course_code, course_kind, course_part, count)
+    SELECT pack_id, academic_year, course_code, course_kind, ?, count
+    FROM pack_courses
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		newID.Part, pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return nil, fmt.Errorf("add part to packs: %w", err)
+	}
+
+	if err := pb.setParts(ctx, id, tx); err != nil {
+		return nil, fmt.Errorf("set parts: %w", err)
+	}
+
+	updated, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return nil, fmt.Errorf("get updated course: %w", err)
+	}
+	if _, err := pb.audit(ctx, tx, user, ActionSplit, EntityCourse, id.ID(), current, updated); err != nil {
+		return nil, err
+	}
+
+	created, err := pb.getCourse(ctx, newID, tx)
+	if err != nil {
+		return nil, fmt.Errorf("get created course: %w", err)
+	}
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityCourse, newID.ID(), nil, created); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("split course %s into %s", id.ID(), newID.ID())
+	if err := pb.logAction(user, "SPLIT", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.getParts(ctx, []CourseID{id, newID})
+}
+
+func (pb *PB) MergeParts(ctx context.Context, user string, id CourseID, part int) (Course, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+	if part == id.Part {
+		return Course{}, invalid("part", "cannot merge a part into itself")
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	current, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get course: %w", err)
+	}
+
+	otherID := NewCourseID(id.Code, id.Kind, part)
+	other, err := pb.getCourse(ctx, otherID, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get merged part: %w", err)
+	}
+
+	// The merged part hands its stock over before it goes away, its editions
+	// with it, and the stock then goes to the editions of the course
+	if err := pb.insertMovement(ctx, tx, user, otherID, -other.Quantity, 0, ReasonTransfer, nil); err != nil {
+		return Course{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+    DELETE FROM course_editions
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, otherID.Code, otherID.Kind, otherID.Part); err != nil {
+		return Course{}, fmt.Errorf("delete merged part editions: %w", err)
+	}
+
+	// The course keeps its own prices
+	if _, err := tx.ExecContext(ctx, `
+    DELETE FROM price_changes
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, otherID.Code, otherID.Kind, otherID.Part); err != nil {
+		return Course{}, fmt.Errorf("delete merged part prices: %w", err)
+	}
+
+	// Packs holding both parts keep a single membership
+	if _, err := tx.ExecContext(ctx, `
+    DELETE FROM pack_courses
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?
+      AND pack_id IN (
+        SELECT pack_id FROM pack_courses
+        WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?
+      )`,
+		pb.year, otherID.Code, otherID.Kind, otherID.Part,
+		pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return Course{}, fmt.Errorf("remove duplicate pack memberships: %w", err)
+	}
+
+	// The history of the merged part goes to the course marked with the ID of
+	// the part, which the parts renumbered after it take over
+	if err := pb.moveCourseReferences(ctx, otherID, id, tx); err != nil {
+		return Course{}, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE stock_movements
+    SET course_code = ?, course_kind = ?, course_part = ?, merged_from = COALESCE(merged_from, ?)
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		id.Code, id.Kind, id.Part, otherID.ID(),
+		pb.year, otherID.Code, otherID.Kind, otherID.Part); err != nil {
+		return Course{}, fmt.Errorf("move merged part movements: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE audit_events
+    SET entity_id = ?, merged_from = COALESCE(merged_from, ?)
+    WHERE academic_year = ? AND entity_type = ? AND entity_id = ?`,
+		id.ID(), otherID.ID(), pb.year, string(EntityCourse), otherID.ID()); err != nil {
+		return Course{}, fmt.Errorf("move merged part audit events: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+    DELETE FROM courses
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		pb.year, otherID.Code, otherID.Kind, otherID.Part); err != nil {
+		return Course{}, fmt.Errorf("delete merged part: %w", err)
+	}
+
+	newQuantity := current.Quantity + other.Quantity
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET quantity = ?, total = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		newQuantity, current.Total+other.Total, pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return Course{}, fmt.Errorf("update quantity: %w", err)
+	}
+	if err := pb.recordMovement(ctx, tx, user, id, other.Quantity, newQuantity, ReasonTransfer, nil); err != nil {
+		return Course{}, err
+	}
+
+	merged, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get merged course: %w", err)
+	}
+	if _, err := pb.audit(ctx, tx, user, ActionMerge, EntityCourse, id.ID(), current, merged); err != nil {
+		return Course{}, err
+	}
+
+	parts, err := pb.partNumbers(ctx, tx, id.Code, id.Kind)
+	if err != nil {
+		return Course{}, err
+	}
+	moves := map[int]int{}
+	for _, p := range parts {
+		if p > part {
+			moves[p] = p - 1
+		}
+	}
+	if err := pb.moveParts(ctx, tx, user, id.Code, id.Kind, moves); err != nil {
+		return Course{}, err
+	}
+	if to, ok := moves[id.Part]; ok {
+		id.Part = to
+	}
+
+	if err := pb.setParts(ctx, id, tx); err != nil {
+		return Course{}, fmt.Errorf("set parts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("merged course %s into %s", otherID.ID(), id.ID())
+	if err := pb.logAction(user, "MERGE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.GetCourse(ctx, id)
+}
+
+func (pb *PB) RenumberParts(ctx context.Context, user string, code string, kind string) ([]Course, error) {
+	first, err := pb.validateCourseID(NewCourseID(code, kind, 1))
+	if err != nil {
+		return nil, err
+	}
+	code, kind = first.Code, first.Kind
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	parts, err := pb.partNumbers(ctx, tx, code, kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) == 0 {
+		return nil, notFound("no course %s %s", code, kind)
+	}
+
+	moves := map[int]int{}
+	ids := make([]CourseID, len(parts))
+	for i, part := range parts {
+		moves[part] = i + 1
+		ids[i] = NewCourseID(code, kind, i+1)
+	}
+	if err := pb.moveParts(ctx, tx, user, code, kind, moves); err != nil {
+		return nil, err
+	}
+
+	if err := pb.setParts(ctx, ids[0], tx); err != nil {
+		return nil, fmt.Errorf("set parts: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("renumbered the parts of %s %s", code, kind)
+	if err := pb.logAction(user, "RENUMBER", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.getParts(ctx, ids)
+}
+
+// moveParts renumbers the live parts of a code and kind, moves mapping their
+// part to their new one, and points their references to their new ID. The
+// parts go through negative numbers first, so that they can take each
+// other's place.
+func (pb *PB) moveParts(ctx context.Context, tx *sql.Tx, user string, code string, kind string, moves map[int]int) error {
+	var from []int
+	for part, to := range moves {
+		if part != to {
+			from = append(from, part)
+		}
+	}
+	slices.Sort(from)
+
+	before := make([]Course, len(from))
+	for i, part := range from {
+		to := NewCourseID(code, kind, moves[part])
+		if to.Part <= 0 || to.Part >= 1000 {
+			return invalid("part", "PART must be in 1-1000")
+		}
+		trashed, err := pb.trashed(ctx, to, tx)
+		if err != nil {
+			return fmt.Errorf("failed to check trash: %w", err)
+		}
+		if trashed {
+			return alreadyExists("course %s already exists in the trash", to.ID())
+		}
+		if before[i], err = pb.getCourse(ctx, NewCourseID(code, kind, part), tx); err != nil {
+			return fmt.Errorf("get course: %w", err)
+		}
+	}
+
+	for _, part := range from {
+		if err := pb.movePart(ctx, tx, NewCourseID(code, kind, part), -moves[part], 0); err != nil {
+			return err
+		}
+	}
+	for _, part := range from {
+		if err := pb.movePart(ctx, tx, NewCourseID(code, kind, -moves[part]), moves[part], 1); err != nil {
+			return err
+		}
+	}
+
+	for _, current := range before {
+		id := NewCourseID(code, kind, moves[current.Part])
+		moved, err := pb.getCourse(ctx, id, tx)
+		if err != nil {
+			return fmt.Errorf("get moved course: %w", err)
+		}
+		if _, err := pb.audit(ctx, tx, user, ActionUpdate, EntityCourse, id.ID(), current, moved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pb *PB) movePart(ctx context.Context, tx *sql.Tx, id CourseID, part int, revision int) error {
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET part = ?, revision = revision + ?
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		part, revision, pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return fmt.Errorf("move part: %w", err)
+	}
+	return pb.renameCourseReferences(ctx, id, NewCourseID(id.Code, id.Kind, part), tx)
+}
+
+// partNumbers lists the live parts of a code and kind in order.
+func (pb *PB) partNumbers(ctx context.Context, tx *sql.Tx, code string, kind string) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `
+    SELECT part FROM courses
+    WHERE academic_year = ? AND code = ? AND kind = ? AND deleted_at IS NULL
+    ORDER BY part`,
+		pb.year, code, kind)
+	if err != nil {
+		return nil, fmt.Errorf("list parts: %w", err)
+	}
+	defer rows.Close()
+
+	var parts []int
+	for rows.Next() {
+		var part int
+		if err := rows.Scan(&part); err != nil {
+			return nil, fmt.Errorf("scan part: %w", err)
+		}
+		parts = append(parts, part)
+	}
+	return parts, rows.Err()
+}
+
+func (pb *PB) getParts(ctx context.Context, ids []CourseID) ([]Course, error) {
+	courses := make([]Course, len(ids))
+	for i, id := range ids {
+		course, err := pb.GetCourse(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		courses[i] = course
+	}
+	return courses, nil
+}
@@ -2,27 +2,104 @@ package libpolybase
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"time"
 )
 
 type CourseNotFound struct{}
 
-type CourseID struct {
+// AcademicYear is a school year, named after the calendar year it starts in:
+// 2026 stands for 2026-2027. Courses, packs and their history belong to one.
+type AcademicYear int
+
+// YearInfo describes an academic year. A year is read-only once it has been
+// rolled over.
+type YearInfo struct {
+	Year     AcademicYear `json:"year"`
+	ReadOnly bool         `json:"read_only"`
+	Courses  int          `json:"courses"`
+	Packs    int          `json:"packs"`
+}
+
+// RollOverOptions tunes RollOverYear. Without any, courses keep their
+// quantity and total.
+type RollOverOptions struct {
+	// ResetQuantities refills every course up to its total.
+	ResetQuantities bool
+	// ResetTotals lowers the total of every course to the quantity left,
+	// keeping it for the courses out of stock.
+	ResetTotals bool
+}
+
+// CloneOptions selects the courses CloneCourses copies and what changes on
+// the copies.
+type CloneOptions struct {
+	// Year is the academic year to copy from, the year of the Polybase when
+	// zero.
+	Year AcademicYear
+	// Code is the code to copy. Kind narrows the copy down to a kind, and
+	// Part to a single course of that kind.
 	Code string
 	Kind string
 	Part int
+	// ToCode and ToSemester replace the code and the semester of the copies
+	// when set.
+	ToCode     string
+	ToSemester string
+}
+
+// RevisionConflict is returned when an update is made against a stale
+// revision of a course or a pack.
+type RevisionConflict struct {
+	Expected int
+	Current  int
+}
+
+// RevertConflict is returned when an audited change cannot be reverted,
+// usually because the entity was modified by a later change.
+type RevertConflict struct {
+	ChangeID int
+	Reason   string
+}
+
+type CourseID struct {
+	Code string `json:"code"`
+	Kind string `json:"kind"`
+	Part int    `json:"part"`
 }
 
 type Course struct {
-	Code     string
-	Kind     string
-	Part     int
-	Parts    int
-	Name     string
-	Quantity int
-	Total    int
-	Shown    bool
-	Semester string
-	Year     int
+	Code     string `json:"code"`
+	Kind     string `json:"kind"`
+	Part     int    `json:"part"`
+	Parts    int    `json:"parts"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Total    int    `json:"total"`
+	// Status is where the course stands in its lifecycle. Shown follows
+	// from it: only the available and out of stock courses are public.
+	Status   CourseStatus `json:"status"`
+	Shown    bool         `json:"shown"`
+	Semester string       `json:"semester"`
+	Level    Level        `json:"level,omitempty"`
+	// Pages is the page count of a copy, 0 when unknown.
+	Pages int `json:"pages"`
+	// Price is what students pay for a copy, the price in effect in its
+	// price history. PrintCost is what printing one costs the association.
+	Price     Price  `json:"price"`
+	PrintCost Price  `json:"print_cost"`
+	Teacher   string `json:"teacher"`
+	// Edition names the version of the source document, published on
+	// EditionDate (YYYY-MM-DD). Both are empty when unknown.
+	Edition     string `json:"edition"`
+	EditionDate string `json:"edition_date"`
+	Description string `json:"description"`
+	// Tags are free-form groupings of courses, sorted regardless of case.
+	Tags []string `json:"tags,omitempty"`
+	// HasMaster tells whether a master file was uploaded for the course.
+	HasMaster bool `json:"has_master"`
+	Revision  int  `json:"revision"`
 }
 
 type PartialCourse struct {
@@ -33,36 +110,537 @@ type PartialCourse struct {
 	Name     *string
 	Quantity *int
 	Total    *int
+	Status   *CourseStatus
+	// Shown is kept for compatibility, hiding a course makes it a draft and
+	// showing it makes it available. Status takes precedence.
 	Shown    *bool
 	Semester *string
+
+	Pages       *int
+	Price       *Price
+	PrintCost   *Price
+	Teacher     *string
+	Edition     *string
+	EditionDate *string
+	Description *string
+	Tags        *[]string
+
+	// Revision is the revision the caller expects the course to be at. The
+	// update fails with a RevisionConflict when it does not match.
+	Revision *int
+}
+
+// Edition is a printing of a course, holding its share of the course stock.
+// The quantities of the live editions of a course add up to the course
+// quantity: distributions draw from the oldest edition first and restocks go
+// to the current one. Retired editions no longer hold any stock.
+type Edition struct {
+	ID     int      `json:"id"`
+	Course CourseID `json:"course"`
+	Label  string   `json:"label"`
+	// Date is the publication date of the edition (YYYY-MM-DD), empty when
+	// unknown.
+	Date      string     `json:"date"`
+	Notes     string     `json:"notes"`
+	Quantity  int        `json:"quantity"`
+	Current   bool       `json:"current"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy string     `json:"created_by"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	RetiredBy string     `json:"retired_by,omitempty"`
+}
+
+type PartialEdition struct {
+	Label *string
+	Date  *string
+	Notes *string
+}
+
+// Tag is a tag along with the number of live courses holding it.
+type Tag struct {
+	Name    string `json:"name"`
+	Courses int    `json:"courses"`
+}
+
+// MasterFile is an upload of the source document of a course, stored in the
+// file store under the SHA-256 Checksum of its content. The newest upload of
+// a course is its current master.
+type MasterFile struct {
+	ID         int       `json:"id"`
+	Course     CourseID  `json:"course"`
+	Name       string    `json:"name"`
+	Checksum   string    `json:"checksum"`
+	Size       int64     `json:"size"`
+	Current    bool      `json:"current"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	UploadedBy string    `json:"uploaded_by"`
+}
+
+// Note is a message left by a volunteer on a course or a pack, Course being
+// set for the former and PackID for the latter. UpdatedAt is set once the
+// author edits it.
+type Note struct {
+	ID        int        `json:"id"`
+	Course    *CourseID  `json:"course,omitempty"`
+	PackID    *int       `json:"pack_id,omitempty"`
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Price is an amount in euro cents.
+type Price int
+
+// Level is the level of study of a course, derived from its code by the
+// catalogue. Levels are ordered as listed below, LevelOther coming last.
+type Level string
+
+const (
+	LevelL1 Level = "L1"
+	LevelL2 Level = "L2"
+	LevelL3 Level = "L3"
+	LevelM1 Level = "M1"
+	LevelM2 Level = "M2"
+	// LevelOther is the level of the courses whose code the catalogue does
+	// not map to one of the above.
+	LevelOther Level = "other"
+)
+
+// CourseStatus is a step of the lifecycle of a course. A course goes from
+// draft to awaiting print to available, and then out of stock and back until
+// it is discontinued. Next lists the allowed transitions.
+type CourseStatus string
+
+const (
+	StatusDraft         CourseStatus = "draft"
+	StatusAwaitingPrint CourseStatus = "awaiting_print"
+	StatusAvailable     CourseStatus = "available"
+	StatusOutOfStock    CourseStatus = "out_of_stock"
+	StatusDiscontinued  CourseStatus = "discontinued"
+)
+
+type CourseSort string
+
+const (
+	// SortSemester orders by semester, latest first, then by code, kind and
+	// part. It is the default.
+	SortSemester CourseSort = "semester"
+	SortCode     CourseSort = "code"
+	SortName     CourseSort = "name"
+	SortQuantity CourseSort = "quantity"
+)
+
+type StockLevel string
+
+const (
+	// StockLow selects the courses with at most LowStockPercent of their
+	// total left, including the empty ones.
+	StockLow StockLevel = "low"
+	StockOut StockLevel = "out"
+)
+
+const LowStockPercent = 10
+
+// CourseFilter selects the courses listed by ListCourses. Its zero value lists
+// every shown course.
+type CourseFilter struct {
+	ShowHidden bool
+	// Search matches a substring of the name or the code, ignoring case.
+	Search   string
+	Semester *string
+	Code     *string
+	Part     *int
+	// Kinds matches any of the given kinds.
+	Kinds []string
+	// Level is one of the levels, all of them when empty.
+	Level  Level
+	Stock  StockLevel
+	PackID *int
+	// Tags matches the courses holding every given tag.
+	Tags []string
+	// Statuses matches any of the given statuses.
+	Statuses []CourseStatus
+	// MissingMaster matches the courses without a master file.
+	MissingMaster bool
+	Sort          CourseSort
+	// Desc reverses the order of the sort key, ties are still ordered by
+	// code, kind and part.
+	Desc  bool
+	Limit int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// Page is a page of results. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T
+	NextCursor string
+}
+
+// SearchOptions tunes SearchCourses. A zero Limit returns every match.
+type SearchOptions struct {
+	ShowHidden bool
+	Limit      int
 }
 
 type Pack struct {
-	ID      int
-	Name    string
-	Courses []CourseID
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Shown packs are listed on the public page.
+	Shown bool `json:"shown"`
+	// Semester and Level are empty for a pack spanning several of them.
+	Semester string       `json:"semester,omitempty"`
+	Level    Level        `json:"level,omitempty"`
+	Courses  []PackCourse `json:"courses"`
+	// Availability is computed from the stock of the courses when the pack
+	// is read, and so is Price from their prices and the pricing in effect.
+	Availability PackAvailability `json:"availability"`
+	Pricing      PackPricing      `json:"pricing"`
+	Price        Price            `json:"price"`
+	Revision     int              `json:"revision"`
+}
+
+// PackCourse is a course of a pack, Count copies of it going in each pack,
+// such as one sheet for each practical group.
+type PackCourse struct {
+	CourseID
+	Count int `json:"count"`
+}
+
+// PackPricing tells how the price of a pack is set: Fixed when not nil,
+// otherwise the sum of the prices of its courses less Discount percent,
+// rounded to the nearest cent.
+type PackPricing struct {
+	Fixed    *Price `json:"fixed,omitempty"`
+	Discount int    `json:"discount"`
+}
+
+// PriceChange is an entry of the price history of a course or a pack, Course
+// being set for the former and PackID for the latter. It is in effect from
+// EffectiveFrom (YYYY-MM-DD) until the next entry takes over, the current one
+// being the latest in effect. Price is the price of the course, or the fixed
+// price of the pack when its pricing has one.
+type PriceChange struct {
+	ID            int       `json:"id"`
+	Course        *CourseID `json:"course,omitempty"`
+	PackID        *int      `json:"pack_id,omitempty"`
+	Price         *Price    `json:"price,omitempty"`
+	Discount      int       `json:"discount,omitempty"`
+	EffectiveFrom string    `json:"effective_from"`
+	Current       bool      `json:"current"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     string    `json:"created_by"`
+}
+
+// PackAvailability tells whether a pack can be handed out.
+type PackAvailability struct {
+	// Complete is the number of complete packs the stock makes, the lowest
+	// quantity among the courses of the pack divided by their count.
+	Complete int `json:"complete"`
+	// Limiting is the first course making the fewest packs, nil for a pack
+	// without courses.
+	Limiting *CourseID `json:"limiting,omitempty"`
+	// OutOfStock lists the courses short of the copies a single pack takes.
+	OutOfStock []CourseID `json:"out_of_stock,omitempty"`
+	Hidden     []CourseID `json:"hidden,omitempty"`
+}
+
+// PackQuantityPolicy tells UpdatePackQuantity what to do with the courses of
+// a pack that cannot take the whole delta, their quantity having to stay
+// between zero and their total.
+type PackQuantityPolicy string
+
+const (
+	// PolicyStrict updates every course or none of them. It is the default.
+	PolicyStrict PackQuantityPolicy = "strict"
+	// PolicyClamp applies to each course as much of the delta as it can take.
+	PolicyClamp PackQuantityPolicy = "clamp"
+	// PolicySkip leaves out the courses that cannot take the whole delta.
+	PolicySkip PackQuantityPolicy = "skip"
+)
+
+// PackQuantityResult is the outcome of UpdatePackQuantity, Pack being the
+// pack as updated.
+type PackQuantityResult struct {
+	Pack    Pack                   `json:"pack"`
+	Policy  PackQuantityPolicy     `json:"policy"`
+	Delta   int                    `json:"delta"`
+	Courses []CourseQuantityResult `json:"courses"`
+}
+
+// CourseQuantityResult tells how a course of a pack was updated, in copies:
+// the delta times the count of the course in the pack. Applied is the part of
+// it the course took, Shortfall the copies it could not take, and Quantity its
+// quantity after the update.
+type CourseQuantityResult struct {
+	Course    CourseID `json:"course"`
+	Applied   int      `json:"applied"`
+	Quantity  int      `json:"quantity"`
+	Shortfall int      `json:"shortfall"`
 }
 
 type PartialPack struct {
-	Name    *string
-	Courses *[]CourseID
+	Name     *string
+	Shown    *bool
+	Semester *string
+	Level    *Level
+	Courses  *[]PackCourse
+	// Pricing takes effect on the day of the update.
+	Pricing *PackPricing
+	// Revision is the revision the caller expects the pack to be at. The
+	// update fails with a RevisionConflict when it does not match.
+	Revision *int
+}
+
+type MovementReason string
+
+const (
+	ReasonDistribution MovementReason = "distribution"
+	ReasonRestock      MovementReason = "restock"
+	ReasonCorrection   MovementReason = "correction"
+	ReasonPack         MovementReason = "pack"
+	ReasonRollOver     MovementReason = "rollover"
+	// ReasonWriteOff is the removal of the stock of a retired edition.
+	ReasonWriteOff MovementReason = "write_off"
+	// ReasonTransfer moves stock between the parts of a course when they are
+	// split or merged.
+	ReasonTransfer MovementReason = "transfer"
+)
+
+// Movement is an entry of the stock ledger, recorded for every change of a
+// course quantity.
+type Movement struct {
+	ID        int
+	Course    CourseID
+	Delta     int
+	Quantity  int
+	Actor     string
+	Reason    MovementReason
+	PackID    *int
+	CreatedAt time.Time
+	// MergedFrom is the ID of the part the movement was made on, if that part
+	// was since merged into the course.
+	MergedFrom string
+}
+
+type MovementFilter struct {
+	Course *CourseID
+	Code   *string
+	PackID *int
+	Actor  *string
+	Reason *MovementReason
+	Since  *time.Time
+	Until  *time.Time
+	Limit  int
+}
+
+type AuditAction string
+
+const (
+	ActionCreate     AuditAction = "create"
+	ActionUpdate     AuditAction = "update"
+	ActionDelete     AuditAction = "delete"
+	ActionQuantity   AuditAction = "quantity"
+	ActionVisibility AuditAction = "visibility"
+	ActionStatus     AuditAction = "status"
+	ActionRestore    AuditAction = "restore"
+	ActionPurge      AuditAction = "purge"
+	ActionRollOver   AuditAction = "rollover"
+	ActionRetire     AuditAction = "retire"
+	ActionSplit      AuditAction = "split"
+	ActionMerge      AuditAction = "merge"
+	// ActionUpload events record a master file uploaded for a course, the
+	// file being their after snapshot.
+	ActionUpload AuditAction = "upload"
+)
+
+type EntityType string
+
+const (
+	EntityCourse EntityType = "course"
+	EntityPack   EntityType = "pack"
+	EntityYear   EntityType = "year"
+	// EntityEdition events are identified by the ID of the edition.
+	EntityEdition EntityType = "edition"
+	// EntityPrice events are identified by the ID of the price change.
+	EntityPrice EntityType = "price"
+)
+
+// AuditEvent is a structured record of a mutation. Before and After hold JSON
+// snapshots of the entity, Before is empty on creation and After on deletion.
+type AuditEvent struct {
+	ID         int
+	Actor      string
+	Action     AuditAction
+	EntityType EntityType
+	EntityID   string
+	Before     json.RawMessage
+	After      json.RawMessage
+	CreatedAt  time.Time
+	// RevertedBy is the ID of the event that reverted this one, if any.
+	RevertedBy *int
+	// MergedFrom is the ID of the part the change was made on, if that part
+	// was since merged into the course.
+	MergedFrom string
+}
+
+// AuditChange is the before and after JSON value of a field changed by an
+// audit event.
+type AuditChange struct {
+	Before json.RawMessage
+	After  json.RawMessage
+}
+
+type AuditFilter struct {
+	EntityType *EntityType
+	EntityID   *string
+	Actor      *string
+	Action     *AuditAction
+	Since      *time.Time
+	Until      *time.Time
+	Limit      int
+}
+
+// TrashedCourse is a deleted course kept in the trash until it is restored
+// or purged.
+type TrashedCourse struct {
+	Course    Course
+	DeletedAt time.Time
+	DeletedBy string
+}
+
+// TrashedPack is a deleted pack kept in the trash until it is restored or
+// purged. Its courses include the ones that are in the trash as well.
+type TrashedPack struct {
+	Pack      Pack
+	DeletedAt time.Time
+	DeletedBy string
+}
+
+type Trash struct {
+	Courses []TrashedCourse
+	Packs   []TrashedPack
 }
 
 type Polybase interface {
+	Catalogue() Catalogue
+
+	// Year is the academic year the operations apply to, ForYear returns the
+	// same Polybase scoped to another one.
+	Year() AcademicYear
+	ForYear(year AcademicYear) Polybase
+	ListYears(ctx context.Context) ([]YearInfo, error)
+	RollOverYear(ctx context.Context, user string, from AcademicYear, to AcademicYear, opts RollOverOptions) (YearInfo, error)
+
 	CreateCourse(ctx context.Context, user string, cours Course) (Course, error)
+	// CreateCourses creates all the courses or none of them.
+	CreateCourses(ctx context.Context, user string, courses []Course) ([]Course, error)
+	// CloneCourses copies courses into the year of the Polybase. The copies
+	// start out of stock.
+	CloneCourses(ctx context.Context, user string, opts CloneOptions) ([]Course, error)
 	GetCourse(ctx context.Context, id CourseID) (Course, error)
 	UpdateCourse(ctx context.Context, user string, id CourseID, partial PartialCourse) (Course, error)
 	DeleteCourse(ctx context.Context, user string, id CourseID) error
-	ListCourse(ctx context.Context, showHidden bool, filterSemester *string, filterCode *string, filterKind *string, filterPart *int) ([]Course, error)
+	ListCourses(ctx context.Context, filter CourseFilter) (Page[Course], error)
+	SearchCourses(ctx context.Context, query string, opts SearchOptions) ([]Course, error)
 
 	UpdateCourseQuantity(ctx context.Context, user string, id CourseID, delta int) (Course, error)
+	// SetCourseStatus moves a course to another step of its lifecycle,
+	// failing with a ValidationError when the transition is not allowed.
+	SetCourseStatus(ctx context.Context, user string, id CourseID, status CourseStatus) (Course, error)
+	// UpdateCourseShown is kept for compatibility, hiding a course makes it a
+	// draft and showing it makes it available.
 	UpdateCourseShown(ctx context.Context, user string, id CourseID, shown bool) (Course, error)
 
-	CreatePack(ctx context.Context, user string, name string, courses []CourseID) (Pack, error)
+	// InsertPart creates a course at its part, moving the following parts of
+	// its code and kind one up.
+	InsertPart(ctx context.Context, user string, course Course) (Course, error)
+	// SplitCourse adds a part right after a course, with the same details
+	// and quantity copies taken from it. It returns both parts.
+	SplitCourse(ctx context.Context, user string, id CourseID, quantity int) ([]Course, error)
+	// MergeParts merges another part of the same code and kind into a
+	// course, which takes its stock and pack memberships.
+	MergeParts(ctx context.Context, user string, id CourseID, part int) (Course, error)
+	// RenumberParts closes the gaps between the parts of a code and kind.
+	RenumberParts(ctx context.Context, user string, code string, kind string) ([]Course, error)
+
+	// ListTags lists the tags of the live courses, shown ones only unless
+	// showHidden is set.
+	ListTags(ctx context.Context, showHidden bool) ([]Tag, error)
+	AddCourseTags(ctx context.Context, user string, id CourseID, tags []string) (Course, error)
+	RemoveCourseTags(ctx context.Context, user string, id CourseID, tags []string) (Course, error)
+	// RenameTag renames a tag on every course holding it, merging it into
+	// the tag it is renamed to. It returns the number of live courses
+	// changed, and so does DeleteTag.
+	RenameTag(ctx context.Context, user string, from string, to string) (int, error)
+	DeleteTag(ctx context.Context, user string, tag string) (int, error)
+
+	// ListEditions lists the editions of a course, oldest first, retired
+	// ones included.
+	ListEditions(ctx context.Context, id CourseID) ([]Edition, error)
+	// AddEdition adds an edition to a course and makes it the current one,
+	// its quantity restocking the course.
+	AddEdition(ctx context.Context, user string, id CourseID, edition Edition) (Edition, error)
+	UpdateEdition(ctx context.Context, user string, id int, partial PartialEdition) (Edition, error)
+	SetCurrentEdition(ctx context.Context, user string, id int) (Edition, error)
+	// RetireEdition writes off the stock of an edition.
+	RetireEdition(ctx context.Context, user string, id int) (Edition, error)
+
+	// PutMasterFile stores a PDF document as the current master file of a
+	// course. Identical contents are stored once.
+	PutMasterFile(ctx context.Context, user string, id CourseID, name string, content io.Reader) (MasterFile, error)
+	// GetMasterFile returns the current master file of a course.
+	GetMasterFile(ctx context.Context, id CourseID) (MasterFile, error)
+	// ListMasterFiles lists the master files of a course, newest first.
+	ListMasterFiles(ctx context.Context, id CourseID) ([]MasterFile, error)
+	// OpenMasterFile opens the content of a master file, which the caller
+	// must close.
+	OpenMasterFile(ctx context.Context, fileID int) (MasterFile, io.ReadCloser, error)
+
+	// ListCourseNotes and ListPackNotes list the notes left on a course or a
+	// pack, newest first.
+	ListCourseNotes(ctx context.Context, id CourseID) ([]Note, error)
+	ListPackNotes(ctx context.Context, id int) ([]Note, error)
+	AddCourseNote(ctx context.Context, user string, id CourseID, body string) (Note, error)
+	AddPackNote(ctx context.Context, user string, id int, body string) (Note, error)
+	GetNote(ctx context.Context, id int) (Note, error)
+	// UpdateNote and DeleteNote fail with ErrForbidden unless user is the
+	// author of the note.
+	UpdateNote(ctx context.Context, user string, id int, body string) (Note, error)
+	DeleteNote(ctx context.Context, user string, id int) error
+
+	CreatePack(ctx context.Context, user string, name string, courses []PackCourse) (Pack, error)
 	GetPack(ctx context.Context, id int) (Pack, error)
 	UpdatePack(ctx context.Context, user string, id int, partial PartialPack) (Pack, error)
 	DeletePack(ctx context.Context, user string, id int) error
 	ListPacks(ctx context.Context) ([]Pack, error)
+	// ListShownPacks lists the packs published on the public page.
+	ListShownPacks(ctx context.Context) ([]Pack, error)
+
+	// UpdatePackQuantity adds delta packs to the stock: every course of the
+	// pack gets delta times its count, following policy for the courses that
+	// cannot take all of it. The strict policy fails with a ValidationError
+	// instead.
+	UpdatePackQuantity(ctx context.Context, user string, id int, delta int, policy PackQuantityPolicy) (PackQuantityResult, error)
+
+	// SetCoursePrice and SetPackPricing add an entry to the price history of
+	// a course or a pack, in effect from a date (YYYY-MM-DD), today when
+	// empty. Prices set through UpdateCourse and UpdatePack take effect
+	// today.
+	SetCoursePrice(ctx context.Context, user string, id CourseID, price Price, from string) (PriceChange, error)
+	SetPackPricing(ctx context.Context, user string, id int, pricing PackPricing, from string) (PriceChange, error)
+	// ListCoursePrices and ListPackPrices list the price history of a course
+	// or a pack, latest effective date first.
+	ListCoursePrices(ctx context.Context, id CourseID) ([]PriceChange, error)
+	ListPackPrices(ctx context.Context, id int) ([]PriceChange, error)
+
+	ListMovements(ctx context.Context, filter MovementFilter) ([]Movement, error)
+	ListAuditEvents(ctx context.Context, filter AuditFilter) ([]AuditEvent, error)
+	RevertChange(ctx context.Context, user string, changeID int) (AuditEvent, error)
 
-	UpdatePackQuantity(ctx context.Context, user string, id int, delta int) (Pack, error)
+	ListTrash(ctx context.Context) (Trash, error)
+	RestoreCourse(ctx context.Context, user string, id CourseID) (Course, error)
+	RestorePack(ctx context.Context, user string, id int) (Pack, error)
+	PurgeTrash(ctx context.Context, user string, before time.Time) (int, error)
 }
@@ -0,0 +1,383 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsePrice reads an amount of euros such as 3, 3.5 or 3,50.
+func ParsePrice(s string) (Price, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "€"))
+	if s == "" {
+		return 0, nil
+	}
+	euros, cents, decimal := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if decimal && len(cents) == 1 {
+		cents += "0"
+	}
+	e, err := strconv.Atoi(euros)
+	if err != nil || e < 0 || strings.HasPrefix(euros, "+") {
+		return 0, invalid("price", "price must be an amount of euros such as 3.50")
+	}
+	c := 0
+	if decimal {
+		c, err = strconv.Atoi(cents)
+		if err != nil || len(cents) != 2 || c < 0 {
+			return 0, invalid("price", "price must be an amount of euros such as 3.50")
+		}
+	}
+	return Price(e*100 + c), nil
+}
+
+// String writes the price in euros with two decimals, such as 3.50.
+func (p Price) String() string {
+	sign := ""
+	if p < 0 {
+		sign, p = "-", -p
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, p/100, p%100)
+}
+
+func (pb *PB) SetCoursePrice(ctx context.Context, user string, id CourseID, price Price, from string) (PriceChange, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return PriceChange{}, err
+	}
+	if price < 0 {
+		return PriceChange{}, invalid("price", "price cannot be negative")
+	}
+	from, err = effectiveDate(from)
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return PriceChange{}, err
+	}
+
+	exists, err := pb.exists(ctx, id, tx)
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return PriceChange{}, notFound("course %s does not exist", id.ID())
+	}
+
+	changeID, err := pb.insertPriceChange(ctx, tx, user, PriceChange{Course: &id, Price: &price, EffectiveFrom: from})
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	// A price in effect right away is an edit of the course
+	if from <= today() {
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE courses SET revision = revision + 1
+      WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+			pb.year, id.Code, id.Kind, id.Part); err != nil {
+			return PriceChange{}, fmt.Errorf("update course revision: %w", err)
+		}
+	}
+
+	change, err := pb.getPriceChange(ctx, changeID, tx)
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityPrice, priceEntityID(changeID), nil, change); err != nil {
+		return PriceChange{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PriceChange{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("set price of course %s to %s from %s", id.ID(), price, from)
+	if err := pb.logAction(user, "SET PRICE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return change, nil
+}
+
+func (pb *PB) SetPackPricing(ctx context.Context, user string, id int, pricing PackPricing, from string) (PriceChange, error) {
+	if err := validatePricing(pricing); err != nil {
+		return PriceChange{}, err
+	}
+	from, err := effectiveDate(from)
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return PriceChange{}, err
+	}
+
+	if _, err := pb.getPack(ctx, id, tx); err != nil {
+		return PriceChange{}, err
+	}
+
+	changeID, err := pb.insertPriceChange(ctx, tx, user, PriceChange{PackID: &id, Price: pricing.Fixed, Discount: pricing.Discount, EffectiveFrom: from})
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	if from <= today() {
+		if _, err := tx.ExecContext(ctx, "UPDATE packs SET revision = revision + 1 WHERE academic_year = ? AND id = ?", pb.year, id); err != nil {
+			return PriceChange{}, fmt.Errorf("update pack revision: %w", err)
+		}
+	}
+
+	change, err := pb.getPriceChange(ctx, changeID, tx)
+	if err != nil {
+		return PriceChange{}, err
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionCreate, EntityPrice, priceEntityID(changeID), nil, change); err != nil {
+		return PriceChange{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PriceChange{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("set pricing of pack %d to %s from %s", id, pricing, from)
+	if err := pb.logAction(user, "SET PRICE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return change, nil
+}
+
+func (pb *PB) ListCoursePrices(ctx context.Context, id CourseID) ([]PriceChange, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := pb.exists(ctx, id, pb.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check course existence: %w", err)
+	}
+	if !exists {
+		return nil, notFound("course %s does not exist", id.ID())
+	}
+
+	return pb.listPriceChanges(ctx, `p.course_code = ? AND p.course_kind = ? AND p.course_part = ?`, id.Code, id.Kind, id.Part)
+}
+
+func (pb *PB) ListPackPrices(ctx context.Context, id int) ([]PriceChange, error) {
+	if _, err := pb.getPack(ctx, id, pb.db); err != nil {
+		return nil, err
+	}
+
+	return pb.listPriceChanges(ctx, "p.pack_id = ?", id)
+}
+
+// String describes a pricing, such as "5.00" or "-10%".
+func (p PackPricing) String() string {
+	if p.Fixed != nil {
+		return p.Fixed.String()
+	}
+	return fmt.Sprintf("-%d%%", p.Discount)
+}
+
+// price computes the price of a pack from the prices of its courses.
+func (p PackPricing) price(courses []Price) Price {
+	if p.Fixed != nil {
+		return *p.Fixed
+	}
+	var sum Price
+	for _, price := range courses {
+		sum += price
+	}
+	return (sum*Price(100-p.Discount) + 50) / 100
+}
+
+func validatePricing(pricing PackPricing) error {
+	if pricing.Fixed != nil {
+		if *pricing.Fixed < 0 {
+			return invalid("price", "price cannot be negative")
+		}
+		if pricing.Discount != 0 {
+			return invalid("discount", "a fixed price cannot have a discount")
+		}
+	}
+	if pricing.Discount < 0 || pricing.Discount > 100 {
+		return invalid("discount", "discount must be between 0 and 100 percent")
+	}
+	return nil
+}
+
+func samePricing(a PackPricing, b PackPricing) bool {
+	if (a.Fixed == nil) != (b.Fixed == nil) {
+		return false
+	}
+	return (a.Fixed == nil || *a.Fixed == *b.Fixed) && a.Discount == b.Discount
+}
+
+// effectiveDate checks the date a price takes effect on, today when empty.
+func effectiveDate(from string) (string, error) {
+	from = strings.TrimSpace(from)
+	if from == "" {
+		return today(), nil
+	}
+	if _, err := time.Parse(time.DateOnly, from); err != nil {
+		return "", invalid("from", "date must be written as YYYY-MM-DD")
+	}
+	return from, nil
+}
+
+// today is the local date prices are in effect on, as SQLite writes it with
+// date('now', 'localtime').
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
+
+// insertPriceChange adds an entry to the price history of the course or the
+// pack of the change.
+func (pb *PB) insertPriceChange(ctx context.Context, tx *sql.Tx, user string, change PriceChange) (int, error) {
+	var code, kind sql.NullString
+	var part sql.NullInt64
+	if change.Course != nil {
+		code = sql.NullString{String: change.Course.Code, Valid: true}
+		kind = sql.NullString{String: change.Course.Kind, Valid: true}
+		part = sql.NullInt64{Int64: int64(change.Course.Part), Valid: true}
+	}
+	result, err := tx.ExecContext(ctx, `
+    INSERT INTO price_changes (academic_year, course_code, course_kind, course_part, pack_id, price, discount, effective_from, created_at, created_by)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		pb.year, code, kind, part, change.PackID, change.Price, change.Discount, change.EffectiveFrom, time.Now().UTC(), user)
+	if err != nil {
+		return 0, fmt.Errorf("record price: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("get price change id: %w", err)
+	}
+	return int(id), nil
+}
+
+// priceChangeColumns selects the entries of the price histories.
+var priceChangeColumns = `p.id, p.course_code, p.course_kind, p.course_part, p.pack_id, p.price, p.discount, p.effective_from,
+    p.id IS (` + currentPriceChange("p") + `), p.created_at, p.created_by
+    FROM price_changes p`
+
+func (pb *PB) getPriceChange(ctx context.Context, id int, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (PriceChange, error) {
+	row := querier.QueryRowContext(ctx, "SELECT "+priceChangeColumns+`
+    WHERE p.academic_year = ? AND p.id = ?`,
+		pb.year, id)
+	change, err := scanPriceChange(row)
+	if err == sql.ErrNoRows {
+		return PriceChange{}, notFound("price change %d does not exist", id)
+	}
+	if err != nil {
+		return PriceChange{}, fmt.Errorf("get price change: %w", err)
+	}
+	return change, nil
+}
+
+func (pb *PB) listPriceChanges(ctx context.Context, condition string, args ...any) ([]PriceChange, error) {
+	rows, err := pb.db.QueryContext(ctx, "SELECT "+priceChangeColumns+`
+    WHERE p.academic_year = ? AND `+condition+`
+    ORDER BY p.effective_from DESC, p.id DESC`,
+		append([]any{pb.year}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("list price changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []PriceChange
+	for rows.Next() {
+		change, err := scanPriceChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan price change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate price changes: %w", err)
+	}
+	return changes, nil
+}
+
+func scanPriceChange(row interface{ Scan(...any) error }) (PriceChange, error) {
+	var c PriceChange
+	var code, kind sql.NullString
+	var part, packID, price sql.NullInt64
+	if err := row.Scan(&c.ID, &code, &kind, &part, &packID, &price, &c.Discount, &c.EffectiveFrom,
+		&c.Current, &c.CreatedAt, &c.CreatedBy); err != nil {
+		return PriceChange{}, err
+	}
+	if code.Valid {
+		c.Course = &CourseID{Code: code.String, Kind: kind.String, Part: int(part.Int64)}
+	}
+	if packID.Valid {
+		id := int(packID.Int64)
+		c.PackID = &id
+	}
+	if price.Valid {
+		p := Price(price.Int64)
+		c.Price = &p
+	}
+	return c, nil
+}
+
+// currentPriceChange selects the ID of the entry in effect of the price
+// history a row of the price_changes table belongs to.
+func currentPriceChange(table string) string {
+	return `SELECT cur.id FROM price_changes cur
+      WHERE cur.academic_year = ` + table + `.academic_year AND cur.pack_id IS ` + table + `.pack_id
+        AND cur.course_code IS ` + table + `.course_code AND cur.course_kind IS ` + table + `.course_kind
+        AND cur.course_part IS ` + table + `.course_part AND cur.effective_from <= date('now', 'localtime')
+      ORDER BY cur.effective_from DESC, cur.id DESC LIMIT 1`
+}
+
+// priceColumn selects the price in effect of the course of a row of the
+// courses table, its price column when its history has none.
+func priceColumn(table string) string {
+	return `COALESCE((SELECT cur.price FROM price_changes cur
+      WHERE cur.academic_year = ` + table + `.academic_year AND cur.course_code = ` + table + `.code
+        AND cur.course_kind = ` + table + `.kind AND cur.course_part = ` + table + `.part
+        AND cur.effective_from <= date('now', 'localtime')
+      ORDER BY cur.effective_from DESC, cur.id DESC LIMIT 1), ` + table + `.price)`
+}
+
+// packPricingJoin joins the pricing in effect of the pack of a row of the
+// packs table as pricing, its columns being NULL for the packs without any.
+func packPricingJoin(table string) string {
+	return `LEFT JOIN price_changes pricing ON pricing.id = (SELECT cur.id FROM price_changes cur
+      WHERE cur.pack_id = ` + table + `.id AND cur.effective_from <= date('now', 'localtime')
+      ORDER BY cur.effective_from DESC, cur.id DESC LIMIT 1)`
+}
+
+func priceEntityID(id int) string {
+	return strconv.Itoa(id)
+}
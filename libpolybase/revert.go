@@ -0,0 +1,471 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RevertChange restores the state an audited change started from and returns
+// the audit event recording the revert, which can itself be reverted. It
+// fails with a RevertConflict when the entity was modified since the change.
+func (pb *PB) RevertChange(ctx context.Context, user string, changeID int) (AuditEvent, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return AuditEvent{}, err
+	}
+
+	event, err := pb.getAuditEvent(ctx, changeID, tx)
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	if event.RevertedBy != nil {
+		return AuditEvent{}, &RevertConflict{
+			ChangeID: changeID,
+			Reason:   fmt.Sprintf("already reverted by change %d", *event.RevertedBy),
+		}
+	}
+
+	if event.MergedFrom != "" {
+		return AuditEvent{}, &RevertConflict{
+			ChangeID: changeID,
+			Reason:   fmt.Sprintf("made on part %s, since merged into %s", event.MergedFrom, event.EntityID),
+		}
+	}
+
+	if event.Action == ActionPurge {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "purges cannot be reverted"}
+	}
+
+	if event.Action == ActionRollOver {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "roll-overs cannot be reverted"}
+	}
+
+	if event.Action == ActionSplit || event.Action == ActionMerge {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "part splits and merges cannot be reverted"}
+	}
+
+	if event.Action == ActionUpload {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "uploads are kept in the file history, upload the previous master again instead"}
+	}
+
+	if event.EntityType == EntityEdition {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "edition changes cannot be reverted"}
+	}
+
+	if event.EntityType == EntityPrice {
+		return AuditEvent{}, &RevertConflict{ChangeID: changeID, Reason: "price changes are kept in the price history, set another price instead"}
+	}
+
+	var revertID int
+	switch event.EntityType {
+	case EntityCourse:
+		revertID, err = pb.revertCourse(ctx, tx, user, event)
+	case EntityPack:
+		revertID, err = pb.revertPack(ctx, tx, user, event)
+	default:
+		err = fmt.Errorf("unknown entity type %s", event.EntityType)
+	}
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE audit_events SET reverted_by = ? WHERE id = ?", revertID, changeID)
+	if err != nil {
+		return AuditEvent{}, fmt.Errorf("mark change as reverted: %w", err)
+	}
+
+	revert, err := pb.getAuditEvent(ctx, revertID, tx)
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return AuditEvent{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("reverted change %d on %s %s", changeID, event.EntityType, event.EntityID)
+	if err := pb.logAction(user, "REVERT", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return revert, nil
+}
+
+func (pb *PB) revertCourse(ctx context.Context, tx *sql.Tx, user string, event AuditEvent) (int, error) {
+	var before, after *Course
+	if len(event.Before) > 0 {
+		before = &Course{}
+		if err := json.Unmarshal(event.Before, before); err != nil {
+			return 0, fmt.Errorf("decode before snapshot: %w", err)
+		}
+	}
+	if len(event.After) > 0 {
+		after = &Course{}
+		if err := json.Unmarshal(event.After, after); err != nil {
+			return 0, fmt.Errorf("decode after snapshot: %w", err)
+		}
+	}
+
+	// Snapshots taken before courses had a status only tell whether they
+	// were shown
+	for _, snapshot := range []*Course{before, after} {
+		if snapshot != nil && snapshot.Status == "" {
+			snapshot.Status = shownStatus(StatusDraft, snapshot.Shown)
+		}
+	}
+
+	// Reverting a deletion takes the course out of the trash, or creates it
+	// again once purged
+	if after == nil {
+		exists, err := pb.exists(ctx, before.CID(), tx)
+		if err != nil {
+			return 0, fmt.Errorf("check course existence: %w", err)
+		}
+		if exists {
+			return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("course %s exists again", before.ID())}
+		}
+
+		trashed, err := pb.trashed(ctx, before.CID(), tx)
+		if err != nil {
+			return 0, fmt.Errorf("check trash: %w", err)
+		}
+		if trashed {
+			if err := pb.restoreCourse(ctx, before.CID(), tx); err != nil {
+				return 0, err
+			}
+			restored, err := pb.getCourse(ctx, before.CID(), tx)
+			if err != nil {
+				return 0, fmt.Errorf("get restored course: %w", err)
+			}
+			return pb.audit(ctx, tx, user, ActionRestore, EntityCourse, before.ID(), nil, restored)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+      INSERT INTO courses (academic_year, code, kind, part, parts, name, quantity, total, status, shown, semester,
+        pages, price, print_cost, teacher, edition, edition_date, description, revision)
+      VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			pb.year, before.Code, before.Kind, before.Part, before.Parts, before.Name,
+			before.Quantity, before.Total, before.Status, before.Shown, before.Semester,
+			before.Pages, before.Price, before.PrintCost, before.Teacher,
+			before.Edition, before.EditionDate, before.Description, before.Revision+1)
+		if err != nil {
+			return 0, fmt.Errorf("restore course: %w", err)
+		}
+
+		if err := pb.setParts(ctx, before.CID(), tx); err != nil {
+			return 0, fmt.Errorf("set parts: %w", err)
+		}
+		if err := pb.setCourseTags(ctx, tx, before.CID(), before.Tags); err != nil {
+			return 0, err
+		}
+
+		restored, err := pb.getCourse(ctx, before.CID(), tx)
+		if err != nil {
+			return 0, fmt.Errorf("get restored course: %w", err)
+		}
+		return pb.audit(ctx, tx, user, ActionCreate, EntityCourse, before.ID(), nil, restored)
+	}
+
+	current, err := pb.getCourse(ctx, after.CID(), tx)
+	if _, ok := err.(*CourseNotFound); ok {
+		return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("course %s no longer exists", after.ID())}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get current course: %w", err)
+	}
+
+	if !sameCourse(current, *after) {
+		return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("course %s was modified since", after.ID())}
+	}
+
+	// Reverting a creation moves the course to the trash
+	if before == nil {
+		if err := pb.trashCourse(ctx, user, current.CID(), tx); err != nil {
+			return 0, err
+		}
+		return pb.audit(ctx, tx, user, ActionDelete, EntityCourse, current.ID(), current, nil)
+	}
+
+	if before.CID() != current.CID() {
+		exists, err := pb.exists(ctx, before.CID(), tx)
+		if err != nil {
+			return 0, fmt.Errorf("check course existence: %w", err)
+		}
+		if exists {
+			return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("course %s already exists", before.ID())}
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE courses
+    SET code = ?, kind = ?, part = ?, name = ?, quantity = ?, total = ?, status = ?, shown = ?, semester = ?,
+      pages = ?, price = ?, print_cost = ?, teacher = ?, edition = ?, edition_date = ?, description = ?,
+      revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		before.Code, before.Kind, before.Part, before.Name,
+		before.Quantity, before.Total, before.Status, before.Shown, before.Semester,
+		before.Pages, before.Price, before.PrintCost, before.Teacher,
+		before.Edition, before.EditionDate, before.Description,
+		pb.year, current.Code, current.Kind, current.Part)
+	if err != nil {
+		return 0, fmt.Errorf("restore course: %w", err)
+	}
+
+	if err := pb.setParts(ctx, before.CID(), tx); err != nil {
+		return 0, fmt.Errorf("set parts: %w", err)
+	}
+
+	if before.CID() != current.CID() {
+		if err := pb.setParts(ctx, current.CID(), tx); err != nil {
+			return 0, fmt.Errorf("set parts: %w", err)
+		}
+		if err := pb.renameCourseReferences(ctx, current.CID(), before.CID(), tx); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := pb.setCourseTags(ctx, tx, before.CID(), before.Tags); err != nil {
+		return 0, err
+	}
+
+	if before.Price != current.Price {
+		id := before.CID()
+		if _, err := pb.insertPriceChange(ctx, tx, user, PriceChange{Course: &id, Price: &before.Price, EffectiveFrom: today()}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := pb.recordMovement(ctx, tx, user, before.CID(), before.Quantity-current.Quantity, before.Quantity, ReasonCorrection, nil); err != nil {
+		return 0, err
+	}
+
+	restored, err := pb.getCourse(ctx, before.CID(), tx)
+	if err != nil {
+		return 0, fmt.Errorf("get restored course: %w", err)
+	}
+	return pb.audit(ctx, tx, user, event.Action, EntityCourse, before.ID(), current, restored)
+}
+
+func (pb *PB) revertPack(ctx context.Context, tx *sql.Tx, user string, event AuditEvent) (int, error) {
+	id, err := strconv.Atoi(event.EntityID)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pack id %s", event.EntityID)
+	}
+
+	if event.Action == ActionQuantity {
+		return pb.revertPackQuantity(ctx, tx, user, id, event)
+	}
+
+	var before, after *Pack
+	if len(event.Before) > 0 {
+		before = &Pack{}
+		if err := json.Unmarshal(event.Before, before); err != nil {
+			return 0, fmt.Errorf("decode before snapshot: %w", err)
+		}
+	}
+	if len(event.After) > 0 {
+		after = &Pack{}
+		if err := json.Unmarshal(event.After, after); err != nil {
+			return 0, fmt.Errorf("decode after snapshot: %w", err)
+		}
+	}
+
+	var exists, trashed bool
+	err = tx.QueryRowContext(ctx, "SELECT deleted_at IS NULL, deleted_at IS NOT NULL FROM packs WHERE academic_year = ? AND id = ?", pb.year, id).
+		Scan(&exists, &trashed)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("check pack existence: %w", err)
+	}
+
+	// Reverting a deletion takes the pack out of the trash, or creates it
+	// again with the same ID once purged
+	if after == nil {
+		if exists {
+			return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("pack %d exists again", id)}
+		}
+
+		if trashed {
+			if err := pb.restorePack(ctx, id, tx); err != nil {
+				return 0, err
+			}
+			restored, err := pb.getPack(ctx, id, tx)
+			if err != nil {
+				return 0, fmt.Errorf("get restored pack: %w", err)
+			}
+			return pb.audit(ctx, tx, user, ActionRestore, EntityPack, event.EntityID, nil, restored)
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO packs (id, academic_year, name, shown, semester, level, revision) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			id, pb.year, before.Name, before.Shown, before.Semester, before.Level, before.Revision+1)
+		if err != nil {
+			return 0, fmt.Errorf("restore pack: %w", err)
+		}
+		if err := pb.setPackCourses(ctx, tx, event.ID, id, before.Courses); err != nil {
+			return 0, err
+		}
+		if err := pb.restorePackPricing(ctx, tx, user, id, PackPricing{}, before.Pricing); err != nil {
+			return 0, err
+		}
+
+		restored, err := pb.getPack(ctx, id, tx)
+		if err != nil {
+			return 0, fmt.Errorf("get restored pack: %w", err)
+		}
+		return pb.audit(ctx, tx, user, ActionCreate, EntityPack, event.EntityID, nil, restored)
+	}
+
+	if !exists {
+		return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("pack %d no longer exists", id)}
+	}
+
+	current, err := pb.getPack(ctx, id, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	if current.Name != after.Name || current.Shown != after.Shown || current.Semester != after.Semester ||
+		current.Level != after.Level || !slices.Equal(current.Courses, after.Courses) || !samePricing(current.Pricing, after.Pricing) {
+		return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("pack %d was modified since", id)}
+	}
+
+	// Reverting a creation moves the pack to the trash
+	if before == nil {
+		if err := pb.trashPack(ctx, user, id, tx); err != nil {
+			return 0, err
+		}
+		return pb.audit(ctx, tx, user, ActionDelete, EntityPack, event.EntityID, current, nil)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE packs SET name = ?, shown = ?, semester = ?, level = ?, revision = revision + 1 WHERE academic_year = ? AND id = ?",
+		before.Name, before.Shown, before.Semester, before.Level, pb.year, id)
+	if err != nil {
+		return 0, fmt.Errorf("restore pack: %w", err)
+	}
+	if err := pb.setPackCourses(ctx, tx, event.ID, id, before.Courses); err != nil {
+		return 0, err
+	}
+	if err := pb.restorePackPricing(ctx, tx, user, id, current.Pricing, before.Pricing); err != nil {
+		return 0, err
+	}
+
+	restored, err := pb.getPack(ctx, id, tx)
+	if err != nil {
+		return 0, fmt.Errorf("get restored pack: %w", err)
+	}
+	return pb.audit(ctx, tx, user, ActionUpdate, EntityPack, event.EntityID, current, restored)
+}
+
+// restorePackPricing puts the pricing of a pack snapshot back in effect from
+// today, when it differs from the current one.
+func (pb *PB) restorePackPricing(ctx context.Context, tx *sql.Tx, user string, id int, current PackPricing, before PackPricing) error {
+	if samePricing(current, before) {
+		return nil
+	}
+	_, err := pb.insertPriceChange(ctx, tx, user, PriceChange{PackID: &id, Price: before.Fixed, Discount: before.Discount, EffectiveFrom: today()})
+	return err
+}
+
+// revertPackQuantity gives back the quantities of every course touched by a
+// pack distribution. Its snapshots map course IDs to quantities.
+func (pb *PB) revertPackQuantity(ctx context.Context, tx *sql.Tx, user string, packID int, event AuditEvent) (int, error) {
+	var before, after map[string]int
+	if err := json.Unmarshal(event.Before, &before); err != nil {
+		return 0, fmt.Errorf("decode before snapshot: %w", err)
+	}
+	if err := json.Unmarshal(event.After, &after); err != nil {
+		return 0, fmt.Errorf("decode after snapshot: %w", err)
+	}
+
+	current := make(map[string]int)
+	for courseID, quantity := range after {
+		id, err := parseCourseID(courseID)
+		if err != nil {
+			return 0, err
+		}
+
+		course, err := pb.getCourse(ctx, id, tx)
+		if _, ok := err.(*CourseNotFound); ok {
+			return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("course %s no longer exists", courseID)}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("get current course: %w", err)
+		}
+		if course.Quantity != quantity {
+			return 0, &RevertConflict{ChangeID: event.ID, Reason: fmt.Sprintf("quantity of course %s was modified since", courseID)}
+		}
+		current[courseID] = course.Quantity
+
+		_, err = tx.ExecContext(ctx, `
+      UPDATE courses
+      SET quantity = ?, revision = revision + 1
+      WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+			before[courseID], pb.year, id.Code, id.Kind, id.Part)
+		if err != nil {
+			return 0, fmt.Errorf("restore course quantity: %w", err)
+		}
+
+		if err := pb.recordMovement(ctx, tx, user, id, before[courseID]-quantity, before[courseID], ReasonCorrection, &packID); err != nil {
+			return 0, err
+		}
+	}
+
+	return pb.audit(ctx, tx, user, ActionQuantity, EntityPack, event.EntityID, current, before)
+}
+
+func (pb *PB) setPackCourses(ctx context.Context, tx *sql.Tx, changeID int, packID int, courses []PackCourse) error {
+	if err := pb.clearPackCourses(ctx, packID, tx); err != nil {
+		return err
+	}
+
+	for _, course := range courses {
+		exists, err := pb.exists(ctx, course.CourseID, tx)
+		if err != nil {
+			return fmt.Errorf("check course existence: %w", err)
+		}
+		if !exists {
+			return &RevertConflict{ChangeID: changeID, Reason: fmt.Sprintf("course %s no longer exists", course.ID())}
+		}
+	}
+	return pb.addPackCourses(ctx, packID, courses, tx)
+}
+
+// sameCourse compares a course to a snapshot, ignoring the fields that change
+// without the course being edited.
+func sameCourse(course Course, snapshot Course) bool {
+	snapshot.Revision = course.Revision
+	snapshot.Parts = course.Parts
+	snapshot.HasMaster = course.HasMaster
+	if slices.Equal(course.Tags, snapshot.Tags) {
+		snapshot.Tags = course.Tags
+	}
+	return reflect.DeepEqual(course, snapshot)
+}
+
+func parseCourseID(id string) (CourseID, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 3 {
+		return CourseID{}, fmt.Errorf("invalid course id %s", id)
+	}
+	part, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return CourseID{}, fmt.Errorf("invalid course id %s", id)
+	}
+	return CourseID{Code: parts[0], Kind: parts[1], Part: part}, nil
+}
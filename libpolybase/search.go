@@ -0,0 +1,93 @@
+package libpolybase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SearchCourses looks up the live courses matching every term of the query in
+// their code, name, kind, teacher or description, best matches first. Terms
+// match word prefixes, ignoring case and accents.
+func (pb *PB) SearchCourses(ctx context.Context, query string, opts SearchOptions) ([]Course, error) {
+	match, err := matchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"course_search MATCH ?", "c.academic_year = ?", "c.deleted_at IS NULL"}
+	args := []any{match, pb.year}
+	if !opts.ShowHidden {
+		conditions = append(conditions, "c.shown = 1")
+	}
+
+	// A match in the code weighs more than one in the name, itself more than
+	// one in the teacher, the kind or the description
+	q := `
+    SELECT c.code, c.kind, c.part, c.parts, c.name, c.quantity, c.total, c.status, c.shown, c.semester,
+      c.pages, ` + priceColumn("c") + `, c.print_cost, c.teacher, c.edition, c.edition_date, c.description, c.revision,
+      ` + courseTagsColumn("c") + ", " + hasMasterColumn("c") + `
+    FROM course_search
+    JOIN courses c ON c.academic_year = course_search.academic_year
+      AND c.code = course_search.code AND c.kind = course_search.kind AND c.part = course_search.part
+    WHERE ` + strings.Join(conditions, " AND ") + `
+    ORDER BY bm25(course_search, 10.0, 5.0, 1.0, 2.0, 1.0), c.code, c.kind, c.part`
+	if opts.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := pb.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search courses: %w", err)
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		var c Course
+
+		if err := rows.Scan(&c.Code, &c.Kind, &c.Part, &c.Parts, &c.Name,
+			&c.Quantity, &c.Total, &c.Status, &c.Shown, &c.Semester,
+			&c.Pages, &c.Price, &c.PrintCost, &c.Teacher, &c.Edition, &c.EditionDate, &c.Description,
+			&c.Revision, (*tagList)(&c.Tags), &c.HasMaster); err != nil {
+			return nil, fmt.Errorf("scan course: %w", err)
+		}
+
+		var errIn error
+		c.Level, errIn = pb.catalogue.Level(c.Code)
+		if errIn != nil {
+			err = errors.Join(err, errIn, fmt.Errorf("invalid course %s (%s)", c.Name, c.Code))
+		} else {
+			courses = append(courses, c)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate courses: %w", err)
+	}
+
+	return courses, nil
+}
+
+// matchQuery turns the terms typed by a user into an FTS5 query matching
+// words starting with each of them. Punctuation only separates terms, so the
+// FTS5 query syntax cannot be injected.
+func matchQuery(query string) (string, error) {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 {
+		return "", invalid("query", "search query cannot be empty")
+	}
+
+	for i, term := range terms {
+		terms[i] = `"` + term + `"*`
+	}
+	return strings.Join(terms, " "), nil
+}
@@ -0,0 +1,155 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+var statuses = []CourseStatus{StatusDraft, StatusAwaitingPrint, StatusAvailable, StatusOutOfStock, StatusDiscontinued}
+
+// transitions lists the statuses each status may move to. A discontinued
+// course comes back as a draft.
+var transitions = map[CourseStatus][]CourseStatus{
+	StatusDraft:         {StatusAwaitingPrint, StatusAvailable, StatusDiscontinued},
+	StatusAwaitingPrint: {StatusDraft, StatusAvailable, StatusDiscontinued},
+	StatusAvailable:     {StatusDraft, StatusAwaitingPrint, StatusOutOfStock, StatusDiscontinued},
+	StatusOutOfStock:    {StatusDraft, StatusAwaitingPrint, StatusAvailable, StatusDiscontinued},
+	StatusDiscontinued:  {StatusDraft},
+}
+
+// Statuses returns every status in lifecycle order.
+func Statuses() []CourseStatus {
+	return slices.Clone(statuses)
+}
+
+// ParseStatus reads a status name, ignoring case and accepting dashes for
+// underscores.
+func ParseStatus(s string) (CourseStatus, error) {
+	s = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), "-", "_")
+	if status := CourseStatus(s); slices.Contains(statuses, status) {
+		return status, nil
+	}
+	return "", invalid("status", "status must be one of draft, awaiting_print, available, out_of_stock or discontinued")
+}
+
+// Visible tells whether the courses with the status are shown to the public.
+func (s CourseStatus) Visible() bool {
+	return s == StatusAvailable || s == StatusOutOfStock
+}
+
+// Next lists the statuses a course may move to from s.
+func (s CourseStatus) Next() []CourseStatus {
+	return slices.Clone(transitions[s])
+}
+
+// CanBecome tells whether a course may move from s to status. Staying at the
+// same status is always allowed.
+func (s CourseStatus) CanBecome(status CourseStatus) bool {
+	return s == status || slices.Contains(transitions[s], status)
+}
+
+func (pb *PB) SetCourseStatus(ctx context.Context, user string, id CourseID, status CourseStatus) (Course, error) {
+	return pb.updateStatus(ctx, user, id, ActionStatus, func(Course) (CourseStatus, error) {
+		return ParseStatus(string(status))
+	})
+}
+
+func (pb *PB) UpdateCourseShown(ctx context.Context, user string, id CourseID, shown bool) (Course, error) {
+	return pb.updateStatus(ctx, user, id, ActionVisibility, func(current Course) (CourseStatus, error) {
+		return shownStatus(current.Status, shown), nil
+	})
+}
+
+// updateStatus moves a course to the status picked from its current state,
+// unless it is already there.
+func (pb *PB) updateStatus(ctx context.Context, user string, id CourseID, action AuditAction, pick func(Course) (CourseStatus, error)) (Course, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	current, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("failed to get current course: %w", err)
+	}
+
+	status, err := pick(current)
+	if err != nil {
+		return Course{}, err
+	}
+	if status == current.Status {
+		return current, nil
+	}
+	if err := checkTransition(current.Status, status); err != nil {
+		return Course{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    UPDATE courses
+    SET status = ?, shown = ?, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		status, status.Visible(), pb.year, id.Code, id.Kind, id.Part,
+	)
+	if err != nil {
+		return Course{}, fmt.Errorf("update status: %w", err)
+	}
+
+	updated, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get updated course: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, action, EntityCourse, id.ID(), current, updated); err != nil {
+		return Course{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("moved course %s from %s to %s", id.ID(), current.Status, status)
+	if err := pb.logAction(user, "UPDATE STATUS", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return updated, nil
+}
+
+func checkTransition(from CourseStatus, to CourseStatus) error {
+	if !from.CanBecome(to) {
+		return invalid("status", "a course cannot go from %s to %s", from, to)
+	}
+	return nil
+}
+
+// shownStatus is the status a course takes when shown or hidden the way
+// courses were before they had a status. A shown course that is out of stock
+// stays so.
+func shownStatus(current CourseStatus, shown bool) CourseStatus {
+	switch {
+	case shown == current.Visible():
+		return current
+	case shown:
+		return StatusAvailable
+	default:
+		return StatusDraft
+	}
+}
@@ -0,0 +1,374 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTagLength bounds the length of a tag, in characters.
+const maxTagLength = 50
+
+func (pb *PB) ListTags(ctx context.Context, showHidden bool) ([]Tag, error) {
+	query := `
+    SELECT t.tag, COUNT(*)
+    FROM course_tags t
+    JOIN courses c ON c.academic_year = t.academic_year
+      AND c.code = t.course_code AND c.kind = t.course_kind AND c.part = t.course_part
+    WHERE t.academic_year = ? AND c.deleted_at IS NULL`
+	if !showHidden {
+		query += " AND c.shown = 1"
+	}
+	query += " GROUP BY t.tag ORDER BY t.tag"
+
+	rows, err := pb.db.QueryContext(ctx, query, pb.year)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []Tag
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Courses); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (pb *PB) AddCourseTags(ctx context.Context, user string, id CourseID, tags []string) (Course, error) {
+	return pb.changeCourseTags(ctx, user, id, tags, true)
+}
+
+func (pb *PB) RemoveCourseTags(ctx context.Context, user string, id CourseID, tags []string) (Course, error) {
+	return pb.changeCourseTags(ctx, user, id, tags, false)
+}
+
+// changeCourseTags adds tags to a course, or removes them. Adding a tag the
+// course already has does nothing, removing one it does not have fails.
+func (pb *PB) changeCourseTags(ctx context.Context, user string, id CourseID, tags []string, add bool) (Course, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+
+	tags, err = validateTags(tags)
+	if err != nil {
+		return Course{}, err
+	}
+	if len(tags) == 0 {
+		return Course{}, invalid("tag", "at least one tag must be given")
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	current, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get course: %w", err)
+	}
+
+	changed := 0
+	for _, tag := range tags {
+		var result sql.Result
+		if add {
+			result, err = tx.ExecContext(ctx, `
+        INSERT OR IGNORE INTO course_tags (academic_year, course_code, course_kind, course_part, tag)
+        VALUES (?, ?, ?, ?, ?)`,
+				pb.year, id.Code, id.Kind, id.Part, tag)
+		} else {
+			result, err = tx.ExecContext(ctx, `
+        DELETE FROM course_tags
+        WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ? AND tag = ?`,
+				pb.year, id.Code, id.Kind, id.Part, tag)
+		}
+		if err != nil {
+			return Course{}, fmt.Errorf("update tag %s: %w", tag, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return Course{}, fmt.Errorf("update tag %s: %w", tag, err)
+		}
+		if n == 0 && !add {
+			return Course{}, notFound("course %s has no tag %s", id.ID(), tag)
+		}
+		changed += int(n)
+	}
+
+	if changed > 0 {
+		if err := pb.auditTagChange(ctx, tx, user, current); err != nil {
+			return Course{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	if changed > 0 {
+		verb := "removed"
+		if add {
+			verb = "added"
+		}
+		details := fmt.Sprintf("%s tags %s on course %s", verb, strings.Join(tags, ", "), id.ID())
+		if err := pb.logAction(user, "TAG", details); err != nil {
+			log.Printf("Warning: failed to log action: %v", err)
+		}
+	}
+
+	return pb.GetCourse(ctx, id)
+}
+
+func (pb *PB) RenameTag(ctx context.Context, user string, from string, to string) (int, error) {
+	from, err := normalizeTag(from)
+	if err != nil {
+		return 0, err
+	}
+	to, err = normalizeTag(to)
+	if err != nil {
+		return 0, err
+	}
+	if from == to {
+		return 0, invalid("tag", "tag %s is renamed to itself", from)
+	}
+
+	return pb.retag(ctx, user, from, func(tx *sql.Tx) error {
+		// Courses holding both tags keep a single one
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE OR IGNORE course_tags SET tag = ?
+      WHERE academic_year = ? AND tag = ?`,
+			to, pb.year, from); err != nil {
+			return fmt.Errorf("rename tag: %w", err)
+		}
+		if strings.EqualFold(from, to) {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, `
+      DELETE FROM course_tags
+      WHERE academic_year = ? AND tag = ?`,
+			pb.year, from); err != nil {
+			return fmt.Errorf("remove renamed tag: %w", err)
+		}
+		return nil
+	}, fmt.Sprintf("renamed tag %s to %s", from, to))
+}
+
+func (pb *PB) DeleteTag(ctx context.Context, user string, tag string) (int, error) {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return 0, err
+	}
+
+	return pb.retag(ctx, user, tag, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `
+      DELETE FROM course_tags
+      WHERE academic_year = ? AND tag = ?`,
+			pb.year, tag); err != nil {
+			return fmt.Errorf("delete tag: %w", err)
+		}
+		return nil
+	}, fmt.Sprintf("deleted tag %s", tag))
+}
+
+// retag applies a change to every course of the year holding a tag, and
+// records it as an update of each live course. It returns how many live
+// courses held the tag.
+func (pb *PB) retag(ctx context.Context, user string, tag string, change func(tx *sql.Tx) error, details string) (int, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	courses, err := pb.taggedCourses(ctx, tx, tag)
+	if err != nil {
+		return 0, err
+	}
+	if len(courses) == 0 {
+		return 0, notFound("no course is tagged %s", tag)
+	}
+
+	if err := change(tx); err != nil {
+		return 0, err
+	}
+
+	for _, course := range courses {
+		if err := pb.auditTagChange(ctx, tx, user, course); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	if err := pb.logAction(user, "TAG", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return len(courses), nil
+}
+
+// taggedCourses lists the live courses holding a tag.
+func (pb *PB) taggedCourses(ctx context.Context, tx *sql.Tx, tag string) ([]Course, error) {
+	rows, err := tx.QueryContext(ctx, `
+    SELECT c.code, c.kind, c.part
+    FROM course_tags t
+    JOIN courses c ON c.academic_year = t.academic_year
+      AND c.code = t.course_code AND c.kind = t.course_kind AND c.part = t.course_part
+    WHERE t.academic_year = ? AND t.tag = ? AND c.deleted_at IS NULL
+    ORDER BY c.code, c.kind, c.part`,
+		pb.year, tag)
+	if err != nil {
+		return nil, fmt.Errorf("list tagged courses: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []CourseID
+	for rows.Next() {
+		var id CourseID
+		if err := rows.Scan(&id.Code, &id.Kind, &id.Part); err != nil {
+			return nil, fmt.Errorf("scan tagged course: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate tagged courses: %w", err)
+	}
+	rows.Close()
+
+	courses := make([]Course, len(ids))
+	for i, id := range ids {
+		if courses[i], err = pb.getCourse(ctx, id, tx); err != nil {
+			return nil, fmt.Errorf("get tagged course: %w", err)
+		}
+	}
+	return courses, nil
+}
+
+// auditTagChange records the change of the tags of a course, as of before,
+// as an update of the course.
+func (pb *PB) auditTagChange(ctx context.Context, tx *sql.Tx, user string, before Course) error {
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		pb.year, before.Code, before.Kind, before.Part); err != nil {
+		return fmt.Errorf("update revision: %w", err)
+	}
+
+	updated, err := pb.getCourse(ctx, before.CID(), tx)
+	if err != nil {
+		return fmt.Errorf("get updated course: %w", err)
+	}
+
+	_, err = pb.audit(ctx, tx, user, ActionUpdate, EntityCourse, before.ID(), before, updated)
+	return err
+}
+
+// setCourseTags replaces the tags of a course.
+func (pb *PB) setCourseTags(ctx context.Context, tx *sql.Tx, id CourseID, tags []string) error {
+	if _, err := tx.ExecContext(ctx, `
+    DELETE FROM course_tags
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, id.Code, id.Kind, id.Part); err != nil {
+		return fmt.Errorf("clear tags: %w", err)
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, `
+      INSERT OR IGNORE INTO course_tags (academic_year, course_code, course_kind, course_part, tag)
+      VALUES (?, ?, ?, ?, ?)`,
+			pb.year, id.Code, id.Kind, id.Part, tag); err != nil {
+			return fmt.Errorf("add tag %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// courseTagsColumn selects the tags of the course of a row of the courses
+// table, sorted and joined by commas, or NULL when it has none.
+func courseTagsColumn(table string) string {
+	return `(SELECT group_concat(t.tag, ',' ORDER BY t.tag) FROM course_tags t
+      WHERE t.academic_year = ` + table + `.academic_year AND t.course_code = ` + table + `.code
+        AND t.course_kind = ` + table + `.kind AND t.course_part = ` + table + `.part)`
+}
+
+// tagList scans the tags selected by courseTagsColumn.
+type tagList []string
+
+func (t *tagList) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*t = nil
+	case string:
+		*t = strings.Split(src, ",")
+	case []byte:
+		*t = strings.Split(string(src), ",")
+	default:
+		return fmt.Errorf("cannot scan %T into tags", src)
+	}
+	return nil
+}
+
+// validateTags normalizes tags and drops the duplicates, which are found
+// regardless of case.
+func validateTags(tags []string) ([]string, error) {
+	var valid []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			valid = append(valid, tag)
+		}
+	}
+	return valid, nil
+}
+
+// normalizeTag trims a tag and collapses its inner spaces. Tags cannot hold
+// commas, which separate them in lists.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.Join(strings.Fields(tag), " ")
+	if tag == "" {
+		return "", invalid("tag", "tag cannot be empty")
+	}
+	if strings.Contains(tag, ",") {
+		return "", invalid("tag", "tag %s cannot contain a comma", tag)
+	}
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", invalid("tag", "tag cannot exceed %d characters", maxTagLength)
+	}
+	return tag, nil
+}
@@ -0,0 +1,412 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+func (pb *PB) ListTrash(ctx context.Context) (Trash, error) {
+	return pb.listTrash(ctx, pb.db)
+}
+
+func (pb *PB) RestoreCourse(ctx context.Context, user string, id CourseID) (Course, error) {
+	id, err := pb.validateCourseID(id)
+	if err != nil {
+		return Course{}, err
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Course{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Course{}, err
+	}
+
+	trashed, err := pb.trashed(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if !trashed {
+		return Course{}, notFound("course %s is not in the trash", id.ID())
+	}
+
+	if err := pb.restoreCourse(ctx, id, tx); err != nil {
+		return Course{}, err
+	}
+
+	restored, err := pb.getCourse(ctx, id, tx)
+	if err != nil {
+		return Course{}, fmt.Errorf("get restored course: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionRestore, EntityCourse, id.ID(), nil, restored); err != nil {
+		return Course{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Course{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("restored course %s", id.ID())
+	if err := pb.logAction(user, "RESTORE", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.GetCourse(ctx, id)
+}
+
+func (pb *PB) RestorePack(ctx context.Context, user string, id int) (Pack, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Pack{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	if err := pb.checkWritable(ctx, tx); err != nil {
+		return Pack{}, err
+	}
+
+	var trashed bool
+	err = tx.QueryRowContext(ctx, `
+    SELECT EXISTS(SELECT 1 FROM packs WHERE academic_year = ? AND id = ? AND deleted_at IS NOT NULL)`,
+		pb.year, id).Scan(&trashed)
+	if err != nil {
+		return Pack{}, fmt.Errorf("failed to check trash: %w", err)
+	}
+	if !trashed {
+		return Pack{}, notFound("pack %d is not in the trash", id)
+	}
+
+	if err := pb.restorePack(ctx, id, tx); err != nil {
+		return Pack{}, err
+	}
+
+	restored, err := pb.getPack(ctx, id, tx)
+	if err != nil {
+		return Pack{}, fmt.Errorf("get restored pack: %w", err)
+	}
+
+	if _, err := pb.audit(ctx, tx, user, ActionRestore, EntityPack, packEntityID(id), nil, restored); err != nil {
+		return Pack{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Pack{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("restored pack %d", id)
+	if err := pb.logAction(user, "RESTORE PACK", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return pb.GetPack(ctx, id)
+}
+
+// PurgeTrash permanently deletes the courses and packs moved to the trash
+// before the given time and returns how many were purged. It covers the trash
+// of every academic year whatever the year of pb, except the read-only years
+// which keep theirs.
+func (pb *PB) PurgeTrash(ctx context.Context, user string, before time.Time) (int, error) {
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	years, err := pb.trashYears(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, year := range years {
+		n, err := pb.forYear(year).purgeTrash(ctx, tx, user, before)
+		if err != nil {
+			return 0, err
+		}
+		purged += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	if purged > 0 {
+		details := fmt.Sprintf("purged %d courses and packs from the trash", purged)
+		if err := pb.logAction(user, "PURGE", details); err != nil {
+			log.Printf("Warning: failed to log action: %v", err)
+		}
+	}
+
+	return purged, nil
+}
+
+// trashYears lists the writable academic years with courses or packs in the
+// trash.
+func (pb *PB) trashYears(ctx context.Context, tx *sql.Tx) ([]AcademicYear, error) {
+	rows, err := tx.QueryContext(ctx, `
+    SELECT academic_year FROM courses WHERE deleted_at IS NOT NULL
+    UNION SELECT academic_year FROM packs WHERE deleted_at IS NOT NULL
+    EXCEPT SELECT year FROM academic_years WHERE read_only
+    ORDER BY 1`)
+	if err != nil {
+		return nil, fmt.Errorf("list trash years: %w", err)
+	}
+	defer rows.Close()
+
+	var years []AcademicYear
+	for rows.Next() {
+		var year AcademicYear
+		if err := rows.Scan(&year); err != nil {
+			return nil, fmt.Errorf("scan trash year: %w", err)
+		}
+		years = append(years, year)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trash years: %w", err)
+	}
+
+	return years, nil
+}
+
+// purgeTrash purges the trash of the year of pb.
+func (pb *PB) purgeTrash(ctx context.Context, tx *sql.Tx, user string, before time.Time) (int, error) {
+	trash, err := pb.listTrash(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, p := range trash.Packs {
+		if !p.DeletedAt.Before(before) {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM pack_courses WHERE pack_id = ?", p.Pack.ID); err != nil {
+			return 0, fmt.Errorf("purge pack courses: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM notes WHERE pack_id = ?", p.Pack.ID); err != nil {
+			return 0, fmt.Errorf("purge pack notes: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM price_changes WHERE pack_id = ?", p.Pack.ID); err != nil {
+			return 0, fmt.Errorf("purge pack prices: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM packs WHERE id = ?", p.Pack.ID); err != nil {
+			return 0, fmt.Errorf("purge pack: %w", err)
+		}
+
+		if _, err := pb.audit(ctx, tx, user, ActionPurge, EntityPack, packEntityID(p.Pack.ID), p.Pack, nil); err != nil {
+			return 0, err
+		}
+		purged++
+	}
+
+	for _, c := range trash.Courses {
+		if !c.DeletedAt.Before(before) {
+			continue
+		}
+
+		if err := pb.purgeCourse(ctx, tx, user, c.Course); err != nil {
+			return 0, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// purgeCourse permanently deletes a trashed course, its pack memberships, its
+// editions, its tags, its notes, its price history and the records of its
+// master files. The content of the files stays in the file store.
+func (pb *PB) purgeCourse(ctx context.Context, tx *sql.Tx, user string, course Course) error {
+	_, err := tx.ExecContext(ctx, `
+    DELETE FROM pack_courses
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course from packs: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM course_editions
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course editions: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM course_tags
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM notes
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course notes: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM master_files
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course master files: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM price_changes
+    WHERE academic_year = ? AND course_code = ? AND course_kind = ? AND course_part = ?`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course prices: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    DELETE FROM courses
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NOT NULL`,
+		pb.year, course.Code, course.Kind, course.Part)
+	if err != nil {
+		return fmt.Errorf("purge course: %w", err)
+	}
+
+	_, err = pb.audit(ctx, tx, user, ActionPurge, EntityCourse, course.ID(), course, nil)
+	return err
+}
+
+func (pb *PB) restoreCourse(ctx context.Context, id CourseID, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE courses
+    SET deleted_at = NULL, deleted_by = NULL, revision = revision + 1
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ?`,
+		pb.year, id.Code, id.Kind, id.Part)
+	if err != nil {
+		return fmt.Errorf("restore course: %w", err)
+	}
+
+	if err := pb.setParts(ctx, id, tx); err != nil {
+		return fmt.Errorf("set parts: %w", err)
+	}
+	return nil
+}
+
+func (pb *PB) restorePack(ctx context.Context, id int, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+    UPDATE packs
+    SET deleted_at = NULL, deleted_by = NULL, revision = revision + 1
+    WHERE academic_year = ? AND id = ?`, pb.year, id)
+	if err != nil {
+		return fmt.Errorf("restore pack: %w", err)
+	}
+	return nil
+}
+
+func (pb *PB) listTrash(ctx context.Context, querier interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) (Trash, error) {
+	var trash Trash
+
+	rows, err := querier.QueryContext(ctx, `
+    SELECT code, kind, part, parts, name, quantity, total, status, shown, semester,
+      pages, `+priceColumn("courses")+`, print_cost, teacher, edition, edition_date, description, revision,
+      `+courseTagsColumn("courses")+", "+hasMasterColumn("courses")+`, deleted_at, deleted_by
+    FROM courses
+    WHERE academic_year = ? AND deleted_at IS NOT NULL
+    ORDER BY deleted_at DESC`, pb.year)
+	if err != nil {
+		return Trash{}, fmt.Errorf("list trashed courses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t TrashedCourse
+		c := &t.Course
+		if err := rows.Scan(&c.Code, &c.Kind, &c.Part, &c.Parts, &c.Name,
+			&c.Quantity, &c.Total, &c.Status, &c.Shown, &c.Semester,
+			&c.Pages, &c.Price, &c.PrintCost, &c.Teacher, &c.Edition, &c.EditionDate, &c.Description,
+			&c.Revision, (*tagList)(&c.Tags), &c.HasMaster, &t.DeletedAt, &t.DeletedBy); err != nil {
+			return Trash{}, fmt.Errorf("scan trashed course: %w", err)
+		}
+		c.Level, _ = pb.catalogue.Level(c.Code)
+		trash.Courses = append(trash.Courses, t)
+	}
+	if err = rows.Err(); err != nil {
+		return Trash{}, fmt.Errorf("iterate trashed courses: %w", err)
+	}
+	rows.Close()
+
+	rows, err = querier.QueryContext(ctx, `
+    SELECT id, name, shown, semester, level, revision, deleted_at, deleted_by
+    FROM packs
+    WHERE academic_year = ? AND deleted_at IS NOT NULL
+    ORDER BY deleted_at DESC`, pb.year)
+	if err != nil {
+		return Trash{}, fmt.Errorf("list trashed packs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t TrashedPack
+		if err := rows.Scan(&t.Pack.ID, &t.Pack.Name, &t.Pack.Shown, &t.Pack.Semester, &t.Pack.Level, &t.Pack.Revision,
+			&t.DeletedAt, &t.DeletedBy); err != nil {
+			return Trash{}, fmt.Errorf("scan trashed pack: %w", err)
+		}
+		trash.Packs = append(trash.Packs, t)
+	}
+	if err = rows.Err(); err != nil {
+		return Trash{}, fmt.Errorf("iterate trashed packs: %w", err)
+	}
+	rows.Close()
+
+	for i := range trash.Packs {
+		pack := &trash.Packs[i].Pack
+		rows, err := querier.QueryContext(ctx, `
+      SELECT course_code, course_kind, course_part, count
+      FROM pack_courses
+      WHERE pack_id = ?
+      ORDER BY course_code, course_kind, course_part`, pack.ID)
+		if err != nil {
+			return Trash{}, fmt.Errorf("get trashed pack courses: %w", err)
+		}
+
+		for rows.Next() {
+			var course PackCourse
+			if err := rows.Scan(&course.Code, &course.Kind, &course.Part, &course.Count); err != nil {
+				rows.Close()
+				return Trash{}, fmt.Errorf("scan course: %w", err)
+			}
+			pack.Courses = append(pack.Courses, course)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return Trash{}, fmt.Errorf("iterate courses: %w", err)
+		}
+	}
+
+	return trash, nil
+}
@@ -3,6 +3,7 @@ package libpolybase
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"regexp"
@@ -15,35 +16,71 @@ type PB struct {
 	db        *sql.DB
 	logPath   string
 	logStdout bool
+	catalogue Catalogue
+	storage   string
+	year      AcademicYear
 }
 
+// New returns a Polybase working on the current academic year, see ForYear
+// for the other ones.
 func New(db *sql.DB, logPath string, logStdout bool) *PB {
-	return &PB{db: db, logPath: logPath, logStdout: logStdout}
+	return &PB{
+		db:        db,
+		logPath:   logPath,
+		logStdout: logStdout,
+		catalogue: defaultCatalogue,
+		year:      CurrentAcademicYear(time.Now()),
+	}
 }
 
-func NewCourseID(code string, kind string, part int) CourseID {
-	return CourseID{code, kind, part}
+// WithCatalogue replaces the default catalogue rules. The catalogue must have
+// been compiled.
+func (pb *PB) WithCatalogue(catalogue Catalogue) *PB {
+	pb.catalogue = catalogue
+	return pb
+}
+
+// WithStorage sets the directory of the file store holding the content of
+// the master files, which cannot be stored or read without one.
+func (pb *PB) WithStorage(dir string) *PB {
+	pb.storage = dir
+	return pb
 }
 
-func (e *CourseNotFound) Error() string {
-	return "course not found"
+func (pb *PB) Catalogue() Catalogue {
+	return pb.catalogue
+}
+
+func NewCourseID(code string, kind string, part int) CourseID {
+	return CourseID{code, kind, part}
 }
 
 func ValidateCourseID(id CourseID) (CourseID, error) {
 	// Validate code: only uppercase, numbers, dashes, and curly braces
 	if !codeRegexp.MatchString(id.Code) {
-		//TODO: update error
-		return CourseID{}, fmt.Errorf("invalid code format: must only contain uppercase letters, numbers, dashes, and curly braces")
+		return CourseID{}, invalid("code", "invalid code format: must only contain uppercase letters, numbers, dashes, and curly braces")
 	}
 
 	// Validate kind: only letters (upper and lowercase)
-	if !regexp.MustCompile(`^[a-zA-Z]+$`).MatchString(id.Kind) {
-		return CourseID{}, fmt.Errorf("invalid kind format: must only contain letters")
+	if !kindRegexp.MatchString(id.Kind) {
+		return CourseID{}, invalid("kind", "invalid kind format: must only contain letters")
 	}
 
 	return id, nil
 }
 
+// validateCourseID also checks the code against the catalogue.
+func (pb *PB) validateCourseID(id CourseID) (CourseID, error) {
+	id, err := ValidateCourseID(id)
+	if err != nil {
+		return CourseID{}, err
+	}
+	if _, err := pb.catalogue.Level(id.Code); err != nil {
+		return CourseID{}, err
+	}
+	return id, nil
+}
+
 func (c Course) ID() string {
 	return fmt.Sprintf("%s/%s/%d", c.Code, c.Kind, c.Part)
 }
@@ -78,45 +115,97 @@ func (c CourseID) PID() string {
 	return fmt.Sprintf("%s %s %d", c.Code, c.Kind, c.Part)
 }
 
-func validateSemester(semester string) error {
-	if semester == "" {
-		return fmt.Errorf("semester cannot be empty")
-	}
-
-	if !strings.HasPrefix(semester, "S") {
-		return fmt.Errorf("semester must start with 'S'")
+func validateQuantity(quantity int, total int) error {
+	if quantity < 0 {
+		return invalid("quantity", "quantity cannot be negative")
 	}
 
-	n, err := strconv.Atoi(semester[1:])
-	if err != nil {
-		return fmt.Errorf("invalid semester format: must be S followed by a number")
+	if total <= 0 {
+		return invalid("total", "total cannot be negative or nil")
 	}
 
-	if n != 1 && n != 2 {
-		return fmt.Errorf("invalid semester format: semester number must be either 1 or 2")
+	if quantity > total {
+		return invalid("quantity", "quantity (%d) cannot exceed total (%d)", quantity, total)
 	}
 
 	return nil
 }
 
-func validateQuantity(quantity int, total int) error {
-	if quantity < 0 {
-		return fmt.Errorf("quantity cannot be negative")
+func clampQuantity(quantity, total int) int {
+	return min(max(quantity, 0), total)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// codesAtLevel lists the codes of the courses of the year at a level. Levels
+// come from the catalogue rules, which SQLite cannot apply.
+func (pb *PB) codesAtLevel(ctx context.Context, level Level) ([]any, error) {
+	rows, err := pb.db.QueryContext(ctx, `
+    SELECT DISTINCT code FROM courses WHERE academic_year = ?`, pb.year)
+	if err != nil {
+		return nil, fmt.Errorf("list codes: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []any
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("scan code: %w", err)
+		}
+		if l, err := pb.catalogue.Level(code); err == nil && l == level {
+			codes = append(codes, code)
+		}
 	}
 
-	if total <= 0 {
-		return fmt.Errorf("total cannot be negative or nil")
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate codes: %w", err)
 	}
 
-	if quantity > total {
-		return fmt.Errorf("quantity (%d) cannot exceed total (%d)", quantity, total)
+	return codes, nil
+}
+
+func courseOrder(sort CourseSort, desc bool) (string, error) {
+	var key string
+	switch sort {
+	case "", SortSemester:
+		// Latest semester first unless reversed
+		desc = !desc
+		key = "semester"
+	case SortCode:
+		key = "code"
+	case SortName:
+		key = "name COLLATE NOCASE"
+	case SortQuantity:
+		key = "quantity"
+	default:
+		return "", invalid("sort", "sort must be one of semester, code, name or quantity")
 	}
 
-	return nil
+	if desc {
+		key += " DESC"
+	}
+	return key + ", code, kind, part", nil
 }
 
-func clampQuantity(quantity, total int) int {
-	return min(max(quantity, 0), total)
+// Cursors are opaque to callers, they only carry the offset of the next page.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid("cursor", "invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(b))
+	if err != nil || offset < 0 {
+		return 0, invalid("cursor", "invalid cursor")
+	}
+	return offset, nil
 }
 
 func (pb *PB) exists(ctx context.Context, id CourseID, querier interface {
@@ -124,8 +213,9 @@ func (pb *PB) exists(ctx context.Context, id CourseID, querier interface {
 }) (bool, error) {
 	var exists int
 	err := querier.QueryRowContext(ctx, `
-    SELECT 1 FROM courses WHERE code = ? AND kind = ? AND part = ?`,
-		id.Code, id.Kind, id.Part).Scan(&exists)
+    SELECT 1 FROM courses
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		pb.year, id.Code, id.Kind, id.Part).Scan(&exists)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
@@ -135,18 +225,37 @@ func (pb *PB) exists(ctx context.Context, id CourseID, querier interface {
 	return true, nil
 }
 
+// trashed reports whether a course with this ID is in the trash.
+func (pb *PB) trashed(ctx context.Context, id CourseID, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}) (bool, error) {
+	var trashed bool
+	err := querier.QueryRowContext(ctx, `
+    SELECT EXISTS(
+      SELECT 1 FROM courses
+      WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NOT NULL
+    )`,
+		pb.year, id.Code, id.Kind, id.Part).Scan(&trashed)
+	return trashed, err
+}
+
 func (pb *PB) getCourse(ctx context.Context, id CourseID, querier interface {
 	QueryRowContext(context.Context, string, ...any) *sql.Row
 }) (Course, error) {
 	var course Course
 	var shown int
 	err := querier.QueryRowContext(ctx, `
-    SELECT code, kind, part, parts, name, quantity, total, shown, semester
+    SELECT code, kind, part, parts, name, quantity, total, status, shown, semester,
+      pages, `+priceColumn("courses")+`, print_cost, teacher, edition, edition_date, description, revision,
+      `+courseTagsColumn("courses")+", "+hasMasterColumn("courses")+`
     FROM courses
-    WHERE code = ? AND kind = ? AND part = ?`,
-		id.Code, id.Kind, id.Part).Scan(
+    WHERE academic_year = ? AND code = ? AND kind = ? AND part = ? AND deleted_at IS NULL`,
+		pb.year, id.Code, id.Kind, id.Part).Scan(
 		&course.Code, &course.Kind, &course.Part, &course.Parts,
-		&course.Name, &course.Quantity, &course.Total, &shown, &course.Semester)
+		&course.Name, &course.Quantity, &course.Total, &course.Status, &shown, &course.Semester,
+		&course.Pages, &course.Price, &course.PrintCost, &course.Teacher,
+		&course.Edition, &course.EditionDate, &course.Description,
+		&course.Revision, (*tagList)(&course.Tags), &course.HasMaster)
 	if err == sql.ErrNoRows {
 		return Course{}, &CourseNotFound{}
 	}
@@ -157,7 +266,16 @@ func (pb *PB) getCourse(ctx context.Context, id CourseID, querier interface {
 	return course, nil
 }
 
+// logAction writes a human readable line to the optional text log. The
+// audit_events table is the source of truth, see audit.go.
 func (pb *PB) logAction(user string, action string, details string) error {
+	timestamp := time.Now().Format("2006/01/02 15:04:05")
+	logEntry := fmt.Sprintf("%s [%s] %s: %s\n", timestamp, user, action, details)
+
+	if pb.logStdout {
+		fmt.Printf("%s", logEntry)
+	}
+
 	if pb.logPath == "" {
 		return nil
 	}
@@ -168,14 +286,9 @@ func (pb *PB) logAction(user string, action string, details string) error {
 	}
 	defer f.Close()
 
-	timestamp := time.Now().Format("2006/01/02 15:04:05")
-	logEntry := fmt.Sprintf("%s [%s] %s: %s\n", timestamp, user, action, details)
-
 	if _, err := f.WriteString(logEntry); err != nil {
 		return fmt.Errorf("failed to write to log file: %v", err)
 	}
 
-	fmt.Printf("%s", logEntry)
-
 	return nil
 }
@@ -0,0 +1,339 @@
+package libpolybase
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CurrentAcademicYear returns the academic year running at the given time.
+// Academic years start in July.
+func CurrentAcademicYear(now time.Time) AcademicYear {
+	now = now.UTC()
+	year := now.Year()
+	if now.Month() < time.July {
+		year--
+	}
+	return AcademicYear(year)
+}
+
+// ParseAcademicYear reads an academic year written as 2026-2027 or 2026.
+func ParseAcademicYear(s string) (AcademicYear, error) {
+	start, end, ranged := strings.Cut(strings.TrimSpace(s), "-")
+	year, err := strconv.Atoi(start)
+	if err != nil || year < 1000 || year > 9998 {
+		return 0, invalid("year", "YEAR must be written as 2026-2027 or 2026")
+	}
+	if ranged {
+		if next, err := strconv.Atoi(end); err != nil || next != year+1 {
+			return 0, invalid("year", "YEAR must be written as 2026-2027 or 2026")
+		}
+	}
+	return AcademicYear(year), nil
+}
+
+func (y AcademicYear) String() string {
+	return fmt.Sprintf("%d-%d", int(y), int(y)+1)
+}
+
+func (pb *PB) Year() AcademicYear {
+	return pb.year
+}
+
+func (pb *PB) ForYear(year AcademicYear) Polybase {
+	return pb.forYear(year)
+}
+
+func (pb *PB) forYear(year AcademicYear) *PB {
+	scoped := *pb
+	scoped.year = year
+	return &scoped
+}
+
+// ListYears lists the academic years holding courses or packs, along with
+// the year of pb, latest first.
+func (pb *PB) ListYears(ctx context.Context) ([]YearInfo, error) {
+	rows, err := pb.db.QueryContext(ctx, `
+    SELECT y.year, COALESCE(a.read_only, 0),
+      (SELECT COUNT(*) FROM courses c WHERE c.academic_year = y.year AND c.deleted_at IS NULL),
+      (SELECT COUNT(*) FROM packs p WHERE p.academic_year = y.year AND p.deleted_at IS NULL)
+    FROM (
+      SELECT year FROM academic_years
+      UNION SELECT academic_year FROM courses
+      UNION SELECT academic_year FROM packs
+      UNION SELECT ?
+    ) AS y
+    LEFT JOIN academic_years a ON a.year = y.year
+    ORDER BY y.year DESC`, pb.year)
+	if err != nil {
+		return nil, fmt.Errorf("list years: %w", err)
+	}
+	defer rows.Close()
+
+	var years []YearInfo
+	for rows.Next() {
+		var info YearInfo
+		if err := rows.Scan(&info.Year, &info.ReadOnly, &info.Courses, &info.Packs); err != nil {
+			return nil, fmt.Errorf("scan year: %w", err)
+		}
+		years = append(years, info)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate years: %w", err)
+	}
+
+	return years, nil
+}
+
+// RollOverYear starts a new academic year from the live courses and packs of
+// a previous one, which becomes read-only. The new year must be empty.
+// Editions are not carried over: the courses of the new year keep the label
+// of their current edition and start without any. Likewise, courses and packs
+// keep the price in effect, which starts their price history.
+func (pb *PB) RollOverYear(ctx context.Context, user string, from AcademicYear, to AcademicYear, opts RollOverOptions) (YearInfo, error) {
+	if to <= from {
+		return YearInfo{}, invalid("year", "cannot roll %s over to %s, an earlier year", from, to)
+	}
+	if opts.ResetQuantities && opts.ResetTotals {
+		return YearInfo{}, invalid("", "quantities and totals cannot both be reset")
+	}
+
+	tx, err := pb.db.BeginTx(ctx, nil)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.Printf("failed to rollback transaction: %v", err)
+		}
+	}()
+
+	source, err := yearInfo(ctx, tx, from)
+	if err != nil {
+		return YearInfo{}, err
+	}
+	if source.Courses == 0 {
+		return YearInfo{}, notFound("academic year %s has no courses", from)
+	}
+
+	var used bool
+	err = tx.QueryRowContext(ctx, `
+    SELECT EXISTS(SELECT 1 FROM courses WHERE academic_year = ?)
+      OR EXISTS(SELECT 1 FROM packs WHERE academic_year = ?)`,
+		to, to).Scan(&used)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("check academic year: %w", err)
+	}
+	if used {
+		return YearInfo{}, alreadyExists("academic year %s already has courses or packs", to)
+	}
+
+	// Courses sold out last year are available again once restocked
+	quantity, total, status := "quantity", "total", "status"
+	if opts.ResetQuantities {
+		quantity = "total"
+		status = "CASE WHEN status = 'out_of_stock' THEN 'available' ELSE status END"
+	}
+	if opts.ResetTotals {
+		total = "CASE WHEN quantity > 0 THEN quantity ELSE total END"
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO courses (academic_year, code, kind, part, parts, name, quantity, total, status, shown, semester,
+      pages, price, print_cost, teacher, edition, edition_date, description)
+    SELECT ?, code, kind, part, parts, name, `+quantity+`, `+total+`, `+status+`, shown, semester,
+      pages, `+priceColumn("courses")+`, print_cost, teacher, edition, edition_date, description
+    FROM courses
+    WHERE academic_year = ? AND deleted_at IS NULL`,
+		to, from)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("copy courses: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO course_tags (academic_year, course_code, course_kind, course_part, tag)
+    SELECT ?, t.course_code, t.course_kind, t.course_part, t.tag
+    FROM course_tags t
+    JOIN courses c ON c.academic_year = t.academic_year
+      AND c.code = t.course_code AND c.kind = t.course_kind AND c.part = t.course_part
+    WHERE t.academic_year = ? AND c.deleted_at IS NULL`,
+		to, from)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("copy tags: %w", err)
+	}
+
+	// The current master file of each course carries over, its content is
+	// already in the file store
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO master_files (academic_year, course_code, course_kind, course_part, name, checksum, size, uploaded_at, uploaded_by)
+    SELECT ?, m.course_code, m.course_kind, m.course_part, m.name, m.checksum, m.size, m.uploaded_at, m.uploaded_by
+    FROM master_files m
+    JOIN courses c ON c.academic_year = m.academic_year
+      AND c.code = m.course_code AND c.kind = m.course_kind AND c.part = m.course_part
+    WHERE m.academic_year = ? AND c.deleted_at IS NULL AND m.id = (`+currentMasterFile("m")+`)
+    ORDER BY m.id`,
+		to, from)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("copy master files: %w", err)
+	}
+
+	// The stock ledger of the new year starts from the quantities carried over
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO stock_movements (academic_year, course_code, course_kind, course_part, delta, quantity, actor, reason, created_at)
+    SELECT academic_year, code, kind, part, quantity, quantity, ?, ?, ?
+    FROM courses
+    WHERE academic_year = ? AND quantity > 0`,
+		user, string(ReasonRollOver), time.Now().UTC(), to)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("record movements: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO price_changes (academic_year, course_code, course_kind, course_part, price, effective_from, created_at, created_by)
+    SELECT academic_year, code, kind, part, price, ?, ?, ?
+    FROM courses
+    WHERE academic_year = ? AND price > 0`,
+		today(), time.Now().UTC(), user, to)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("record prices: %w", err)
+	}
+
+	if err := copyPacks(ctx, tx, user, from, to); err != nil {
+		return YearInfo{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+    INSERT INTO academic_years (year, read_only) VALUES (?, 1), (?, 0)
+    ON CONFLICT (year) DO UPDATE SET read_only = excluded.read_only`,
+		from, to)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("close academic year: %w", err)
+	}
+
+	source.ReadOnly = true
+	created, err := yearInfo(ctx, tx, to)
+	if err != nil {
+		return YearInfo{}, err
+	}
+
+	target := pb.forYear(to)
+	if _, err := target.audit(ctx, tx, user, ActionRollOver, EntityYear, to.String(), source, created); err != nil {
+		return YearInfo{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return YearInfo{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	details := fmt.Sprintf("rolled %s over to %s with %d courses and %d packs", from, to, created.Courses, created.Packs)
+	if err := pb.logAction(user, "ROLL OVER", details); err != nil {
+		log.Printf("Warning: failed to log action: %v", err)
+	}
+
+	return created, nil
+}
+
+// copyPacks gives every live pack of a year a copy in another, holding the
+// same live courses and priced the same.
+func copyPacks(ctx context.Context, tx *sql.Tx, user string, from AcademicYear, to AcademicYear) error {
+	rows, err := tx.QueryContext(ctx, `
+    SELECT packs.id, name, shown, semester, level, pricing.price, COALESCE(pricing.discount, 0) FROM packs
+    `+packPricingJoin("packs")+`
+    WHERE packs.academic_year = ? AND deleted_at IS NULL
+    ORDER BY packs.id`, from)
+	if err != nil {
+		return fmt.Errorf("list packs: %w", err)
+	}
+	defer rows.Close()
+
+	var packs []Pack
+	for rows.Next() {
+		var pack Pack
+		var fixed sql.NullInt64
+		if err := rows.Scan(&pack.ID, &pack.Name, &pack.Shown, &pack.Semester, &pack.Level, &fixed, &pack.Pricing.Discount); err != nil {
+			return fmt.Errorf("scan pack: %w", err)
+		}
+		pack.Pricing.Fixed = fixedPrice(fixed)
+		packs = append(packs, pack)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("iterate packs: %w", err)
+	}
+	rows.Close()
+
+	for _, pack := range packs {
+		result, err := tx.ExecContext(ctx, `
+      INSERT INTO packs (academic_year, name, shown, semester, level) VALUES (?, ?, ?, ?, ?)`,
+			to, pack.Name, pack.Shown, pack.Semester, pack.Level)
+		if err != nil {
+			return fmt.Errorf("copy pack: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("get pack id: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+      INSERT INTO pack_courses (pack_id, academic_year, course_code, course_kind, course_part, count)
+      SELECT ?, ?, pc.course_code, pc.course_kind, pc.course_part, pc.count
+      FROM pack_courses pc
+      JOIN courses c ON c.academic_year = pc.academic_year
+        AND c.code = pc.course_code
+        AND c.kind = pc.course_kind
+        AND c.part = pc.course_part
+      WHERE pc.pack_id = ? AND c.deleted_at IS NULL`,
+			id, to, pack.ID)
+		if err != nil {
+			return fmt.Errorf("copy pack courses: %w", err)
+		}
+
+		if !samePricing(pack.Pricing, PackPricing{}) {
+			_, err = tx.ExecContext(ctx, `
+        INSERT INTO price_changes (academic_year, pack_id, price, discount, effective_from, created_at, created_by)
+        VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				to, id, pack.Pricing.Fixed, pack.Pricing.Discount, today(), time.Now().UTC(), user)
+			if err != nil {
+				return fmt.Errorf("copy pack pricing: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func yearInfo(ctx context.Context, querier interface {
+	QueryRowContext(context.Context, string, ...any) *sql.Row
+}, year AcademicYear) (YearInfo, error) {
+	info := YearInfo{Year: year}
+	err := querier.QueryRowContext(ctx, `
+    SELECT
+      COALESCE((SELECT read_only FROM academic_years WHERE year = ?), 0),
+      (SELECT COUNT(*) FROM courses WHERE academic_year = ? AND deleted_at IS NULL),
+      (SELECT COUNT(*) FROM packs WHERE academic_year = ? AND deleted_at IS NULL)`,
+		year, year, year).Scan(&info.ReadOnly, &info.Courses, &info.Packs)
+	if err != nil {
+		return YearInfo{}, fmt.Errorf("get academic year: %w", err)
+	}
+	return info, nil
+}
+
+// checkWritable fails when the academic year of pb has been rolled over.
+// It must be called in the transaction of the mutation.
+func (pb *PB) checkWritable(ctx context.Context, tx *sql.Tx) error {
+	var readOnly bool
+	err := tx.QueryRowContext(ctx, `
+    SELECT COALESCE((SELECT read_only FROM academic_years WHERE year = ?), 0)`,
+		pb.year).Scan(&readOnly)
+	if err != nil {
+		return fmt.Errorf("check academic year: %w", err)
+	}
+	if readOnly {
+		return conflict("academic year %s is read-only", pb.year)
+	}
+	return nil
+}
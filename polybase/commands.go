@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/term"
 
@@ -17,6 +21,30 @@ var (
 	ErrInvalidUsage = errors.New("invalid usage")
 )
 
+// parseOperands parses the options of a command taking operands. The operands
+// come first, then the options, unless the options come first.
+func parseOperands(flags *flag.FlagSet, args []string) ([]string, error) {
+	if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		return flags.Args(), nil
+	}
+
+	operands := args
+	for i, arg := range args {
+		// A lone dash is an operand naming the standard input
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			operands = args[:i]
+			break
+		}
+	}
+	if err := flags.Parse(args[len(operands):]); err != nil {
+		return nil, err
+	}
+	return operands, nil
+}
+
 func scope(args []string, usage func()) ([]string, string, string, uint8, error) {
 	if len(args) < 3 {
 		usage()
@@ -37,6 +65,8 @@ func runCreate(ctx context.Context, pb libpolybase.Polybase, args []string) erro
 	quantity := flags.Int("q", -1, "initial quantity")
 	total := flags.Int("t", 0, "total quantity")
 	semester := flags.String("s", "", "semester")
+	status := flags.String("status", "", "lifecycle status (default: available)")
+	details := newCourseDetails(flags)
 	jsonOutput := flags.Bool("json", false, "output in JSON format")
 
 	args, code, kind, part, err := scope(args, flags.Usage)
@@ -57,23 +87,121 @@ func runCreate(ctx context.Context, pb libpolybase.Polybase, args []string) erro
 		*total = *quantity
 	}
 
-	created, err := pb.CreateCourse(ctx, getCurrentUser(), libpolybase.Course{
+	course, err := details.course(libpolybase.Course{
 		Code:     code,
 		Kind:     kind,
 		Part:     int(part),
 		Name:     *name,
 		Quantity: *quantity,
 		Total:    *total,
-		Shown:    true,
+		Status:   libpolybase.CourseStatus(*status),
 		Semester: *semester,
 	})
 	if err != nil {
 		return err
 	}
 
+	created, err := pb.CreateCourse(ctx, getCurrentUser(), course)
+	if err != nil {
+		return err
+	}
+
 	return printCourse(created, *jsonOutput)
 }
 
+// courseDetails holds the flags setting the optional details of a course.
+type courseDetails struct {
+	pages       *int
+	price       *string
+	printCost   *string
+	teacher     *string
+	edition     *string
+	editionDate *string
+	description *string
+	tags        *string
+}
+
+func newCourseDetails(flags *flag.FlagSet) courseDetails {
+	return courseDetails{
+		pages:       flags.Int("pages", 0, "page count"),
+		price:       flags.String("price", "", "sale price in euros, such as 3.50"),
+		printCost:   flags.String("print-cost", "", "print cost of a copy in euros"),
+		teacher:     flags.String("teacher", "", "responsible teacher"),
+		edition:     flags.String("edition", "", "edition of the source document"),
+		editionDate: flags.String("edition-date", "", "date of the edition (YYYY-MM-DD)"),
+		description: flags.String("description", "", "free-form description"),
+		tags:        flags.String("tags", "", "tags, comma separated"),
+	}
+}
+
+func (d courseDetails) has(name string) bool {
+	switch name {
+	case "pages", "price", "print-cost", "teacher", "edition", "edition-date", "description", "tags":
+		return true
+	}
+	return false
+}
+
+// course fills the details of a new course.
+func (d courseDetails) course(course libpolybase.Course) (libpolybase.Course, error) {
+	var err error
+	if course.Price, err = libpolybase.ParsePrice(*d.price); err != nil {
+		return libpolybase.Course{}, err
+	}
+	if course.PrintCost, err = libpolybase.ParsePrice(*d.printCost); err != nil {
+		return libpolybase.Course{}, err
+	}
+	course.Pages = *d.pages
+	course.Teacher = *d.teacher
+	course.Edition = *d.edition
+	course.EditionDate = *d.editionDate
+	course.Description = *d.description
+	course.Tags = commaList(*d.tags)
+	return course, nil
+}
+
+// partial updates the details given on the command line.
+func (d courseDetails) partial(flags *flag.FlagSet, partial *libpolybase.PartialCourse) error {
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "pages":
+			partial.Pages = d.pages
+		case "price":
+			price, errIn := libpolybase.ParsePrice(*d.price)
+			err = errors.Join(err, errIn)
+			partial.Price = &price
+		case "print-cost":
+			cost, errIn := libpolybase.ParsePrice(*d.printCost)
+			err = errors.Join(err, errIn)
+			partial.PrintCost = &cost
+		case "teacher":
+			partial.Teacher = d.teacher
+		case "edition":
+			partial.Edition = d.edition
+		case "edition-date":
+			partial.EditionDate = d.editionDate
+		case "description":
+			partial.Description = d.description
+		case "tags":
+			tags := commaList(*d.tags)
+			partial.Tags = &tags
+		}
+	})
+	return err
+}
+
+// commaList splits a comma separated list, dropping the blank items.
+func commaList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func runGet(ctx context.Context, pb libpolybase.Polybase, args []string) error {
 	flags := flag.NewFlagSet("get", flag.ExitOnError)
 	flags.Usage = getUsage(flags)
@@ -114,6 +242,9 @@ func runUpdate(ctx context.Context, pb libpolybase.Polybase, args []string) erro
 	newQuantity := flags.Int("q", 0, "update quantity")
 	newTotal := flags.Int("t", 0, "update total")
 	newSemester := flags.String("s", "", "update semester")
+	newStatus := flags.String("status", "", "update status")
+	details := newCourseDetails(flags)
+	revision := flags.Int("r", 0, "expected revision of the course")
 	jsonOutput := flags.Bool("json", false, "output in JSON format")
 
 	args, code, kind, part, err := scope(args, flags.Usage)
@@ -148,10 +279,21 @@ func runUpdate(ctx context.Context, pb libpolybase.Polybase, args []string) erro
 			partial.Total = newTotal
 		case "s":
 			partial.Semester = newSemester
+		case "status":
+			status := libpolybase.CourseStatus(*newStatus)
+			partial.Status = &status
+		case "r":
+			partial.Revision = revision
+		case "json":
 		default:
-			panic(errors.Join(ErrInvalidUsage, fmt.Errorf("unknown flag %s", f.Name)))
+			if !details.has(f.Name) {
+				panic(errors.Join(ErrInvalidUsage, fmt.Errorf("unknown flag %s", f.Name)))
+			}
 		}
 	})
+	if err := details.partial(flags, &partial); err != nil {
+		return err
+	}
 
 	username := getCurrentUser()
 	updated, err := pb.UpdateCourse(ctx, username, id, partial)
@@ -214,32 +356,103 @@ func runList(ctx context.Context, pb libpolybase.Polybase, args []string) error
 	showHidden := flags.Bool("a", false, "show hidden courses")
 	semester := flags.String("s", "", "filter by semester")
 	code := flags.String("c", "", "filter by course code")
-	kind := flags.String("k", "", "filter by kind")
+	kinds := flags.String("k", "", "filter by kinds, comma separated")
 	part := flags.Int("p", 0, "filter by part number")
+	search := flags.String("q", "", "search the name or the code")
+	level := flags.String("l", "", "filter by level (L1, L2, L3, M1, M2 or other)")
+	stock := flags.String("stock", "", "filter by stock (low or out)")
+	pack := flags.Int("pack", 0, "filter by pack")
+	tags := flags.String("tag", "", "filter by tags, comma separated, all of them")
+	statuses := flags.String("status", "", "filter by statuses, comma separated, hidden ones included")
+	noMaster := flags.Bool("no-master", false, "only list the courses without a master file")
+	sort := flags.String("sort", "", "sort by semester, code, name or quantity")
+	desc := flags.Bool("desc", false, "reverse the sort")
+	limit := flags.Int("n", 0, "list at most N courses")
+	cursor := flags.String("cursor", "", "continue a previous listing")
 	jsonOutput := flags.Bool("json", false, "output in JSON format")
 
 	if err := flags.Parse(args); err != nil {
 		return err
 	}
 
-	var filterSemester, filterCode, filterKind *string
-	var filterPart *int
+	filter := libpolybase.CourseFilter{
+		ShowHidden: *showHidden,
+		Search:     *search,
+		Level:      libpolybase.Level(*level),
+		Stock:      libpolybase.StockLevel(*stock),
+		Sort:       libpolybase.CourseSort(*sort),
+		Desc:       *desc,
+		Limit:      *limit,
+		Cursor:     *cursor,
+
+		MissingMaster: *noMaster,
+	}
 	flags.Visit(func(f *flag.Flag) {
 		switch f.Name {
 		case "s":
-			filterSemester = semester
+			filter.Semester = semester
 		case "c":
-			filterCode = code
+			filter.Code = code
 		case "k":
-			filterKind = kind
+			filter.Kinds = commaList(*kinds)
 		case "p":
-			filterPart = part
-		default:
-			panic(errors.Join(ErrInvalidUsage, fmt.Errorf("unknown flag %s", f.Name)))
+			filter.Part = part
+		case "pack":
+			filter.PackID = pack
+		case "tag":
+			filter.Tags = commaList(*tags)
+		case "status":
+			// Asking for a status lists its courses, shown or not
+			filter.ShowHidden = true
+			for _, status := range commaList(*statuses) {
+				filter.Statuses = append(filter.Statuses, libpolybase.CourseStatus(status))
+			}
 		}
 	})
 
-	courses, err := pb.ListCourse(ctx, *showHidden, filterSemester, filterCode, filterKind, filterPart)
+	page, err := pb.ListCourses(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if err := printCourses(page.Items, *jsonOutput); err != nil {
+		return err
+	}
+	if page.NextCursor != "" {
+		fmt.Fprintf(os.Stderr, "next page: -cursor %s\n", page.NextCursor)
+	}
+	return nil
+}
+
+func runSearch(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	flags.Usage = searchUsage(flags)
+
+	showHidden := flags.Bool("a", false, "search hidden courses too")
+	limit := flags.Int("n", 0, "list at most N courses")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	// Terms come first, then the options
+	terms := args
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			terms = args[:i]
+			break
+		}
+	}
+	if len(terms) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, errors.New("TERMS are required"))
+	}
+
+	if err := flags.Parse(args[len(terms):]); err != nil {
+		return err
+	}
+
+	courses, err := pb.SearchCourses(ctx, strings.Join(terms, " "), libpolybase.SearchOptions{
+		ShowHidden: *showHidden,
+		Limit:      *limit,
+	})
 	if err != nil {
 		return err
 	}
@@ -317,3 +530,1056 @@ func runVisibility(ctx context.Context, pb libpolybase.Polybase, args []string)
 
 	return printCourse(updated, *jsonOutput)
 }
+
+func runStatus(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	flags.Usage = statusUsage(flags)
+
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	args, code, kind, part, err := scope(args, flags.Usage)
+	if err != nil {
+		return err
+	}
+
+	if len(args) < 1 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, errors.New("STATUS is required"))
+	}
+	status := libpolybase.CourseStatus(args[0])
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	id := libpolybase.CourseID{
+		Code: code,
+		Kind: kind,
+		Part: int(part),
+	}
+
+	username := getCurrentUser()
+	updated, err := pb.SetCourseStatus(ctx, username, id, status)
+	if err != nil {
+		return err
+	}
+
+	return printCourse(updated, *jsonOutput)
+}
+
+func runMovements(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("movements", flag.ExitOnError)
+	flags.Usage = movementsUsage(flags)
+
+	code := flags.String("c", "", "filter by course code")
+	kind := flags.String("k", "", "filter by kind (requires -c and -p)")
+	part := flags.Int("p", 0, "filter by part number (requires -c and -k)")
+	pack := flags.Int("pack", 0, "filter by pack ID")
+	actor := flags.String("u", "", "filter by user")
+	reason := flags.String("r", "", "filter by reason (distribution, restock, correction, pack, rollover, write_off, transfer)")
+	since := flags.String("since", "", "only show movements since DATE (YYYY-MM-DD)")
+	until := flags.String("until", "", "only show movements before DATE (YYYY-MM-DD)")
+	limit := flags.Int("n", 50, "maximum number of movements")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := libpolybase.MovementFilter{Limit: *limit}
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "c":
+			filter.Code = code
+		case "pack":
+			filter.PackID = pack
+		case "u":
+			filter.Actor = actor
+		case "r":
+			r := libpolybase.MovementReason(*reason)
+			filter.Reason = &r
+		case "since":
+			var t time.Time
+			t, err = time.ParseInLocation(time.DateOnly, *since, time.Local)
+			filter.Since = &t
+		case "until":
+			var t time.Time
+			t, err = time.ParseInLocation(time.DateOnly, *until, time.Local)
+			filter.Until = &t
+		}
+	})
+	if err != nil {
+		return errors.Join(ErrInvalidUsage, err)
+	}
+
+	if *kind != "" || *part != 0 {
+		if *code == "" || *kind == "" || *part == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("-c, -k and -p must be used together"))
+		}
+		filter.Code = nil
+		filter.Course = &libpolybase.CourseID{Code: *code, Kind: *kind, Part: *part}
+	}
+
+	movements, err := pb.ListMovements(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return printMovements(movements, *jsonOutput)
+}
+
+func runHistory(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	flags.Usage = historyUsage(flags)
+
+	code := flags.String("c", "", "filter by course code (requires -k and -p)")
+	kind := flags.String("k", "", "filter by kind (requires -c and -p)")
+	part := flags.Int("p", 0, "filter by part number (requires -c and -k)")
+	pack := flags.Int("pack", 0, "filter by pack ID")
+	actor := flags.String("u", "", "filter by user")
+	action := flags.String("a", "", "filter by action (create, update, delete, quantity, status, visibility, restore, purge)")
+	since := flags.String("since", "", "only show events since DATE (YYYY-MM-DD)")
+	until := flags.String("until", "", "only show events before DATE (YYYY-MM-DD)")
+	limit := flags.Int("n", 50, "maximum number of events")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := libpolybase.AuditFilter{Limit: *limit}
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "pack":
+			entityType := libpolybase.EntityPack
+			entityID := strconv.Itoa(*pack)
+			filter.EntityType = &entityType
+			filter.EntityID = &entityID
+		case "u":
+			filter.Actor = actor
+		case "a":
+			a := libpolybase.AuditAction(*action)
+			filter.Action = &a
+		case "since":
+			var t time.Time
+			t, err = time.ParseInLocation(time.DateOnly, *since, time.Local)
+			filter.Since = &t
+		case "until":
+			var t time.Time
+			t, err = time.ParseInLocation(time.DateOnly, *until, time.Local)
+			filter.Until = &t
+		}
+	})
+	if err != nil {
+		return errors.Join(ErrInvalidUsage, err)
+	}
+
+	if *code != "" || *kind != "" || *part != 0 {
+		if *code == "" || *kind == "" || *part == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("-c, -k and -p must be used together"))
+		}
+		if filter.EntityType != nil {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("-pack cannot be used with a course"))
+		}
+		entityType := libpolybase.EntityCourse
+		entityID := libpolybase.NewCourseID(*code, *kind, *part).ID()
+		filter.EntityType = &entityType
+		filter.EntityID = &entityID
+	}
+
+	events, err := pb.ListAuditEvents(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return printHistory(events, *jsonOutput)
+}
+
+func runRevert(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("revert", flag.ExitOnError)
+	flags.Usage = revertUsage(flags)
+
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected a change ID"))
+	}
+
+	changeID, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid change ID %s", flags.Arg(0)))
+	}
+
+	event, err := pb.RevertChange(ctx, getCurrentUser(), changeID)
+	if err != nil {
+		return err
+	}
+
+	return printHistory([]libpolybase.AuditEvent{event}, *jsonOutput)
+}
+
+func runTrash(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("trash", flag.ExitOnError)
+	flags.Usage = trashUsage(flags)
+
+	jsonOutput := flags.Bool("json", false, "output in JSON format (list)")
+	pack := flags.Int("pack", 0, "restore the pack ID instead of a course (restore)")
+	days := flags.Int("days", 30, "only purge what was deleted more than N days ago, 0 purges everything (purge)")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list, restore or purge"))
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "list":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		trash, err := pb.ListTrash(ctx)
+		if err != nil {
+			return err
+		}
+		return printTrash(trash, *jsonOutput)
+	case "restore":
+		if len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			if err := flags.Parse(args); err != nil {
+				return err
+			}
+			if *pack == 0 {
+				flags.Usage()
+				return errors.Join(ErrInvalidUsage, fmt.Errorf("expected a course or -pack"))
+			}
+			_, err := pb.RestorePack(ctx, getCurrentUser(), *pack)
+			return err
+		}
+
+		_, code, kind, part, err := scope(args, flags.Usage)
+		if err != nil {
+			return err
+		}
+		id := libpolybase.CourseID{
+			Code: code,
+			Kind: kind,
+			Part: int(part),
+		}
+		_, err = pb.RestoreCourse(ctx, getCurrentUser(), id)
+		return err
+	case "purge":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if *days < 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid number of days %d", *days))
+		}
+		purged, err := pb.PurgeTrash(ctx, getCurrentUser(), time.Now().AddDate(0, 0, -*days))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Purged %d courses and packs\n", purged)
+		return nil
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown trash action %s", action))
+	}
+}
+
+func runEdition(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("edition", flag.ExitOnError)
+	flags.Usage = editionUsage(flags)
+
+	label := flags.String("l", "", "edition label (add, update)")
+	date := flags.String("d", "", "publication date, YYYY-MM-DD (add, update)")
+	notes := flags.String("notes", "", "notes on the edition (add, update)")
+	quantity := flags.Int("q", 0, "copies of the edition added to the stock (add)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list, add, update, current or retire"))
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "list", "add":
+		args, code, kind, part, err := scope(args, flags.Usage)
+		if err != nil {
+			return err
+		}
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		id := libpolybase.CourseID{
+			Code: code,
+			Kind: kind,
+			Part: int(part),
+		}
+
+		if action == "list" {
+			editions, err := pb.ListEditions(ctx, id)
+			if err != nil {
+				return err
+			}
+			return printEditions(editions, *jsonOutput)
+		}
+
+		edition := libpolybase.Edition{Label: *label, Date: *date, Notes: *notes, Quantity: *quantity}
+		created, err := pb.AddEdition(ctx, getCurrentUser(), id, edition)
+		if err != nil {
+			return err
+		}
+		return printEditions([]libpolybase.Edition{created}, *jsonOutput)
+	case "update", "current", "retire":
+		if len(args) == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("edition ID is required"))
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid edition ID: %s", args[0]))
+		}
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		var edition libpolybase.Edition
+		switch action {
+		case "update":
+			var partial libpolybase.PartialEdition
+			flags.Visit(func(f *flag.Flag) {
+				switch f.Name {
+				case "l":
+					partial.Label = label
+				case "d":
+					partial.Date = date
+				case "notes":
+					partial.Notes = notes
+				}
+			})
+			edition, err = pb.UpdateEdition(ctx, getCurrentUser(), id, partial)
+		case "current":
+			edition, err = pb.SetCurrentEdition(ctx, getCurrentUser(), id)
+		case "retire":
+			edition, err = pb.RetireEdition(ctx, getCurrentUser(), id)
+		}
+		if err != nil {
+			return err
+		}
+		return printEditions([]libpolybase.Edition{edition}, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown edition action %s", action))
+	}
+}
+
+func runTag(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("tag", flag.ExitOnError)
+	flags.Usage = tagUsage(flags)
+
+	showHidden := flags.Bool("a", false, "count hidden courses too (list)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list, add, rm, rename or delete"))
+	}
+	action, args := args[0], args[1:]
+
+	// Arguments come first, then the options
+	operands := args
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			operands = args[:i]
+			break
+		}
+	}
+	if err := flags.Parse(args[len(operands):]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "list":
+		if len(operands) == 0 {
+			tags, err := pb.ListTags(ctx, *showHidden)
+			if err != nil {
+				return err
+			}
+			return printTags(tags, *jsonOutput)
+		}
+		_, code, kind, part, err := scope(operands, flags.Usage)
+		if err != nil {
+			return err
+		}
+		course, err := pb.GetCourse(ctx, libpolybase.NewCourseID(code, kind, int(part)))
+		if err != nil {
+			return err
+		}
+		return printCourseTags(course, *jsonOutput)
+	case "add", "rm":
+		tags, code, kind, part, err := scope(operands, flags.Usage)
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("at least one TAG is required"))
+		}
+		id := libpolybase.NewCourseID(code, kind, int(part))
+
+		var course libpolybase.Course
+		if action == "add" {
+			course, err = pb.AddCourseTags(ctx, getCurrentUser(), id, tags)
+		} else {
+			course, err = pb.RemoveCourseTags(ctx, getCurrentUser(), id, tags)
+		}
+		if err != nil {
+			return err
+		}
+		return printCourseTags(course, *jsonOutput)
+	case "rename", "delete":
+		var changed int
+		var err error
+		if action == "rename" {
+			if len(operands) != 2 {
+				flags.Usage()
+				return errors.Join(ErrInvalidUsage, errors.New("FROM and TO are required"))
+			}
+			changed, err = pb.RenameTag(ctx, getCurrentUser(), operands[0], operands[1])
+		} else {
+			if len(operands) != 1 {
+				flags.Usage()
+				return errors.Join(ErrInvalidUsage, errors.New("TAG is required"))
+			}
+			changed, err = pb.DeleteTag(ctx, getCurrentUser(), operands[0])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Changed %d courses\n", changed)
+		return nil
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown tag action %s", action))
+	}
+}
+
+func runNote(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("note", flag.ExitOnError)
+	flags.Usage = noteUsage(flags)
+
+	pack := flags.Int("pack", 0, "use the notes of the pack ID instead of a course (list, add)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list, add, edit or rm"))
+	}
+	action, args := args[0], args[1:]
+
+	operands, err := parseOperands(flags, args)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "list", "add":
+		var id libpolybase.CourseID
+		if *pack == 0 {
+			var code, kind string
+			var part uint8
+			var err error
+			operands, code, kind, part, err = scope(operands, flags.Usage)
+			if err != nil {
+				return err
+			}
+			id = libpolybase.NewCourseID(code, kind, int(part))
+		}
+
+		if action == "list" {
+			var notes []libpolybase.Note
+			var err error
+			if *pack != 0 {
+				notes, err = pb.ListPackNotes(ctx, *pack)
+			} else {
+				notes, err = pb.ListCourseNotes(ctx, id)
+			}
+			if err != nil {
+				return err
+			}
+			return printNotes(notes, *jsonOutput)
+		}
+
+		if len(operands) == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("TEXT is required"))
+		}
+		body := strings.Join(operands, " ")
+		var note libpolybase.Note
+		var err error
+		if *pack != 0 {
+			note, err = pb.AddPackNote(ctx, getCurrentUser(), *pack, body)
+		} else {
+			note, err = pb.AddCourseNote(ctx, getCurrentUser(), id, body)
+		}
+		if err != nil {
+			return err
+		}
+		return printNotes([]libpolybase.Note{note}, *jsonOutput)
+	case "edit", "rm":
+		if len(operands) == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("note ID is required"))
+		}
+		id, err := strconv.Atoi(operands[0])
+		if err != nil {
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid note ID: %s", operands[0]))
+		}
+
+		if action == "rm" {
+			return pb.DeleteNote(ctx, getCurrentUser(), id)
+		}
+
+		if len(operands) == 1 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("TEXT is required"))
+		}
+		note, err := pb.UpdateNote(ctx, getCurrentUser(), id, strings.Join(operands[1:], " "))
+		if err != nil {
+			return err
+		}
+		return printNotes([]libpolybase.Note{note}, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown note action %s", action))
+	}
+}
+
+func runPrice(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("price", flag.ExitOnError)
+	flags.Usage = priceUsage(flags)
+
+	pack := flags.Int("pack", 0, "use the prices of the pack ID instead of a course")
+	from := flags.String("from", "", "date the price takes effect on, YYYY-MM-DD (set, default: today)")
+	discount := flags.Int("discount", 0, "percent taken off the prices of the courses of a pack without a fixed price (set)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list or set"))
+	}
+	action, args := args[0], args[1:]
+
+	operands, err := parseOperands(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var id libpolybase.CourseID
+	if *pack == 0 {
+		var code, kind string
+		var part uint8
+		operands, code, kind, part, err = scope(operands, flags.Usage)
+		if err != nil {
+			return err
+		}
+		id = libpolybase.NewCourseID(code, kind, int(part))
+	}
+
+	switch action {
+	case "list":
+		var changes []libpolybase.PriceChange
+		if *pack != 0 {
+			changes, err = pb.ListPackPrices(ctx, *pack)
+		} else {
+			changes, err = pb.ListCoursePrices(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		return printPrices(changes, *jsonOutput)
+	case "set":
+		var price *libpolybase.Price
+		if len(operands) > 0 {
+			parsed, err := libpolybase.ParsePrice(operands[0])
+			if err != nil {
+				return err
+			}
+			price = &parsed
+		}
+
+		var change libpolybase.PriceChange
+		if *pack != 0 {
+			pricing := libpolybase.PackPricing{Fixed: price, Discount: *discount}
+			change, err = pb.SetPackPricing(ctx, getCurrentUser(), *pack, pricing, *from)
+		} else {
+			if price == nil {
+				flags.Usage()
+				return errors.Join(ErrInvalidUsage, errors.New("PRICE is required"))
+			}
+			change, err = pb.SetCoursePrice(ctx, getCurrentUser(), id, *price, *from)
+		}
+		if err != nil {
+			return err
+		}
+		return printPrices([]libpolybase.PriceChange{change}, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown price action %s", action))
+	}
+}
+
+func runPack(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("pack", flag.ExitOnError)
+	flags.Usage = packUsage(flags)
+
+	policy := flags.String("policy", string(libpolybase.PolicyStrict), "what to do with the courses that cannot take the whole delta: strict, clamp or skip (quantity)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected quantity"))
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "quantity":
+		// DELTA may be negative, so the operands are read before the options
+		if len(args) < 2 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("ID and DELTA are required"))
+		}
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid pack ID: %s", args[0]))
+		}
+		delta, err := strconv.Atoi(args[1])
+		if err != nil {
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid delta value: %s", args[1]))
+		}
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+
+		policy, err := libpolybase.ParsePackQuantityPolicy(*policy)
+		if err != nil {
+			return err
+		}
+		result, err := pb.UpdatePackQuantity(ctx, getCurrentUser(), id, delta, policy)
+		if err != nil {
+			return err
+		}
+		return printPackQuantity(result, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown pack action %s", action))
+	}
+}
+
+func runFile(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("file", flag.ExitOnError)
+	flags.Usage = fileUsage(flags)
+
+	name := flags.String("name", "", "name of the uploaded file (put, default: the name of FILE)")
+	fileID := flags.Int("id", 0, "download the file ID instead of the current master (get)")
+	output := flags.String("o", "", "write the file to PATH instead of the standard output (get)")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected put, get or list"))
+	}
+	action, args := args[0], args[1:]
+
+	operands, err := parseOperands(flags, args)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "put":
+		operands, code, kind, part, err := scope(operands, flags.Usage)
+		if err != nil {
+			return err
+		}
+		if len(operands) != 1 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("FILE is required"))
+		}
+
+		content := os.Stdin
+		if operands[0] != "-" {
+			content, err = os.Open(operands[0])
+			if err != nil {
+				return err
+			}
+			defer content.Close()
+			if *name == "" {
+				*name = operands[0]
+			}
+		}
+		if *name == "" {
+			return errors.Join(ErrInvalidUsage, errors.New("-name is required when reading the standard input"))
+		}
+
+		file, err := pb.PutMasterFile(ctx, getCurrentUser(), libpolybase.NewCourseID(code, kind, int(part)), *name, content)
+		if err != nil {
+			return err
+		}
+		return printMasterFiles([]libpolybase.MasterFile{file}, *jsonOutput)
+	case "get":
+		if *fileID == 0 {
+			_, code, kind, part, err := scope(operands, flags.Usage)
+			if err != nil {
+				return err
+			}
+			file, err := pb.GetMasterFile(ctx, libpolybase.NewCourseID(code, kind, int(part)))
+			if err != nil {
+				return err
+			}
+			*fileID = file.ID
+		}
+
+		_, content, err := pb.OpenMasterFile(ctx, *fileID)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+
+		if *output == "" {
+			_, err = io.Copy(os.Stdout, content)
+			return err
+		}
+		out, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, content); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	case "list":
+		_, code, kind, part, err := scope(operands, flags.Usage)
+		if err != nil {
+			return err
+		}
+		files, err := pb.ListMasterFiles(ctx, libpolybase.NewCourseID(code, kind, int(part)))
+		if err != nil {
+			return err
+		}
+		return printMasterFiles(files, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown file action %s", action))
+	}
+}
+
+func runParts(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("parts", flag.ExitOnError)
+	flags.Usage = partsUsage(flags)
+
+	name := flags.String("n", "", "course name (insert)")
+	quantity := flags.Int("q", -1, "initial quantity (insert), copies moved to the new part (split)")
+	total := flags.Int("t", 0, "total quantity (insert)")
+	semester := flags.String("s", "", "semester (insert)")
+	other := flags.Int("p", 0, "part merged into the course (merge)")
+	details := newCourseDetails(flags)
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected insert, split, merge or renumber"))
+	}
+	action, args := args[0], args[1:]
+
+	if action == "renumber" {
+		if len(args) < 2 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("CODE and KIND are required"))
+		}
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		courses, err := pb.RenumberParts(ctx, getCurrentUser(), args[0], args[1])
+		if err != nil {
+			return err
+		}
+		return printCourses(courses, *jsonOutput)
+	}
+
+	args, code, kind, part, err := scope(args, flags.Usage)
+	if err != nil {
+		return err
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	id := libpolybase.CourseID{
+		Code: code,
+		Kind: kind,
+		Part: int(part),
+	}
+
+	switch action {
+	case "insert":
+		if *name == "" || *quantity == -1 || *semester == "" {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("name (-n), quantity (-q) and semester (-s) are required"))
+		}
+		if *total == 0 {
+			*total = *quantity
+		}
+		course, err := details.course(libpolybase.Course{
+			Code:     code,
+			Kind:     kind,
+			Part:     int(part),
+			Name:     *name,
+			Quantity: *quantity,
+			Total:    *total,
+			Semester: *semester,
+		})
+		if err != nil {
+			return err
+		}
+		inserted, err := pb.InsertPart(ctx, getCurrentUser(), course)
+		if err != nil {
+			return err
+		}
+		return printCourse(inserted, *jsonOutput)
+	case "split":
+		if *quantity == -1 {
+			*quantity = 0
+		}
+		courses, err := pb.SplitCourse(ctx, getCurrentUser(), id, *quantity)
+		if err != nil {
+			return err
+		}
+		return printCourses(courses, *jsonOutput)
+	case "merge":
+		if *other == 0 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("the merged part (-p) is required"))
+		}
+		merged, err := pb.MergeParts(ctx, getCurrentUser(), id, *other)
+		if err != nil {
+			return err
+		}
+		return printCourse(merged, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown parts action %s", action))
+	}
+}
+
+func runTemplate(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("template", flag.ExitOnError)
+	flags.Usage = templateUsage(flags)
+
+	name := flags.String("n", "", "course name (apply)")
+	quantity := flags.Int("q", -1, "initial quantity of each course (apply)")
+	total := flags.Int("t", 0, "total quantity of each course (apply)")
+	semester := flags.String("s", "", "semester (apply)")
+	details := newCourseDetails(flags)
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("expected list or apply"))
+	}
+	action, args := args[0], args[1:]
+
+	switch action {
+	case "list":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		return printTemplates(pb.Catalogue().Templates, *jsonOutput)
+	case "apply":
+		if len(args) < 2 {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, errors.New("TEMPLATE and CODE are required"))
+		}
+		if err := flags.Parse(args[2:]); err != nil {
+			return err
+		}
+		if *name == "" || *quantity == -1 || *semester == "" {
+			flags.Usage()
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("name (-n), quantity (-q) and semester (-s) are required"))
+		}
+		if *total == 0 {
+			*total = *quantity
+		}
+
+		template, err := pb.Catalogue().Template(args[0])
+		if err != nil {
+			return err
+		}
+		base, err := details.course(libpolybase.Course{
+			Code:     args[1],
+			Name:     *name,
+			Quantity: *quantity,
+			Total:    *total,
+			Semester: *semester,
+		})
+		if err != nil {
+			return err
+		}
+
+		created, err := pb.CreateCourses(ctx, getCurrentUser(), template.Expand(base))
+		if err != nil {
+			return err
+		}
+		return printCourses(created, *jsonOutput)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown template action %s", action))
+	}
+}
+
+func runClone(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("clone", flag.ExitOnError)
+	flags.Usage = cloneUsage(flags)
+
+	from := flags.String("from", "", "academic year to copy from (default: the one of -y)")
+	code := flags.String("c", "", "code of the copies")
+	semester := flags.String("s", "", "semester of the copies")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	// CODE, then optionally KIND and PART, come before the options
+	var scope []string
+	for len(args) > 0 && len(scope) < 3 && !strings.HasPrefix(args[0], "-") {
+		scope, args = append(scope, args[0]), args[1:]
+	}
+	if len(scope) == 0 {
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, errors.New("CODE is required"))
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	opts := libpolybase.CloneOptions{
+		Code:       scope[0],
+		ToCode:     *code,
+		ToSemester: *semester,
+	}
+	if len(scope) > 1 {
+		opts.Kind = scope[1]
+	}
+	if len(scope) > 2 {
+		part, err := strconv.Atoi(scope[2])
+		if err != nil {
+			return errors.Join(ErrInvalidUsage, fmt.Errorf("invalid part number: %s", scope[2]))
+		}
+		opts.Part = part
+	}
+	if *from != "" {
+		year, err := libpolybase.ParseAcademicYear(*from)
+		if err != nil {
+			return err
+		}
+		opts.Year = year
+	}
+
+	clones, err := pb.CloneCourses(ctx, getCurrentUser(), opts)
+	if err != nil {
+		return err
+	}
+	return printCourses(clones, *jsonOutput)
+}
+
+func runYears(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("years", flag.ExitOnError)
+	flags.Usage = yearsUsage(flags)
+
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	years, err := pb.ListYears(ctx)
+	if err != nil {
+		return err
+	}
+
+	return printYears(years, *jsonOutput)
+}
+
+func runRollOver(ctx context.Context, pb libpolybase.Polybase, args []string) error {
+	flags := flag.NewFlagSet("rollover", flag.ExitOnError)
+	flags.Usage = rollOverUsage(flags)
+
+	to := flags.String("to", "", "academic year to create (default: the year after the one rolled over)")
+	resetQuantities := flags.Bool("reset-quantities", false, "refill every course up to its total")
+	resetTotals := flags.Bool("reset-totals", false, "lower the total of every course to the quantity left")
+	jsonOutput := flags.Bool("json", false, "output in JSON format")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	from := pb.Year()
+	target := from + 1
+	if *to != "" {
+		year, err := libpolybase.ParseAcademicYear(*to)
+		if err != nil {
+			return err
+		}
+		target = year
+	}
+
+	opts := libpolybase.RollOverOptions{
+		ResetQuantities: *resetQuantities,
+		ResetTotals:     *resetTotals,
+	}
+	created, err := pb.RollOverYear(ctx, getCurrentUser(), from, target, opts)
+	if err != nil {
+		return err
+	}
+
+	return printYears([]libpolybase.YearInfo{created}, *jsonOutput)
+}
+
+func runMigrate(ctx context.Context, db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Usage = migrateUsage(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	action := "status"
+	if flags.NArg() > 0 {
+		action = flags.Arg(0)
+	}
+
+	switch action {
+	case "status":
+		states, err := libpolybase.MigrationStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		return printMigrations(states)
+	case "up":
+		return libpolybase.Migrate(ctx, db)
+	case "down":
+		return libpolybase.MigrateDown(ctx, db)
+	default:
+		flags.Usage()
+		return errors.Join(ErrInvalidUsage, fmt.Errorf("unknown migrate action %s", action))
+	}
+}
@@ -6,16 +6,20 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/user"
 	"strings"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
+	"github.com/alias-asso/polybase-go/polybased/config"
 	_ "modernc.org/sqlite"
 )
 
 const (
-	version       = "0.1.0"
-	defaultDBPath = "/var/lib/polybase/polybase.db"
+	version           = "0.1.0"
+	defaultDBPath     = "/var/lib/polybase/polybase.db"
+	defaultConfigPath = "/etc/polybase/config.cfg"
 )
 
 // Global args
@@ -23,6 +27,8 @@ var (
 	showHelp    = false
 	showVersion = false
 	dbPath      = defaultDBPath
+	configPath  = defaultConfigPath
+	yearArg     = ""
 )
 
 func init() {
@@ -30,6 +36,8 @@ func init() {
 	flag.BoolVar(&showHelp, "help", showHelp, "display the help")
 	flag.BoolVar(&showVersion, "v", showVersion, "display the version of polybase")
 	flag.StringVar(&dbPath, "db", dbPath, "path of the database")
+	flag.StringVar(&configPath, "c", configPath, "path of the polybased config, for its catalogue rules and file storage")
+	flag.StringVar(&yearArg, "y", yearArg, "academic year to work on, such as 2026-2027 (default: the current one)")
 }
 
 func main() {
@@ -55,20 +63,107 @@ func main() {
 
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
-		panic(err)
+		fatal(err)
 	}
 	defer db.Close()
 
 	if err = db.Ping(); err != nil {
-		panic(err)
+		fatal(err)
 	}
 
-	err = dispatch(libpolybase.New(db, "/var/log/polybase/polybase.log", false), flag.Args())
+	if args[0] == "migrate" {
+		if err := runMigrate(context.Background(), db, args[1:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	if err := libpolybase.CheckSchema(context.Background(), db); err != nil {
+		fatal(fmt.Errorf("%w (run polybase migrate up)", err))
+	}
+
+	catalogue, err := loadCatalogue()
+	if err != nil {
+		fatal(err)
+	}
+
+	storage, err := loadStorage()
 	if err != nil {
-		panic(err)
+		fatal(err)
+	}
+
+	var pb libpolybase.Polybase = libpolybase.New(db, "/var/log/polybase/polybase.log", false).WithCatalogue(catalogue).WithStorage(storage.Dir)
+	if yearArg != "" {
+		year, err := libpolybase.ParseAcademicYear(yearArg)
+		if err != nil {
+			fatal(err)
+		}
+		pb = pb.ForYear(year)
+	}
+
+	err = dispatch(pb, flag.Args())
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// loadCatalogue reads the catalogue rules from the polybased config. Without
+// one at the default path, the default rules apply.
+func loadCatalogue() (libpolybase.Catalogue, error) {
+	catalogue, err := config.LoadCatalogue(configPath)
+	if errors.Is(err, fs.ErrNotExist) && configPath == defaultConfigPath {
+		catalogue = libpolybase.DefaultCatalogue()
+		err = catalogue.Compile()
+	}
+	return catalogue, err
+}
+
+// loadStorage reads where the master files are kept from the polybased
+// config. Without one at the default path, the default directory is used.
+func loadStorage() (config.Storage, error) {
+	storage, err := config.LoadStorage(configPath)
+	if errors.Is(err, fs.ErrNotExist) && configPath == defaultConfigPath {
+		return config.DefaultStorage(), nil
+	}
+	return storage, err
+}
+
+// Exit statuses, documented in polybase(1)
+const (
+	exitError         = 1
+	exitUsage         = 2
+	exitNotFound      = 3
+	exitAlreadyExists = 4
+	exitConflict      = 5
+	exitInvalid       = 6
+	exitForbidden     = 7
+)
+
+func exitCode(err error) int {
+	var validation *libpolybase.ValidationError
+	switch {
+	case errors.Is(err, ErrInvalidUsage), errors.Is(err, ErrNoCommand), errors.Is(err, ErrUnknownCommand):
+		return exitUsage
+	case errors.Is(err, libpolybase.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, libpolybase.ErrAlreadyExists):
+		return exitAlreadyExists
+	case errors.Is(err, libpolybase.ErrConflict):
+		return exitConflict
+	case errors.As(err, &validation):
+		return exitInvalid
+	case errors.Is(err, libpolybase.ErrForbidden):
+		return exitForbidden
+	default:
+		return exitError
 	}
 }
 
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "polybase: %v\n", err)
+	os.Exit(exitCode(err))
+}
+
 var (
 	ErrNoCommand      = errors.New("no command specified")
 	ErrUnknownCommand = errors.New("unknown command")
@@ -94,10 +189,44 @@ func dispatch(pb libpolybase.Polybase, args []string) error {
 		return runDelete(ctx, pb, cmdArgs)
 	case "list":
 		return runList(ctx, pb, cmdArgs)
+	case "search":
+		return runSearch(ctx, pb, cmdArgs)
 	case "quantity":
 		return runQuantity(ctx, pb, cmdArgs)
+	case "status":
+		return runStatus(ctx, pb, cmdArgs)
 	case "visibility":
 		return runVisibility(ctx, pb, cmdArgs)
+	case "movements":
+		return runMovements(ctx, pb, cmdArgs)
+	case "history":
+		return runHistory(ctx, pb, cmdArgs)
+	case "revert":
+		return runRevert(ctx, pb, cmdArgs)
+	case "trash":
+		return runTrash(ctx, pb, cmdArgs)
+	case "edition":
+		return runEdition(ctx, pb, cmdArgs)
+	case "tag":
+		return runTag(ctx, pb, cmdArgs)
+	case "note":
+		return runNote(ctx, pb, cmdArgs)
+	case "price":
+		return runPrice(ctx, pb, cmdArgs)
+	case "pack":
+		return runPack(ctx, pb, cmdArgs)
+	case "file":
+		return runFile(ctx, pb, cmdArgs)
+	case "parts":
+		return runParts(ctx, pb, cmdArgs)
+	case "template":
+		return runTemplate(ctx, pb, cmdArgs)
+	case "clone":
+		return runClone(ctx, pb, cmdArgs)
+	case "years":
+		return runYears(ctx, pb, cmdArgs)
+	case "rollover":
+		return runRollOver(ctx, pb, cmdArgs)
 	default:
 		printUsage()
 		return errors.Join(ErrUnknownCommand, fmt.Errorf("command %s not supported", cmd))
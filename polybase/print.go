@@ -5,7 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
 )
@@ -15,18 +18,40 @@ func printUsage() {
 
 OPTIONS
     -db PATH    Path to database file (default: %s)
+    -c PATH     Path to the polybased config holding the catalogue rules
+                and the file storage (default: %s)
+    -y YEAR     Academic year to work on, such as 2026-2027 (default: the
+                current one)
     -h          Print help information
     -v          Print version information
 
 COMMANDS
     create      Create a new course entry
+    template    List the course templates or create the courses of one
+    clone       Copy a course, or all the courses of a code
     get         Display details for a specific course
     update      Update course information
-    delete      Remove a course from the database
+    delete      Move a course to the trash
     list        List all courses
+    search      Search the courses by code, name, kind, teacher or description
     quantity    Update course quantity
-    visibility  Set course visibility
-`, defaultDBPath)
+    status      Move a course to another lifecycle status
+    visibility  Show or hide a course, kept for compatibility with status
+    movements   List the stock movements
+    history     List the changes made to courses and packs
+    revert      Revert a change listed by history
+    trash       List, restore or purge deleted courses and packs
+    edition     List, add, update or retire the editions of a course
+    tag         List, add or remove the tags of the courses
+    note        List, add, edit or remove the notes on a course or a pack
+    price       List or set the prices of a course or a pack
+    pack        Update the quantities of the courses of a pack
+    file        Upload, download or list the master files of a course
+    parts       Insert, split, merge or renumber the parts of a course
+    years       List the academic years
+    rollover    Start the next academic year from the current one
+    migrate     Show or change the database schema version
+`, defaultDBPath, defaultConfigPath)
 }
 
 func printVersion() {
@@ -77,7 +102,7 @@ func updateUsage(flags *flag.FlagSet) func() {
 func deleteUsage(flags *flag.FlagSet) func() {
 	return usage(
 		`polybase delete <CODE> <KIND> <PART>`,
-		`Remove a course from the database`,
+		`Move a course to the trash`,
 		flags,
 	)
 }
@@ -90,6 +115,14 @@ func listUsage(flags *flag.FlagSet) func() {
 	)
 }
 
+func searchUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase search <TERMS>... [OPTIONS]`,
+		`Search the courses by code, name, kind, teacher or description, best matches first`,
+		flags,
+	)
+}
+
 func quantityUsage(flags *flag.FlagSet) func() {
 	return usage(
 		`polybase quantity <CODE> <KIND> <PART> <DELTA> [OPTIONS]`,
@@ -98,10 +131,173 @@ func quantityUsage(flags *flag.FlagSet) func() {
 	)
 }
 
+func statusUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase status <CODE> <KIND> <PART> <STATUS> [OPTIONS]`,
+		`Move a course to STATUS: draft, awaiting_print, available, out_of_stock or discontinued`,
+		flags,
+	)
+}
+
 func visibilityUsage(flags *flag.FlagSet) func() {
 	return usage(
 		`polybase visibility <CODE> <KIND> <PART> [-s STATE] [OPTIONS]`,
-		`Set course visibility`,
+		`Show or hide a course, kept for compatibility: a shown course becomes
+	available and a hidden one a draft`,
+		flags,
+	)
+}
+
+func movementsUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase movements [OPTIONS]`,
+		`List the stock movements, newest first`,
+		flags,
+	)
+}
+
+func historyUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase history [OPTIONS]`,
+		`List the changes made to courses and packs, newest first`,
+		flags,
+	)
+}
+
+func revertUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase revert <ID> [OPTIONS]`,
+		`Revert the change ID listed by history`,
+		flags,
+	)
+}
+
+func trashUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase trash list [OPTIONS]
+	polybase trash restore <CODE> <KIND> <PART>
+	polybase trash restore -pack <ID>
+	polybase trash purge [-days N]`,
+		`List, restore or permanently delete the courses and packs in the trash`,
+		flags,
+	)
+}
+
+func editionUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase edition list <CODE> <KIND> <PART> [OPTIONS]
+	polybase edition add <CODE> <KIND> <PART> -l LABEL [OPTIONS]
+	polybase edition update <ID> [OPTIONS]
+	polybase edition current <ID>
+	polybase edition retire <ID>`,
+		`Manage the editions of a course, retiring one writes off its stock`,
+		flags,
+	)
+}
+
+func templateUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase template list [OPTIONS]
+	polybase template apply <TEMPLATE> <CODE> -n NAME -q QUANTITY -s SEMESTER [OPTIONS]`,
+		`List the course templates of the catalogue, or create every course of one for a code`,
+		flags,
+	)
+}
+
+func cloneUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase clone <CODE> [<KIND> [<PART>]] [OPTIONS]`,
+		`Copy the courses of a code, of one of its kinds or a single course, out of stock`,
+		flags,
+	)
+}
+
+func tagUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase tag list [<CODE> <KIND> <PART>] [OPTIONS]
+	polybase tag add <CODE> <KIND> <PART> <TAG>... [OPTIONS]
+	polybase tag rm <CODE> <KIND> <PART> <TAG>... [OPTIONS]
+	polybase tag rename <FROM> <TO>
+	polybase tag delete <TAG>`,
+		`List the tags with how many courses hold them, or the tags of a course, tag courses or rename and delete tags`,
+		flags,
+	)
+}
+
+func noteUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase note list <CODE> <KIND> <PART> [OPTIONS]
+	polybase note list -pack <ID> [OPTIONS]
+	polybase note add <CODE> <KIND> <PART> <TEXT>... [OPTIONS]
+	polybase note add -pack <ID> <TEXT>... [OPTIONS]
+	polybase note edit <ID> <TEXT>... [OPTIONS]
+	polybase note rm <ID>`,
+		`List the notes on a course or a pack newest first, leave one, or edit and remove your own`,
+		flags,
+	)
+}
+
+func priceUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase price list <CODE> <KIND> <PART> [OPTIONS]
+	polybase price list -pack <ID> [OPTIONS]
+	polybase price set <CODE> <KIND> <PART> <PRICE> [OPTIONS]
+	polybase price set -pack <ID> [<PRICE>] [OPTIONS]`,
+		`List the price history of a course or a pack, latest first, or set a price from a date. A pack without PRICE costs the sum of its courses less -discount percent`,
+		flags,
+	)
+}
+
+func packUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase pack quantity <ID> <DELTA> [OPTIONS]`,
+		`Add DELTA (can be negative) to the quantity of every course of a pack, and list what each course took`,
+		flags,
+	)
+}
+
+func fileUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase file put <CODE> <KIND> <PART> <FILE> [OPTIONS]
+	polybase file get <CODE> <KIND> <PART> [OPTIONS]
+	polybase file get -id <ID> [OPTIONS]
+	polybase file list <CODE> <KIND> <PART> [OPTIONS]`,
+		`Upload a PDF as the master file of a course, FILE - reading the standard input, download the current master, or list the uploads newest first`,
+		flags,
+	)
+}
+
+func partsUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase parts insert <CODE> <KIND> <PART> -n NAME -q QUANTITY -s SEMESTER [OPTIONS]
+	polybase parts split <CODE> <KIND> <PART> [-q QUANTITY]
+	polybase parts merge <CODE> <KIND> <PART> -p PART
+	polybase parts renumber <CODE> <KIND>`,
+		`Insert, split or merge parts of a course, moving the following parts, or close the gaps between them`,
+		flags,
+	)
+}
+
+func yearsUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase years [OPTIONS]`,
+		`List the academic years and how many courses and packs they hold`,
+		flags,
+	)
+}
+
+func rollOverUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase [-y YEAR] rollover [OPTIONS]`,
+		`Copy the courses and packs of the academic year into a new one and make it read-only`,
+		flags,
+	)
+}
+
+func migrateUsage(flags *flag.FlagSet) func() {
+	return usage(
+		`polybase migrate [status|up|down]`,
+		`Show applied migrations, apply pending ones or revert the last one`,
 		flags,
 	)
 }
@@ -114,22 +310,51 @@ type CourseJSON struct {
 	Name     string `json:"name"`
 	Quantity int    `json:"quantity"`
 	Total    int    `json:"total"`
+	Status   string `json:"status"`
 	Shown    bool   `json:"visible"`
 	Semester string `json:"semester"`
+	Level    string `json:"level"`
+	Pages    int    `json:"pages"`
+	// Prices are in euros, as written on the command line
+	Price       string   `json:"price"`
+	PrintCost   string   `json:"print_cost"`
+	Teacher     string   `json:"teacher"`
+	Edition     string   `json:"edition"`
+	EditionDate string   `json:"edition_date"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	HasMaster   bool     `json:"has_master"`
+	Revision    int      `json:"revision"`
 }
 
 func newCourseJSON(c *libpolybase.Course) CourseJSON {
-	return CourseJSON{
-		Code:     c.Code,
-		Kind:     c.Kind,
-		Part:     c.Part,
-		Parts:    c.Parts,
-		Name:     c.Name,
-		Quantity: c.Quantity,
-		Total:    c.Total,
-		Shown:    c.Shown,
-		Semester: c.Semester,
+	course := CourseJSON{
+		Code:        c.Code,
+		Kind:        c.Kind,
+		Part:        c.Part,
+		Parts:       c.Parts,
+		Name:        c.Name,
+		Quantity:    c.Quantity,
+		Total:       c.Total,
+		Status:      string(c.Status),
+		Shown:       c.Shown,
+		Semester:    c.Semester,
+		Level:       string(c.Level),
+		Pages:       c.Pages,
+		Price:       c.Price.String(),
+		PrintCost:   c.PrintCost.String(),
+		Teacher:     c.Teacher,
+		Edition:     c.Edition,
+		EditionDate: c.EditionDate,
+		Description: c.Description,
+		Tags:        c.Tags,
+		HasMaster:   c.HasMaster,
+		Revision:    c.Revision,
+	}
+	if course.Tags == nil {
+		course.Tags = []string{}
 	}
+	return course
 }
 
 func printCourses(courses []libpolybase.Course, jsonOutput bool) error {
@@ -138,7 +363,7 @@ func printCourses(courses []libpolybase.Course, jsonOutput bool) error {
 		for _, c := range courses {
 			coursesJSON = append(coursesJSON, newCourseJSON(&c))
 		}
-		return json.NewEncoder(os.Stdout).Encode(courses)
+		return json.NewEncoder(os.Stdout).Encode(coursesJSON)
 	}
 
 	for i, course := range courses {
@@ -164,6 +389,568 @@ func printCourse(c libpolybase.Course, jsonOutput bool) error {
 	fmt.Fprintf(w, "Name:\t%s\n", c.Name)
 	fmt.Fprintf(w, "Quantity:\t%d/%d\n", c.Quantity, c.Total)
 	fmt.Fprintf(w, "Semester:\t%s\n", c.Semester)
+	fmt.Fprintf(w, "Status:\t%s\n", c.Status)
 	fmt.Fprintf(w, "Visible:\t%v\n", c.Shown)
+	if c.Pages > 0 {
+		fmt.Fprintf(w, "Pages:\t%d\n", c.Pages)
+	}
+	if c.Price > 0 {
+		fmt.Fprintf(w, "Price:\t%s €\n", c.Price)
+	}
+	if c.PrintCost > 0 {
+		fmt.Fprintf(w, "Print cost:\t%s €\n", c.PrintCost)
+	}
+	if c.Teacher != "" {
+		fmt.Fprintf(w, "Teacher:\t%s\n", c.Teacher)
+	}
+	switch {
+	case c.Edition != "" && c.EditionDate != "":
+		fmt.Fprintf(w, "Edition:\t%s (%s)\n", c.Edition, c.EditionDate)
+	case c.Edition != "" || c.EditionDate != "":
+		fmt.Fprintf(w, "Edition:\t%s%s\n", c.Edition, c.EditionDate)
+	}
+	if c.Description != "" {
+		fmt.Fprintf(w, "Description:\t%s\n", c.Description)
+	}
+	if len(c.Tags) > 0 {
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(c.Tags, ", "))
+	}
+	if !c.HasMaster {
+		fmt.Fprintf(w, "Master:\tnone\n")
+	}
+	fmt.Fprintf(w, "Revision:\t%d\n", c.Revision)
+	return w.Flush()
+}
+
+func printMigrations(states []libpolybase.MigrationState) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, state := range states {
+		applied := "pending"
+		if state.Applied {
+			applied = state.AppliedAt.Local().Format("2006/01/02 15:04:05")
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\n", state.Version, state.Name, applied)
+	}
+	return w.Flush()
+}
+
+type MovementJSON struct {
+	ID         int    `json:"id"`
+	Code       string `json:"code"`
+	Kind       string `json:"kind"`
+	Part       int    `json:"part"`
+	Delta      int    `json:"delta"`
+	Quantity   int    `json:"quantity"`
+	Actor      string `json:"actor"`
+	Reason     string `json:"reason"`
+	PackID     *int   `json:"pack_id,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	MergedFrom string `json:"merged_from,omitempty"`
+}
+
+func printMovements(movements []libpolybase.Movement, jsonOutput bool) error {
+	if jsonOutput {
+		movementsJSON := []MovementJSON{}
+		for _, m := range movements {
+			movementsJSON = append(movementsJSON, MovementJSON{
+				ID:         m.ID,
+				Code:       m.Course.Code,
+				Kind:       m.Course.Kind,
+				Part:       m.Course.Part,
+				Delta:      m.Delta,
+				Quantity:   m.Quantity,
+				Actor:      m.Actor,
+				Reason:     string(m.Reason),
+				PackID:     m.PackID,
+				CreatedAt:  m.CreatedAt.Format(time.RFC3339),
+				MergedFrom: m.MergedFrom,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(movementsJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, m := range movements {
+		reason := string(m.Reason)
+		if m.PackID != nil {
+			reason = fmt.Sprintf("%s PK%03d", reason, *m.PackID)
+		}
+		if m.MergedFrom != "" {
+			reason = fmt.Sprintf("%s (merged from %s)", reason, m.MergedFrom)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%+d\t%d\t%s\t%s\n",
+			m.CreatedAt.Local().Format("2006/01/02 15:04:05"), m.Course.PID(),
+			m.Delta, m.Quantity, m.Actor, reason)
+	}
+	return w.Flush()
+}
+
+type AuditEventJSON struct {
+	ID         int             `json:"id"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	RevertedBy *int            `json:"reverted_by,omitempty"`
+	MergedFrom string          `json:"merged_from,omitempty"`
+}
+
+func printHistory(events []libpolybase.AuditEvent, jsonOutput bool) error {
+	if jsonOutput {
+		eventsJSON := []AuditEventJSON{}
+		for _, e := range events {
+			eventsJSON = append(eventsJSON, AuditEventJSON{
+				ID:         e.ID,
+				Actor:      e.Actor,
+				Action:     string(e.Action),
+				EntityType: string(e.EntityType),
+				EntityID:   e.EntityID,
+				Before:     e.Before,
+				After:      e.After,
+				CreatedAt:  e.CreatedAt.Format(time.RFC3339),
+				RevertedBy: e.RevertedBy,
+				MergedFrom: e.MergedFrom,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(eventsJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range events {
+		changes, err := e.Changes()
+		if err != nil {
+			return err
+		}
+		// Creations and deletions are self-explanatory, and the revision
+		// changes with every write
+		fields := make([]string, 0, len(changes))
+		for field, change := range changes {
+			if e.Before == nil || e.After == nil || field == "revision" {
+				continue
+			}
+			fields = append(fields, fmt.Sprintf("%s: %s -> %s", field, change.Before, change.After))
+		}
+		sort.Strings(fields)
+		action := string(e.Action)
+		if e.RevertedBy != nil {
+			action = fmt.Sprintf("%s (reverted by %d)", action, *e.RevertedBy)
+		}
+		if e.MergedFrom != "" {
+			action = fmt.Sprintf("%s (merged from %s)", action, e.MergedFrom)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s %s\t%s\t%s\n",
+			e.ID, e.CreatedAt.Local().Format("2006/01/02 15:04:05"), e.Actor,
+			e.EntityType, e.EntityID, action, strings.Join(fields, ", "))
+	}
+	return w.Flush()
+}
+
+type TrashItemJSON struct {
+	Type      string   `json:"type"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Courses   []string `json:"courses,omitempty"`
+	DeletedAt string   `json:"deleted_at"`
+	DeletedBy string   `json:"deleted_by"`
+}
+
+func printTrash(trash libpolybase.Trash, jsonOutput bool) error {
+	if jsonOutput {
+		itemsJSON := []TrashItemJSON{}
+		for _, p := range trash.Packs {
+			courses := []string{}
+			for _, c := range p.Pack.Courses {
+				courses = append(courses, c.ID())
+			}
+			itemsJSON = append(itemsJSON, TrashItemJSON{
+				Type:      "pack",
+				ID:        fmt.Sprintf("%d", p.Pack.ID),
+				Name:      p.Pack.Name,
+				Courses:   courses,
+				DeletedAt: p.DeletedAt.Format(time.RFC3339),
+				DeletedBy: p.DeletedBy,
+			})
+		}
+		for _, c := range trash.Courses {
+			itemsJSON = append(itemsJSON, TrashItemJSON{
+				Type:      "course",
+				ID:        c.Course.ID(),
+				Name:      c.Course.Name,
+				DeletedAt: c.DeletedAt.Format(time.RFC3339),
+				DeletedBy: c.DeletedBy,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(itemsJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, p := range trash.Packs {
+		fmt.Fprintf(w, "%s\t%s\tPK%03d\t%s\n",
+			p.DeletedAt.Local().Format("2006/01/02 15:04:05"), p.DeletedBy, p.Pack.ID, p.Pack.Name)
+	}
+	for _, c := range trash.Courses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			c.DeletedAt.Local().Format("2006/01/02 15:04:05"), c.DeletedBy, c.Course.CID().PID(), c.Course.Name)
+	}
+	return w.Flush()
+}
+
+type YearJSON struct {
+	Year     string `json:"year"`
+	ReadOnly bool   `json:"read_only"`
+	Courses  int    `json:"courses"`
+	Packs    int    `json:"packs"`
+}
+
+func printYears(years []libpolybase.YearInfo, jsonOutput bool) error {
+	if jsonOutput {
+		yearsJSON := []YearJSON{}
+		for _, y := range years {
+			yearsJSON = append(yearsJSON, YearJSON{
+				Year:     y.Year.String(),
+				ReadOnly: y.ReadOnly,
+				Courses:  y.Courses,
+				Packs:    y.Packs,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(yearsJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, y := range years {
+		state := "open"
+		if y.ReadOnly {
+			state = "read-only"
+		}
+		fmt.Fprintf(w, "%s\t%d courses\t%d packs\t%s\n", y.Year, y.Courses, y.Packs, state)
+	}
+	return w.Flush()
+}
+
+func printTemplates(templates []libpolybase.Template, jsonOutput bool) error {
+	if jsonOutput {
+		if templates == nil {
+			templates = []libpolybase.Template{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(templates)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range templates {
+		courses := make([]string, len(t.Courses))
+		for i, c := range t.Courses {
+			courses[i] = fmt.Sprintf("%s×%d", c.Kind, c.Parts)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.DisplayName(), strings.Join(courses, " + "))
+	}
+	return w.Flush()
+}
+
+func printTags(tags []libpolybase.Tag, jsonOutput bool) error {
+	if jsonOutput {
+		if tags == nil {
+			tags = []libpolybase.Tag{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(tags)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, tag := range tags {
+		fmt.Fprintf(w, "%s\t%d\n", tag.Name, tag.Courses)
+	}
+	return w.Flush()
+}
+
+// printCourseTags prints the tags of a course, one per line.
+func printCourseTags(course libpolybase.Course, jsonOutput bool) error {
+	tags := course.Tags
+	if jsonOutput {
+		if tags == nil {
+			tags = []string{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(tags)
+	}
+
+	for _, tag := range tags {
+		fmt.Println(tag)
+	}
+	return nil
+}
+
+type EditionJSON struct {
+	ID        int    `json:"id"`
+	Code      string `json:"code"`
+	Kind      string `json:"kind"`
+	Part      int    `json:"part"`
+	Label     string `json:"label"`
+	Date      string `json:"date"`
+	Notes     string `json:"notes"`
+	Quantity  int    `json:"quantity"`
+	Current   bool   `json:"current"`
+	CreatedAt string `json:"created_at"`
+	CreatedBy string `json:"created_by"`
+	RetiredAt string `json:"retired_at,omitempty"`
+	RetiredBy string `json:"retired_by,omitempty"`
+}
+
+func printEditions(editions []libpolybase.Edition, jsonOutput bool) error {
+	if jsonOutput {
+		editionsJSON := []EditionJSON{}
+		for _, e := range editions {
+			edition := EditionJSON{
+				ID:        e.ID,
+				Code:      e.Course.Code,
+				Kind:      e.Course.Kind,
+				Part:      e.Course.Part,
+				Label:     e.Label,
+				Date:      e.Date,
+				Notes:     e.Notes,
+				Quantity:  e.Quantity,
+				Current:   e.Current,
+				CreatedAt: e.CreatedAt.Format(time.RFC3339),
+				CreatedBy: e.CreatedBy,
+				RetiredBy: e.RetiredBy,
+			}
+			if e.RetiredAt != nil {
+				edition.RetiredAt = e.RetiredAt.Format(time.RFC3339)
+			}
+			editionsJSON = append(editionsJSON, edition)
+		}
+		return json.NewEncoder(os.Stdout).Encode(editionsJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, e := range editions {
+		state := ""
+		switch {
+		case e.RetiredAt != nil:
+			state = "retired"
+		case e.Current:
+			state = "current"
+		}
+		date := e.Date
+		if date == "" {
+			date = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n", e.ID, e.Course.PID(), e.Label, date, e.Quantity, state)
+	}
+	return w.Flush()
+}
+
+type NoteJSON struct {
+	ID        int    `json:"id"`
+	Code      string `json:"code,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Part      int    `json:"part,omitempty"`
+	PackID    int    `json:"pack_id,omitempty"`
+	Author    string `json:"author"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+}
+
+// printNotes prints a header line per note followed by its indented body.
+func printNotes(notes []libpolybase.Note, jsonOutput bool) error {
+	if jsonOutput {
+		notesJSON := []NoteJSON{}
+		for _, n := range notes {
+			note := NoteJSON{
+				ID:        n.ID,
+				Author:    n.Author,
+				Body:      n.Body,
+				CreatedAt: n.CreatedAt.Format(time.RFC3339),
+			}
+			if n.Course != nil {
+				note.Code, note.Kind, note.Part = n.Course.Code, n.Course.Kind, n.Course.Part
+			}
+			if n.PackID != nil {
+				note.PackID = *n.PackID
+			}
+			if n.UpdatedAt != nil {
+				note.UpdatedAt = n.UpdatedAt.Format(time.RFC3339)
+			}
+			notesJSON = append(notesJSON, note)
+		}
+		return json.NewEncoder(os.Stdout).Encode(notesJSON)
+	}
+
+	for i, n := range notes {
+		if i > 0 {
+			fmt.Println()
+		}
+		edited := ""
+		if n.UpdatedAt != nil {
+			edited = fmt.Sprintf(" (edited %s)", n.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		fmt.Printf("#%d %s %s%s\n", n.ID, n.Author, n.CreatedAt.Local().Format("2006-01-02 15:04"), edited)
+		for _, line := range strings.Split(n.Body, "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+	return nil
+}
+
+type MasterFileJSON struct {
+	ID         int    `json:"id"`
+	Code       string `json:"code"`
+	Kind       string `json:"kind"`
+	Part       int    `json:"part"`
+	Name       string `json:"name"`
+	Checksum   string `json:"checksum"`
+	Size       int64  `json:"size"`
+	Current    bool   `json:"current"`
+	UploadedAt string `json:"uploaded_at"`
+	UploadedBy string `json:"uploaded_by"`
+}
+
+func printMasterFiles(files []libpolybase.MasterFile, jsonOutput bool) error {
+	if jsonOutput {
+		filesJSON := []MasterFileJSON{}
+		for _, f := range files {
+			filesJSON = append(filesJSON, MasterFileJSON{
+				ID:         f.ID,
+				Code:       f.Course.Code,
+				Kind:       f.Course.Kind,
+				Part:       f.Course.Part,
+				Name:       f.Name,
+				Checksum:   f.Checksum,
+				Size:       f.Size,
+				Current:    f.Current,
+				UploadedAt: f.UploadedAt.Format(time.RFC3339),
+				UploadedBy: f.UploadedBy,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(filesJSON)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, f := range files {
+		state := ""
+		if f.Current {
+			state = "current"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d KiB\t%s\t%s\t%s\t%s\n", f.ID, f.Course.PID(), f.Name, (f.Size+1023)/1024,
+			f.Checksum[:12], f.UploadedAt.Local().Format("2006-01-02 15:04"), f.UploadedBy, state)
+	}
+	return w.Flush()
+}
+
+type PriceChangeJSON struct {
+	ID     int    `json:"id"`
+	Code   string `json:"code,omitempty"`
+	Kind   string `json:"kind,omitempty"`
+	Part   int    `json:"part,omitempty"`
+	PackID int    `json:"pack_id,omitempty"`
+	// Price is in euros, empty for a pack priced from its courses
+	Price         string `json:"price,omitempty"`
+	Discount      int    `json:"discount,omitempty"`
+	EffectiveFrom string `json:"effective_from"`
+	Current       bool   `json:"current"`
+	CreatedAt     string `json:"created_at"`
+	CreatedBy     string `json:"created_by"`
+}
+
+func printPrices(changes []libpolybase.PriceChange, jsonOutput bool) error {
+	if jsonOutput {
+		changesJSON := []PriceChangeJSON{}
+		for _, c := range changes {
+			change := PriceChangeJSON{
+				ID:            c.ID,
+				Discount:      c.Discount,
+				EffectiveFrom: c.EffectiveFrom,
+				Current:       c.Current,
+				CreatedAt:     c.CreatedAt.Format(time.RFC3339),
+				CreatedBy:     c.CreatedBy,
+			}
+			if c.Course != nil {
+				change.Code, change.Kind, change.Part = c.Course.Code, c.Course.Kind, c.Course.Part
+			}
+			if c.PackID != nil {
+				change.PackID = *c.PackID
+			}
+			if c.Price != nil {
+				change.Price = c.Price.String()
+			}
+			changesJSON = append(changesJSON, change)
+		}
+		return json.NewEncoder(os.Stdout).Encode(changesJSON)
+	}
+
+	today := time.Now().Format(time.DateOnly)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range changes {
+		target := ""
+		if c.Course != nil {
+			target = c.Course.PID()
+		} else if c.PackID != nil {
+			target = fmt.Sprintf("pack %d", *c.PackID)
+		}
+		price := fmt.Sprintf("courses -%d%%", c.Discount)
+		if c.Price != nil {
+			price = c.Price.String() + " €"
+		}
+		state := ""
+		switch {
+		case c.Current:
+			state = "current"
+		case c.EffectiveFrom > today:
+			state = "scheduled"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", c.ID, target, price, c.EffectiveFrom, c.CreatedBy, state)
+	}
+	return w.Flush()
+}
+
+type CourseQuantityJSON struct {
+	Code      string `json:"code"`
+	Kind      string `json:"kind"`
+	Part      int    `json:"part"`
+	Applied   int    `json:"applied"`
+	Quantity  int    `json:"quantity"`
+	Shortfall int    `json:"shortfall"`
+}
+
+type PackQuantityJSON struct {
+	ID      int                  `json:"id"`
+	Name    string               `json:"name"`
+	Policy  string               `json:"policy"`
+	Delta   int                  `json:"delta"`
+	Courses []CourseQuantityJSON `json:"courses"`
+}
+
+func printPackQuantity(result libpolybase.PackQuantityResult, jsonOutput bool) error {
+	if jsonOutput {
+		resultJSON := PackQuantityJSON{
+			ID:      result.Pack.ID,
+			Name:    result.Pack.Name,
+			Policy:  string(result.Policy),
+			Delta:   result.Delta,
+			Courses: []CourseQuantityJSON{},
+		}
+		for _, c := range result.Courses {
+			resultJSON.Courses = append(resultJSON.Courses, CourseQuantityJSON{
+				Code:      c.Course.Code,
+				Kind:      c.Course.Kind,
+				Part:      c.Course.Part,
+				Applied:   c.Applied,
+				Quantity:  c.Quantity,
+				Shortfall: c.Shortfall,
+			})
+		}
+		return json.NewEncoder(os.Stdout).Encode(resultJSON)
+	}
+
+	fmt.Printf("Pack %d %s: %+d (%s)\n", result.Pack.ID, result.Pack.Name, result.Delta, result.Policy)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range result.Courses {
+		shortfall := ""
+		if c.Shortfall > 0 {
+			shortfall = fmt.Sprintf("short %d", c.Shortfall)
+		}
+		fmt.Fprintf(w, "%s\t%+d\t%d\t%s\n", c.Course.PID(), c.Applied, c.Quantity, shortfall)
+	}
 	return w.Flush()
 }
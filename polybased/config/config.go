@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
 )
 
 type Server struct {
@@ -17,7 +19,8 @@ type Server struct {
 }
 
 type Database struct {
-	Path string
+	Path    string
+	Migrate bool
 }
 
 type OIDC struct {
@@ -33,11 +36,27 @@ type Auth struct {
 	JWTExpiry string `toml:"jwt_expiry"`
 }
 
+// Trash configures how long deleted courses and packs are kept before being
+// purged. A retention of 0 keeps them forever.
+type Trash struct {
+	Retention string
+}
+
+// Storage configures where the master files of the courses are kept, and how
+// large an uploaded file may be, in MiB.
+type Storage struct {
+	Dir     string
+	MaxSize int64 `toml:"max_size"`
+}
+
 type Config struct {
-	Server   Server
-	Database Database
-	OIDC     OIDC
-	Auth     Auth
+	Server    Server
+	Database  Database
+	OIDC      OIDC
+	Auth      Auth
+	Trash     Trash
+	Storage   Storage
+	Catalogue libpolybase.Catalogue
 }
 
 func DefaultConfig() Config {
@@ -48,11 +67,23 @@ func DefaultConfig() Config {
 			Log:  "/var/log/polybase/polybase.log",
 		},
 		Database: Database{
-			Path: "/var/lib/polybase/polybase.db",
+			Path:    "/var/lib/polybase/polybase.db",
+			Migrate: true,
 		},
 		Auth: Auth{
 			JWTExpiry: "72h",
 		},
+		Trash: Trash{
+			Retention: "720h",
+		},
+		Storage: DefaultStorage(),
+	}
+}
+
+func DefaultStorage() Storage {
+	return Storage{
+		Dir:     "/var/lib/polybase/files",
+		MaxSize: 50,
 	}
 }
 
@@ -63,6 +94,7 @@ func LoadConfig(configPath string) (Config, error) {
 	}
 
 	config.loadFromEnv()
+	config.Catalogue = withDefaultRules(config.Catalogue)
 
 	if err := config.Validate(); err != nil {
 		return Config{}, fmt.Errorf("invalid configuration: %w", err)
@@ -71,6 +103,62 @@ func LoadConfig(configPath string) (Config, error) {
 	return config, nil
 }
 
+// LoadCatalogue reads the catalogue section of a configuration file, for the
+// tools that need nothing else from it.
+func LoadCatalogue(configPath string) (libpolybase.Catalogue, error) {
+	var config struct {
+		Catalogue libpolybase.Catalogue
+	}
+	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+		return libpolybase.Catalogue{}, err
+	}
+
+	catalogue := withDefaultRules(config.Catalogue)
+	if err := catalogue.Compile(); err != nil {
+		return libpolybase.Catalogue{}, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return catalogue, nil
+}
+
+// LoadStorage reads the storage section of a configuration file, for the
+// tools that need nothing else from it.
+func LoadStorage(configPath string) (Storage, error) {
+	config := struct {
+		Storage Storage
+	}{DefaultStorage()}
+	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+		return Storage{}, err
+	}
+
+	if dir := os.Getenv("POLYBASE_STORAGE_DIR"); dir != "" {
+		config.Storage.Dir = dir
+	}
+	if config.Storage.Dir == "" {
+		return Storage{}, fmt.Errorf("invalid configuration: storage.dir is required")
+	}
+	return config.Storage, nil
+}
+
+// withDefaultRules fills the rules left out of the catalogue section with the
+// default ones.
+func withDefaultRules(catalogue libpolybase.Catalogue) libpolybase.Catalogue {
+	defaults := libpolybase.DefaultCatalogue()
+	if len(catalogue.CodePatterns) == 0 {
+		catalogue.CodePatterns = defaults.CodePatterns
+	}
+	if len(catalogue.Kinds) == 0 {
+		catalogue.Kinds = defaults.Kinds
+		// The default templates are made of the default kinds
+		if len(catalogue.Templates) == 0 {
+			catalogue.Templates = defaults.Templates
+		}
+	}
+	if len(catalogue.Semesters) == 0 {
+		catalogue.Semesters = defaults.Semesters
+	}
+	return catalogue
+}
+
 func (c *Config) loadFromEnv() {
 	if host := os.Getenv("POLYBASE_SERVER_HOST"); host != "" {
 		c.Server.Host = host
@@ -85,6 +173,11 @@ func (c *Config) loadFromEnv() {
 	if path := os.Getenv("POLYBASE_DATABASE_PATH"); path != "" {
 		c.Database.Path = path
 	}
+	if migrate := os.Getenv("POLYBASE_DATABASE_MIGRATE"); migrate != "" {
+		if v, err := strconv.ParseBool(migrate); err == nil {
+			c.Database.Migrate = v
+		}
+	}
 
 	if clientID := os.Getenv("POLYBASE_OIDC_CLIENT_ID"); clientID != "" {
 		c.OIDC.ClientID = clientID
@@ -108,6 +201,19 @@ func (c *Config) loadFromEnv() {
 	if expiry := os.Getenv("POLYBASE_AUTH_JWT_EXPIRY"); expiry != "" {
 		c.Auth.JWTExpiry = expiry
 	}
+
+	if retention := os.Getenv("POLYBASE_TRASH_RETENTION"); retention != "" {
+		c.Trash.Retention = retention
+	}
+
+	if dir := os.Getenv("POLYBASE_STORAGE_DIR"); dir != "" {
+		c.Storage.Dir = dir
+	}
+	if maxSize := os.Getenv("POLYBASE_STORAGE_MAX_SIZE"); maxSize != "" {
+		if v, err := strconv.ParseInt(maxSize, 10, 64); err == nil {
+			c.Storage.MaxSize = v
+		}
+	}
 }
 
 func (c *Config) Validate() error {
@@ -164,5 +270,26 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("auth.jwt_expiry must be a valid duration (e.g., '24h', '168h')")
 	}
 
+	// Trash validation
+	if c.Trash.Retention == "" {
+		return fmt.Errorf("trash.retention is required")
+	}
+	if d, err := time.ParseDuration(c.Trash.Retention); err != nil || d < 0 {
+		return fmt.Errorf("trash.retention must be a valid duration (e.g., '720h', '0' to disable)")
+	}
+
+	// Storage validation
+	if c.Storage.Dir == "" {
+		return fmt.Errorf("storage.dir is required")
+	}
+	if c.Storage.MaxSize < 1 {
+		return fmt.Errorf("storage.max_size must be a positive number of MiB")
+	}
+
+	// Catalogue validation
+	if err := c.Catalogue.Compile(); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -47,7 +47,7 @@ func main() {
 
 	srv, err := routes.NewServer(&cfg)
 	if err != nil {
-		log.Printf("Could not create server %s", err)
+		log.Fatalf("Could not create server: %v", err)
 	}
 	srv.Run(config.CreateContext(context.Background(), &cfg, devMode))
 }
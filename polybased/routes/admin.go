@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"mime"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,27 +18,44 @@ import (
 func (s *Server) getAdmin(w http.ResponseWriter, r *http.Request) {
 	username := config.GetUsername(r.Context())
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, filter, err := s.listGridCourses(r, true)
 	if err != nil {
-		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		renderError(w, r, err, "Failed to list courses")
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("%s", err)
 		return
 	}
 
-	err = views.Admin(courses, packs, username).Render(r.Context(), w)
+	tags, err := s.yearPB(r).ListTags(r.Context(), true)
+	if err != nil {
+		http.Error(w, "Failed to list tags", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
+	years, err := s.yearSelection(r)
+	if err != nil {
+		http.Error(w, "Failed to list years", http.StatusInternalServerError)
+		log.Printf("Failed to list years: %v", err)
+		return
+	}
+
+	err = views.Admin(courses, filter, packs, tags, years, username).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
 	}
 }
 
+func (s *Server) getAdminSearch(w http.ResponseWriter, r *http.Request) {
+	s.renderSearch(w, r, true)
+}
+
 func (s *Server) getAdminCoursesNew(w http.ResponseWriter, r *http.Request) {
 	err := views.NewCourseForm().Render(r.Context(), w)
 	if err != nil {
@@ -44,6 +64,43 @@ func (s *Server) getAdminCoursesNew(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) getAdminCoursesTemplate(w http.ResponseWriter, r *http.Request) {
+	err := views.NewTemplateCoursesForm(s.yearPB(r).Catalogue().Templates).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getAdminCoursesClone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/clone/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	pb := s.yearPB(r)
+	course, err := pb.GetCourse(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get course")
+		return
+	}
+
+	years, err := pb.ListYears(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list years", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
+	err = views.CloneCourseForm(course, years, pb.Year()).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
 func (s *Server) getAdminCoursesEdit(w http.ResponseWriter, r *http.Request) {
 	id, err := parseCourseUrl("/admin/courses/edit/", r)
 	if err != nil {
@@ -52,10 +109,10 @@ func (s *Server) getAdminCoursesEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	course, err := s.pb.GetCourse(r.Context(), id)
+	course, err := s.yearPB(r).GetCourse(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to get course", http.StatusInternalServerError)
-		log.Printf("Failed to get course: %v", err)
+		renderError(w, r, err, "Failed to get course")
+		return
 	}
 
 	err = views.EditCourseForm(course).Render(r.Context(), w)
@@ -73,10 +130,10 @@ func (s *Server) getAdminCoursesDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	course, err := s.pb.GetCourse(r.Context(), id)
+	course, err := s.yearPB(r).GetCourse(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to get course", http.StatusInternalServerError)
-		log.Printf("Failed to get course: %v", err)
+		renderError(w, r, err, "Failed to get course")
+		return
 	}
 
 	err = views.CourseDeleteConfirm(course).Render(r.Context(), w)
@@ -86,14 +143,137 @@ func (s *Server) getAdminCoursesDelete(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) getAdminCoursesEditions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/editions/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	pb := s.yearPB(r)
+	course, err := pb.GetCourse(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get course")
+		return
+	}
+
+	editions, err := pb.ListEditions(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to list editions")
+		return
+	}
+
+	err = views.CourseEditions(course, editions).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getAdminCoursesFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/files/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	pb := s.yearPB(r)
+	course, err := pb.GetCourse(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get course")
+		return
+	}
+
+	files, err := pb.ListMasterFiles(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to list master files")
+		return
+	}
+
+	err = views.CourseFiles(course, files).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getAdminCoursesMaster(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/master/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := s.yearPB(r).GetMasterFile(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get master file")
+		return
+	}
+
+	s.serveMasterFile(w, r, file.ID)
+}
+
+func (s *Server) getAdminFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	s.serveMasterFile(w, r, id)
+}
+
+// serveMasterFile sends a master file as an attachment named after the
+// uploaded file.
+func (s *Server) serveMasterFile(w http.ResponseWriter, r *http.Request, id int) {
+	file, content, err := s.yearPB(r).OpenMasterFile(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to open master file")
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	if _, err := io.Copy(w, content); err != nil {
+		log.Printf("Failed to send master file %d: %v", id, err)
+	}
+}
+
+func (s *Server) getAdminCoursesParts(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/parts/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	course, err := s.yearPB(r).GetCourse(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get course")
+		return
+	}
+
+	err = views.CourseParts(course).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
 func (s *Server) getAdminPacksNew(w http.ResponseWriter, r *http.Request) {
-	courses, err := s.pb.ListCourse(r.Context(), false, nil, nil, nil, nil)
+	courses, err := s.yearPB(r).ListCourses(r.Context(), libpolybase.CourseFilter{})
 	if err != nil {
 		http.Error(w, "Failed to get course", http.StatusInternalServerError)
 		log.Printf("Failed to get course: %v", err)
 	}
 
-	err = views.NewPackForm(courses).Render(r.Context(), w)
+	err = views.NewPackForm(courses.Items).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
@@ -108,19 +288,19 @@ func (s *Server) getAdminPacksEdit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), false, nil, nil, nil, nil)
+	courses, err := s.yearPB(r).ListCourses(r.Context(), libpolybase.CourseFilter{})
 	if err != nil {
 		http.Error(w, "Failed to get courses", http.StatusInternalServerError)
 		log.Printf("Failed to get courses: %v", err)
 	}
 
-	pack, err := s.pb.GetPack(r.Context(), id)
+	pack, err := s.yearPB(r).GetPack(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to get pack", http.StatusInternalServerError)
-		log.Printf("Failed to get pack: %v", err)
+		renderError(w, r, err, "Failed to get pack")
+		return
 	}
 
-	err = views.EditPackForm(pack, courses).Render(r.Context(), w)
+	err = views.EditPackForm(pack, courses.Items).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
@@ -135,10 +315,10 @@ func (s *Server) getAdminPacksDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pack, err := s.pb.GetPack(r.Context(), id)
+	pack, err := s.yearPB(r).GetPack(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to get course", http.StatusInternalServerError)
-		log.Printf("Failed to get course: %v", err)
+		renderError(w, r, err, "Failed to get pack")
+		return
 	}
 
 	err = views.PackDeleteConfirm(pack).Render(r.Context(), w)
@@ -176,10 +356,10 @@ func (s *Server) getAdminPack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	pack, err := s.pb.GetPack(r.Context(), id)
+	pack, err := s.yearPB(r).GetPack(r.Context(), id)
 	if err != nil {
-		http.Error(w, "Failed to get course", http.StatusInternalServerError)
-		log.Printf("Failed to get course: %v", err)
+		renderError(w, r, err, "Failed to get pack")
+		return
 	}
 
 	err = views.PackCard(pack, expanded).Render(r.Context(), w)
@@ -189,12 +369,54 @@ func (s *Server) getAdminPack(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *Server) getAdminTrash(w http.ResponseWriter, r *http.Request) {
+	username := config.GetUsername(r.Context())
+
+	trash, err := s.yearPB(r).ListTrash(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list trash", http.StatusInternalServerError)
+		log.Printf("Failed to list trash: %v", err)
+		return
+	}
+
+	years, err := s.yearSelection(r)
+	if err != nil {
+		http.Error(w, "Failed to list years", http.StatusInternalServerError)
+		log.Printf("Failed to list years: %v", err)
+		return
+	}
+
+	err = views.TrashPage(trash, years, username).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+// postAdminYear switches the academic year displayed in the admin.
+func (s *Server) postAdminYear(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	year, err := libpolybase.ParseAcademicYear(r.FormValue("year"))
+	if err != nil {
+		renderError(w, r, err, "Invalid year")
+		return
+	}
+
+	setYearCookie(w, year, config.IsDev(r.Context()))
+	http.Redirect(w, r, "/admin", http.StatusSeeOther)
+}
+
 //func (s *Server) getAdminStatistics(w http.ResponseWriter, r *http.Request) {
 //	log.Printf("Get admin statistics - Config: %+v, Polybase: %+v", s.cfg, s.pb)
 //	w.Write([]byte("Get admin statistics"))
 //}
 
 func (s *Server) postAdminCourses(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
 		log.Println(err)
@@ -211,24 +433,6 @@ func (s *Server) postAdminCourses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.pb.GetCourse(r.Context(), id)
-	exists := true
-	if err != nil {
-		if _, ok := err.(*libpolybase.CourseNotFound); ok {
-			exists = false
-		} else {
-			http.Error(w, "Failed to get course", http.StatusInternalServerError)
-			log.Printf("%s", err)
-			return
-		}
-	}
-
-	if exists {
-		http.Error(w, "Course already exists", http.StatusBadRequest)
-		log.Printf("Failed to add course: course already exists")
-		return
-	}
-
 	code := id.Code
 	kind := id.Kind
 	part := id.Part
@@ -255,36 +459,37 @@ func (s *Server) postAdminCourses(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	shown := true
 	semester := r.Form.Get("semester")
 
-	course := libpolybase.Course{
-		Code:     code,
-		Kind:     kind,
-		Part:     part,
-		Parts:    parts,
-		Name:     name,
-		Quantity: quantity,
-		Total:    total,
-		Shown:    shown,
-		Semester: semester,
+	course, err := parseCourseDetails(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid course details")
+		return
 	}
+	course.Code = code
+	course.Kind = kind
+	course.Part = part
+	course.Parts = parts
+	course.Name = name
+	course.Quantity = quantity
+	course.Total = total
+	course.Status = libpolybase.CourseStatus(r.Form.Get("status"))
+	course.Semester = semester
 
-	_, err = s.pb.CreateCourse(r.Context(), username, course)
+	created, err := s.yearPB(r).CreateCourse(ctx, username, course)
 	if err != nil {
-		http.Error(w, "Failed to add course", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		renderError(w, r, err, "Failed to add course")
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("%s", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("%s", err)
@@ -295,10 +500,16 @@ func (s *Server) postAdminCourses(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.CourseChange(created.CID()); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) putAdminCourses(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
 		log.Println(err)
@@ -315,24 +526,6 @@ func (s *Server) putAdminCourses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = s.pb.GetCourse(r.Context(), id)
-	exists := true
-	if err != nil {
-		if err == fmt.Errorf("course not found") {
-			exists = false
-		} else {
-			http.Error(w, "Failed to get course", http.StatusInternalServerError)
-			log.Printf("%s", err)
-			return
-		}
-	}
-
-	if !exists {
-		http.Error(w, "Course does not exists", http.StatusBadRequest)
-		log.Printf("Failed to edit course: course does not exists")
-		return
-	}
-
 	code := r.Form.Get("code")
 	kind := r.Form.Get("kind")
 	part, err := strconv.Atoi(r.Form.Get("part"))
@@ -364,37 +557,71 @@ func (s *Server) putAdminCourses(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	shown := true
-
 	semester := r.Form.Get("semester")
 
+	details, err := parseCourseDetails(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid course details")
+		return
+	}
+
 	course := libpolybase.PartialCourse{
-		Code:     &code,
-		Kind:     &kind,
-		Part:     &part,
-		Parts:    &parts,
-		Name:     &name,
-		Quantity: &quantity,
-		Total:    &total,
-		Shown:    &shown,
-		Semester: &semester,
+		Code:        &code,
+		Kind:        &kind,
+		Part:        &part,
+		Parts:       &parts,
+		Name:        &name,
+		Quantity:    &quantity,
+		Total:       &total,
+		Semester:    &semester,
+		Pages:       &details.Pages,
+		Price:       &details.Price,
+		PrintCost:   &details.PrintCost,
+		Teacher:     &details.Teacher,
+		Edition:     &details.Edition,
+		EditionDate: &details.EditionDate,
+		Description: &details.Description,
+		Tags:        &details.Tags,
+	}
+
+	if status := r.Form.Get("status"); status != "" {
+		status := libpolybase.CourseStatus(status)
+		course.Status = &status
+	}
+
+	if revisionStr := r.Form.Get("revision"); revisionStr != "" {
+		revision, err := strconv.Atoi(revisionStr)
+		if err != nil {
+			http.Error(w, "Invalid revision parameter", http.StatusBadRequest)
+			log.Printf("Failed to parse revision: %s", err)
+			return
+		}
+		course.Revision = &revision
 	}
 
-	_, err = s.pb.UpdateCourse(r.Context(), username, id, course)
-	if err != nil {
-		http.Error(w, "Failed to add course", http.StatusInternalServerError)
+	updated, err := s.yearPB(r).UpdateCourse(ctx, username, id, course)
+	var conflict *libpolybase.RevisionConflict
+	if errors.As(err, &conflict) {
+		w.WriteHeader(http.StatusConflict)
+		if err := views.ConflictError(fmt.Sprintf("/admin/courses/edit/%s", id.ID())).Render(r.Context(), w); err != nil {
+			log.Printf("Failed to render template: %v", err)
+		}
 		log.Printf("%s", err)
 		return
 	}
+	if err != nil {
+		renderError(w, r, err, "Failed to update course")
+		return
+	}
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("%s", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("%s", err)
@@ -405,10 +632,16 @@ func (s *Server) putAdminCourses(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.CourseChange(updated.CID()); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) deleteAdminCourses(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
 		log.Println(err)
@@ -418,39 +651,20 @@ func (s *Server) deleteAdminCourses(w http.ResponseWriter, r *http.Request) {
 
 	username := config.GetUsername(r.Context())
 
-	_, err = s.pb.GetCourse(r.Context(), id)
-	exists := true
-	if err != nil {
-		if err == fmt.Errorf("course not found") {
-			exists = false
-		} else {
-			http.Error(w, "Failed to get course", http.StatusInternalServerError)
-			log.Printf("%s", err)
-			return
-		}
-	}
-
-	if !exists {
-		http.Error(w, "Course does not exists", http.StatusBadRequest)
-		log.Printf("Failed to edit course: course does not exists")
-		return
-	}
-
-	err = s.pb.DeleteCourse(r.Context(), username, id)
+	err = s.yearPB(r).DeleteCourse(ctx, username, id)
 	if err != nil {
-		http.Error(w, "Failed to add course", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		renderError(w, r, err, "Failed to delete course")
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("%s", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("%s", err)
@@ -461,10 +675,16 @@ func (s *Server) deleteAdminCourses(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.CourseChange(id); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) patchAdminCoursesQuantity(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
 		log.Println(err)
@@ -481,10 +701,9 @@ func (s *Server) patchAdminCoursesQuantity(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	course, err := s.pb.UpdateCourseQuantity(r.Context(), username, id, delta)
+	course, err := s.yearPB(r).UpdateCourseQuantity(ctx, username, id, delta)
 	if err != nil {
-		log.Println("Patch admin course quantity - error:", err)
-		http.Error(w, "Failed to update quantity", http.StatusInternalServerError)
+		renderError(w, r, err, "Failed to update quantity")
 		return
 	}
 
@@ -492,10 +711,46 @@ func (s *Server) patchAdminCoursesQuantity(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		log.Printf("Failed to render template: %v", err)
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+
+	if event, ok := changes.CourseChange(id); ok {
+		renderUndoToast(w, r, event)
+	}
+}
+
+func (s *Server) patchAdminCoursesStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+
+	status := libpolybase.CourseStatus(r.FormValue("status"))
+	course, err := s.yearPB(r).SetCourseStatus(ctx, username, id, status)
+	if err != nil {
+		renderError(w, r, err, "Failed to update status")
+		return
+	}
+
+	err = views.CourseCard(course, true).Render(r.Context(), w)
+	if err != nil {
+		log.Printf("Failed to render template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+
+	if event, ok := changes.CourseChange(id); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) patchAdminCoursesVisibility(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
 		log.Println(err)
@@ -512,10 +767,9 @@ func (s *Server) patchAdminCoursesVisibility(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	course, err := s.pb.UpdateCourseShown(r.Context(), username, id, visibility)
+	course, err := s.yearPB(r).UpdateCourseShown(ctx, username, id, visibility)
 	if err != nil {
-		log.Println("Patch admin course quantity - error:", err)
-		http.Error(w, "Failed to update quantity", http.StatusInternalServerError)
+		renderError(w, r, err, "Failed to update visibility")
 		return
 	}
 
@@ -523,10 +777,16 @@ func (s *Server) patchAdminCoursesVisibility(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		log.Printf("Failed to render template: %v", err)
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		return
+	}
+
+	if event, ok := changes.CourseChange(id); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) patchAdminPacksQuantity(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	id, err := parsePackUrl("/admin/packs/", r)
 	if err != nil {
 		log.Println(err)
@@ -543,73 +803,640 @@ func (s *Server) patchAdminPacksQuantity(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err = s.pb.UpdatePackQuantity(r.Context(), username, id, delta)
+	// The buttons hand out what is left unless told otherwise
+	policy := libpolybase.PolicyClamp
+	if value := r.FormValue("policy"); value != "" {
+		policy = libpolybase.PackQuantityPolicy(value)
+	}
+	result, err := s.yearPB(r).UpdatePackQuantity(ctx, username, id, delta, policy)
 	if err != nil {
-		log.Println("Patch admin course quantity - error:", err)
-		http.Error(w, "Failed to update quantity", http.StatusInternalServerError)
+		renderError(w, r, err, "Failed to update quantity")
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	err = views.PackQuantityCard(result).Render(r.Context(), w)
 	if err != nil {
-		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		log.Printf("Failed to render template: %v", err)
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
-	if err != nil {
-		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
-		log.Printf("%s", err)
-		return
+	if event, ok := changes.PackChange(id); ok {
+		renderUndoToast(w, r, event)
 	}
+}
 
-	err = views.Grid(views.GroupCoursesBySemesterAndKind(courses), packs, true).Render(r.Context(), w)
+func (s *Server) postAdminCoursesEditions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	edition := libpolybase.Edition{
+		Label: r.FormValue("label"),
+		Date:  r.FormValue("date"),
+		Notes: r.FormValue("notes"),
+	}
+	if quantity := strings.TrimSpace(r.FormValue("quantity")); quantity != "" {
+		edition.Quantity, err = strconv.Atoi(quantity)
+		if err != nil {
+			renderError(w, r, &libpolybase.ValidationError{Field: "quantity", Msg: "invalid quantity"}, "Invalid quantity")
+			return
+		}
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).AddEdition(r.Context(), username, id, edition); err != nil {
+		renderError(w, r, err, "Failed to add edition")
+		return
+	}
+
+	s.renderCourseEditionList(w, r, id)
+}
+
+func (s *Server) postAdminEditionsCurrent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	edition, err := s.yearPB(r).SetCurrentEdition(r.Context(), username, id)
+	if err != nil {
+		renderError(w, r, err, "Failed to set current edition")
+		return
+	}
+
+	s.renderCourseEditionList(w, r, edition.Course)
+}
+
+func (s *Server) postAdminEditionsRetire(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	edition, err := s.yearPB(r).RetireEdition(r.Context(), username, id)
+	if err != nil {
+		renderError(w, r, err, "Failed to retire edition")
+		return
+	}
+
+	s.renderCourseEditionList(w, r, edition.Course)
+}
+
+// renderCourseEditionList answers an edition change with the editions of the
+// course, updating the quantity of its card as well.
+func (s *Server) renderCourseEditionList(w http.ResponseWriter, r *http.Request, id libpolybase.CourseID) {
+	pb := s.yearPB(r)
+	course, err := pb.GetCourse(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get course", http.StatusInternalServerError)
+		log.Printf("Failed to get course: %v", err)
+		return
+	}
+
+	editions, err := pb.ListEditions(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to list editions", http.StatusInternalServerError)
+		log.Printf("Failed to list editions: %v", err)
+		return
+	}
+
+	err = views.CourseEditionList(course, editions).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	err = views.CardQuantityUpdate(course).Render(r.Context(), w)
+	if err != nil {
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) postAdminCoursesFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	// The rest of the form is small next to the file
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxFileSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			renderError(w, r, &libpolybase.ValidationError{Field: "file", Msg: fmt.Sprintf("file cannot exceed %d MiB", s.maxFileSize>>20)}, "File too large")
+			return
+		}
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	content, header, err := r.FormFile("file")
+	if err != nil {
+		renderError(w, r, &libpolybase.ValidationError{Field: "file", Msg: "file is required"}, "Missing file")
+		return
+	}
+	defer content.Close()
+	if header.Size > s.maxFileSize {
+		renderError(w, r, &libpolybase.ValidationError{Field: "file", Msg: fmt.Sprintf("file cannot exceed %d MiB", s.maxFileSize>>20)}, "File too large")
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).PutMasterFile(r.Context(), username, id, header.Filename, content); err != nil {
+		renderError(w, r, err, "Failed to upload master file")
+		return
+	}
+
+	s.renderCourseFileList(w, r, id)
+}
+
+func (s *Server) renderCourseFileList(w http.ResponseWriter, r *http.Request, id libpolybase.CourseID) {
+	pb := s.yearPB(r)
+	course, err := pb.GetCourse(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get course", http.StatusInternalServerError)
+		log.Printf("Failed to get course: %v", err)
+		return
+	}
+
+	files, err := pb.ListMasterFiles(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to list master files", http.StatusInternalServerError)
+		log.Printf("Failed to list master files: %v", err)
+		return
+	}
+
+	err = views.CourseFileList(course, files).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	err = views.CourseMasterUpdate(course).Render(r.Context(), w)
+	if err != nil {
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getAdminCoursesNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/notes/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	s.renderCourseNoteList(w, r, id)
+}
+
+func (s *Server) postAdminCoursesNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).AddCourseNote(r.Context(), username, id, r.FormValue("body")); err != nil {
+		renderError(w, r, err, "Failed to add note")
+		return
+	}
+
+	s.renderCourseNoteList(w, r, id)
+}
+
+func (s *Server) getAdminPacksNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	s.renderPackNoteList(w, r, id)
+}
+
+func (s *Server) postAdminPacksNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).AddPackNote(r.Context(), username, id, r.FormValue("body")); err != nil {
+		renderError(w, r, err, "Failed to add note")
+		return
+	}
+
+	s.renderPackNoteList(w, r, id)
+}
+
+func (s *Server) renderCourseNoteList(w http.ResponseWriter, r *http.Request, id libpolybase.CourseID) {
+	notes, err := s.yearPB(r).ListCourseNotes(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to list notes")
+		return
+	}
+
+	action := fmt.Sprintf("/admin/courses/%s/notes", id.ID())
+	err = views.NoteList(notes, action, config.GetUsername(r.Context())).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) renderPackNoteList(w http.ResponseWriter, r *http.Request, id int) {
+	notes, err := s.yearPB(r).ListPackNotes(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to list notes")
+		return
+	}
+
+	action := fmt.Sprintf("/admin/packs/%d/notes", id)
+	err = views.NoteList(notes, action, config.GetUsername(r.Context())).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+// getAdminNote renders a single note, to put it back when its edition is
+// cancelled.
+func (s *Server) getAdminNote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	note, err := s.yearPB(r).GetNote(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get note")
+		return
+	}
+
+	err = views.NoteItem(note, config.GetUsername(r.Context())).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getAdminNotesEdit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	note, err := s.yearPB(r).GetNote(r.Context(), id)
+	if err != nil {
+		renderError(w, r, err, "Failed to get note")
+		return
+	}
+
+	err = views.NoteEditForm(note).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) putAdminNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	note, err := s.yearPB(r).UpdateNote(r.Context(), username, id, r.FormValue("body"))
+	if err != nil {
+		renderError(w, r, err, "Failed to update note")
+		return
+	}
+
+	err = views.NoteItem(note, username).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) deleteAdminNotes(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if err := s.yearPB(r).DeleteNote(r.Context(), username, id); err != nil {
+		renderError(w, r, err, "Failed to delete note")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) postAdminCoursesSplit(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	quantity := 0
+	if value := strings.TrimSpace(r.FormValue("quantity")); value != "" {
+		quantity, err = strconv.Atoi(value)
+		if err != nil {
+			renderError(w, r, &libpolybase.ValidationError{Field: "quantity", Msg: "invalid quantity"}, "Invalid quantity")
+			return
+		}
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).SplitCourse(r.Context(), username, id, quantity); err != nil {
+		renderError(w, r, err, "Failed to split course")
+		return
+	}
+
+	s.renderAdminGrid(w, r)
+}
+
+func (s *Server) postAdminCoursesMerge(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
-		http.Error(w, "Failed to render template", http.StatusInternalServerError)
-		log.Printf("Failed to render template: %v", err)
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	part, err := strconv.Atoi(r.FormValue("part"))
+	if err != nil {
+		renderError(w, r, &libpolybase.ValidationError{Field: "part", Msg: "invalid part"}, "Invalid part")
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).MergeParts(r.Context(), username, id, part); err != nil {
+		renderError(w, r, err, "Failed to merge parts")
+		return
 	}
+
+	s.renderAdminGrid(w, r)
 }
 
-func (s *Server) postAdminPacks(w http.ResponseWriter, r *http.Request) {
+func (s *Server) postAdminCoursesInsert(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	course := libpolybase.Course{
+		Code:     id.Code,
+		Kind:     id.Kind,
+		Name:     r.FormValue("name"),
+		Semester: r.FormValue("semester"),
+	}
+	if course.Part, err = strconv.Atoi(r.FormValue("part")); err != nil {
+		renderError(w, r, &libpolybase.ValidationError{Field: "part", Msg: "invalid part"}, "Invalid part")
+		return
+	}
+	if course.Quantity, err = strconv.Atoi(r.FormValue("quantity")); err != nil {
+		renderError(w, r, &libpolybase.ValidationError{Field: "quantity", Msg: "invalid quantity"}, "Invalid quantity")
+		return
+	}
+	course.Total = course.Quantity
+	if total := strings.TrimSpace(r.FormValue("total")); total != "" {
+		if course.Total, err = strconv.Atoi(total); err != nil {
+			renderError(w, r, &libpolybase.ValidationError{Field: "total", Msg: "invalid total"}, "Invalid total")
+			return
+		}
+	}
+
 	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).InsertPart(r.Context(), username, course); err != nil {
+		renderError(w, r, err, "Failed to insert part")
+		return
+	}
 
-	// Parse form data
-	err := r.ParseForm()
+	s.renderAdminGrid(w, r)
+}
+
+func (s *Server) postAdminCoursesRenumber(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
 	if err != nil {
-		log.Printf("Failed to parse form: %v", err)
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+	if _, err := s.yearPB(r).RenumberParts(r.Context(), username, id.Code, id.Kind); err != nil {
+		renderError(w, r, err, "Failed to renumber parts")
+		return
+	}
+
+	s.renderAdminGrid(w, r)
+}
+
+func (s *Server) postAdminCoursesTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
 
-	name := r.Form.Get("name")
-	var coursesId []libpolybase.CourseID
-	for _, idStr := range r.Form["courses"] {
-		parts := strings.Split(idStr, "/")
+	pb := s.yearPB(r)
+	template, err := pb.Catalogue().Template(r.FormValue("template"))
+	if err != nil {
+		renderError(w, r, err, "Failed to get template")
+		return
+	}
 
-		code := parts[0]
-		kind := parts[1]
-		part, err := strconv.Atoi(parts[2])
-		if err != nil {
-			http.Error(w, "Failed to get part", http.StatusInternalServerError)
-			log.Printf("%s", err)
+	course, err := parseCourseDetails(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid course details")
+		return
+	}
+	course.Code = r.FormValue("code")
+	course.Name = r.FormValue("name")
+	course.Semester = r.FormValue("semester")
+	if course.Quantity, err = strconv.Atoi(r.FormValue("quantity")); err != nil {
+		renderError(w, r, &libpolybase.ValidationError{Field: "quantity", Msg: "invalid quantity"}, "Invalid quantity")
+		return
+	}
+	course.Total = course.Quantity
+	if total := strings.TrimSpace(r.FormValue("total")); total != "" {
+		if course.Total, err = strconv.Atoi(total); err != nil {
+			renderError(w, r, &libpolybase.ValidationError{Field: "total", Msg: "invalid total"}, "Invalid total")
 			return
 		}
+	}
 
-		id := libpolybase.CourseID{
-			Code: code,
-			Kind: kind,
-			Part: part,
-		}
+	username := config.GetUsername(r.Context())
+	if _, err := pb.CreateCourses(r.Context(), username, template.Expand(course)); err != nil {
+		renderError(w, r, err, "Failed to create courses")
+		return
+	}
 
-		_, err = s.pb.GetCourse(r.Context(), id)
-		if err != nil {
-			http.Error(w, "Failed to get course", http.StatusInternalServerError)
-			log.Printf("%s", err)
+	s.renderAdminGrid(w, r)
+}
+
+// postAdminCoursesClone copies a course, or every course of its code, into the
+// year picked in the form. Clones into another year do not show in the grid,
+// so a toast tells where they went.
+func (s *Server) postAdminCoursesClone(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	pb := s.yearPB(r)
+	year := pb.Year()
+	if value := r.FormValue("year"); value != "" {
+		if year, err = libpolybase.ParseAcademicYear(value); err != nil {
+			renderError(w, r, err, "Invalid year")
 			return
 		}
+	}
+
+	opts := libpolybase.CloneOptions{
+		Year:       pb.Year(),
+		Code:       id.Code,
+		ToCode:     strings.TrimSpace(r.FormValue("code")),
+		ToSemester: r.FormValue("semester"),
+	}
+	if r.FormValue("scope") != "code" {
+		opts.Kind = id.Kind
+		opts.Part = id.Part
+	}
+
+	username := config.GetUsername(r.Context())
+	clones, err := s.pb.ForYear(year).CloneCourses(r.Context(), username, opts)
+	if err != nil {
+		renderError(w, r, err, "Failed to clone courses")
+		return
+	}
+
+	s.renderAdminGrid(w, r)
+	if year != pb.Year() {
+		message := fmt.Sprintf("%d poly(s) copié(s) vers %s", len(clones), year)
+		if err := views.InfoToast(message).Render(r.Context(), w); err != nil {
+			log.Printf("Failed to render template: %v", err)
+		}
+	}
+}
+
+// renderAdminGrid answers a change touching several courses with the whole
+// admin grid.
+func (s *Server) renderAdminGrid(w http.ResponseWriter, r *http.Request) {
+	courses, _, err := s.listGridCourses(r, true)
+	if err != nil {
+		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
+	packs, err := s.yearPB(r).ListPacks(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
+	err = views.Grid(views.GroupCoursesBySemesterAndKind(courses), packs, true).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) postAdminPacks(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
+	username := config.GetUsername(r.Context())
+
+	// Parse form data
+	err := r.ParseForm()
+	if err != nil {
+		log.Printf("Failed to parse form: %v", err)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
 
-		coursesId = append(coursesId, id)
+	name := r.Form.Get("name")
+	coursesId, err := parsePackCourses(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid pack courses")
+		return
 	}
 
 	fmt.Println(username)
@@ -618,21 +1445,20 @@ func (s *Server) postAdminPacks(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(course)
 	}
 
-	_, err = s.pb.CreatePack(r.Context(), username, name, coursesId)
+	pack, err := s.yearPB(r).CreatePack(ctx, username, name, coursesId)
 	if err != nil {
-		http.Error(w, "Failed to add pack", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		renderError(w, r, err, "Failed to add pack")
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("%s", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("%s", err)
@@ -643,10 +1469,16 @@ func (s *Server) postAdminPacks(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.PackChange(pack.ID); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) putAdminPacks(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	log.Println("putAdminPacks")
 	username := config.GetUsername(r.Context())
 
@@ -669,55 +1501,68 @@ func (s *Server) putAdminPacks(w http.ResponseWriter, r *http.Request) {
 	// Get updated pack name
 	name := r.Form.Get("name")
 
-	// Parse course IDs from form
-	var coursesId []libpolybase.CourseID
-	for _, idStr := range r.Form["courses"] {
-		parts := strings.Split(idStr, "/")
-		if len(parts) != 3 {
-			http.Error(w, "Invalid course ID format", http.StatusBadRequest)
-			log.Printf("Invalid course ID format: %s", idStr)
-			return
-		}
+	// Parse courses and their counts from form
+	coursesId, err := parsePackCourses(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid pack courses")
+		return
+	}
 
-		code := parts[0]
-		kind := parts[1]
-		part, err := strconv.Atoi(parts[2])
-		if err != nil {
-			http.Error(w, "Failed to parse part number", http.StatusBadRequest)
-			log.Printf("Failed to parse part number: %s", err)
-			return
-		}
+	shown := r.Form.Get("shown") != ""
+	semester := r.Form.Get("semester")
+	level := libpolybase.Level(r.Form.Get("level"))
 
-		coursesId = append(coursesId, libpolybase.CourseID{
-			Code: code,
-			Kind: kind,
-			Part: part,
-		})
+	pricing, err := parsePackPricing(r.Form)
+	if err != nil {
+		renderError(w, r, err, "Invalid pack pricing")
+		return
 	}
 
 	// Create PartialPack for update
 	pack := libpolybase.PartialPack{
-		Name:    &name,
-		Courses: &coursesId,
+		Name:     &name,
+		Shown:    &shown,
+		Semester: &semester,
+		Level:    &level,
+		Courses:  &coursesId,
+		Pricing:  &pricing,
+	}
+
+	if revisionStr := r.Form.Get("revision"); revisionStr != "" {
+		revision, err := strconv.Atoi(revisionStr)
+		if err != nil {
+			http.Error(w, "Invalid revision parameter", http.StatusBadRequest)
+			log.Printf("Failed to parse revision: %s", err)
+			return
+		}
+		pack.Revision = &revision
 	}
 
 	// Update the pack
-	_, err = s.pb.UpdatePack(r.Context(), username, id, pack)
-	if err != nil {
-		http.Error(w, "Failed to update pack", http.StatusInternalServerError)
+	_, err = s.yearPB(r).UpdatePack(ctx, username, id, pack)
+	var conflict *libpolybase.RevisionConflict
+	if errors.As(err, &conflict) {
+		w.WriteHeader(http.StatusConflict)
+		if err := views.ConflictError(fmt.Sprintf("/admin/packs/edit/%d", id)).Render(r.Context(), w); err != nil {
+			log.Printf("Failed to render template: %v", err)
+		}
 		log.Printf("Failed to update pack: %s", err)
 		return
 	}
+	if err != nil {
+		renderError(w, r, err, "Failed to update pack")
+		return
+	}
 
 	// Fetch updated data for rendering
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("Failed to list courses: %s", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("Failed to list packs: %s", err)
@@ -729,10 +1574,16 @@ func (s *Server) putAdminPacks(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.PackChange(id); ok {
+		renderUndoToast(w, r, event)
 	}
 }
 
 func (s *Server) deleteAdminPacks(w http.ResponseWriter, r *http.Request) {
+	ctx, changes := libpolybase.RecordChanges(r.Context())
 	username := config.GetUsername(r.Context())
 
 	id, err := parsePackUrl("/admin/packs/", r)
@@ -742,22 +1593,125 @@ func (s *Server) deleteAdminPacks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = s.pb.DeletePack(r.Context(), username, id)
+	err = s.yearPB(r).DeletePack(ctx, username, id)
 	if err != nil {
-		http.Error(w, "Failed to delete pack", http.StatusInternalServerError)
-		log.Printf("Failed to delete pack: %v", err)
+		renderError(w, r, err, "Failed to delete pack")
 		return
 	}
 
 	// Re-render grid
-	courses, err := s.pb.ListCourse(r.Context(), true, nil, nil, nil, nil)
+	courses, _, err := s.listGridCourses(r, true)
+	if err != nil {
+		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
+		log.Printf("Failed to list courses: %v", err)
+		return
+	}
+
+	packs, err := s.yearPB(r).ListPacks(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
+		log.Printf("Failed to list packs: %v", err)
+		return
+	}
+
+	err = views.Grid(views.GroupCoursesBySemesterAndKind(courses), packs, true).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	if event, ok := changes.PackChange(id); ok {
+		renderUndoToast(w, r, event)
+	}
+}
+
+func (s *Server) postAdminTrashCoursesRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseUrl("/admin/trash/courses/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+
+	_, err = s.yearPB(r).RestoreCourse(r.Context(), username, id)
+	if err != nil {
+		renderError(w, r, err, "Failed to restore course")
+		return
+	}
+
+	s.renderTrashList(w, r)
+}
+
+func (s *Server) postAdminTrashPacksRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePackUrl("/admin/trash/packs/", r)
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+
+	_, err = s.yearPB(r).RestorePack(r.Context(), username, id)
+	if err != nil {
+		renderError(w, r, err, "Failed to restore pack")
+		return
+	}
+
+	s.renderTrashList(w, r)
+}
+
+func (s *Server) renderTrashList(w http.ResponseWriter, r *http.Request) {
+	trash, err := s.yearPB(r).ListTrash(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list trash", http.StatusInternalServerError)
+		log.Printf("Failed to list trash: %v", err)
+		return
+	}
+
+	err = views.TrashList(trash).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) postAdminChangesRevert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		log.Println(err)
+		http.NotFound(w, r)
+		return
+	}
+
+	username := config.GetUsername(r.Context())
+
+	event, err := s.yearPB(r).RevertChange(r.Context(), username, id)
+	var conflict *libpolybase.RevertConflict
+	if errors.As(err, &conflict) {
+		w.Header().Set("HX-Reswap", "none")
+		if err := views.MessageToast("Impossible d'annuler : modifié entre-temps.").Render(r.Context(), w); err != nil {
+			log.Printf("Failed to render template: %v", err)
+		}
+		log.Printf("Failed to revert change: %s", err)
+		return
+	}
+	if err != nil {
+		renderError(w, r, err, "Failed to revert change")
+		return
+	}
+
+	courses, _, err := s.listGridCourses(r, true)
 	if err != nil {
 		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
 		log.Printf("Failed to list courses: %v", err)
 		return
 	}
 
-	packs, err := s.pb.ListPacks(r.Context())
+	packs, err := s.yearPB(r).ListPacks(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
 		log.Printf("Failed to list packs: %v", err)
@@ -768,5 +1722,20 @@ func (s *Server) deleteAdminPacks(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
+		return
+	}
+
+	err = views.UndoToast(event).Render(r.Context(), w)
+	if err != nil {
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+// renderUndoToast appends to the response the toast offering to revert the
+// change the user just made.
+func renderUndoToast(w http.ResponseWriter, r *http.Request, event libpolybase.AuditEvent) {
+	err := views.UndoToast(event).Render(r.Context(), w)
+	if err != nil {
+		log.Printf("Failed to render template: %v", err)
 	}
 }
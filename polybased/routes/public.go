@@ -1,10 +1,12 @@
 package routes
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/alias-asso/polybase-go/libpolybase"
 	"github.com/alias-asso/polybase-go/polybased/config"
 	"github.com/alias-asso/polybase-go/views"
 )
@@ -15,10 +17,9 @@ func (s *Server) getHome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	courses, err := s.pb.ListCourse(r.Context(), false, nil, nil, nil, nil)
+	courses, filter, err := s.listGridCourses(r, false)
 	if err != nil {
-		http.Error(w, "Failed to list courses", http.StatusInternalServerError)
-		log.Printf("%s", err)
+		renderError(w, r, err, "Failed to list courses")
 		return
 	}
 	for i, c := range courses {
@@ -26,15 +27,68 @@ func (s *Server) getHome(w http.ResponseWriter, r *http.Request) {
 		courses[i] = c
 	}
 
+	packs, err := s.yearPB(r).ListShownPacks(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list packs", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
+	tags, err := s.yearPB(r).ListTags(r.Context(), false)
+	if err != nil {
+		http.Error(w, "Failed to list tags", http.StatusInternalServerError)
+		log.Printf("%s", err)
+		return
+	}
+
 	s.count += 1
 
-	err = views.Public(courses, s.count).Render(r.Context(), w)
+	err = views.Public(courses, filterPacks(packs, filter), filter, tags, s.count).Render(r.Context(), w)
 	if err != nil {
 		http.Error(w, "Failed to render template", http.StatusInternalServerError)
 		log.Printf("Failed to render template: %v", err)
 	}
 }
 
+func (s *Server) getPack(w http.ResponseWriter, r *http.Request) {
+	id, err := parsePackUrl("/packs/", r)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		s.getNotFound(w, r)
+		return
+	}
+
+	// Packs are only public once shown
+	pack, err := s.yearPB(r).GetPack(r.Context(), id)
+	if errors.Is(err, libpolybase.ErrNotFound) || err == nil && !pack.Shown {
+		w.WriteHeader(http.StatusNotFound)
+		s.getNotFound(w, r)
+		return
+	}
+	if err != nil {
+		renderError(w, r, err, "Failed to get pack")
+		return
+	}
+
+	page, err := s.yearPB(r).ListCourses(r.Context(), libpolybase.CourseFilter{PackID: &id})
+	if err != nil {
+		renderError(w, r, err, "Failed to list courses")
+		return
+	}
+
+	s.count += 1
+
+	err = views.PublicPack(pack, page.Items, s.count).Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+func (s *Server) getSearch(w http.ResponseWriter, r *http.Request) {
+	s.renderSearch(w, r, false)
+}
+
 func (s *Server) getLogin(w http.ResponseWriter, r *http.Request) {
 	if config.IsLogged(r.Context()) {
 		http.Redirect(w, r, "/admin", http.StatusSeeOther)
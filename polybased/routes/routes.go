@@ -16,24 +16,46 @@ func (s *Server) registerRoutes() {
 	s.registerStatic()
 
 	s.mux.HandleFunc("GET /{$}", s.getHome)
+	s.mux.HandleFunc("GET /search", s.getSearch)
+	s.mux.HandleFunc("GET /packs/{id}", s.getPack)
 	s.mux.HandleFunc("GET /login", s.getLogin)
 	s.mux.HandleFunc("GET /auth/callback", s.getAuthCallback)
 
 	s.mux.HandleFunc("GET /admin", s.withAuth(s.getAdmin))
+	s.mux.HandleFunc("GET /admin/search", s.withAuth(s.getAdminSearch))
 
 	s.mux.HandleFunc("GET /admin/courses/new", s.withAuth(s.getAdminCoursesNew))
+	s.mux.HandleFunc("GET /admin/courses/template", s.withAuth(s.getAdminCoursesTemplate))
+	s.mux.HandleFunc("GET /admin/courses/clone/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesClone))
 	s.mux.HandleFunc("GET /admin/courses/edit/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesEdit))
 	s.mux.HandleFunc("GET /admin/courses/delete/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesDelete))
+	s.mux.HandleFunc("GET /admin/courses/editions/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesEditions))
+	s.mux.HandleFunc("GET /admin/courses/files/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesFiles))
+	s.mux.HandleFunc("GET /admin/courses/master/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesMaster))
+	s.mux.HandleFunc("GET /admin/courses/parts/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesParts))
+	s.mux.HandleFunc("GET /admin/courses/notes/{code}/{kind}/{part}", s.withAuth(s.getAdminCoursesNotes))
 
 	s.mux.HandleFunc("GET /admin/packs/new", s.withAuth(s.getAdminPacksNew))
 	s.mux.HandleFunc("GET /admin/packs/edit/{id}", s.withAuth(s.getAdminPacksEdit))
 	s.mux.HandleFunc("GET /admin/packs/delete/{id}", s.withAuth(s.getAdminPacksDelete))
+	s.mux.HandleFunc("GET /admin/packs/notes/{id}", s.withAuth(s.getAdminPacksNotes))
+
+	s.mux.HandleFunc("GET /admin/files/{id}", s.withAuth(s.getAdminFiles))
+
+	s.mux.HandleFunc("GET /admin/notes/{id}", s.withAuth(s.getAdminNote))
+	s.mux.HandleFunc("GET /admin/notes/edit/{id}", s.withAuth(s.getAdminNotesEdit))
 
 	s.mux.HandleFunc("GET /admin/packs/{id}", s.withAuth(s.getAdminPack))
 
+	s.mux.HandleFunc("GET /admin/trash", s.withAuth(s.getAdminTrash))
+
+	s.mux.HandleFunc("POST /admin/year", s.withAuth(s.postAdminYear))
+
 	// s.mux.HandleFunc("GET /admin/statistics", s.withAuth(s.getAdminStatistics))
 
+	s.mux.HandleFunc("POST /admin/courses/template", s.withAuth(s.postAdminCoursesTemplate))
 	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}", s.withAuth(s.postAdminCourses))
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/clone", s.withAuth(s.postAdminCoursesClone))
 	s.mux.HandleFunc("PUT /admin/courses/{code}/{kind}/{part}", s.withAuth(s.putAdminCourses))
 	s.mux.HandleFunc("DELETE /admin/courses/{code}/{kind}/{part}", s.withAuth(s.deleteAdminCourses))
 
@@ -42,10 +64,32 @@ func (s *Server) registerRoutes() {
 	s.mux.HandleFunc("DELETE /admin/packs/{id}", s.withAuth(s.deleteAdminPacks))
 
 	s.mux.HandleFunc("PATCH /admin/courses/{code}/{kind}/{part}/quantity", s.withAuth(s.patchAdminCoursesQuantity))
+	s.mux.HandleFunc("PATCH /admin/courses/{code}/{kind}/{part}/status", s.withAuth(s.patchAdminCoursesStatus))
 	s.mux.HandleFunc("PATCH /admin/courses/{code}/{kind}/{part}/visibility", s.withAuth(s.patchAdminCoursesVisibility))
 
 	s.mux.HandleFunc("PATCH /admin/packs/{id}/quantity", s.withAuth(s.patchAdminPacksQuantity))
 
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/editions", s.withAuth(s.postAdminCoursesEditions))
+	s.mux.HandleFunc("POST /admin/editions/{id}/current", s.withAuth(s.postAdminEditionsCurrent))
+	s.mux.HandleFunc("POST /admin/editions/{id}/retire", s.withAuth(s.postAdminEditionsRetire))
+
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/files", s.withAuth(s.postAdminCoursesFiles))
+
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/notes", s.withAuth(s.postAdminCoursesNotes))
+	s.mux.HandleFunc("POST /admin/packs/{id}/notes", s.withAuth(s.postAdminPacksNotes))
+	s.mux.HandleFunc("PUT /admin/notes/{id}", s.withAuth(s.putAdminNotes))
+	s.mux.HandleFunc("DELETE /admin/notes/{id}", s.withAuth(s.deleteAdminNotes))
+
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/split", s.withAuth(s.postAdminCoursesSplit))
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/merge", s.withAuth(s.postAdminCoursesMerge))
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/insert", s.withAuth(s.postAdminCoursesInsert))
+	s.mux.HandleFunc("POST /admin/courses/{code}/{kind}/{part}/renumber", s.withAuth(s.postAdminCoursesRenumber))
+
+	s.mux.HandleFunc("POST /admin/trash/courses/{code}/{kind}/{part}/restore", s.withAuth(s.postAdminTrashCoursesRestore))
+	s.mux.HandleFunc("POST /admin/trash/packs/{id}/restore", s.withAuth(s.postAdminTrashPacksRestore))
+
+	s.mux.HandleFunc("POST /admin/changes/{id}/revert", s.withAuth(s.postAdminChangesRevert))
+
 	s.mux.HandleFunc("GET /health", s.getHealth)
 }
 
@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
 	"github.com/alias-asso/polybase-go/polybased/config"
+	"github.com/alias-asso/polybase-go/views"
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
 	_ "modernc.org/sqlite"
@@ -22,6 +24,8 @@ type Server struct {
 	oauth2Config    *oauth2.Config
 	oidcAuthOptions []oauth2.AuthCodeOption
 	oidcVerifier    *oidc.IDTokenVerifier
+	trashRetention  time.Duration
+	maxFileSize     int64
 	count           int
 }
 
@@ -32,8 +36,16 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	pb := libpolybase.New(db, cfg.Server.Log, true)
 	ctx := context.Background()
+	if cfg.Database.Migrate {
+		if err := libpolybase.Migrate(ctx, db); err != nil {
+			return nil, fmt.Errorf("migrate database: %w", err)
+		}
+	} else if err := libpolybase.CheckSchema(ctx, db); err != nil {
+		return nil, fmt.Errorf("check database schema: %w", err)
+	}
+
+	pb := libpolybase.New(db, cfg.Server.Log, true).WithCatalogue(cfg.Catalogue).WithStorage(cfg.Storage.Dir)
 	provider, err := oidc.NewProvider(ctx, cfg.OIDC.IssuerURL)
 	if err != nil {
 		return nil, fmt.Errorf("create OIDC provider: %w", err)
@@ -45,6 +57,11 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		return nil, fmt.Errorf("parse oidc.extra_params: %w", err)
 	}
 
+	trashRetention, err := time.ParseDuration(cfg.Trash.Retention)
+	if err != nil {
+		return nil, fmt.Errorf("parse trash.retention: %w", err)
+	}
+
 	srv := &Server{
 		mux:             http.NewServeMux(),
 		addr:            fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.Server.Port),
@@ -52,6 +69,8 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		oauth2Config:    oauth2Config,
 		oidcAuthOptions: oidcAuthOptions,
 		oidcVerifier:    provider.Verifier(&oidc.Config{ClientID: cfg.OIDC.ClientID}),
+		trashRetention:  trashRetention,
+		maxFileSize:     cfg.Storage.MaxSize << 20,
 		count:           0,
 	}
 
@@ -62,8 +81,34 @@ func NewServer(cfg *config.Config) (*Server, error) {
 }
 
 func (s *Server) Run(ctx context.Context) {
+	if s.trashRetention > 0 {
+		go s.purgeTrash(ctx)
+	}
+
 	log.Printf("Starting server on %s", s.addr)
-	if err := http.ListenAndServe(s.addr, s.withContext(ctx, s.mux)); err != nil {
+	if err := http.ListenAndServe(s.addr, s.withContext(views.WithCatalogue(ctx, s.pb.Catalogue()), s.mux)); err != nil {
 		log.Fatalf("Error when listening and serving %s", err)
 	}
 }
+
+// purgeTrash periodically deletes the trashed courses and packs older than
+// the configured retention.
+func (s *Server) purgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.pb.PurgeTrash(ctx, "polybased", time.Now().Add(-s.trashRetention))
+		if err != nil {
+			log.Printf("error: purge trash: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d items from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
+	"github.com/alias-asso/polybase-go/views"
 )
 
 func parseCourseUrl(filter string, r *http.Request) (libpolybase.CourseID, error) {
@@ -43,3 +47,225 @@ func parsePackUrl(filter string, r *http.Request) (int, error) {
 
 	return id, nil
 }
+
+// errorStatus maps the errors of libpolybase to HTTP statuses.
+func errorStatus(err error) int {
+	var validation *libpolybase.ValidationError
+	switch {
+	case errors.Is(err, libpolybase.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, libpolybase.ErrAlreadyExists), errors.Is(err, libpolybase.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, libpolybase.ErrForbidden):
+		return http.StatusForbidden
+	case errors.As(err, &validation):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// renderError answers a failed libpolybase call with the status matching its
+// error. Refused actions get a fragment for the error target of the forms,
+// other failures only get msg.
+func renderError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	log.Printf("%s: %v", msg, err)
+
+	status := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		http.Error(w, msg, status)
+		return
+	}
+
+	w.WriteHeader(status)
+	if err := views.ErrorMessage(err.Error()).Render(r.Context(), w); err != nil {
+		log.Printf("Failed to render template: %v", err)
+	}
+}
+
+// parseCourseFilter reads the filters of the course grids from a query string.
+// kind and tag may be repeated or hold a comma separated list.
+func parseCourseFilter(query url.Values) (libpolybase.CourseFilter, error) {
+	filter := libpolybase.CourseFilter{
+		Search: query.Get("q"),
+		Level:  libpolybase.Level(query.Get("level")),
+		Stock:  libpolybase.StockLevel(query.Get("stock")),
+		Sort:   libpolybase.CourseSort(query.Get("sort")),
+		Desc:   query.Get("desc") != "",
+
+		MissingMaster: query.Get("master") == "missing",
+	}
+
+	filter.Kinds = listParam(query, "kind")
+	filter.Tags = listParam(query, "tag")
+	for _, status := range listParam(query, "status") {
+		filter.Statuses = append(filter.Statuses, libpolybase.CourseStatus(status))
+	}
+
+	if pack := query.Get("pack"); pack != "" {
+		id, err := strconv.Atoi(pack)
+		if err != nil {
+			return libpolybase.CourseFilter{}, &libpolybase.ValidationError{Field: "pack", Msg: "invalid pack id"}
+		}
+		filter.PackID = &id
+	}
+
+	return filter, nil
+}
+
+// filterPacks keeps the packs at the level the course grid is filtered on,
+// along with the ones spanning several levels.
+func filterPacks(packs []libpolybase.Pack, filter libpolybase.CourseFilter) []libpolybase.Pack {
+	if filter.Level == "" {
+		return packs
+	}
+	var kept []libpolybase.Pack
+	for _, pack := range packs {
+		if pack.Level == "" || strings.EqualFold(string(pack.Level), string(filter.Level)) {
+			kept = append(kept, pack)
+		}
+	}
+	return kept
+}
+
+// listParam reads a query parameter that may be repeated or hold a comma
+// separated list.
+func listParam(query url.Values, key string) []string {
+	var items []string
+	for _, values := range query[key] {
+		for _, item := range strings.Split(values, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parseCourseDetails reads the optional details of a course from a submitted
+// form, left at their zero value when empty.
+func parseCourseDetails(form url.Values) (libpolybase.Course, error) {
+	details := libpolybase.Course{
+		Teacher:     form.Get("teacher"),
+		Edition:     form.Get("edition"),
+		EditionDate: form.Get("edition_date"),
+		Description: form.Get("description"),
+		Tags:        listParam(form, "tags"),
+	}
+
+	if pages := strings.TrimSpace(form.Get("pages")); pages != "" {
+		n, err := strconv.Atoi(pages)
+		if err != nil {
+			return libpolybase.Course{}, &libpolybase.ValidationError{Field: "pages", Msg: "invalid page count"}
+		}
+		details.Pages = n
+	}
+
+	var err error
+	if details.Price, err = libpolybase.ParsePrice(form.Get("price")); err != nil {
+		return libpolybase.Course{}, err
+	}
+	if details.PrintCost, err = libpolybase.ParsePrice(form.Get("print_cost")); err != nil {
+		return libpolybase.Course{}, err
+	}
+
+	return details, nil
+}
+
+// parsePackCourses reads the courses of a pack from a submitted form, each
+// checked course coming with the number of its copies in a count-ID field, one
+// when empty.
+func parsePackCourses(form url.Values) ([]libpolybase.PackCourse, error) {
+	var courses []libpolybase.PackCourse
+	for _, id := range form["courses"] {
+		parts := strings.Split(id, "/")
+		if len(parts) != 3 {
+			return nil, &libpolybase.ValidationError{Field: "courses", Msg: fmt.Sprintf("invalid course %s", id)}
+		}
+		part, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, &libpolybase.ValidationError{Field: "courses", Msg: fmt.Sprintf("invalid course %s", id)}
+		}
+
+		course := libpolybase.PackCourse{CourseID: libpolybase.NewCourseID(parts[0], parts[1], part), Count: 1}
+		if count := strings.TrimSpace(form.Get("count-" + id)); count != "" {
+			course.Count, err = strconv.Atoi(count)
+			if err != nil {
+				return nil, &libpolybase.ValidationError{Field: "count", Msg: fmt.Sprintf("invalid count for course %s", id)}
+			}
+		}
+		courses = append(courses, course)
+	}
+	return courses, nil
+}
+
+// parsePackPricing reads the pricing of a pack from a submitted form, the
+// price being computed from the courses without a fixed price.
+func parsePackPricing(form url.Values) (libpolybase.PackPricing, error) {
+	var pricing libpolybase.PackPricing
+	if fixed := strings.TrimSpace(form.Get("fixed_price")); fixed != "" {
+		price, err := libpolybase.ParsePrice(fixed)
+		if err != nil {
+			return libpolybase.PackPricing{}, err
+		}
+		pricing.Fixed = &price
+	}
+	if discount := strings.TrimSpace(form.Get("discount")); discount != "" {
+		n, err := strconv.Atoi(discount)
+		if err != nil {
+			return libpolybase.PackPricing{}, &libpolybase.ValidationError{Field: "discount", Msg: "invalid discount"}
+		}
+		pricing.Discount = n
+	}
+	return pricing, nil
+}
+
+// gridFilter returns the filters of the grid being displayed. htmx requests
+// re-rendering the grid after an action carry them in the page URL.
+func gridFilter(r *http.Request) (libpolybase.CourseFilter, error) {
+	query := r.URL.Query()
+	if current := r.Header.Get("HX-Current-URL"); current != "" && r.Header.Get("HX-Request") == "true" {
+		u, err := url.Parse(current)
+		if err == nil {
+			query = u.Query()
+		}
+	}
+	return parseCourseFilter(query)
+}
+
+// listGridCourses lists the courses of the grid being displayed.
+func (s *Server) listGridCourses(r *http.Request, showHidden bool) ([]libpolybase.Course, libpolybase.CourseFilter, error) {
+	filter, err := gridFilter(r)
+	if err != nil {
+		return nil, filter, err
+	}
+	filter.ShowHidden = showHidden
+
+	page, err := s.yearPB(r).ListCourses(r.Context(), filter)
+	if err != nil {
+		return nil, filter, err
+	}
+	return page.Items, filter, nil
+}
+
+// searchLimit bounds the results of the search boxes.
+const searchLimit = 20
+
+// renderSearch answers the search boxes, a blank query clearing the results.
+func (s *Server) renderSearch(w http.ResponseWriter, r *http.Request, isAdmin bool) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		return
+	}
+
+	courses, err := s.yearPB(r).SearchCourses(r.Context(), query, libpolybase.SearchOptions{ShowHidden: isAdmin, Limit: searchLimit})
+	if err != nil {
+		renderError(w, r, err, "Failed to search courses")
+		return
+	}
+
+	if err := views.SearchResults(courses, isAdmin).Render(r.Context(), w); err != nil {
+		http.Error(w, "Failed to render template", http.StatusInternalServerError)
+		log.Printf("Failed to render template: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&libpolybase.CourseNotFound{}, http.StatusNotFound},
+		{fmt.Errorf("get pack: %w", libpolybase.ErrNotFound), http.StatusNotFound},
+		{libpolybase.ErrAlreadyExists, http.StatusConflict},
+		{&libpolybase.RevisionConflict{Expected: 1, Current: 2}, http.StatusConflict},
+		{fmt.Errorf("update note: %w", libpolybase.ErrForbidden), http.StatusForbidden},
+		{&libpolybase.ValidationError{Field: "quantity", Msg: "quantity cannot be negative"}, http.StatusUnprocessableEntity},
+		{errors.New("disk full"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := errorStatus(tt.err); got != tt.want {
+			t.Errorf("errorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestParseCourseDetails(t *testing.T) {
+	details, err := parseCourseDetails(url.Values{
+		"pages":        {"120"},
+		"price":        {"3,50"},
+		"print_cost":   {""},
+		"teacher":      {"Mme Durand"},
+		"edition_date": {"2026-09-01"},
+		"tags":         {"option, parcours MIND"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse details: %v", err)
+	}
+	want := libpolybase.Course{Pages: 120, Price: 350, Teacher: "Mme Durand", EditionDate: "2026-09-01", Tags: []string{"option", "parcours MIND"}}
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("got %+v, want %+v", details, want)
+	}
+
+	for _, form := range []url.Values{{"pages": {"many"}}, {"price": {"3.5.0"}}, {"print_cost": {"-1"}}} {
+		var validation *libpolybase.ValidationError
+		if _, err := parseCourseDetails(form); !errors.As(err, &validation) {
+			t.Errorf("parseCourseDetails(%v) error = %v, want a ValidationError", form, err)
+		}
+	}
+}
+
+func TestParsePackPricing(t *testing.T) {
+	pricing, err := parsePackPricing(url.Values{"fixed_price": {"5,00"}, "discount": {""}})
+	if err != nil {
+		t.Fatalf("failed to parse pricing: %v", err)
+	}
+	if pricing.Fixed == nil || *pricing.Fixed != 500 || pricing.Discount != 0 {
+		t.Errorf("got %+v, want a fixed price of 5.00", pricing)
+	}
+
+	pricing, err = parsePackPricing(url.Values{"fixed_price": {" "}, "discount": {"15"}})
+	if err != nil {
+		t.Fatalf("failed to parse pricing: %v", err)
+	}
+	if pricing.Fixed != nil || pricing.Discount != 15 {
+		t.Errorf("got %+v, want a discount of 15%%", pricing)
+	}
+
+	for _, form := range []url.Values{{"fixed_price": {"cinq"}}, {"discount": {"10%"}}} {
+		var validation *libpolybase.ValidationError
+		if _, err := parsePackPricing(form); !errors.As(err, &validation) {
+			t.Errorf("parsePackPricing(%v) error = %v, want a ValidationError", form, err)
+		}
+	}
+}
+
+func TestFilterPacks(t *testing.T) {
+	packs := []libpolybase.Pack{
+		{ID: 1, Level: libpolybase.LevelL1},
+		{ID: 2, Level: libpolybase.LevelL2},
+		{ID: 3},
+	}
+
+	got := filterPacks(packs, libpolybase.CourseFilter{Level: "l2"})
+	if len(got) != 2 || got[0].ID != 2 || got[1].ID != 3 {
+		t.Errorf("got %+v, want the L2 pack and the one spanning several levels", got)
+	}
+	if got := filterPacks(packs, libpolybase.CourseFilter{}); len(got) != 3 {
+		t.Errorf("got %+v without a level, want every pack", got)
+	}
+}
+
+func TestParsePackCourses(t *testing.T) {
+	courses, err := parsePackCourses(url.Values{
+		"courses":             {"LU2IN002/TD/1", "LU2IN003/TD/1"},
+		"count-LU2IN002/TD/1": {"2"},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse courses: %v", err)
+	}
+	want := []libpolybase.PackCourse{
+		{CourseID: libpolybase.NewCourseID("LU2IN002", "TD", 1), Count: 2},
+		{CourseID: libpolybase.NewCourseID("LU2IN003", "TD", 1), Count: 1},
+	}
+	if !reflect.DeepEqual(courses, want) {
+		t.Errorf("got %+v, want %+v", courses, want)
+	}
+
+	for _, form := range []url.Values{{"courses": {"LU2IN002"}}, {"courses": {"LU2IN002/TD/1"}, "count-LU2IN002/TD/1": {"deux"}}} {
+		var validation *libpolybase.ValidationError
+		if _, err := parsePackCourses(form); !errors.As(err, &validation) {
+			t.Errorf("parsePackCourses(%v) error = %v, want a ValidationError", form, err)
+		}
+	}
+}
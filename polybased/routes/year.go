@@ -0,0 +1,55 @@
+package routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+	"github.com/alias-asso/polybase-go/views"
+)
+
+// yearCookieName holds the academic year picked in the admin. It lasts for
+// the browser session, so that admins come back to the current year.
+const yearCookieName = "X-Polybase-Year"
+
+// yearPB returns the Polybase scoped to the academic year picked by the
+// admin, the current one by default. The current year is worked out for each
+// request, the server running across the change of year.
+func (s *Server) yearPB(r *http.Request) libpolybase.Polybase {
+	year := libpolybase.CurrentAcademicYear(time.Now())
+	if cookie, err := r.Cookie(yearCookieName); err == nil {
+		if picked, err := libpolybase.ParseAcademicYear(cookie.Value); err == nil {
+			year = picked
+		}
+	}
+	return s.pb.ForYear(year)
+}
+
+func setYearCookie(w http.ResponseWriter, year libpolybase.AcademicYear, isDev bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     yearCookieName,
+		Value:    year.String(),
+		Path:     "/admin",
+		HttpOnly: true,
+		Secure:   !isDev,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// yearSelection lists the academic years an admin can switch to, along with
+// the one picked.
+func (s *Server) yearSelection(r *http.Request) (views.YearSelection, error) {
+	pb := s.yearPB(r)
+	years, err := pb.ListYears(r.Context())
+	if err != nil {
+		return views.YearSelection{}, err
+	}
+
+	selection := views.YearSelection{Years: years}
+	for _, year := range years {
+		if year.Year == pb.Year() {
+			selection.Current = year
+		}
+	}
+	return selection, nil
+}
@@ -0,0 +1,293 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// Every course mutation records an audit event with its snapshots
+func TestAuditCourseLifecycle(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if _, err := pb.UpdateCourse(ctx, "bob", created.CID(), libpolybase.PartialCourse{Name: stringPtr("Algorithmique")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", created.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if _, err := pb.UpdateCourseShown(ctx, "alice", created.CID(), false); err != nil {
+		t.Fatalf("failed to update visibility: %v", err)
+	}
+	if err := pb.DeleteCourse(ctx, "bob", created.CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+
+	entityType := libpolybase.EntityCourse
+	entityID := created.ID()
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityType: &entityType, EntityID: &entityID})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+
+	want := []struct {
+		actor  string
+		action libpolybase.AuditAction
+	}{
+		{"bob", libpolybase.ActionDelete},
+		{"alice", libpolybase.ActionVisibility},
+		{"alice", libpolybase.ActionQuantity},
+		{"bob", libpolybase.ActionUpdate},
+		{"alice", libpolybase.ActionCreate},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Actor != w.actor || events[i].Action != w.action {
+			t.Errorf("event %d: got %s by %s, want %s by %s", i, events[i].Action, events[i].Actor, w.action, w.actor)
+		}
+	}
+
+	if events[0].After != nil {
+		t.Errorf("deletion has an after snapshot: %s", events[0].After)
+	}
+	if events[4].Before != nil {
+		t.Errorf("creation has a before snapshot: %s", events[4].Before)
+	}
+
+	var deleted libpolybase.Course
+	if err := json.Unmarshal(events[0].Before, &deleted); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if deleted.Name != "Algorithmique" || deleted.Quantity != 9 || deleted.Shown {
+		t.Errorf("got deleted course %+v", deleted)
+	}
+
+	changes, err := events[3].Changes()
+	if err != nil {
+		t.Fatalf("failed to compute changes: %v", err)
+	}
+	name, ok := changes["name"]
+	if !ok || string(name.Before) != `"Algo"` || string(name.After) != `"Algorithmique"` {
+		t.Errorf("got name change %+v", name)
+	}
+	if _, ok := changes["quantity"]; ok {
+		t.Errorf("unchanged quantity reported as changed")
+	}
+}
+
+// Events follow a course when its ID changes
+func TestAuditCourseRename(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if _, err := pb.UpdateCourse(ctx, "bob", created.CID(), libpolybase.PartialCourse{Code: stringPtr("LU2IN003")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+
+	entityID := "LU2IN003/TD/1"
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityID: &entityID})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+}
+
+// Pack mutations are audited, quantity changes with per course quantities
+func TestAuditPack(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 0, Total: 20, Shown: true, Semester: "S1"},
+	}
+	db.InsertMany(courses)
+
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(courses[0].CID(), courses[1].CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if _, err := pb.UpdatePackQuantity(ctx, "bob", pack.ID, -1, libpolybase.PolicyClamp); err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+	if err := pb.DeletePack(ctx, "alice", pack.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+
+	entityType := libpolybase.EntityPack
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityType: &entityType})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+
+	var before, after map[string]int
+	if err := json.Unmarshal(events[1].Before, &before); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if err := json.Unmarshal(events[1].After, &after); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	// The empty course is left untouched
+	if len(after) != 1 || before[courses[0].ID()] != 10 || after[courses[0].ID()] != 9 {
+		t.Errorf("got quantities %v -> %v", before, after)
+	}
+
+	var deleted libpolybase.Pack
+	if err := json.Unmarshal(events[0].Before, &deleted); err != nil {
+		t.Fatalf("failed to decode snapshot: %v", err)
+	}
+	if deleted.Name != "L2 S1" || len(deleted.Courses) != 2 {
+		t.Errorf("got deleted pack %+v", deleted)
+	}
+}
+
+// A failed mutation leaves no audit event behind
+func TestAuditRollback(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+
+	if _, err := pb.UpdatePackQuantity(ctx, "alice", 1, 20, libpolybase.PolicyStrict); err == nil {
+		t.Fatal("expected error when exceeding total")
+	}
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{
+		Name:     stringPtr("Algorithmique"),
+		Revision: intPtr(42),
+	}); err == nil {
+		t.Fatal("expected revision conflict")
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %d events, want 0: %+v", len(events), events)
+	}
+}
+
+// Events can be filtered by actor, action and time range
+func TestAuditFilter(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	start := time.Now().Add(-time.Second)
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if _, err := pb.UpdateCourseShown(ctx, "bob", course.CID(), false); err != nil {
+		t.Fatalf("failed to update visibility: %v", err)
+	}
+
+	actor := "bob"
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Actor: &actor})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 1 || events[0].Action != libpolybase.ActionVisibility {
+		t.Errorf("got %+v, want a single visibility change by bob", events)
+	}
+
+	action := libpolybase.ActionQuantity
+	events, err = pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Action: &action, Since: &start})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 1 || events[0].Actor != "alice" {
+		t.Errorf("got %+v, want a single quantity change by alice", events)
+	}
+
+	events, err = pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Until: &start})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %d events before start, want 0", len(events))
+	}
+
+	events, err = pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 1 || events[0].Actor != "bob" {
+		t.Errorf("got %+v, want the latest event only", events)
+	}
+}
+
+// The changes recorded for a caller are the events its own mutations wrote,
+// the event on the entity it called coming first
+func TestRecordChanges(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	ctx, changes := libpolybase.RecordChanges(context.Background())
+	if _, err := pb.SplitCourse(ctx, "alice", course.CID(), 4); err != nil {
+		t.Fatalf("failed to split course: %v", err)
+	}
+	// A change made meanwhile by the same user is not taken for this one
+	if _, err := pb.UpdateCourseQuantity(context.Background(), "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+
+	event, ok := changes.CourseChange(course.CID())
+	if !ok || event.Action != libpolybase.ActionSplit || event.Actor != "alice" {
+		t.Errorf("got %+v (%v), want the split of the course", event, ok)
+	}
+	if created, ok := changes.CourseChange(libpolybase.NewCourseID("LU2IN002", "TD", 2)); !ok || created.Action != libpolybase.ActionCreate {
+		t.Errorf("got %+v (%v), want the creation of the new part", created, ok)
+	}
+	if _, ok := changes.PackChange(1); ok {
+		t.Error("got a change on a pack that was not touched")
+	}
+
+	events, err := pb.ListAuditEvents(context.Background(), libpolybase.AuditFilter{Limit: 3})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 3 || events[2].ID != event.ID {
+		t.Errorf("got %+v, want the split recorded as event %d", events, event.ID)
+	}
+}
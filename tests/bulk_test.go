@@ -4,3 +4,139 @@ package tests
 // update multiple courses
 // mass visibility changes
 // complex filtering scenarios
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// A batch is created as a whole or not at all
+func TestCreateCourses(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "Cours", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "Cours", Part: 2, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 5, Total: 20, Semester: "S1"},
+	}
+	created, err := pb.CreateCourses(ctx, "alice", courses)
+	if err != nil {
+		t.Fatalf("failed to create courses: %v", err)
+	}
+	if len(created) != 3 || created[0].Parts != 2 || created[2].Parts != 1 || !created[2].Shown {
+		t.Errorf("got %+v", created)
+	}
+	db.AssertCount(3)
+
+	batch := []libpolybase.Course{
+		{Code: "LU2IN003", Kind: "TD", Part: 1, Name: "Prog", Quantity: 5, Total: 20, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 5, Total: 20, Semester: "S1"},
+	}
+	_, err = pb.CreateCourses(ctx, "alice", batch)
+	if !errors.Is(err, libpolybase.ErrAlreadyExists) || !strings.Contains(err.Error(), "LU2IN002/TD/1") {
+		t.Errorf("got %v, want LU2IN002/TD/1 to already exist", err)
+	}
+	db.AssertNotExists(libpolybase.NewCourseID("LU2IN003", "TD", 1))
+
+	batch[1].Semester = "S9"
+	var validation *libpolybase.ValidationError
+	if _, err := pb.CreateCourses(ctx, "alice", batch); !errors.As(err, &validation) || validation.Field != "semester" {
+		t.Errorf("got %v, want a validation error on the semester", err)
+	}
+	db.AssertCount(3)
+}
+
+// Templates expand into every part of every kind, ready for CreateCourses
+func TestTemplateCourses(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	template, err := pb.Catalogue().Template("standard")
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	courses := template.Expand(libpolybase.Course{Code: "LU3IN009", Name: "BDD", Quantity: 10, Total: 40, Semester: "S2"})
+	if len(courses) != 4 {
+		t.Fatalf("got %d courses, want 4", len(courses))
+	}
+
+	created, err := pb.CreateCourses(ctx, "alice", courses)
+	if err != nil {
+		t.Fatalf("failed to create courses: %v", err)
+	}
+	for _, course := range created {
+		if course.Name != "BDD" || course.Semester != "S2" || course.Quantity != 10 {
+			t.Errorf("got %+v", course)
+		}
+	}
+	if created[1].Kind != "Cours" || created[1].Part != 2 || created[1].Parts != 2 {
+		t.Errorf("got %+v", created[1])
+	}
+
+	if _, err := pb.Catalogue().Template("huge"); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+// Clones keep everything but the stock, under a new code or semester and
+// possibly from another academic year
+func TestCloneCourses(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "Cours", Part: 1, Parts: 2, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "Cours", Part: 2, Parts: 2, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 5, Total: 30, Shown: false, Semester: "S1"},
+	})
+
+	clones, err := pb.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Code: "LU2IN002", ToCode: "LU2IN012", ToSemester: "S2"})
+	if err != nil {
+		t.Fatalf("failed to clone courses: %v", err)
+	}
+	if len(clones) != 3 {
+		t.Fatalf("got %d clones, want 3", len(clones))
+	}
+	for _, clone := range clones {
+		if clone.Code != "LU2IN012" || clone.Semester != "S2" || clone.Quantity != 0 || clone.Name != "Algo" {
+			t.Errorf("got %+v", clone)
+		}
+	}
+
+	single, err := pb.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Code: "LU2IN002", Kind: "TD", Part: 1, ToCode: "LU2IN022"})
+	if err != nil {
+		t.Fatalf("failed to clone course: %v", err)
+	}
+	if len(single) != 1 || single[0].Total != 30 || single[0].Semester != "S1" {
+		t.Errorf("got %+v", single)
+	}
+
+	// The same code can only be cloned into another year
+	if _, err := pb.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Code: "LU2IN002", ToSemester: "S2"}); !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Errorf("got %v, want already exists", err)
+	}
+	next := pb.ForYear(pb.Year() + 1)
+	clones, err = next.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Year: pb.Year(), Code: "LU2IN002", ToSemester: "S2"})
+	if err != nil {
+		t.Fatalf("failed to clone courses into the next year: %v", err)
+	}
+	if len(clones) != 3 || clones[0].Semester != "S2" {
+		t.Errorf("got %+v", clones)
+	}
+
+	if _, err := pb.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Code: "LU2IN003"}); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+	var validation *libpolybase.ValidationError
+	if _, err := pb.CloneCourses(ctx, "alice", libpolybase.CloneOptions{Code: "LU2IN002", Part: 1}); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
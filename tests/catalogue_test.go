@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func mathsCatalogue(t *testing.T) libpolybase.Catalogue {
+	t.Helper()
+
+	catalogue := libpolybase.Catalogue{
+		CodePatterns: []string{`^[LM]U(\d)MA\d{3}$`},
+		Kinds: []libpolybase.Kind{
+			{Name: "Exercices", Label: "Feuilles d'exercices", Colour: "hsl(40, 90%, 80%)", Order: 2},
+			{Name: "Annales", Order: 1},
+		},
+		Semesters: []string{"Automne", "Printemps"},
+	}
+	if err := catalogue.Compile(); err != nil {
+		t.Fatalf("failed to compile catalogue: %v", err)
+	}
+	return catalogue
+}
+
+// Courses follow the rules of the catalogue given to PB
+func TestCatalogueRules(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false).WithCatalogue(mathsCatalogue(t))
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU3MA101", Kind: "Exercices", Part: 1, Parts: 1, Name: "Analyse", Quantity: 10, Total: 20, Shown: true, Semester: "Automne",
+	}
+	created, err := pb.CreateCourse(ctx, "alice", course)
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if created.Level != libpolybase.LevelL3 {
+		t.Errorf("got level %q, want L3", created.Level)
+	}
+
+	cases := []struct {
+		name    string
+		course  libpolybase.Course
+		field   string
+		wantErr string
+	}{
+		{
+			name:    "code of another department",
+			course:  libpolybase.Course{Code: "LU2IN002", Kind: "Annales", Part: 1, Quantity: 1, Total: 1, Semester: "Automne"},
+			field:   "code",
+			wantErr: "invalid course id",
+		},
+		{
+			name:    "unknown kind",
+			course:  libpolybase.Course{Code: "LU3MA102", Kind: "TD", Part: 1, Quantity: 1, Total: 1, Semester: "Automne"},
+			field:   "kind",
+			wantErr: "KIND must be either Exercices or Annales",
+		},
+		{
+			name:    "unknown semester",
+			course:  libpolybase.Course{Code: "LU3MA102", Kind: "Annales", Part: 1, Quantity: 1, Total: 1, Semester: "S1"},
+			field:   "semester",
+			wantErr: "SEMESTER must be either Automne or Printemps",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := pb.CreateCourse(ctx, "alice", tc.course)
+			var validation *libpolybase.ValidationError
+			if !errors.As(err, &validation) || validation.Field != tc.field || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want a validation error on %s containing %q", err, tc.field, tc.wantErr)
+			}
+		})
+	}
+}
+
+// Courses of a same code are sorted in packs by the order of their kinds
+func TestCatalogueKindOrder(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false).WithCatalogue(mathsCatalogue(t))
+	ctx := context.Background()
+
+	ids := []libpolybase.CourseID{
+		{Code: "LU3MA101", Kind: "Exercices", Part: 1},
+		{Code: "LU3MA101", Kind: "Annales", Part: 1},
+	}
+	for _, id := range ids {
+		course := libpolybase.Course{Code: id.Code, Kind: id.Kind, Part: id.Part, Parts: 1, Name: "Analyse", Quantity: 10, Total: 20, Shown: true, Semester: "Automne"}
+		if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+			t.Fatalf("failed to create course: %v", err)
+		}
+	}
+
+	pack, err := pb.CreatePack(ctx, "alice", "L3", packCourses(ids...))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+
+	want := packCourses(ids[1], ids[0])
+	if got, err := pb.GetPack(ctx, pack.ID); err != nil || !slices.Equal(got.Courses, want) {
+		t.Errorf("got pack courses %v (%v), want %v", got.Courses, err, want)
+	}
+}
+
+// Invalid catalogues are refused
+func TestCatalogueCompile(t *testing.T) {
+	cases := []struct {
+		name      string
+		catalogue func(*libpolybase.Catalogue)
+	}{
+		{name: "invalid pattern", catalogue: func(c *libpolybase.Catalogue) { c.CodePatterns = []string{"(["} }},
+		{name: "pattern without group", catalogue: func(c *libpolybase.Catalogue) { c.CodePatterns = []string{`^LU\dIN\d{3}$`} }},
+		{name: "no kinds", catalogue: func(c *libpolybase.Catalogue) { c.Kinds = nil }},
+		{name: "kind with digits", catalogue: func(c *libpolybase.Catalogue) { c.Kinds = []libpolybase.Kind{{Name: "TD2"}} }},
+		{name: "kind listed twice", catalogue: func(c *libpolybase.Catalogue) { c.Kinds = []libpolybase.Kind{{Name: "TD"}, {Name: "TD"}} }},
+		{name: "unsafe colour", catalogue: func(c *libpolybase.Catalogue) {
+			c.Kinds = []libpolybase.Kind{{Name: "TD", Colour: "red; display: none"}}
+		}},
+		{name: "no semesters", catalogue: func(c *libpolybase.Catalogue) { c.Semesters = nil }},
+		{name: "template of an unknown kind", catalogue: func(c *libpolybase.Catalogue) {
+			c.Templates = []libpolybase.Template{{Name: "ue", Courses: []libpolybase.TemplateCourse{{Kind: "Annales", Parts: 1}}}}
+		}},
+		{name: "template without parts", catalogue: func(c *libpolybase.Catalogue) {
+			c.Templates = []libpolybase.Template{{Name: "ue", Courses: []libpolybase.TemplateCourse{{Kind: "TD"}}}}
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			catalogue := libpolybase.DefaultCatalogue()
+			tc.catalogue(&catalogue)
+			if err := catalogue.Compile(); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    libpolybase.Price
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"3", 300, false},
+		{"3.5", 350, false},
+		{"3,50", 350, false},
+		{"12.05 €", 1205, false},
+		{"0.99", 99, false},
+		{"-1", 0, true},
+		{"3.505", 0, true},
+		{"3.", 0, true},
+		{"three", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := libpolybase.ParsePrice(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePrice(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePrice(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+
+	if got := libpolybase.Price(1205).String(); got != "12.05" {
+		t.Errorf("String() = %q, want 12.05", got)
+	}
+}
+
+// The details of a course are stored, updated one at a time and trimmed
+func TestCourseDetails(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1",
+		Pages: 120, Price: 350, PrintCost: 210, Teacher: " Mme Durand ", Edition: "v2", EditionDate: "2026-09-01",
+		Description: "Sujets de TD avec corrigés",
+	}
+	created, err := pb.CreateCourse(ctx, "alice", course)
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	course.Teacher = "Mme Durand"
+	course.Level = libpolybase.LevelL2
+	course.Revision = created.Revision
+	if !reflect.DeepEqual(created, course) {
+		t.Errorf("got %+v, want %+v", created, course)
+	}
+
+	price := libpolybase.Price(400)
+	edition := "v3"
+	updated, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Price: &price, Edition: &edition})
+	if err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	if updated.Price != 400 || updated.Edition != "v3" || updated.Pages != 120 || updated.Teacher != "Mme Durand" {
+		t.Errorf("got %+v", updated)
+	}
+
+	listed, err := pb.ListCourses(ctx, libpolybase.CourseFilter{})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(listed.Items) != 1 || !reflect.DeepEqual(listed.Items[0], updated) {
+		t.Errorf("got %+v, want %+v", listed.Items, updated)
+	}
+}
+
+func TestCourseDetailsValidation(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	base := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"}
+
+	cases := []struct {
+		name   string
+		modify func(*libpolybase.Course)
+		field  string
+	}{
+		{"pages", func(c *libpolybase.Course) { c.Pages = -1 }, "pages"},
+		{"price", func(c *libpolybase.Course) { c.Price = -50 }, "price"},
+		{"print cost", func(c *libpolybase.Course) { c.PrintCost = -50 }, "print_cost"},
+		{"edition date", func(c *libpolybase.Course) { c.EditionDate = "01/09/2026" }, "edition_date"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			course := base
+			tc.modify(&course)
+			_, err := pb.CreateCourse(ctx, "alice", course)
+			var validation *libpolybase.ValidationError
+			if !errors.As(err, &validation) || validation.Field != tc.field {
+				t.Errorf("got error %v, want a validation error on %s", err, tc.field)
+			}
+		})
+	}
+}
+
+// Reverting an update restores the details of the course
+func TestRevertCourseDetails(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1", Teacher: "Mme Durand"}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	teacher := "M. Martin"
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Teacher: &teacher}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); err != nil {
+		t.Fatalf("failed to revert: %v", err)
+	}
+
+	got, err := pb.GetCourse(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	if got.Teacher != "Mme Durand" {
+		t.Errorf("got teacher %q, want Mme Durand", got.Teacher)
+	}
+}
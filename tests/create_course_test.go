@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -21,9 +22,10 @@ func TestCreateBasicCourse(t *testing.T) {
 		Name:     "Architecture des ordinateurs",
 		Quantity: 30,
 		Total:    50,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
-		Year:     3,
+		Level:    libpolybase.LevelL3,
 	}
 
 	created, err := pb.CreateCourse(context.Background(), "testuser", course)
@@ -31,7 +33,9 @@ func TestCreateBasicCourse(t *testing.T) {
 		t.Fatalf("failed to create course: %v", err)
 	}
 
-	if created != course {
+	// new courses start at their first revision
+	course.Revision = 1
+	if !reflect.DeepEqual(created, course) {
 		t.Errorf("returned course mismatch:\ngot: %+v\nwant: %+v", created, course)
 	}
 	db.AssertCount(1)
@@ -61,6 +65,7 @@ func TestCreateMultipleCourses(t *testing.T) {
 			Name:     "Database Systems",
 			Quantity: 60,
 			Total:    60,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -72,6 +77,7 @@ func TestCreateMultipleCourses(t *testing.T) {
 			Name:     "Database Systems Lab",
 			Quantity: 30,
 			Total:    30,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -83,6 +89,7 @@ func TestCreateMultipleCourses(t *testing.T) {
 			Name:     "Operating Systems",
 			Quantity: 100,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -115,6 +122,7 @@ func TestCreateDuplicateCourse(t *testing.T) {
 		Name:     "Database Systems",
 		Quantity: 60,
 		Total:    60,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -132,6 +140,7 @@ func TestCreateDuplicateCourse(t *testing.T) {
 		Name:     "Different Name",
 		Quantity: 30,
 		Total:    30,
+		Status:   libpolybase.StatusDraft,
 		Shown:    false,
 		Semester: "S2",
 	}
@@ -342,9 +351,10 @@ func TestCreateWithMaxValues(t *testing.T) {
 		Name:     "Advanced Topics in Theoretical Computer Science and Distributed Systems Engineering with Applications in Machine Learning",
 		Quantity: 9999,
 		Total:    10000,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
-		Year:     9,
+		Level:    libpolybase.LevelOther,
 	}
 
 	created, err := pb.CreateCourse(context.Background(), "testUser", course)
@@ -363,7 +373,8 @@ func TestCreateWithMaxValues(t *testing.T) {
 
 	db.AssertCourseEqual(id, course)
 
-	if created != course {
+	course.Revision = 1
+	if !reflect.DeepEqual(created, course) {
 		t.Errorf("created course does not match input\ngot: %+v\nwant: %+v", created, course)
 	}
 }
@@ -381,8 +392,10 @@ func TestCreateWithMinValues(t *testing.T) {
 		Name:     "x",
 		Quantity: 0,
 		Total:    1,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
+		Level:    libpolybase.LevelOther,
 	}
 
 	created, err := pb.CreateCourse(context.Background(), "testUser", course)
@@ -400,7 +413,8 @@ func TestCreateWithMinValues(t *testing.T) {
 	db.AssertExists(id)
 	db.AssertCourseEqual(id, course)
 
-	if created != course {
+	course.Revision = 1
+	if !reflect.DeepEqual(created, course) {
 		t.Errorf("created course does not match input\ngot: %+v\nwant: %+v", created, course)
 	}
 }
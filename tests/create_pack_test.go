@@ -90,7 +90,7 @@ func TestCreatePackWithValidCourses(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := pb.CreatePack(ctx, "testuser", tt.packName, tt.courses)
+			created, err := pb.CreatePack(ctx, "testuser", tt.packName, packCourses(tt.courses...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
@@ -98,7 +98,7 @@ func TestCreatePackWithValidCourses(t *testing.T) {
 			want := libpolybase.Pack{
 				ID:      created.ID,
 				Name:    strings.TrimSpace(tt.packName),
-				Courses: tt.courses,
+				Courses: packCourses(tt.courses...),
 			}
 
 			db.AssertPackExists(created.ID)
@@ -140,7 +140,7 @@ func TestCreatePackWithNoCourses(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := pb.CreatePack(ctx, "testuser", tt.packName, tt.courses)
+			_, err := pb.CreatePack(ctx, "testuser", tt.packName, packCourses(tt.courses...))
 			if err == nil {
 				t.Fatal("expected error when creating pack with no courses, got nil")
 			}
@@ -246,7 +246,7 @@ func TestCreatePackWithNonExistentCourses(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := pb.CreatePack(ctx, "testuser", tt.packName, tt.courses)
+			_, err := pb.CreatePack(ctx, "testuser", tt.packName, packCourses(tt.courses...))
 			if err == nil {
 				t.Fatal("expected error when creating pack with non-existent courses, got nil")
 			}
@@ -353,7 +353,7 @@ func TestCreatePackWithDuplicateCourses(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := pb.CreatePack(ctx, "testuser", tt.packName, tt.courses)
+			_, err := pb.CreatePack(ctx, "testuser", tt.packName, packCourses(tt.courses...))
 			if err == nil {
 				t.Fatal("expected error when creating pack with duplicate courses, got nil")
 			}
@@ -460,7 +460,7 @@ func TestCreatePackNameTrimming(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := pb.CreatePack(ctx, "testuser", tt.inputName, tt.courses)
+			created, err := pb.CreatePack(ctx, "testuser", tt.inputName, packCourses(tt.courses...))
 			if tt.wantName == "" {
 				if err == nil {
 					t.Fatal("expected error for empty pack name, got nil")
@@ -483,7 +483,7 @@ func TestCreatePackNameTrimming(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    tt.wantName,
-				Courses: tt.courses,
+				Courses: packCourses(tt.courses...),
 			})
 		})
 	}
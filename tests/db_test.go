@@ -1,43 +1,16 @@
 package tests
 
 import (
+	"context"
 	"database/sql"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
 	_ "modernc.org/sqlite"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS courses (
-    code TEXT,
-    kind TEXT,
-    part INTEGER DEFAULT 1,
-    parts INTEGER DEFAULT 1,
-    name TEXT,
-    quantity INTEGER,
-    total INTEGER,
-    shown INTEGER DEFAULT 1,
-    semester TEXT,
-    PRIMARY KEY (code, kind, part)
-);
-
-CREATE TABLE IF NOT EXISTS packs (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT NOT NULL
-);
-
-CREATE TABLE IF NOT EXISTS pack_courses (
-    pack_id INTEGER,
-    course_code TEXT,
-    course_kind TEXT,
-    course_part INTEGER,
-    FOREIGN KEY (pack_id) REFERENCES packs(id) ON DELETE CASCADE,
-    FOREIGN KEY (course_code, course_kind, course_part) 
-        REFERENCES courses(code, kind, part) ON UPDATE CASCADE,
-    PRIMARY KEY (pack_id, course_code, course_kind, course_part)
-);`
-
 // DB encapsulates a test database connection and test helper functions
 type DB struct {
 	*sql.DB
@@ -61,13 +34,18 @@ func NewDB(t *testing.T) *DB {
 		}
 	})
 
-	if _, err := db.Exec(schema); err != nil {
-		t.Fatalf("failed to create schema: %v", err)
+	if err := libpolybase.Migrate(context.Background(), db); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
 	}
 
 	return &DB{DB: db, t: t}
 }
 
+// currentYear is the academic year PB works on by default
+func currentYear() libpolybase.AcademicYear {
+	return libpolybase.CurrentAcademicYear(time.Now())
+}
+
 // Insert adds a course to the test database
 func (db *DB) Insert(c libpolybase.Course) {
 	db.t.Helper()
@@ -75,11 +53,19 @@ func (db *DB) Insert(c libpolybase.Course) {
 	if c.Shown {
 		shown = 1
 	}
+	// Hidden courses are drafts unless told otherwise
+	status := c.Status
+	if status == "" {
+		status = libpolybase.StatusDraft
+		if c.Shown {
+			status = libpolybase.StatusAvailable
+		}
+	}
 
 	_, err := db.Exec(`
-		INSERT INTO courses (code, kind, part, parts, name, quantity, total, shown, semester)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		c.Code, c.Kind, c.Part, c.Parts, c.Name, c.Quantity, c.Total, shown, c.Semester)
+		INSERT INTO courses (academic_year, code, kind, part, parts, name, quantity, total, status, shown, semester)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		currentYear(), c.Code, c.Kind, c.Part, c.Parts, c.Name, c.Quantity, c.Total, status, shown, c.Semester)
 	if err != nil {
 		db.t.Fatalf("failed to insert test course: %v", err)
 	}
@@ -104,17 +90,22 @@ func (db *DB) Clear() {
 	if err != nil {
 		db.t.Fatalf("failed to clear packs: %v", err)
 	}
+	_, err = db.Exec("DELETE FROM course_editions")
+	if err != nil {
+		db.t.Fatalf("failed to clear course_editions: %v", err)
+	}
 	_, err = db.Exec("DELETE FROM courses")
 	if err != nil {
 		db.t.Fatalf("failed to clear courses: %v", err)
 	}
 }
 
-// Count returns the number of courses in the test database
+// Count returns the number of courses in the test database, not counting the
+// ones in the trash
 func (db *DB) Count() int {
 	db.t.Helper()
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM courses").Scan(&count)
+	err := db.QueryRow("SELECT COUNT(*) FROM courses WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		db.t.Fatalf("failed to count courses: %v", err)
 	}
@@ -128,12 +119,12 @@ func (db *DB) Get(id libpolybase.CourseID) libpolybase.Course {
 	var shown int
 
 	err := db.QueryRow(`
-		SELECT code, kind, part, parts, name, quantity, total, shown, semester
+		SELECT code, kind, part, parts, name, quantity, total, status, shown, semester
 		FROM courses
 		WHERE code = ? AND kind = ? AND part = ?`,
 		id.Code, id.Kind, id.Part).Scan(
 		&c.Code, &c.Kind, &c.Part, &c.Parts,
-		&c.Name, &c.Quantity, &c.Total, &shown, &c.Semester)
+		&c.Name, &c.Quantity, &c.Total, &c.Status, &shown, &c.Semester)
 
 	if err != nil {
 		db.t.Fatalf("failed to get course: %v", err)
@@ -152,14 +143,15 @@ func (db *DB) AssertCount(want int) {
 	}
 }
 
-// AssertExists checks if a course exists in the database
+// AssertExists checks if a course exists in the database and is not in the
+// trash
 func (db *DB) AssertExists(id libpolybase.CourseID) {
 	db.t.Helper()
 	var exists bool
 	err := db.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM courses 
-			WHERE code = ? AND kind = ? AND part = ?
+			WHERE code = ? AND kind = ? AND part = ? AND deleted_at IS NULL
 		)`,
 		id.Code, id.Kind, id.Part).Scan(&exists)
 	if err != nil {
@@ -170,14 +162,15 @@ func (db *DB) AssertExists(id libpolybase.CourseID) {
 	}
 }
 
-// AssertNotExists checks if a course does not exist in the database
+// AssertNotExists checks if a course does not exist in the database or is in
+// the trash
 func (db *DB) AssertNotExists(id libpolybase.CourseID) {
 	db.t.Helper()
 	var exists bool
 	err := db.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM courses 
-			WHERE code = ? AND kind = ? AND part = ?
+			WHERE code = ? AND kind = ? AND part = ? AND deleted_at IS NULL
 		)`,
 		id.Code, id.Kind, id.Part).Scan(&exists)
 	if err != nil {
@@ -193,17 +186,19 @@ func (db *DB) AssertCourseEqual(id libpolybase.CourseID, want libpolybase.Course
 	db.t.Helper()
 	got := db.Get(id)
 	var err error
-	// because year is not stored in database
-	got.Year, err = libpolybase.GetYear(got.Code)
+	// because level is not stored in database
+	got.Level, err = libpolybase.GetLevel(got.Code)
 	if err != nil {
 		db.t.Fatalf("%#v", err)
 	}
 	// because I don't want to update all the tests
-	want.Year, err = libpolybase.GetYear(want.Code)
+	want.Level, err = libpolybase.GetLevel(want.Code)
 	if err != nil {
 		db.t.Fatalf("%#v", err)
 	}
-	if got != want {
+	// because revisions are managed by libpolybase
+	want.Revision = 0
+	if !reflect.DeepEqual(got, want) {
 		db.t.Errorf("course mismatch\ngot: %+v\nwant: %+v", got, want)
 	}
 }
@@ -213,9 +208,9 @@ func (db *DB) InsertPack(p libpolybase.Pack) {
 	db.t.Helper()
 
 	result, err := db.Exec(`
-		INSERT INTO packs (id, name)
-		VALUES (?, ?)`,
-		p.ID, p.Name)
+		INSERT INTO packs (id, academic_year, name, shown, semester, level)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		p.ID, currentYear(), p.Name, p.Shown, p.Semester, p.Level)
 	if err != nil {
 		db.t.Fatalf("failed to insert test pack: %v", err)
 	}
@@ -230,19 +225,19 @@ func (db *DB) InsertPack(p libpolybase.Pack) {
 	}
 
 	// Insert pack courses if any
-	for _, courseID := range p.Courses {
-		db.InsertPackCourse(p.ID, courseID)
+	for _, course := range p.Courses {
+		db.InsertPackCourse(p.ID, course)
 	}
 }
 
 // InsertPackCourse links a course to a pack
-func (db *DB) InsertPackCourse(packID int, courseID libpolybase.CourseID) {
+func (db *DB) InsertPackCourse(packID int, course libpolybase.PackCourse) {
 	db.t.Helper()
 
 	_, err := db.Exec(`
-		INSERT INTO pack_courses (pack_id, course_code, course_kind, course_part)
-		VALUES (?, ?, ?, ?)`,
-		packID, courseID.Code, courseID.Kind, courseID.Part)
+		INSERT INTO pack_courses (pack_id, academic_year, course_code, course_kind, course_part, count)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		packID, currentYear(), course.Code, course.Kind, course.Part, course.Count)
 	if err != nil {
 		db.t.Fatalf("failed to insert pack course: %v", err)
 	}
@@ -254,16 +249,16 @@ func (db *DB) GetPack(id int) libpolybase.Pack {
 
 	var pack libpolybase.Pack
 	err := db.QueryRow(`
-		SELECT id, name
+		SELECT id, name, shown, semester, level
 		FROM packs
-		WHERE id = ?`, id).Scan(&pack.ID, &pack.Name)
+		WHERE id = ?`, id).Scan(&pack.ID, &pack.Name, &pack.Shown, &pack.Semester, &pack.Level)
 	if err != nil {
 		db.t.Fatalf("failed to get pack: %v", err)
 	}
 
 	// Get pack courses
 	rows, err := db.Query(`
-		SELECT course_code, course_kind, course_part
+		SELECT course_code, course_kind, course_part, count
 		FROM pack_courses
 		WHERE pack_id = ?
 		ORDER BY course_code, course_kind, course_part`, id)
@@ -273,11 +268,11 @@ func (db *DB) GetPack(id int) libpolybase.Pack {
 	defer rows.Close()
 
 	for rows.Next() {
-		var courseID libpolybase.CourseID
-		if err := rows.Scan(&courseID.Code, &courseID.Kind, &courseID.Part); err != nil {
+		var course libpolybase.PackCourse
+		if err := rows.Scan(&course.Code, &course.Kind, &course.Part, &course.Count); err != nil {
 			db.t.Fatalf("failed to scan pack course: %v", err)
 		}
-		pack.Courses = append(pack.Courses, courseID)
+		pack.Courses = append(pack.Courses, course)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -287,7 +282,16 @@ func (db *DB) GetPack(id int) libpolybase.Pack {
 	return pack
 }
 
-// AssertPackExists checks if a pack exists
+// packCourses lists the courses of a pack holding a single copy of each
+func packCourses(ids ...libpolybase.CourseID) []libpolybase.PackCourse {
+	courses := make([]libpolybase.PackCourse, len(ids))
+	for i, id := range ids {
+		courses[i] = libpolybase.PackCourse{CourseID: id, Count: 1}
+	}
+	return courses
+}
+
+// AssertPackExists checks if a pack exists and is not in the trash
 func (db *DB) AssertPackExists(id int) {
 	db.t.Helper()
 
@@ -295,7 +299,7 @@ func (db *DB) AssertPackExists(id int) {
 	err := db.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM packs 
-			WHERE id = ?
+			WHERE id = ? AND deleted_at IS NULL
 		)`, id).Scan(&exists)
 	if err != nil {
 		db.t.Fatalf("failed to check pack existence: %v", err)
@@ -305,7 +309,7 @@ func (db *DB) AssertPackExists(id int) {
 	}
 }
 
-// AssertPackNotExists checks if a pack doesn't exist
+// AssertPackNotExists checks if a pack doesn't exist or is in the trash
 func (db *DB) AssertPackNotExists(id int) {
 	db.t.Helper()
 
@@ -313,7 +317,7 @@ func (db *DB) AssertPackNotExists(id int) {
 	err := db.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM packs 
-			WHERE id = ?
+			WHERE id = ? AND deleted_at IS NULL
 		)`, id).Scan(&exists)
 	if err != nil {
 		db.t.Fatalf("failed to check pack existence: %v", err)
@@ -344,20 +348,22 @@ func (db *DB) AssertPackEqual(id int, want libpolybase.Pack) {
 	}
 
 	// Convert courses to maps for set comparison
-	wantCourses := make(map[string]libpolybase.CourseID)
+	wantCourses := make(map[string]libpolybase.PackCourse)
 	for _, course := range want.Courses {
 		wantCourses[course.ID()] = course
 	}
 
-	gotCourses := make(map[string]libpolybase.CourseID)
+	gotCourses := make(map[string]libpolybase.PackCourse)
 	for _, course := range got.Courses {
 		gotCourses[course.ID()] = course
 	}
 
-	// Check for missing courses
+	// Check for missing courses and their counts
 	for id, wantCourse := range wantCourses {
-		if _, exists := gotCourses[id]; !exists {
+		if gotCourse, exists := gotCourses[id]; !exists {
 			db.t.Errorf("missing course: %+v", wantCourse)
+		} else if gotCourse.Count != wantCourse.Count {
+			db.t.Errorf("course %s count = %d, want %d", id, gotCourse.Count, wantCourse.Count)
 		}
 	}
 
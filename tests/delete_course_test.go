@@ -2,8 +2,10 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
 )
@@ -23,6 +25,7 @@ func TestDeleteExistingCourse(t *testing.T) {
 		Name:     "Programming I",
 		Quantity: 50,
 		Total:    100,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -39,7 +42,7 @@ func TestDeleteExistingCourse(t *testing.T) {
 		Part: created.Part,
 	}
 
-	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", []libpolybase.CourseID{courseID})
+	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(courseID))
 	if err != nil {
 		t.Fatalf("failed to create test pack: %v", err)
 	}
@@ -53,8 +56,8 @@ func TestDeleteExistingCourse(t *testing.T) {
 	// Verify course is deleted
 	db.AssertNotExists(courseID)
 
-	// Verify course is removed from pack
-	db.AssertCourseNotInPack(pack.ID, courseID)
+	// Verify course stays in pack, so that restoring it puts it back
+	db.AssertCourseInPack(pack.ID, courseID)
 
 	// Verify get returns CourseNotFound
 	_, err = pb.GetCourse(ctx, courseID)
@@ -111,6 +114,7 @@ func TestDeleteNonExistentCourse(t *testing.T) {
 					Name:     "Programming",
 					Quantity: 50,
 					Total:    100,
+					Status:   libpolybase.StatusAvailable,
 					Shown:    true,
 					Semester: "S1",
 				}
@@ -172,6 +176,7 @@ func TestDeleteAndRecreateCourse(t *testing.T) {
 		Name:     "Programming",
 		Quantity: 50,
 		Total:    100,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -213,9 +218,19 @@ func TestDeleteAndRecreateCourse(t *testing.T) {
 		Quantity: 30,                         // Different quantity
 		Total:    75,                         // Different total
 		Semester: "S2",                       // Different semester
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 	}
 
+	// The deleted course waits in the trash until purged
+	_, err = pb.CreateCourse(ctx, "testuser", recreated)
+	if !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Fatalf("got %v recreating a trashed course, want already exists", err)
+	}
+	if _, err := pb.PurgeTrash(ctx, "testuser", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+
 	_, err = pb.CreateCourse(ctx, "testuser", recreated)
 	if err != nil {
 		t.Fatalf("failed to recreate course: %v", err)
@@ -289,6 +304,7 @@ func TestDeleteLastPartOfMultiPartCourse(t *testing.T) {
 			Name:     "Programming I",
 			Quantity: 50,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -300,6 +316,7 @@ func TestDeleteLastPartOfMultiPartCourse(t *testing.T) {
 			Name:     "Programming II",
 			Quantity: 45,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -311,6 +328,7 @@ func TestDeleteLastPartOfMultiPartCourse(t *testing.T) {
 			Name:     "Programming III",
 			Quantity: 40,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
 		},
@@ -349,16 +367,16 @@ func TestDeleteLastPartOfMultiPartCourse(t *testing.T) {
 			}
 
 			// Check remaining parts have correct parts count
-			remaining, err := pb.ListCourse(ctx, true, nil, nil, nil, nil)
+			remaining, err := pb.ListCourses(ctx, libpolybase.CourseFilter{ShowHidden: true})
 			if err != nil {
 				t.Fatalf("failed to list remaining courses: %v", err)
 			}
 
-			if len(remaining) != d.remainParts {
-				t.Errorf("got %d remaining courses, want %d", len(remaining), d.remainParts)
+			if len(remaining.Items) != d.remainParts {
+				t.Errorf("got %d remaining courses, want %d", len(remaining.Items), d.remainParts)
 			}
 
-			for _, course := range remaining {
+			for _, course := range remaining.Items {
 				if course.Parts != d.remainParts {
 					t.Errorf("course part %d has Parts=%d, want %d",
 						course.Part, course.Parts, d.remainParts)
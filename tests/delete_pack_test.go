@@ -54,7 +54,7 @@ func TestDeletePackSuccessfully(t *testing.T) {
 		{Code: "UL1IN002", Kind: "TME", Part: 1},
 	}
 
-	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", courseIDs)
+	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(courseIDs...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -74,9 +74,9 @@ func TestDeletePackSuccessfully(t *testing.T) {
 	// Verify pack no longer exists
 	db.AssertPackNotExists(pack.ID)
 
-	// Verify pack courses removed
-	if count := db.CountPackCourses(pack.ID); count != 0 {
-		t.Errorf("pack course count after deletion = %d, want 0", count)
+	// Verify pack courses kept in the trash
+	if count := db.CountPackCourses(pack.ID); count != 2 {
+		t.Errorf("pack course count after deletion = %d, want 2", count)
 	}
 
 	// Verify courses still exist
@@ -159,7 +159,7 @@ func TestDeletePackRemovesCourseLinks(t *testing.T) {
 	}
 
 	// Create pack
-	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", []libpolybase.CourseID{courseID})
+	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(courseID))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -173,8 +173,8 @@ func TestDeletePackRemovesCourseLinks(t *testing.T) {
 		t.Fatalf("failed to delete pack: %v", err)
 	}
 
-	// Verify associations removed
-	db.AssertCourseNotInPack(pack.ID, courseID)
+	// Verify associations kept in the trash
+	db.AssertCourseInPack(pack.ID, courseID)
 
 	// Verify course still exists
 	_, err = pb.GetCourse(ctx, courseID)
@@ -216,7 +216,7 @@ func TestRecreateDeletedPack(t *testing.T) {
 	packName := "Test Pack"
 
 	// Create pack
-	pack1, err := pb.CreatePack(ctx, "testuser", packName, []libpolybase.CourseID{courseID})
+	pack1, err := pb.CreatePack(ctx, "testuser", packName, packCourses(courseID))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -230,7 +230,7 @@ func TestRecreateDeletedPack(t *testing.T) {
 	}
 
 	// Recreate pack with same name
-	pack2, err := pb.CreatePack(ctx, "testuser", packName, []libpolybase.CourseID{courseID})
+	pack2, err := pb.CreatePack(ctx, "testuser", packName, packCourses(courseID))
 	if err != nil {
 		t.Fatalf("failed to recreate pack: %v", err)
 	}
@@ -245,7 +245,7 @@ func TestRecreateDeletedPack(t *testing.T) {
 		t.Errorf("recreated pack name = %q, want %q", pack2.Name, packName)
 	}
 
-	if len(pack2.Courses) != 1 || pack2.Courses[0] != courseID {
+	if len(pack2.Courses) != 1 || pack2.Courses[0].CourseID != courseID {
 		t.Errorf("recreated pack courses = %v, want %v", pack2.Courses, []libpolybase.CourseID{courseID})
 	}
 
@@ -315,7 +315,7 @@ func TestDeleteMultiplePacksSequentially(t *testing.T) {
 
 	var createdPacks []libpolybase.Pack
 	for _, packDef := range packs {
-		pack, err := pb.CreatePack(ctx, "testuser", packDef.name, packDef.courses)
+		pack, err := pb.CreatePack(ctx, "testuser", packDef.name, packCourses(packDef.courses...))
 		if err != nil {
 			t.Fatalf("failed to create pack %s: %v", packDef.name, err)
 		}
@@ -338,9 +338,12 @@ func TestDeleteMultiplePacksSequentially(t *testing.T) {
 		}
 	}
 
-	// Verify all pack_courses links are cleaned up
+	// Verify no live pack_courses links are left
 	var totalLinks int
-	err := db.QueryRow("SELECT COUNT(*) FROM pack_courses").Scan(&totalLinks)
+	err := db.QueryRow(`
+		SELECT COUNT(*) FROM pack_courses pc
+		JOIN packs p ON p.id = pc.pack_id
+		WHERE p.deleted_at IS NULL`).Scan(&totalLinks)
 	if err != nil {
 		t.Fatalf("failed to count pack_courses: %v", err)
 	}
@@ -388,7 +391,7 @@ func TestDeletePackWithManyCourses(t *testing.T) {
 	}
 
 	// Create pack with all courses
-	pack, err := pb.CreatePack(ctx, "testuser", "Large Pack", courseIDs)
+	pack, err := pb.CreatePack(ctx, "testuser", "Large Pack", packCourses(courseIDs...))
 	if err != nil {
 		t.Fatalf("failed to create large pack: %v", err)
 	}
@@ -407,9 +410,9 @@ func TestDeletePackWithManyCourses(t *testing.T) {
 	// Verify pack deleted
 	db.AssertPackNotExists(pack.ID)
 
-	// Verify all course links removed
-	if count := db.CountPackCourses(pack.ID); count != 0 {
-		t.Errorf("pack course count after deletion = %d, want 0", count)
+	// Verify all course links kept in the trash
+	if count := db.CountPackCourses(pack.ID); count != 20 {
+		t.Errorf("pack course count after deletion = %d, want 20", count)
 	}
 
 	// Verify all courses still exist
@@ -443,7 +446,7 @@ func TestDeleteAlreadyDeletedPack(t *testing.T) {
 		Code: course.Code, Kind: course.Kind, Part: course.Part,
 	}
 
-	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", []libpolybase.CourseID{courseID})
+	pack, err := pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(courseID))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -524,7 +527,7 @@ func TestDeletePackStressTest(t *testing.T) {
 	for i := range 100 {
 		// Create pack
 		pack, err := pb.CreatePack(ctx, "testuser",
-			fmt.Sprintf("Pack %d", i), []libpolybase.CourseID{courseID})
+			fmt.Sprintf("Pack %d", i), packCourses(courseID))
 		if err != nil {
 			t.Fatalf("failed to create pack %d: %v", i, err)
 		}
@@ -538,13 +541,16 @@ func TestDeletePackStressTest(t *testing.T) {
 		db.AssertPackNotExists(pack.ID)
 	}
 
-	// Verify database is clean
+	// Verify no live pack is left
 	var packCount, linkCount int
-	err = db.QueryRow("SELECT COUNT(*) FROM packs").Scan(&packCount)
+	err = db.QueryRow("SELECT COUNT(*) FROM packs WHERE deleted_at IS NULL").Scan(&packCount)
 	if err != nil {
 		t.Fatalf("failed to count packs: %v", err)
 	}
-	err = db.QueryRow("SELECT COUNT(*) FROM pack_courses").Scan(&linkCount)
+	err = db.QueryRow(`
+		SELECT COUNT(*) FROM pack_courses pc
+		JOIN packs p ON p.id = pc.pack_id
+		WHERE p.deleted_at IS NULL`).Scan(&linkCount)
 	if err != nil {
 		t.Fatalf("failed to count pack_courses: %v", err)
 	}
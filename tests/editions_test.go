@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func editionQuantities(t *testing.T, pb libpolybase.Polybase, id libpolybase.CourseID) []int {
+	t.Helper()
+	editions, err := pb.ListEditions(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to list editions: %v", err)
+	}
+	quantities := make([]int, len(editions))
+	for i, edition := range editions {
+		quantities[i] = edition.Quantity
+	}
+	return quantities
+}
+
+// The stock on the shelf becomes an edition of its own when the first
+// edition is added, and the new edition becomes the current one
+func TestAddEdition(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1", Edition: "v1"}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	edition, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: " v2 ", Date: "2026-10-01", Notes: "Exercice 3 corrigé", Quantity: 20})
+	if err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+	if edition.Label != "v2" || edition.Quantity != 20 || !edition.Current || edition.CreatedBy != "alice" {
+		t.Errorf("got %+v", edition)
+	}
+
+	editions, err := pb.ListEditions(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list editions: %v", err)
+	}
+	if len(editions) != 2 || editions[0].Label != "v1" || editions[0].Quantity != 10 || editions[0].Current {
+		t.Fatalf("got %+v", editions)
+	}
+
+	updated, err := pb.GetCourse(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	if updated.Quantity != 30 || updated.Edition != "v2" || updated.EditionDate != "2026-10-01" {
+		t.Errorf("got %+v", updated)
+	}
+
+	id := course.CID()
+	reason := libpolybase.ReasonRestock
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &id, Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 2 || movements[0].Delta != 20 || movements[0].Quantity != 30 {
+		t.Errorf("got %+v", movements)
+	}
+
+	if _, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: "v3", Quantity: 21}); err == nil {
+		t.Error("added an edition beyond the total")
+	}
+	var validation *libpolybase.ValidationError
+	if _, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: " "}); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+	if _, err := pb.AddEdition(ctx, "alice", libpolybase.NewCourseID("LU2IN003", "TD", 1), libpolybase.Edition{Label: "v1"}); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+// Distributions draw from the oldest edition first and restocks go to the
+// current edition
+func TestEditionAllocation(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 5, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	if _, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: "v2", Quantity: 10}); err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+	if got := editionQuantities(t, pb, course.CID()); !slices.Equal(got, []int{5, 10}) {
+		t.Fatalf("got %v", got)
+	}
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -7); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if got := editionQuantities(t, pb, course.CID()); !slices.Equal(got, []int{0, 8}) {
+		t.Errorf("got %v after distributing", got)
+	}
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), 4); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if got := editionQuantities(t, pb, course.CID()); !slices.Equal(got, []int{0, 12}) {
+		t.Errorf("got %v after restocking", got)
+	}
+
+	pack, err := pb.CreatePack(ctx, "alice", "L2", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if _, err := pb.UpdatePackQuantity(ctx, "alice", pack.ID, -2, libpolybase.PolicyClamp); err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+	if got := editionQuantities(t, pb, course.CID()); !slices.Equal(got, []int{0, 10}) {
+		t.Errorf("got %v after distributing the pack", got)
+	}
+}
+
+// Retiring an edition writes off its stock and hands the current edition
+// over to the newest one left
+func TestRetireEdition(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 5, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	v2, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: "v2", Quantity: 10})
+	if err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+
+	retired, err := pb.RetireEdition(ctx, "bob", v2.ID)
+	if err != nil {
+		t.Fatalf("failed to retire edition: %v", err)
+	}
+	if retired.RetiredAt == nil || retired.RetiredBy != "bob" || retired.Quantity != 0 || retired.Current {
+		t.Errorf("got %+v", retired)
+	}
+
+	updated, err := pb.GetCourse(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	if updated.Quantity != 5 || updated.Edition != "initial" {
+		t.Errorf("got %+v", updated)
+	}
+
+	editions, err := pb.ListEditions(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list editions: %v", err)
+	}
+	if !editions[0].Current {
+		t.Errorf("the initial edition is not current: %+v", editions)
+	}
+
+	reason := libpolybase.ReasonWriteOff
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Delta != -10 || movements[0].Quantity != 5 {
+		t.Errorf("got %+v", movements)
+	}
+
+	if _, err := pb.RetireEdition(ctx, "bob", v2.ID); !errors.Is(err, libpolybase.ErrConflict) {
+		t.Errorf("got %v, want a conflict", err)
+	}
+	if _, err := pb.SetCurrentEdition(ctx, "bob", v2.ID); !errors.Is(err, libpolybase.ErrConflict) {
+		t.Errorf("got %v, want a conflict", err)
+	}
+
+	// Restocks go to the only edition left
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), 3); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if got := editionQuantities(t, pb, course.CID()); !slices.Equal(got, []int{8, 0}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+// Editions are updated and picked as current without touching the stock
+func TestUpdateEdition(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 5, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	v2, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: "v2", Quantity: 10})
+	if err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+
+	label := "v2.1"
+	date := "2026-10-02"
+	updated, err := pb.UpdateEdition(ctx, "alice", v2.ID, libpolybase.PartialEdition{Label: &label, Date: &date})
+	if err != nil {
+		t.Fatalf("failed to update edition: %v", err)
+	}
+	if updated.Label != "v2.1" || updated.Date != "2026-10-02" || updated.Quantity != 10 {
+		t.Errorf("got %+v", updated)
+	}
+	if got, _ := pb.GetCourse(ctx, course.CID()); got.Edition != "v2.1" || got.EditionDate != "2026-10-02" {
+		t.Errorf("course edition not updated: %+v", got)
+	}
+
+	bad := "01/10/2026"
+	var validation *libpolybase.ValidationError
+	if _, err := pb.UpdateEdition(ctx, "alice", v2.ID, libpolybase.PartialEdition{Date: &bad}); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+	if _, err := pb.UpdateEdition(ctx, "alice", 999, libpolybase.PartialEdition{Label: &label}); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+
+	editions, err := pb.ListEditions(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list editions: %v", err)
+	}
+	current, err := pb.SetCurrentEdition(ctx, "alice", editions[0].ID)
+	if err != nil {
+		t.Fatalf("failed to set current edition: %v", err)
+	}
+	if !current.Current {
+		t.Errorf("got %+v", current)
+	}
+	if got, _ := pb.GetCourse(ctx, course.CID()); got.Edition != "initial" || got.Quantity != 15 {
+		t.Errorf("got %+v", got)
+	}
+
+	entity := libpolybase.EntityEdition
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityType: &entity})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("got %d edition events, want 4", len(events))
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); !errors.Is(err, libpolybase.ErrConflict) {
+		t.Errorf("got %v, want a conflict", err)
+	}
+}
+
+// Editions follow their course when it is renamed
+func TestRenameCourseKeepsEditions(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 5, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	if _, err := pb.AddEdition(ctx, "alice", course.CID(), libpolybase.Edition{Label: "v2", Quantity: 10}); err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+
+	code := "LU2IN012"
+	renamed, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Code: &code})
+	if err != nil {
+		t.Fatalf("failed to rename course: %v", err)
+	}
+	if got := editionQuantities(t, pb, renamed.CID()); !slices.Equal(got, []int{5, 10}) {
+		t.Errorf("got %v", got)
+	}
+}
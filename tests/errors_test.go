@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// Every operation reports a not found, duplicate or conflicting entity with
+// the matching sentinel error
+func TestErrorKinds(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	missing := libpolybase.CourseID{Code: "LU2IN003", Kind: "TD", Part: 1}
+
+	tests := []struct {
+		name string
+		err  func() error
+		want error
+	}{
+		{"get course", func() error { _, err := pb.GetCourse(ctx, missing); return err }, libpolybase.ErrNotFound},
+		{"update course", func() error {
+			_, err := pb.UpdateCourse(ctx, "alice", missing, libpolybase.PartialCourse{Name: stringPtr("x")})
+			return err
+		}, libpolybase.ErrNotFound},
+		{"delete course", func() error { return pb.DeleteCourse(ctx, "alice", missing) }, libpolybase.ErrNotFound},
+		{"course quantity", func() error { _, err := pb.UpdateCourseQuantity(ctx, "alice", missing, 1); return err }, libpolybase.ErrNotFound},
+		{"get pack", func() error { _, err := pb.GetPack(ctx, 42); return err }, libpolybase.ErrNotFound},
+		{"delete pack", func() error { return pb.DeletePack(ctx, "alice", 42) }, libpolybase.ErrNotFound},
+		{"revert", func() error { _, err := pb.RevertChange(ctx, "alice", 42); return err }, libpolybase.ErrNotFound},
+		{"restore", func() error { _, err := pb.RestoreCourse(ctx, "alice", course.CID()); return err }, libpolybase.ErrNotFound},
+		{"create course", func() error { _, err := pb.CreateCourse(ctx, "alice", course); return err }, libpolybase.ErrAlreadyExists},
+		{"revision", func() error {
+			_, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Name: stringPtr("x"), Revision: intPtr(42)})
+			return err
+		}, libpolybase.ErrConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.err(); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// Invalid inputs are reported as a ValidationError naming the field
+func TestValidationError(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	_, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S3",
+	})
+	var validation *libpolybase.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("got error %v, want ValidationError", err)
+	}
+	if validation.Field != "semester" {
+		t.Errorf("got field %q, want semester", validation.Field)
+	}
+
+	_, err = pb.CreatePack(ctx, "alice", "L2 S1", packCourses([]libpolybase.CourseID{{Code: "LU2IN002", Kind: "TD", Part: 1}}...))
+	if !errors.As(err, &validation) || validation.Field != "courses" {
+		t.Errorf("got error %v, want ValidationError on courses", err)
+	}
+}
@@ -0,0 +1,187 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func readMasterFile(t *testing.T, pb libpolybase.Polybase, fileID int) string {
+	t.Helper()
+	_, content, err := pb.OpenMasterFile(context.Background(), fileID)
+	if err != nil {
+		t.Fatalf("failed to open master file: %v", err)
+	}
+	defer content.Close()
+	b, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatalf("failed to read master file: %v", err)
+	}
+	return string(b)
+}
+
+// The newest upload of a course is its master, identical contents being
+// stored once
+func TestMasterFiles(t *testing.T) {
+	db := NewDB(t)
+	storage := t.TempDir()
+	pb := libpolybase.New(db.DB, "", false).WithStorage(storage)
+	ctx := context.Background()
+
+	algo := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	web := libpolybase.Course{Code: "LU2IN003", Kind: "TD", Part: 1, Parts: 1, Name: "Web", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.InsertMany([]libpolybase.Course{algo, web})
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.PutMasterFile(ctx, "alice", algo.CID(), "algo.docx", strings.NewReader("PK\x03\x04")); !errors.As(err, &validation) {
+		t.Errorf("got %v for a document that is not a PDF, want a validation error", err)
+	}
+	if _, err := pb.GetMasterFile(ctx, algo.CID()); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+
+	v1 := "%PDF-1.7 algo v1"
+	first, err := pb.PutMasterFile(ctx, "alice", algo.CID(), "/home/alice/algo.pdf", strings.NewReader(v1))
+	if err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+	sum := sha256.Sum256([]byte(v1))
+	if first.Name != "algo.pdf" || first.Checksum != hex.EncodeToString(sum[:]) || first.Size != int64(len(v1)) || !first.Current || first.UploadedBy != "alice" {
+		t.Errorf("got %+v", first)
+	}
+	if _, err := os.Stat(filepath.Join(storage, first.Checksum[:2], first.Checksum)); err != nil {
+		t.Errorf("master file not in the store: %v", err)
+	}
+
+	if _, err := pb.PutMasterFile(ctx, "bob", web.CID(), "web.pdf", strings.NewReader(v1)); err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(storage, first.Checksum[:2]))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("got %d files for the same content, want 1 (%v)", len(entries), err)
+	}
+
+	v2 := "%PDF-1.7 algo v2"
+	second, err := pb.PutMasterFile(ctx, "alice", algo.CID(), "algo-v2.pdf", strings.NewReader(v2))
+	if err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+
+	current, err := pb.GetMasterFile(ctx, algo.CID())
+	if err != nil {
+		t.Fatalf("failed to get master file: %v", err)
+	}
+	if current.ID != second.ID {
+		t.Errorf("got master %d, want the newest upload %d", current.ID, second.ID)
+	}
+	files, err := pb.ListMasterFiles(ctx, algo.CID())
+	if err != nil {
+		t.Fatalf("failed to list master files: %v", err)
+	}
+	if len(files) != 2 || files[0].ID != second.ID || files[1].Current {
+		t.Errorf("got %+v, want the newest upload first and current", files)
+	}
+	if got := readMasterFile(t, pb, first.ID); got != v1 {
+		t.Errorf("read %q from the first upload", got)
+	}
+	if got := readMasterFile(t, pb, second.ID); got != v2 {
+		t.Errorf("read %q from the current master", got)
+	}
+
+	action := libpolybase.ActionUpload
+	entityID := algo.ID()
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Action: &action, EntityID: &entityID})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 2 || events[0].Actor != "alice" || !strings.Contains(string(events[0].After), second.Checksum) {
+		t.Errorf("got %+v, want both uploads in the history", events)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); !errors.Is(err, libpolybase.ErrConflict) {
+		t.Errorf("got %v reverting an upload, want a conflict", err)
+	}
+
+	course, err := pb.GetCourse(ctx, algo.CID())
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	if !course.HasMaster {
+		t.Errorf("course with a master file not flagged")
+	}
+
+	missing := libpolybase.Course{Code: "LU2IN004", Kind: "TD", Part: 1, Parts: 1, Name: "Réseau", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(missing)
+	page, err := pb.ListCourses(ctx, libpolybase.CourseFilter{MissingMaster: true})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Code != missing.Code || page.Items[0].HasMaster {
+		t.Errorf("got %+v, want the course without a master file", page.Items)
+	}
+}
+
+// Master files follow their course when it is renamed and the current one is
+// carried over to the next year
+func TestMasterFilesFollowCourse(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false).WithStorage(t.TempDir())
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	if _, err := pb.PutMasterFile(ctx, "alice", course.CID(), "v1.pdf", strings.NewReader("%PDF-1.7 v1")); err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+	if _, err := pb.PutMasterFile(ctx, "alice", course.CID(), "v2.pdf", strings.NewReader("%PDF-1.7 v2")); err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+
+	code := "LU2IN012"
+	renamed, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Code: &code})
+	if err != nil {
+		t.Fatalf("failed to rename course: %v", err)
+	}
+	if files, _ := pb.ListMasterFiles(ctx, renamed.CID()); len(files) != 2 {
+		t.Errorf("got %d master files after renaming, want 2", len(files))
+	}
+
+	// A revert is not blocked by the uploads made since the change
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if _, err := pb.PutMasterFile(ctx, "alice", renamed.CID(), "v3.pdf", strings.NewReader("%PDF-1.7 v3")); err != nil {
+		t.Fatalf("failed to put master file: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); err != nil {
+		t.Fatalf("failed to revert renaming: %v", err)
+	}
+
+	if _, err := pb.RollOverYear(ctx, "alice", pb.Year(), pb.Year()+1, libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+	next := pb.ForYear(pb.Year() + 1)
+	files, err := next.ListMasterFiles(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list master files: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "v3.pdf" || !files[0].Current {
+		t.Fatalf("got %+v in the next year, want the current master only", files)
+	}
+	if got := readMasterFile(t, next, files[0].ID); got != "%PDF-1.7 v3" {
+		t.Errorf("read %q in the next year", got)
+	}
+
+	if _, err := libpolybase.New(db.DB, "", false).PutMasterFile(ctx, "alice", course.CID(), "v4.pdf", strings.NewReader("%PDF-1.7 v4")); err == nil {
+		t.Errorf("stored a master file without a file store")
+	}
+}
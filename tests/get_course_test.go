@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	"github.com/alias-asso/polybase-go/libpolybase"
@@ -20,9 +21,10 @@ func TestGetExistingCourse(t *testing.T) {
 		Name:     "Introduction to Programming",
 		Quantity: 50,
 		Total:    100,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
-		Year:     1,
+		Level:    libpolybase.LevelL1,
 	}
 
 	db.Insert(course)
@@ -38,7 +40,8 @@ func TestGetExistingCourse(t *testing.T) {
 		t.Fatalf("failed to get course: %v", err)
 	}
 
-	if got != course {
+	course.Revision = 1
+	if !reflect.DeepEqual(got, course) {
 		t.Errorf("got course %+v, want %+v", got, course)
 	}
 }
@@ -115,6 +118,7 @@ func TestGetAfterDeletion(t *testing.T) {
 		Name:     "Programming",
 		Quantity: 50,
 		Total:    100,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -158,9 +162,10 @@ func TestGetMultiPartCourse(t *testing.T) {
 			Name:     "Programming I",
 			Quantity: 50,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
-			Year:     1,
+			Level:    libpolybase.LevelL1,
 		},
 		{
 			Code:     "UL1IN001",
@@ -170,9 +175,10 @@ func TestGetMultiPartCourse(t *testing.T) {
 			Name:     "Programming II",
 			Quantity: 40,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
-			Year:     1,
+			Level:    libpolybase.LevelL1,
 		},
 		{
 			Code:     "UL1IN001",
@@ -182,9 +188,10 @@ func TestGetMultiPartCourse(t *testing.T) {
 			Name:     "Programming III",
 			Quantity: 30,
 			Total:    100,
+			Status:   libpolybase.StatusAvailable,
 			Shown:    true,
 			Semester: "S1",
-			Year:     1,
+			Level:    libpolybase.LevelL1,
 		},
 	}
 
@@ -202,7 +209,8 @@ func TestGetMultiPartCourse(t *testing.T) {
 			t.Fatalf("failed to get course part %d: %v", want.Part, err)
 		}
 
-		if got != want {
+		want.Revision = 1
+		if !reflect.DeepEqual(got, want) {
 			t.Errorf("part %d: got %+v, want %+v", want.Part, got, want)
 		}
 	}
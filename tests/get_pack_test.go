@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -90,7 +91,7 @@ func TestGetExistingPack(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := pb.CreatePack(ctx, "testuser", tt.packName, tt.courses)
+			created, err := pb.CreatePack(ctx, "testuser", tt.packName, packCourses(tt.courses...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
@@ -103,7 +104,7 @@ func TestGetExistingPack(t *testing.T) {
 			want := libpolybase.Pack{
 				ID:      created.ID,
 				Name:    tt.packName,
-				Courses: tt.courses,
+				Courses: packCourses(tt.courses...),
 			}
 
 			if got.ID != want.ID {
@@ -184,7 +185,7 @@ func TestGetNonExistentPack(t *testing.T) {
 					Part: course.Part,
 				}
 
-				_, err = pb.CreatePack(ctx, "testuser", "Test Pack", []libpolybase.CourseID{courseID})
+				_, err = pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(courseID))
 				if err != nil {
 					t.Fatalf("failed to create pack: %v", err)
 				}
@@ -220,7 +221,10 @@ func TestGetNonExistentPack(t *testing.T) {
 			db.AssertPackNotExists(tt.packID)
 
 			var count int
-			err = db.QueryRow("SELECT COUNT(*) FROM pack_courses WHERE pack_id = ?", tt.packID).Scan(&count)
+			err = db.QueryRow(`
+				SELECT COUNT(*) FROM pack_courses pc
+				JOIN packs p ON p.id = pc.pack_id
+				WHERE pc.pack_id = ? AND p.deleted_at IS NULL`, tt.packID).Scan(&count)
 			if err != nil {
 				t.Fatalf("failed to count pack courses: %v", err)
 			}
@@ -363,7 +367,7 @@ func TestPackCourseOrder(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			created, err := pb.CreatePack(ctx, "testuser", "Test Pack", tt.inputOrder)
+			created, err := pb.CreatePack(ctx, "testuser", "Test Pack", packCourses(tt.inputOrder...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
@@ -378,7 +382,7 @@ func TestPackCourseOrder(t *testing.T) {
 			}
 
 			for i, course := range got.Courses {
-				if course != tt.wantOrder[i] {
+				if course.CourseID != tt.wantOrder[i] {
 					t.Errorf("course[%d] = %+v, want %+v", i, course, tt.wantOrder[i])
 				}
 			}
@@ -386,8 +390,52 @@ func TestPackCourseOrder(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    "Test Pack",
-				Courses: tt.wantOrder,
+				Courses: packCourses(tt.wantOrder...),
 			})
 		})
 	}
 }
+
+// The availability of a pack follows the stock of its courses, the same
+// whether the pack is read alone or listed
+func TestPackAvailability(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	algo := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 12, Total: 50, Shown: true, Status: libpolybase.StatusAvailable, Semester: "S1"}
+	web := libpolybase.Course{Code: "LU2IN003", Kind: "TD", Part: 1, Parts: 1, Name: "Web", Quantity: 4, Total: 50, Shown: true, Status: libpolybase.StatusAvailable, Semester: "S1"}
+	draft := libpolybase.Course{Code: "LU2IN004", Kind: "TD", Part: 1, Parts: 1, Name: "Réseau", Quantity: 7, Total: 50, Status: libpolybase.StatusDraft, Semester: "S1"}
+	db.InsertMany([]libpolybase.Course{algo, web, draft})
+
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(algo.CID(), web.CID(), draft.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	got := pack.Availability
+	if got.Complete != 4 || got.Limiting == nil || *got.Limiting != web.CID() || len(got.OutOfStock) != 0 {
+		t.Errorf("got %+v, want 4 complete packs limited by %s", got, web.ID())
+	}
+	if len(got.Hidden) != 1 || got.Hidden[0] != draft.CID() {
+		t.Errorf("got hidden %v, want %s", got.Hidden, draft.ID())
+	}
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", web.CID(), -4); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	pack, err = pb.GetPack(ctx, pack.ID)
+	if err != nil {
+		t.Fatalf("failed to get pack: %v", err)
+	}
+	if pack.Availability.Complete != 0 || len(pack.Availability.OutOfStock) != 1 || pack.Availability.OutOfStock[0] != web.CID() {
+		t.Errorf("got %+v, want the pack incomplete without %s", pack.Availability, web.ID())
+	}
+
+	packs, err := pb.ListPacks(ctx)
+	if err != nil {
+		t.Fatalf("failed to list packs: %v", err)
+	}
+	if len(packs) != 1 || !reflect.DeepEqual(packs[0].Availability, pack.Availability) {
+		t.Errorf("listed %+v, want %+v", packs, pack.Availability)
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func TestGetLevel(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    libpolybase.Level
+		wantErr bool
+	}{
+		{"LU1IN002", libpolybase.LevelL1, false},
+		{"LU3IN009", libpolybase.LevelL3, false},
+		{"MU4IN100", libpolybase.LevelM1, false},
+		{"MU5IN200", libpolybase.LevelM2, false},
+		{"LU6IN001", libpolybase.LevelOther, false},
+		{"LU2MA001", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := libpolybase.GetLevel(tt.code)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetLevel(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetLevel(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range libpolybase.Levels() {
+		got, err := libpolybase.ParseLevel(string(level))
+		if err != nil || got != level {
+			t.Errorf("ParseLevel(%q) = %q, %v", level, got, err)
+		}
+	}
+
+	if got, err := libpolybase.ParseLevel("m2"); err != nil || got != libpolybase.LevelM2 {
+		t.Errorf("ParseLevel(m2) = %q, %v", got, err)
+	}
+	if _, err := libpolybase.ParseLevel("L4"); err == nil {
+		t.Error("ParseLevel(L4) succeeded")
+	}
+}
+
+// Master levels come after licence ones and the other level comes last
+func TestLevelOrder(t *testing.T) {
+	levels := []libpolybase.Level{
+		libpolybase.LevelOther, libpolybase.LevelM2, libpolybase.LevelL3, libpolybase.LevelM1, libpolybase.LevelL1,
+	}
+	slices.SortFunc(levels, libpolybase.Level.Compare)
+
+	want := []libpolybase.Level{
+		libpolybase.LevelL1, libpolybase.LevelL3, libpolybase.LevelM1, libpolybase.LevelM2, libpolybase.LevelOther,
+	}
+	if !slices.Equal(levels, want) {
+		t.Errorf("got %v, want %v", levels, want)
+	}
+}
+
+// Catalogues map the captured values to levels, or capture level names
+func TestCatalogueLevels(t *testing.T) {
+	catalogue := libpolybase.Catalogue{
+		CodePatterns: []string{`^([LM]\d)PHY\d{2}$`, `^PHY(\d)\d{2}$`},
+		Levels:       map[string]libpolybase.Level{"6": libpolybase.LevelM2},
+		Kinds:        []libpolybase.Kind{{Name: "TD"}},
+		Semesters:    []string{"S1"},
+	}
+	if err := catalogue.Compile(); err != nil {
+		t.Fatalf("failed to compile catalogue: %v", err)
+	}
+
+	tests := []struct {
+		code string
+		want libpolybase.Level
+	}{
+		{"L2PHY01", libpolybase.LevelL2},
+		{"M1PHY01", libpolybase.LevelM1},
+		{"L9PHY01", libpolybase.LevelOther},
+		{"PHY601", libpolybase.LevelM2},
+		{"PHY101", libpolybase.LevelOther},
+	}
+	for _, tt := range tests {
+		got, err := catalogue.Level(tt.code)
+		if err != nil || got != tt.want {
+			t.Errorf("Level(%q) = %q, %v, want %q", tt.code, got, err, tt.want)
+		}
+	}
+
+	catalogue.Levels = map[string]libpolybase.Level{"1": "L4"}
+	if err := catalogue.Compile(); err == nil {
+		t.Error("compiled a catalogue with an unknown level")
+	}
+}
+
+func TestListOtherLevel(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU6IN001", Kind: "TD", Part: 1, Parts: 1, Name: "Stage", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+	})
+
+	page, err := pb.ListCourses(context.Background(), libpolybase.CourseFilter{Level: libpolybase.LevelOther})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Code != "LU6IN001" || page.Items[0].Level != libpolybase.LevelOther {
+		t.Errorf("got %+v", page.Items)
+	}
+
+	page, err = pb.ListCourses(context.Background(), libpolybase.CourseFilter{Level: libpolybase.LevelM2})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("got %+v, want no course", page.Items)
+	}
+}
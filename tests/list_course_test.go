@@ -1,13 +1,239 @@
 package tests
 
-// list empty database
-// list single course
-// list multiple courses
-// list with hidden/shown filtering
-// list with semester filtering
-// list with code filtering
-// list with kind filtering
-// list with part filtering
-// list with multiple filtering
-// list with multiple filters
-// list ordering verification
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func listCourses() []libpolybase.Course {
+	return []libpolybase.Course{
+		{Code: "LU1IN001", Kind: "Cours", Part: 1, Parts: 1, Name: "Programmation", Quantity: 50, Total: 100, Shown: true, Semester: "S1"},
+		{Code: "LU1IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Éléments de programmation", Quantity: 5, Total: 100, Shown: true, Semester: "S2"},
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algorithmique", Quantity: 0, Total: 80, Shown: true, Semester: "S1"},
+		{Code: "LU2IN018", Kind: "TME", Part: 1, Parts: 1, Name: "Systèmes", Quantity: 30, Total: 40, Shown: true, Semester: "S2"},
+		{Code: "MU4IN100", Kind: "Cours", Part: 1, Parts: 1, Name: "Algorithmique avancée", Quantity: 10, Total: 20, Shown: false, Semester: "S1"},
+	}
+}
+
+func listIDs(courses []libpolybase.Course) []string {
+	ids := make([]string, len(courses))
+	for i, c := range courses {
+		ids[i] = c.ID()
+	}
+	return ids
+}
+
+// An empty database lists no courses
+func TestListEmpty(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+
+	page, err := pb.ListCourses(context.Background(), libpolybase.CourseFilter{})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 0 || page.NextCursor != "" {
+		t.Errorf("got page %+v, want it empty", page)
+	}
+}
+
+// Each filter narrows the listed courses
+func TestListFilters(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	db.InsertMany(listCourses())
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2", Courses: packCourses([]libpolybase.CourseID{
+		{Code: "LU2IN002", Kind: "TD", Part: 1},
+		{Code: "LU2IN018", Kind: "TME", Part: 1},
+	}...)})
+
+	cases := []struct {
+		name   string
+		filter libpolybase.CourseFilter
+		want   []string
+	}{
+		{
+			name:   "shown courses",
+			filter: libpolybase.CourseFilter{},
+			want:   []string{"LU1IN002/TD/1", "LU2IN018/TME/1", "LU1IN001/Cours/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "hidden courses",
+			filter: libpolybase.CourseFilter{ShowHidden: true, Semester: stringPtr("S1")},
+			want:   []string{"LU1IN001/Cours/1", "LU2IN002/TD/1", "MU4IN100/Cours/1"},
+		},
+		{
+			name:   "search in the name ignoring case",
+			filter: libpolybase.CourseFilter{ShowHidden: true, Search: "algo"},
+			want:   []string{"LU2IN002/TD/1", "MU4IN100/Cours/1"},
+		},
+		{
+			name:   "search in the code",
+			filter: libpolybase.CourseFilter{Search: "IN01"},
+			want:   []string{"LU2IN018/TME/1"},
+		},
+		{
+			name:   "search with wildcards",
+			filter: libpolybase.CourseFilter{Search: "%"},
+			want:   []string{},
+		},
+		{
+			name:   "several kinds",
+			filter: libpolybase.CourseFilter{Kinds: []string{"TD", "TME"}},
+			want:   []string{"LU1IN002/TD/1", "LU2IN018/TME/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "licence level",
+			filter: libpolybase.CourseFilter{Level: "L2"},
+			want:   []string{"LU2IN018/TME/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "master level",
+			filter: libpolybase.CourseFilter{ShowHidden: true, Level: "M1"},
+			want:   []string{"MU4IN100/Cours/1"},
+		},
+		{
+			name:   "low stock",
+			filter: libpolybase.CourseFilter{Stock: libpolybase.StockLow},
+			want:   []string{"LU1IN002/TD/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "out of stock",
+			filter: libpolybase.CourseFilter{Stock: libpolybase.StockOut},
+			want:   []string{"LU2IN002/TD/1"},
+		},
+		{
+			name:   "pack",
+			filter: libpolybase.CourseFilter{PackID: intPtr(1)},
+			want:   []string{"LU2IN018/TME/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "several filters",
+			filter: libpolybase.CourseFilter{Code: stringPtr("LU2IN002"), Kinds: []string{"TD"}, Part: intPtr(1)},
+			want:   []string{"LU2IN002/TD/1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := pb.ListCourses(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("failed to list courses: %v", err)
+			}
+			if got := listIDs(page.Items); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// Courses are sorted by the requested key, ties by code
+func TestListSort(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	db.InsertMany(listCourses())
+
+	cases := []struct {
+		name   string
+		filter libpolybase.CourseFilter
+		want   []string
+	}{
+		{
+			name:   "code",
+			filter: libpolybase.CourseFilter{Sort: libpolybase.SortCode},
+			want:   []string{"LU1IN001/Cours/1", "LU1IN002/TD/1", "LU2IN002/TD/1", "LU2IN018/TME/1"},
+		},
+		{
+			name:   "name",
+			filter: libpolybase.CourseFilter{Sort: libpolybase.SortName},
+			want:   []string{"LU2IN002/TD/1", "LU1IN001/Cours/1", "LU2IN018/TME/1", "LU1IN002/TD/1"},
+		},
+		{
+			name:   "quantity reversed",
+			filter: libpolybase.CourseFilter{Sort: libpolybase.SortQuantity, Desc: true},
+			want:   []string{"LU1IN001/Cours/1", "LU2IN018/TME/1", "LU1IN002/TD/1", "LU2IN002/TD/1"},
+		},
+		{
+			name:   "semester reversed",
+			filter: libpolybase.CourseFilter{Desc: true},
+			want:   []string{"LU1IN001/Cours/1", "LU2IN002/TD/1", "LU1IN002/TD/1", "LU2IN018/TME/1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := pb.ListCourses(context.Background(), tc.filter)
+			if err != nil {
+				t.Fatalf("failed to list courses: %v", err)
+			}
+			if got := listIDs(page.Items); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// Following the cursors lists every course once
+func TestListPagination(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	db.InsertMany(listCourses())
+
+	filter := libpolybase.CourseFilter{ShowHidden: true, Sort: libpolybase.SortCode, Limit: 2}
+	var got []string
+	pages := 0
+	for {
+		page, err := pb.ListCourses(context.Background(), filter)
+		if err != nil {
+			t.Fatalf("failed to list courses: %v", err)
+		}
+		if len(page.Items) > filter.Limit {
+			t.Fatalf("got %d courses, want at most %d", len(page.Items), filter.Limit)
+		}
+		got = append(got, listIDs(page.Items)...)
+		pages++
+		if page.NextCursor == "" {
+			break
+		}
+		filter.Cursor = page.NextCursor
+	}
+
+	want := []string{"LU1IN001/Cours/1", "LU1IN002/TD/1", "LU2IN002/TD/1", "LU2IN018/TME/1", "MU4IN100/Cours/1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if pages != 3 {
+		t.Errorf("got %d pages, want 3", pages)
+	}
+}
+
+// Invalid filters are refused
+func TestListInvalidFilter(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+
+	cases := []struct {
+		name   string
+		filter libpolybase.CourseFilter
+		field  string
+	}{
+		{name: "level", filter: libpolybase.CourseFilter{Level: "L4"}, field: "level"},
+		{name: "stock", filter: libpolybase.CourseFilter{Stock: "empty"}, field: "stock"},
+		{name: "sort", filter: libpolybase.CourseFilter{Sort: "total"}, field: "sort"},
+		{name: "cursor", filter: libpolybase.CourseFilter{Cursor: "not a cursor"}, field: "cursor"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := pb.ListCourses(context.Background(), tc.filter)
+			var validation *libpolybase.ValidationError
+			if !errors.As(err, &validation) || validation.Field != tc.field {
+				t.Errorf("got error %v, want a validation error on %s", err, tc.field)
+			}
+		})
+	}
+}
@@ -93,11 +93,11 @@ func TestListSinglePack(t *testing.T) {
 
 	pack := libpolybase.Pack{
 		Name: "Programming Bundle",
-		Courses: []libpolybase.CourseID{
+		Courses: packCourses([]libpolybase.CourseID{
 			{Code: "UL1IN001", Kind: "Cours", Part: 1},
 			{Code: "UL1IN001", Kind: "Cours", Part: 2},
 			{Code: "UL1IN001", Kind: "TME", Part: 1},
-		},
+		}...),
 	}
 
 	created, err := pb.CreatePack(ctx, "testuser", pack.Name, pack.Courses)
@@ -159,7 +159,7 @@ func TestListSinglePack(t *testing.T) {
 	db.AssertPackEqual(created.ID, want)
 
 	for _, courseID := range pack.Courses {
-		db.AssertCourseInPack(created.ID, courseID)
+		db.AssertCourseInPack(created.ID, courseID.CourseID)
 	}
 
 	if got := db.CountPackCourses(created.ID); got != len(pack.Courses) {
@@ -227,7 +227,7 @@ func TestListPacksOrder(t *testing.T) {
 
 	// Create packs
 	packNames := []string{"Pack with 1 and 2", "Pack with 1 and 3", "Pack with 2 and 3"}
-	packCourses := [][]libpolybase.CourseID{
+	members := [][]libpolybase.CourseID{
 		{courseIDs[0], courseIDs[1]},
 		{courseIDs[0], courseIDs[2]},
 		{courseIDs[1], courseIDs[2]},
@@ -235,7 +235,7 @@ func TestListPacksOrder(t *testing.T) {
 
 	var createdPacks []libpolybase.Pack
 	for i, name := range packNames {
-		pack, err := pb.CreatePack(ctx, "testuser", name, packCourses[i])
+		pack, err := pb.CreatePack(ctx, "testuser", name, packCourses(members[i]...))
 		if err != nil {
 			t.Fatalf("failed to create pack %q: %v", name, err)
 		}
@@ -337,7 +337,7 @@ func TestListPackIncludesAllCourses(t *testing.T) {
 
 	var createdPacks []libpolybase.Pack
 	for _, packDef := range packDefinitions {
-		pack, err := pb.CreatePack(ctx, "testuser", packDef.name, packDef.courses)
+		pack, err := pb.CreatePack(ctx, "testuser", packDef.name, packCourses(packDef.courses...))
 		if err != nil {
 			t.Fatalf("failed to create pack %q: %v", packDef.name, err)
 		}
@@ -418,9 +418,9 @@ func TestListPacksLargeNumberOfPacks(t *testing.T) {
 		for j := i + 1; j < len(courseIDs); j++ {
 			// Create a pack with these two courses
 			packName := fmt.Sprintf("Pack %d", packCount)
-			packCourses := []libpolybase.CourseID{courseIDs[i], courseIDs[j]}
+			members := packCourses(courseIDs[i], courseIDs[j])
 
-			pack, err := pb.CreatePack(ctx, "testuser", packName, packCourses)
+			pack, err := pb.CreatePack(ctx, "testuser", packName, members)
 			if err != nil {
 				t.Fatalf("failed to create pack %q: %v", packName, err)
 			}
@@ -0,0 +1,179 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func newRawDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Errorf("failed to close test database: %v", err)
+		}
+	})
+	return db
+}
+
+// Migrating a fresh database applies every embedded migration
+func TestMigrateFreshDatabase(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	if err := libpolybase.CheckSchema(ctx, db); !errors.Is(err, libpolybase.ErrSchemaOutdated) {
+		t.Fatalf("got error %v, want ErrSchemaOutdated", err)
+	}
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	if err := libpolybase.CheckSchema(ctx, db); err != nil {
+		t.Fatalf("schema should be up to date: %v", err)
+	}
+
+	states, err := libpolybase.MigrationStatus(ctx, db)
+	if err != nil {
+		t.Fatalf("failed to get status: %v", err)
+	}
+	migrations, err := libpolybase.Migrations()
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	if len(states) != len(migrations) {
+		t.Fatalf("got %d states, want %d", len(states), len(migrations))
+	}
+	for _, state := range states {
+		if !state.Applied {
+			t.Errorf("migration %d not applied", state.Version)
+		}
+	}
+}
+
+// Migrating twice is a no-op
+func TestMigrateIdempotent(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := libpolybase.Migrate(ctx, db); err != nil {
+			t.Fatalf("migration %d failed: %v", i, err)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&count); err != nil {
+		t.Fatalf("failed to count migrations: %v", err)
+	}
+	migrations, _ := libpolybase.Migrations()
+	if count != len(migrations) {
+		t.Errorf("got %d recorded migrations, want %d", count, len(migrations))
+	}
+}
+
+// A database created by hand before the runner existed is adopted
+func TestMigrateExistingSchema(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	migrations, err := libpolybase.Migrations()
+	if err != nil {
+		t.Fatalf("failed to list migrations: %v", err)
+	}
+	for _, m := range migrations[:2] {
+		if _, err := db.Exec(m.Up); err != nil {
+			t.Fatalf("failed to apply %s by hand: %v", m.Name, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO courses (code, kind, part, parts, name, quantity, total, shown, semester)
+		VALUES ('LU2IN002', 'TD', 1, 1, 'Algo', 10, 20, 1, 'S1')`); err != nil {
+		t.Fatalf("failed to insert course: %v", err)
+	}
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	pb := libpolybase.New(db, "", false)
+	course, err := pb.GetCourse(ctx, libpolybase.CourseID{Code: "LU2IN002", Kind: "TD", Part: 1})
+	if err != nil {
+		t.Fatalf("course lost during migration: %v", err)
+	}
+	if course.Quantity != 10 {
+		t.Errorf("got quantity %d, want 10", course.Quantity)
+	}
+}
+
+// Reverting a migration removes it from the applied set
+func TestMigrateDown(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := libpolybase.MigrateDown(ctx, db); err != nil {
+		t.Fatalf("failed to migrate down: %v", err)
+	}
+
+	if err := libpolybase.CheckSchema(ctx, db); !errors.Is(err, libpolybase.ErrSchemaOutdated) {
+		t.Errorf("got error %v, want ErrSchemaOutdated", err)
+	}
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+}
+
+// Every migration can be reverted down to an empty database
+func TestMigrateDownAll(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	migrations, _ := libpolybase.Migrations()
+	for range migrations {
+		if err := libpolybase.MigrateDown(ctx, db); err != nil {
+			t.Fatalf("failed to migrate down: %v", err)
+		}
+	}
+
+	if err := libpolybase.MigrateDown(ctx, db); err == nil {
+		t.Error("expected error when nothing is left to revert")
+	}
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+}
+
+// A database migrated by a newer binary is refused
+func TestMigrateUnknownVersion(t *testing.T) {
+	db := newRawDB(t)
+	ctx := context.Background()
+
+	if err := libpolybase.Migrate(ctx, db); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO schema_migrations (version, name, applied_at)
+		VALUES (999, 'future', CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("failed to insert future migration: %v", err)
+	}
+
+	if err := libpolybase.Migrate(ctx, db); !errors.Is(err, libpolybase.ErrSchemaTooNew) {
+		t.Errorf("got error %v, want ErrSchemaTooNew", err)
+	}
+	if err := libpolybase.CheckSchema(ctx, db); !errors.Is(err, libpolybase.ErrSchemaTooNew) {
+		t.Errorf("got error %v, want ErrSchemaTooNew", err)
+	}
+}
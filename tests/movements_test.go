@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// Quantity changes on a course are recorded with their applied delta
+func TestMovementsCourseQuantity(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 3, Total: 10, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if _, err := pb.UpdateCourseQuantity(ctx, "bob", course.CID(), 5); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	// Clamped to zero: only 7 copies can be handed out
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -20); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	// Nothing left to hand out, nothing is recorded
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+
+	id := course.CID()
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &id})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+
+	want := []struct {
+		delta    int
+		quantity int
+		actor    string
+		reason   libpolybase.MovementReason
+	}{
+		{-7, 0, "alice", libpolybase.ReasonDistribution},
+		{5, 7, "bob", libpolybase.ReasonRestock},
+		{-1, 2, "alice", libpolybase.ReasonDistribution},
+	}
+	if len(movements) != len(want) {
+		t.Fatalf("got %d movements, want %d: %+v", len(movements), len(want), movements)
+	}
+	for i, w := range want {
+		m := movements[i]
+		if m.Delta != w.delta || m.Quantity != w.quantity || m.Actor != w.actor || m.Reason != w.reason {
+			t.Errorf("movement %d: got %+v, want %+v", i, m, w)
+		}
+		if m.PackID != nil {
+			t.Errorf("movement %d: got pack %d, want none", i, *m.PackID)
+		}
+	}
+
+	reason := libpolybase.ReasonDistribution
+	distributed, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &id, Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	total := 0
+	for _, m := range distributed {
+		total -= m.Delta
+	}
+	if total != 8 {
+		t.Errorf("got %d copies handed out, want 8", total)
+	}
+}
+
+// Creating and editing a course record the initial stock and corrections
+func TestMovementsCreateAndUpdate(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	if _, err := pb.UpdateCourse(ctx, "bob", created.CID(), libpolybase.PartialCourse{
+		Code:     stringPtr("LU2IN003"),
+		Quantity: intPtr(12),
+	}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+
+	// History follows the course when its ID changes
+	renamed := libpolybase.CourseID{Code: "LU2IN003", Kind: "TD", Part: 1}
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &renamed})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 2 {
+		t.Fatalf("got %d movements, want 2: %+v", len(movements), movements)
+	}
+	if m := movements[0]; m.Reason != libpolybase.ReasonCorrection || m.Delta != 2 || m.Quantity != 12 || m.Actor != "bob" {
+		t.Errorf("got correction %+v", m)
+	}
+	if m := movements[1]; m.Reason != libpolybase.ReasonRestock || m.Delta != 10 || m.Quantity != 10 || m.Actor != "alice" {
+		t.Errorf("got initial stock %+v", m)
+	}
+}
+
+// Pack distributions record one movement per course with the pack ID
+func TestMovementsPackQuantity(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 0, Total: 20, Shown: true, Semester: "S1"},
+	}
+	db.InsertMany(courses)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(courses[0].CID(), courses[1].CID())})
+
+	if _, err := pb.UpdatePackQuantity(ctx, "alice", 1, -1, libpolybase.PolicyClamp); err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+
+	packID := 1
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{PackID: &packID})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 1 {
+		t.Fatalf("got %d movements, want 1: %+v", len(movements), movements)
+	}
+	m := movements[0]
+	if m.Course != courses[0].CID() || m.Delta != -1 || m.Quantity != 9 || m.Reason != libpolybase.ReasonPack {
+		t.Errorf("got movement %+v", m)
+	}
+	if m.PackID == nil || *m.PackID != 1 {
+		t.Errorf("got pack %v, want 1", m.PackID)
+	}
+}
+
+// Movements can be filtered by time range and actor
+func TestMovementsFilterTime(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	before := time.Now().Add(-time.Second)
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if _, err := pb.UpdateCourseQuantity(ctx, "bob", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Since: &before})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 2 {
+		t.Errorf("got %d movements since start, want 2", len(movements))
+	}
+	if !movements[0].CreatedAt.After(before) {
+		t.Errorf("got timestamp %v, want after %v", movements[0].CreatedAt, before)
+	}
+
+	movements, err = pb.ListMovements(ctx, libpolybase.MovementFilter{Until: &before})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 0 {
+		t.Errorf("got %d movements before start, want 0", len(movements))
+	}
+
+	actor := "bob"
+	movements, err = pb.ListMovements(ctx, libpolybase.MovementFilter{Actor: &actor, Limit: 5})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Actor != "bob" {
+		t.Errorf("got %+v, want a single movement by bob", movements)
+	}
+}
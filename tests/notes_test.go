@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func noteBodies(notes []libpolybase.Note) []string {
+	bodies := []string{}
+	for _, note := range notes {
+		bodies = append(bodies, note.Body)
+	}
+	return bodies
+}
+
+// Notes are listed newest first and only their author may edit or delete
+// them
+func TestCourseNotes(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+
+	first, err := pb.AddCourseNote(ctx, "alice", course.CID(), "  prof will send v2 in March ")
+	if err != nil {
+		t.Fatalf("failed to add note: %v", err)
+	}
+	if first.Body != "prof will send v2 in March" || first.Author != "alice" || first.Course == nil || *first.Course != course.CID() || first.UpdatedAt != nil {
+		t.Errorf("got %+v", first)
+	}
+	if _, err := pb.AddCourseNote(ctx, "bob", course.CID(), "box 3 is in the back office"); err != nil {
+		t.Fatalf("failed to add note: %v", err)
+	}
+
+	notes, err := pb.ListCourseNotes(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list notes: %v", err)
+	}
+	if got := noteBodies(notes); len(got) != 2 || got[0] != "box 3 is in the back office" {
+		t.Errorf("got %q, want the newest note first", got)
+	}
+
+	if _, err := pb.UpdateNote(ctx, "bob", first.ID, "v2 is late"); !errors.Is(err, libpolybase.ErrForbidden) {
+		t.Errorf("got %v, want forbidden", err)
+	}
+	if err := pb.DeleteNote(ctx, "bob", first.ID); !errors.Is(err, libpolybase.ErrForbidden) {
+		t.Errorf("got %v, want forbidden", err)
+	}
+
+	updated, err := pb.UpdateNote(ctx, "alice", first.ID, "v2 is late")
+	if err != nil {
+		t.Fatalf("failed to update note: %v", err)
+	}
+	if updated.Body != "v2 is late" || updated.UpdatedAt == nil || !updated.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("got %+v", updated)
+	}
+
+	if err := pb.DeleteNote(ctx, "alice", first.ID); err != nil {
+		t.Fatalf("failed to delete note: %v", err)
+	}
+	if _, err := pb.GetNote(ctx, first.ID); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.AddCourseNote(ctx, "alice", course.CID(), " "); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+	missing := libpolybase.CourseID{Code: "LU2IN003", Kind: "TD", Part: 1}
+	if _, err := pb.AddCourseNote(ctx, "alice", missing, "hello"); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+	if _, err := pb.ListCourseNotes(ctx, missing); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+func TestPackNotes(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.Insert(course)
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+
+	note, err := pb.AddPackNote(ctx, "alice", pack.ID, "sold out until Monday")
+	if err != nil {
+		t.Fatalf("failed to add note: %v", err)
+	}
+	if note.PackID == nil || *note.PackID != pack.ID || note.Course != nil {
+		t.Errorf("got %+v", note)
+	}
+
+	if notes, _ := pb.ListCourseNotes(ctx, course.CID()); len(notes) != 0 {
+		t.Errorf("got %+v on the course of the pack", notes)
+	}
+	if _, err := pb.AddPackNote(ctx, "alice", 42, "hello"); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+
+	if err := pb.DeletePack(ctx, "alice", pack.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if _, err := pb.GetNote(ctx, note.ID); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v for a note on a trashed pack, want not found", err)
+	}
+	if _, err := pb.RestorePack(ctx, "alice", pack.ID); err != nil {
+		t.Fatalf("failed to restore pack: %v", err)
+	}
+	if notes, _ := pb.ListPackNotes(ctx, pack.ID); len(notes) != 1 {
+		t.Errorf("got %+v after restoring the pack", notes)
+	}
+
+	if err := pb.DeletePack(ctx, "alice", pack.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if _, err := pb.PurgeTrash(ctx, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+}
+
+// Notes follow their course when it is renamed or merged into another part,
+// and go away with it when it is purged
+func TestNotesFollowCourse(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	first := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 2, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	second := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 2, Parts: 2, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.InsertMany([]libpolybase.Course{first, second})
+
+	if _, err := pb.AddCourseNote(ctx, "alice", first.CID(), "first part"); err != nil {
+		t.Fatalf("failed to add note: %v", err)
+	}
+	if _, err := pb.AddCourseNote(ctx, "alice", second.CID(), "second part"); err != nil {
+		t.Fatalf("failed to add note: %v", err)
+	}
+
+	merged, err := pb.MergeParts(ctx, "alice", first.CID(), 2)
+	if err != nil {
+		t.Fatalf("failed to merge parts: %v", err)
+	}
+
+	code := "LU2IN012"
+	renamed, err := pb.UpdateCourse(ctx, "alice", merged.CID(), libpolybase.PartialCourse{Code: &code})
+	if err != nil {
+		t.Fatalf("failed to rename course: %v", err)
+	}
+	notes, err := pb.ListCourseNotes(ctx, renamed.CID())
+	if err != nil {
+		t.Fatalf("failed to list notes: %v", err)
+	}
+	if got := noteBodies(notes); len(got) != 2 || got[0] != "second part" {
+		t.Errorf("got %q after merging and renaming", got)
+	}
+
+	if err := pb.DeleteCourse(ctx, "alice", renamed.CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+	if _, err := pb.PurgeTrash(ctx, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM notes").Scan(&count); err != nil {
+		t.Fatalf("failed to count notes: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("%d notes left after purging their course", count)
+	}
+}
@@ -5,3 +5,373 @@ package tests
 // delete part from multi-part
 // parts synchronization
 // invalid parts configurations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func partQuantities(t *testing.T, pb libpolybase.Polybase, code string, kind string) []int {
+	t.Helper()
+	page, err := pb.ListCourses(context.Background(), libpolybase.CourseFilter{Code: &code, Kinds: []string{kind}})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	var quantities []int
+	for _, course := range page.Items {
+		if course.Parts != len(page.Items) {
+			t.Errorf("%s has %d parts, want %d", course.ID(), course.Parts, len(page.Items))
+		}
+		quantities = append(quantities, course.Quantity)
+	}
+	return quantities
+}
+
+// Inserting a part moves the following ones up, pack memberships and
+// history included
+func TestInsertPart(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 2, Name: "Algo", Quantity: 1, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 2, Parts: 2, Name: "Algo", Quantity: 2, Total: 50, Shown: true, Semester: "S1"},
+	})
+	pack, err := pb.CreatePack(ctx, "alice", "L2", packCourses(libpolybase.NewCourseID("LU2IN002", "TD", 2)))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+
+	inserted, err := pb.InsertPart(ctx, "alice", libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 2, Name: "Algo", Quantity: 5, Total: 50, Semester: "S1"})
+	if err != nil {
+		t.Fatalf("failed to insert part: %v", err)
+	}
+	if inserted.Part != 2 || inserted.Parts != 3 || inserted.Quantity != 5 {
+		t.Errorf("got %+v", inserted)
+	}
+	if got := partQuantities(t, pb, "LU2IN002", "TD"); !slices.Equal(got, []int{1, 5, 2}) {
+		t.Errorf("got %v", got)
+	}
+	db.AssertCourseInPack(pack.ID, libpolybase.NewCourseID("LU2IN002", "TD", 3))
+	db.AssertCourseNotInPack(pack.ID, libpolybase.NewCourseID("LU2IN002", "TD", 2))
+
+	entityID := "LU2IN002/TD/3"
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityID: &entityID})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) == 0 || events[0].Action != libpolybase.ActionUpdate {
+		t.Errorf("got %+v", events)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.InsertPart(ctx, "alice", libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 5, Name: "Algo", Quantity: 5, Total: 50, Semester: "S1"}); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
+
+// Splitting a course takes copies from it for the new part, which joins the
+// packs of the course
+func TestSplitCourse(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 2, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 2, Parts: 2, Name: "Algo", Quantity: 2, Total: 50, Shown: true, Semester: "S1"},
+	})
+	id := libpolybase.NewCourseID("LU2IN002", "TD", 1)
+	pack, err := pb.CreatePack(ctx, "alice", "L2", packCourses(id))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if _, err := pb.AddEdition(ctx, "alice", id, libpolybase.Edition{Label: "v2", Quantity: 5}); err != nil {
+		t.Fatalf("failed to add edition: %v", err)
+	}
+
+	parts, err := pb.SplitCourse(ctx, "alice", id, 12)
+	if err != nil {
+		t.Fatalf("failed to split course: %v", err)
+	}
+	if len(parts) != 2 || parts[0].Quantity != 3 || parts[1].Part != 2 || parts[1].Quantity != 12 || parts[1].Name != "Algo" {
+		t.Errorf("got %+v", parts)
+	}
+	if got := partQuantities(t, pb, "LU2IN002", "TD"); !slices.Equal(got, []int{3, 12, 2}) {
+		t.Errorf("got %v", got)
+	}
+	if got := editionQuantities(t, pb, id); !slices.Equal(got, []int{0, 3}) {
+		t.Errorf("got editions %v", got)
+	}
+	db.AssertCourseInPack(pack.ID, id)
+	db.AssertCourseInPack(pack.ID, libpolybase.NewCourseID("LU2IN002", "TD", 2))
+
+	reason := libpolybase.ReasonTransfer
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 2 || movements[0].Delta+movements[1].Delta != 0 {
+		t.Errorf("got %+v", movements)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.SplitCourse(ctx, "alice", id, 4); !errors.As(err, &validation) {
+		t.Errorf("got %v, want a validation error", err)
+	}
+}
+
+// Merging a part hands its stock and pack memberships over to the course and
+// closes the gap it leaves
+func TestMergeParts(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 3, Name: "Algo", Quantity: 1, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 2, Parts: 3, Name: "Algo", Quantity: 2, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 3, Parts: 3, Name: "Algo", Quantity: 4, Total: 50, Shown: true, Semester: "S1"},
+	})
+	first := libpolybase.NewCourseID("LU2IN002", "TD", 1)
+	second := libpolybase.NewCourseID("LU2IN002", "TD", 2)
+	both, err := pb.CreatePack(ctx, "alice", "L2", packCourses(first, second))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	only, err := pb.CreatePack(ctx, "alice", "L2 bis", packCourses(second))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+
+	merged, err := pb.MergeParts(ctx, "alice", first, 2)
+	if err != nil {
+		t.Fatalf("failed to merge parts: %v", err)
+	}
+	if merged.Quantity != 3 || merged.Total != 100 || merged.Parts != 2 {
+		t.Errorf("got %+v", merged)
+	}
+	if got := partQuantities(t, pb, "LU2IN002", "TD"); !slices.Equal(got, []int{3, 4}) {
+		t.Errorf("got %v", got)
+	}
+	if got := db.CountPackCourses(both.ID); got != 1 {
+		t.Errorf("pack has %d courses, want 1", got)
+	}
+	db.AssertCourseInPack(both.ID, first)
+	db.AssertCourseInPack(only.ID, first)
+	db.AssertCourseNotInPack(only.ID, second)
+
+	// The course records the stock it took over, after the stock handed over
+	// by the merged part
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &first})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 2 || movements[0].Delta != 2 || movements[0].Quantity != 3 ||
+		movements[1].Delta != -2 || movements[1].MergedFrom != second.ID() {
+		t.Errorf("got %+v", movements)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	for _, event := range events {
+		if event.Action == libpolybase.ActionMerge {
+			if _, err := pb.RevertChange(ctx, "alice", event.ID); !errors.Is(err, libpolybase.ErrConflict) {
+				t.Errorf("got %v, want a conflict", err)
+			}
+		}
+	}
+
+	if _, err := pb.MergeParts(ctx, "alice", first, 5); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+// A merged part hands its ledger and audit events over to the course it is
+// merged into, marked with its ID, and leaves none behind for the parts
+// renumbered after it
+func TestMergePartsHistory(t *testing.T) {
+	type entry struct {
+		Delta      int
+		Quantity   int
+		MergedFrom string
+	}
+
+	tests := []struct {
+		name    string
+		into    int
+		part    int
+		merged  []entry
+		renamed []entry
+	}{
+		{
+			name: "higher into lower",
+			into: 1,
+			part: 2,
+			merged: []entry{
+				{3, 8, ""},
+				{-3, 0, "LU2IN002/TD/2"},
+				{3, 3, "LU2IN002/TD/2"},
+				{5, 5, ""},
+			},
+			renamed: []entry{{7, 7, ""}},
+		},
+		{
+			name: "lower into higher",
+			into: 2,
+			part: 1,
+			merged: []entry{
+				{5, 8, ""},
+				{-5, 0, "LU2IN002/TD/1"},
+				{3, 3, ""},
+				{5, 5, "LU2IN002/TD/1"},
+			},
+			renamed: []entry{{7, 7, ""}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDB(t)
+			pb := libpolybase.New(db.DB, "", false)
+			ctx := context.Background()
+
+			for i, quantity := range []int{5, 3, 7} {
+				course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: i + 1, Name: "Algo", Quantity: quantity, Total: 50, Semester: "S1"}
+				if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+					t.Fatalf("failed to create course: %v", err)
+				}
+			}
+
+			merged, err := pb.MergeParts(ctx, "alice", libpolybase.NewCourseID("LU2IN002", "TD", tt.into), tt.part)
+			if err != nil {
+				t.Fatalf("failed to merge parts: %v", err)
+			}
+			if merged.Part != 1 || merged.Quantity != 8 {
+				t.Errorf("got %+v, want part 1 with 8 copies", merged)
+			}
+
+			ledger := func(part int) []entry {
+				t.Helper()
+				id := libpolybase.NewCourseID("LU2IN002", "TD", part)
+				movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Course: &id})
+				if err != nil {
+					t.Fatalf("failed to list movements: %v", err)
+				}
+				var got []entry
+				for _, m := range movements {
+					got = append(got, entry{m.Delta, m.Quantity, m.MergedFrom})
+				}
+				return got
+			}
+			if got := ledger(1); !slices.Equal(got, tt.merged) {
+				t.Errorf("got ledger %v for the merged course, want %v", got, tt.merged)
+			}
+			if got := ledger(2); !slices.Equal(got, tt.renamed) {
+				t.Errorf("got ledger %v for the renumbered part, want %v", got, tt.renamed)
+			}
+			if got := ledger(3); len(got) != 0 {
+				t.Errorf("got ledger %v for the former last part, want none", got)
+			}
+
+			history := func(part int) []libpolybase.AuditEvent {
+				t.Helper()
+				entityID := libpolybase.NewCourseID("LU2IN002", "TD", part).ID()
+				events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityID: &entityID})
+				if err != nil {
+					t.Fatalf("failed to list audit events: %v", err)
+				}
+				return events
+			}
+
+			var creations []libpolybase.AuditEvent
+			for _, event := range history(1) {
+				if event.Action == libpolybase.ActionCreate {
+					creations = append(creations, event)
+				}
+			}
+			if len(creations) != 2 {
+				t.Fatalf("got %+v, want the creations of both merged parts", creations)
+			}
+			for _, event := range creations {
+				fromPart := event.MergedFrom == libpolybase.NewCourseID("LU2IN002", "TD", tt.part).ID()
+				if !fromPart && event.MergedFrom != "" {
+					t.Errorf("got an event merged from %s", event.MergedFrom)
+				}
+				if !fromPart {
+					continue
+				}
+				if _, err := pb.RevertChange(ctx, "alice", event.ID); !errors.Is(err, libpolybase.ErrConflict) {
+					t.Errorf("got %v reverting the creation of the merged part, want a conflict", err)
+				}
+			}
+
+			for _, event := range history(2) {
+				if event.MergedFrom != "" {
+					t.Errorf("got %+v on the renumbered part, want none merged", event)
+				}
+				if event.Action == libpolybase.ActionCreate {
+					var created libpolybase.Course
+					if err := json.Unmarshal(event.After, &created); err != nil {
+						t.Fatalf("failed to decode snapshot: %v", err)
+					}
+					if created.Quantity != 7 {
+						t.Errorf("got the creation of %+v on the renumbered part, want its own", created)
+					}
+				}
+			}
+			if events := history(3); len(events) != 0 {
+				t.Errorf("got %+v for the former last part, want none", events)
+			}
+		})
+	}
+}
+
+// Renumbering closes the gaps left by deleted parts, and refuses to take the
+// place of a trashed one
+func TestRenumberParts(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 4, Name: "Algo", Quantity: 1, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 2, Parts: 4, Name: "Algo", Quantity: 2, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 3, Parts: 4, Name: "Algo", Quantity: 3, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "LU2IN002", Kind: "TD", Part: 4, Parts: 4, Name: "Algo", Quantity: 4, Total: 50, Shown: true, Semester: "S1"},
+	})
+
+	if err := pb.DeleteCourse(ctx, "alice", libpolybase.NewCourseID("LU2IN002", "TD", 2)); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+	if _, err := pb.RenumberParts(ctx, "alice", "LU2IN002", "TD"); !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Errorf("got %v, want already exists", err)
+	}
+
+	if _, err := pb.PurgeTrash(ctx, "alice", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	parts, err := pb.RenumberParts(ctx, "alice", "LU2IN002", "TD")
+	if err != nil {
+		t.Fatalf("failed to renumber parts: %v", err)
+	}
+	if len(parts) != 3 || parts[1].Quantity != 3 || parts[2].Part != 3 || parts[2].Parts != 3 {
+		t.Errorf("got %+v", parts)
+	}
+	if got := partQuantities(t, pb, "LU2IN002", "TD"); !slices.Equal(got, []int{1, 3, 4}) {
+		t.Errorf("got %v", got)
+	}
+
+	if _, err := pb.RenumberParts(ctx, "alice", "LU2IN003", "TD"); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
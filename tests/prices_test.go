@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// A course is sold at the latest price in effect, scheduled prices waiting
+// for their date
+func TestCoursePriceHistory(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 50, Semester: "S1", Price: 350})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	nextYear := time.Now().AddDate(1, 0, 0).Format(time.DateOnly)
+	scheduled, err := pb.SetCoursePrice(ctx, "alice", course.CID(), 400, nextYear)
+	if err != nil {
+		t.Fatalf("failed to schedule price: %v", err)
+	}
+	if scheduled.Current {
+		t.Errorf("scheduled price is current")
+	}
+	if got, _ := pb.GetCourse(ctx, course.CID()); got.Price != 350 {
+		t.Errorf("got price %s, want 3.50 until the scheduled price takes effect", got.Price)
+	}
+
+	price := libpolybase.Price(300)
+	updated, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Price: &price})
+	if err != nil {
+		t.Fatalf("failed to update price: %v", err)
+	}
+	if updated.Price != 300 {
+		t.Errorf("got price %s, want 3.00", updated.Price)
+	}
+
+	history, err := pb.ListCoursePrices(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to list prices: %v", err)
+	}
+	if len(history) != 3 || *history[0].Price != 400 || *history[1].Price != 300 || *history[2].Price != 350 {
+		t.Fatalf("got %+v, want the scheduled, updated and initial prices", history)
+	}
+	if history[0].Current || !history[1].Current || history[2].Current {
+		t.Errorf("got %+v, want the updated price current", history)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.SetCoursePrice(ctx, "alice", course.CID(), -1, ""); !errors.As(err, &validation) {
+		t.Errorf("got %v for a negative price, want a validation error", err)
+	}
+	if _, err := pb.SetCoursePrice(ctx, "alice", course.CID(), 300, "01/09/2026"); !errors.As(err, &validation) {
+		t.Errorf("got %v for a malformed date, want a validation error", err)
+	}
+
+	// The history follows the course when it is renamed
+	code := "LU2IN012"
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Code: &code}); err != nil {
+		t.Fatalf("failed to rename course: %v", err)
+	}
+	history, err = pb.ListCoursePrices(ctx, libpolybase.NewCourseID(code, "TD", 1))
+	if err != nil {
+		t.Fatalf("failed to list prices: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("got %d prices after renaming, want 3", len(history))
+	}
+
+	var revert *libpolybase.RevertConflict
+	entity := libpolybase.EntityPrice
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{EntityType: &entity})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d price events, want the scheduled price", len(events))
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); !errors.As(err, &revert) {
+		t.Errorf("got %v reverting a price change, want a revert conflict", err)
+	}
+}
+
+// A pack costs its fixed price, or the prices of its courses less its discount
+func TestPackPricing(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses, err := pb.CreateCourses(ctx, "alice", []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 50, Semester: "S1", Price: 350},
+		{Code: "LU2IN003", Kind: "TD", Part: 1, Name: "Web", Quantity: 10, Total: 50, Semester: "S1", Price: 200},
+	})
+	if err != nil {
+		t.Fatalf("failed to create courses: %v", err)
+	}
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(courses[0].CID(), courses[1].CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if pack.Price != 550 {
+		t.Errorf("got pack price %s, want the 5.50 of its courses", pack.Price)
+	}
+
+	pack, err = pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{Pricing: &libpolybase.PackPricing{Discount: 10}})
+	if err != nil {
+		t.Fatalf("failed to set discount: %v", err)
+	}
+	if pack.Price != 495 {
+		t.Errorf("got pack price %s, want 4.95", pack.Price)
+	}
+
+	fixed := libpolybase.Price(500)
+	if _, err := pb.SetPackPricing(ctx, "alice", pack.ID, libpolybase.PackPricing{Fixed: &fixed}, ""); err != nil {
+		t.Fatalf("failed to set fixed price: %v", err)
+	}
+	packs, err := pb.ListPacks(ctx)
+	if err != nil {
+		t.Fatalf("failed to list packs: %v", err)
+	}
+	if len(packs) != 1 || packs[0].Price != 500 || packs[0].Pricing.Fixed == nil {
+		t.Errorf("got %+v, want the pack at its fixed price", packs)
+	}
+
+	nextYear := time.Now().AddDate(1, 0, 0).Format(time.DateOnly)
+	if _, err := pb.SetPackPricing(ctx, "alice", pack.ID, libpolybase.PackPricing{Discount: 20}, nextYear); err != nil {
+		t.Fatalf("failed to schedule pricing: %v", err)
+	}
+	if got, _ := pb.GetPack(ctx, pack.ID); got.Price != 500 {
+		t.Errorf("got pack price %s, want 5.00 until the scheduled pricing takes effect", got.Price)
+	}
+
+	history, err := pb.ListPackPrices(ctx, pack.ID)
+	if err != nil {
+		t.Fatalf("failed to list prices: %v", err)
+	}
+	if len(history) != 3 || history[0].Discount != 20 || !history[1].Current {
+		t.Errorf("got %+v, want the scheduled, fixed and discounted pricings", history)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.SetPackPricing(ctx, "alice", pack.ID, libpolybase.PackPricing{Fixed: &fixed, Discount: 10}, ""); !errors.As(err, &validation) {
+		t.Errorf("got %v for a discounted fixed price, want a validation error", err)
+	}
+	if _, err := pb.SetPackPricing(ctx, "alice", pack.ID, libpolybase.PackPricing{Discount: 120}, ""); !errors.As(err, &validation) {
+		t.Errorf("got %v for a discount over 100%%, want a validation error", err)
+	}
+	if _, err := pb.SetPackPricing(ctx, "alice", 42, libpolybase.PackPricing{}, ""); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v for an unknown pack, want not found", err)
+	}
+}
+
+// Reverting a pack update puts its previous pricing back
+func TestRevertPackPricing(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 50, Semester: "S1", Price: 400})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	pack, err := pb.CreatePack(ctx, "alice", "Algo", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if _, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{Pricing: &libpolybase.PackPricing{Discount: 25}}); err != nil {
+		t.Fatalf("failed to set discount: %v", err)
+	}
+
+	action := libpolybase.ActionUpdate
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Action: &action, Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); err != nil {
+		t.Fatalf("failed to revert pricing: %v", err)
+	}
+
+	reverted, err := pb.GetPack(ctx, pack.ID)
+	if err != nil {
+		t.Fatalf("failed to get pack: %v", err)
+	}
+	if reverted.Price != 400 || reverted.Pricing.Discount != 0 {
+		t.Errorf("got %s with %+v, want the pack back at the price of its course", reverted.Price, reverted.Pricing)
+	}
+}
@@ -2,6 +2,8 @@ package tests
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math"
 	"testing"
 
@@ -84,13 +86,13 @@ func TestUpdatePackQuantityIncrease(t *testing.T) {
 			}
 
 			// Create fresh pack
-			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", courseIDs)
+			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", packCourses(courseIDs...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
 
 			// Attempt quantity update
-			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta)
+			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta, libpolybase.PolicyStrict)
 
 			if tt.wantError {
 				if err == nil {
@@ -195,12 +197,12 @@ func TestUpdatePackQuantityDecrease(t *testing.T) {
 				db.Insert(course)
 			}
 			// Create fresh pack
-			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", courseIDs)
+			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", packCourses(courseIDs...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
 			// Attempt quantity update
-			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta)
+			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta, libpolybase.PolicyClamp)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -299,12 +301,12 @@ func TestUpdatePackQuantityCourseLimits(t *testing.T) {
 				db.Insert(course)
 			}
 			// Create fresh pack
-			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", courseIDs)
+			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", packCourses(courseIDs...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
 			// Attempt quantity update
-			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta)
+			_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta, libpolybase.PolicyStrict)
 			if tt.wantError {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -433,7 +435,7 @@ func TestUpdatePackQuantityEdgeCases(t *testing.T) {
 				db.Insert(course)
 			}
 			// Create fresh pack
-			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", courseIDs)
+			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", packCourses(courseIDs...))
 			if err != nil {
 				t.Fatalf("failed to create pack: %v", err)
 			}
@@ -441,23 +443,23 @@ func TestUpdatePackQuantityEdgeCases(t *testing.T) {
 			// For sequential update test, do multiple updates
 			if tt.name == "sequential negative updates work properly" {
 				// First update
-				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5)
+				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5, libpolybase.PolicyClamp)
 				if err != nil {
 					t.Fatalf("unexpected error on first update: %v", err)
 				}
 				// Second update
-				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5)
+				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5, libpolybase.PolicyClamp)
 				if err != nil {
 					t.Fatalf("unexpected error on second update: %v", err)
 				}
 				// Third update
-				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5)
+				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, -5, libpolybase.PolicyClamp)
 				if err != nil {
 					t.Fatalf("unexpected error on third update: %v", err)
 				}
 			} else {
 				// Single update for other tests
-				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta)
+				_, err = pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta, libpolybase.PolicyClamp)
 			}
 
 			if tt.wantError {
@@ -527,7 +529,7 @@ func TestUpdatePackQuantityNonExistent(t *testing.T) {
 			name: "deleted pack",
 			setupFunc: func(t *testing.T) int {
 				// Create and then delete a pack
-				pack, err := pb.CreatePack(ctx, "testuser", "Pack to Delete", courseIDs)
+				pack, err := pb.CreatePack(ctx, "testuser", "Pack to Delete", packCourses(courseIDs...))
 				if err != nil {
 					t.Fatalf("failed to create pack: %v", err)
 				}
@@ -575,7 +577,7 @@ func TestUpdatePackQuantityNonExistent(t *testing.T) {
 			}
 
 			// Attempt quantity update on non-existent pack
-			_, err := pb.UpdatePackQuantity(ctx, "testuser", packID, tt.delta)
+			_, err := pb.UpdatePackQuantity(ctx, "testuser", packID, tt.delta, libpolybase.PolicyStrict)
 			if err == nil {
 				t.Error("expected error updating non-existent pack, got nil")
 			}
@@ -595,3 +597,122 @@ func TestUpdatePackQuantityNonExistent(t *testing.T) {
 		})
 	}
 }
+
+// Each policy reports what every course of the pack took and what it could not
+func TestUpdatePackQuantityPolicies(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+	courses := []libpolybase.Course{
+		{Code: "UL1IN001", Kind: "Lecture", Part: 1, Parts: 1, Name: "Programming I", Quantity: 2, Total: 50, Shown: true, Semester: "S1"},
+		{Code: "UL1IN002", Kind: "Lab", Part: 1, Parts: 1, Name: "Programming Lab", Quantity: 15, Total: 30, Shown: true, Semester: "S1"},
+	}
+	courseIDs := []libpolybase.CourseID{courses[0].CID(), courses[1].CID()}
+
+	tests := []struct {
+		policy    libpolybase.PackQuantityPolicy
+		delta     int
+		applied   []int
+		quantity  []int
+		shortfall []int
+	}{
+		{policy: libpolybase.PolicyClamp, delta: -5, applied: []int{-2, -5}, quantity: []int{0, 10}, shortfall: []int{3, 0}},
+		{policy: libpolybase.PolicySkip, delta: -5, applied: []int{0, -5}, quantity: []int{2, 10}, shortfall: []int{5, 0}},
+		{policy: libpolybase.PolicyClamp, delta: 20, applied: []int{20, 15}, quantity: []int{22, 30}, shortfall: []int{0, 5}},
+		{policy: libpolybase.PolicySkip, delta: 20, applied: []int{20, 0}, quantity: []int{22, 15}, shortfall: []int{0, 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s by %d", tt.policy, tt.delta), func(t *testing.T) {
+			db.Clear()
+			db.InsertMany(courses)
+			pack, err := pb.CreatePack(ctx, "testuser", "Programming Pack", packCourses(courseIDs...))
+			if err != nil {
+				t.Fatalf("failed to create pack: %v", err)
+			}
+
+			result, err := pb.UpdatePackQuantity(ctx, "testuser", pack.ID, tt.delta, tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Pack.ID != pack.ID || result.Policy != tt.policy || len(result.Courses) != 2 {
+				t.Fatalf("got %+v, want a result for both courses", result)
+			}
+			for i, course := range result.Courses {
+				if course.Course != courseIDs[i] || course.Applied != tt.applied[i] || course.Quantity != tt.quantity[i] || course.Shortfall != tt.shortfall[i] {
+					t.Errorf("got %+v, want %d applied, quantity %d and shortfall %d", course, tt.applied[i], tt.quantity[i], tt.shortfall[i])
+				}
+				if got := db.Get(courseIDs[i]).Quantity; got != tt.quantity[i] {
+					t.Errorf("course %s quantity = %d, want %d", courseIDs[i].ID(), got, tt.quantity[i])
+				}
+			}
+		})
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := libpolybase.ParsePackQuantityPolicy("best-effort"); !errors.As(err, &validation) {
+		t.Errorf("got %v for an unknown policy, want a validation error", err)
+	}
+	if policy, err := libpolybase.ParsePackQuantityPolicy(" Clamp "); err != nil || policy != libpolybase.PolicyClamp {
+		t.Errorf("got %q (%v), want the clamp policy", policy, err)
+	}
+}
+
+// A course taken several times by a pack counts once per copy in its
+// availability, its price and its quantity updates
+func TestPackCourseCopies(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses, err := pb.CreateCourses(ctx, "alice", []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 7, Total: 50, Semester: "S1", Price: 200},
+		{Code: "LU2IN003", Kind: "TD", Part: 1, Name: "Web", Quantity: 10, Total: 50, Semester: "S1", Price: 100},
+	})
+	if err != nil {
+		t.Fatalf("failed to create courses: %v", err)
+	}
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", []libpolybase.PackCourse{
+		{CourseID: courses[0].CID(), Count: 2},
+		{CourseID: courses[1].CID(), Count: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if pack.Price != 500 {
+		t.Errorf("got pack price %s, want 5.00 for two copies of Algo and one of Web", pack.Price)
+	}
+	if pack.Availability.Complete != 3 {
+		t.Errorf("got %d complete packs, want 3 from 7 copies of Algo", pack.Availability.Complete)
+	}
+	db.AssertPackEqual(pack.ID, pack)
+
+	result, err := pb.UpdatePackQuantity(ctx, "alice", pack.ID, -2, libpolybase.PolicyClamp)
+	if err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+	if result.Courses[0].Applied != -4 || result.Courses[0].Quantity != 3 || result.Courses[1].Applied != -2 {
+		t.Errorf("got %+v, want two copies of Algo taken per pack", result.Courses)
+	}
+	if result.Pack.Availability.Complete != 1 {
+		t.Errorf("got %d complete packs, want 1", result.Pack.Availability.Complete)
+	}
+
+	result, err = pb.UpdatePackQuantity(ctx, "alice", pack.ID, -2, libpolybase.PolicyClamp)
+	if err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+	if result.Courses[0].Applied != -3 || result.Courses[0].Shortfall != 1 {
+		t.Errorf("got %+v, want the last 3 copies of Algo taken and 1 missing", result.Courses[0])
+	}
+	if got := result.Pack.Availability; got.Complete != 0 || len(got.OutOfStock) != 1 || got.OutOfStock[0] != courses[0].CID() {
+		t.Errorf("got %+v, want the pack short of Algo", got)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{
+		Courses: &[]libpolybase.PackCourse{{CourseID: courses[0].CID(), Count: 0}},
+	}); !errors.As(err, &validation) {
+		t.Errorf("got %v for a course with no copy, want a validation error", err)
+	}
+}
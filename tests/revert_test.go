@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func lastChange(t *testing.T, pb *libpolybase.PB) libpolybase.AuditEvent {
+	t.Helper()
+	events, err := pb.ListAuditEvents(context.Background(), libpolybase.AuditFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	return events[0]
+}
+
+// Reverting a quantity change restores the quantity and records a correction
+func TestRevertQuantity(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	change := lastChange(t, pb)
+
+	revert, err := pb.RevertChange(ctx, "alice", change.ID)
+	if err != nil {
+		t.Fatalf("failed to revert change: %v", err)
+	}
+	if revert.Action != libpolybase.ActionQuantity || revert.Actor != "alice" {
+		t.Errorf("got revert event %+v", revert)
+	}
+
+	if got := db.Get(course.CID()); got.Quantity != 10 {
+		t.Errorf("got quantity %d, want 10", got.Quantity)
+	}
+
+	reason := libpolybase.ReasonCorrection
+	movements, err := pb.ListMovements(ctx, libpolybase.MovementFilter{Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Delta != 1 {
+		t.Errorf("got movements %+v, want a single correction of +1", movements)
+	}
+
+	// A change is reverted only once
+	_, err = pb.RevertChange(ctx, "alice", change.ID)
+	var conflict *libpolybase.RevertConflict
+	if !errors.As(err, &conflict) {
+		t.Errorf("got error %v, want RevertConflict", err)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if events[1].RevertedBy == nil || *events[1].RevertedBy != revert.ID {
+		t.Errorf("got reverted by %v, want %d", events[1].RevertedBy, revert.ID)
+	}
+}
+
+// A change is not reverted over a later change of the same course
+func TestRevertConflict(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Name: stringPtr("Algorithmique")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	change := lastChange(t, pb)
+
+	if _, err := pb.UpdateCourseQuantity(ctx, "bob", course.CID(), -1); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+
+	_, err := pb.RevertChange(ctx, "alice", change.ID)
+	var conflict *libpolybase.RevertConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got error %v, want RevertConflict", err)
+	}
+
+	got := db.Get(course.CID())
+	if got.Name != "Algorithmique" || got.Quantity != 9 {
+		t.Errorf("got course %+v, want it untouched", got)
+	}
+}
+
+// Reverting an edit restores the previous ID and its references
+func TestRevertRename(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{
+		Code:     stringPtr("LU2IN003"),
+		Quantity: intPtr(12),
+	}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+
+	if _, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID); err != nil {
+		t.Fatalf("failed to revert change: %v", err)
+	}
+
+	db.AssertCourseEqual(course.CID(), course)
+	db.AssertNotExists(libpolybase.CourseID{Code: "LU2IN003", Kind: "TD", Part: 1})
+	db.AssertPackEqual(1, libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+}
+
+// Reverting a creation deletes the course, reverting that restores it
+func TestRevertCreateAndDelete(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	deletion, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID)
+	if err != nil {
+		t.Fatalf("failed to revert creation: %v", err)
+	}
+	if deletion.Action != libpolybase.ActionDelete {
+		t.Errorf("got action %s, want delete", deletion.Action)
+	}
+	db.AssertNotExists(created.CID())
+
+	if _, err := pb.RevertChange(ctx, "bob", deletion.ID); err != nil {
+		t.Fatalf("failed to revert deletion: %v", err)
+	}
+	db.AssertCourseEqual(created.CID(), created)
+}
+
+// Pack edits and distributions can be reverted
+func TestRevertPack(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 5, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"},
+	}
+	db.InsertMany(courses)
+
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(courses[0].CID(), courses[1].CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+
+	if _, err := pb.UpdatePackQuantity(ctx, "alice", pack.ID, -2, libpolybase.PolicyClamp); err != nil {
+		t.Fatalf("failed to update pack quantity: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID); err != nil {
+		t.Fatalf("failed to revert distribution: %v", err)
+	}
+	if got := db.Get(courses[0].CID()); got.Quantity != 10 {
+		t.Errorf("got quantity %d, want 10", got.Quantity)
+	}
+	if got := db.Get(courses[1].CID()); got.Quantity != 5 {
+		t.Errorf("got quantity %d, want 5", got.Quantity)
+	}
+
+	if _, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{
+		Name:    stringPtr("Pack L2 S1"),
+		Courses: packCoursesPtr(courses[0].CID()),
+	}); err != nil {
+		t.Fatalf("failed to update pack: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID); err != nil {
+		t.Fatalf("failed to revert pack edit: %v", err)
+	}
+	db.AssertPackEqual(pack.ID, libpolybase.Pack{
+		ID:      pack.ID,
+		Name:    "L2 S1",
+		Courses: packCourses(courses[0].CID(), courses[1].CID()),
+	})
+
+	if err := pb.DeletePack(ctx, "alice", pack.ID); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID); err != nil {
+		t.Fatalf("failed to revert pack deletion: %v", err)
+	}
+	db.AssertPackEqual(pack.ID, libpolybase.Pack{
+		ID:      pack.ID,
+		Name:    "L2 S1",
+		Courses: packCourses(courses[0].CID(), courses[1].CID()),
+	})
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// Every write to a course bumps its revision
+func TestCourseRevisionBump(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "testuser", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if created.Revision != 1 {
+		t.Fatalf("got revision %d, want 1", created.Revision)
+	}
+
+	updated, err := pb.UpdateCourse(ctx, "testuser", created.CID(), libpolybase.PartialCourse{Name: stringPtr("Algorithmique")})
+	if err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	if updated.Revision != 2 {
+		t.Errorf("got revision %d after update, want 2", updated.Revision)
+	}
+
+	updated, err = pb.UpdateCourseQuantity(ctx, "testuser", created.CID(), -1)
+	if err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	if updated.Revision != 3 {
+		t.Errorf("got revision %d after quantity change, want 3", updated.Revision)
+	}
+
+	updated, err = pb.UpdateCourseShown(ctx, "testuser", created.CID(), false)
+	if err != nil {
+		t.Fatalf("failed to update visibility: %v", err)
+	}
+	if updated.Revision != 4 {
+		t.Errorf("got revision %d after visibility change, want 4", updated.Revision)
+	}
+}
+
+// Updating a course against a stale revision fails without writing
+func TestCourseRevisionConflict(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	created, err := pb.CreateCourse(ctx, "testuser", libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	// First volunteer saves their edit
+	_, err = pb.UpdateCourse(ctx, "alice", created.CID(), libpolybase.PartialCourse{
+		Name:     stringPtr("Algorithmique"),
+		Revision: intPtr(created.Revision),
+	})
+	if err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+
+	// Second volunteer saves from the same stale form
+	_, err = pb.UpdateCourse(ctx, "bob", created.CID(), libpolybase.PartialCourse{
+		Name:     stringPtr("Algo avancée"),
+		Revision: intPtr(created.Revision),
+	})
+	var conflict *libpolybase.RevisionConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got error %v, want RevisionConflict", err)
+	}
+	if conflict.Expected != 1 || conflict.Current != 2 {
+		t.Errorf("got conflict %+v, want expected 1 and current 2", conflict)
+	}
+
+	got := db.Get(created.CID())
+	if got.Name != "Algorithmique" {
+		t.Errorf("got name %q, want %q", got.Name, "Algorithmique")
+	}
+}
+
+// Updating a pack against a stale revision fails without writing
+func TestPackRevisionConflict(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+
+	pack, err := pb.CreatePack(ctx, "testuser", "L2 S1", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if pack.Revision != 1 {
+		t.Fatalf("got revision %d, want 1", pack.Revision)
+	}
+
+	updated, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{
+		Name:     stringPtr("Pack L2 S1"),
+		Revision: intPtr(pack.Revision),
+	})
+	if err != nil {
+		t.Fatalf("failed to update pack: %v", err)
+	}
+	if updated.Revision != 2 {
+		t.Errorf("got revision %d after update, want 2", updated.Revision)
+	}
+
+	_, err = pb.UpdatePack(ctx, "bob", pack.ID, libpolybase.PartialPack{
+		Courses:  &[]libpolybase.PackCourse{},
+		Revision: intPtr(pack.Revision),
+	})
+	var conflict *libpolybase.RevisionConflict
+	if !errors.As(err, &conflict) {
+		t.Fatalf("got error %v, want RevisionConflict", err)
+	}
+
+	db.AssertPackEqual(pack.ID, libpolybase.Pack{
+		ID:      pack.ID,
+		Name:    "Pack L2 S1",
+		Courses: packCourses(course.CID()),
+	})
+}
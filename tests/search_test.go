@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func searchCourses() []libpolybase.Course {
+	return []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algorithmique élémentaire", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Architecture des ordinateurs", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU3IN003", Kind: "Cours", Part: 1, Parts: 1, Name: "Algorithmique", Quantity: 10, Total: 20, Shown: true, Semester: "S2"},
+		{Code: "MU4IN500", Kind: "TD", Part: 1, Parts: 1, Name: "Algorithmique avancée", Quantity: 10, Total: 20, Shown: false, Semester: "S1"},
+	}
+}
+
+// Terms match word prefixes of the code, name and kind, ignoring accents
+func TestSearchCourses(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	db.InsertMany(searchCourses())
+
+	cases := []struct {
+		name  string
+		query string
+		opts  libpolybase.SearchOptions
+		want  []string
+	}{
+		{
+			name:  "prefix of the name",
+			query: "archi",
+			want:  []string{"LU2IN005/Cours/1"},
+		},
+		{
+			name:  "accents and case",
+			query: "ELEMENTAIRE",
+			want:  []string{"LU2IN002/TD/1"},
+		},
+		{
+			name:  "code",
+			query: "lu2in005",
+			want:  []string{"LU2IN005/Cours/1"},
+		},
+		{
+			name:  "every term must match",
+			query: "algo td",
+			want:  []string{"LU2IN002/TD/1"},
+		},
+		{
+			name:  "hidden courses",
+			query: "avancée",
+			opts:  libpolybase.SearchOptions{ShowHidden: true},
+			want:  []string{"MU4IN500/TD/1"},
+		},
+		{
+			name:  "hidden courses are left out",
+			query: "avancée",
+			want:  []string{},
+		},
+		{
+			name:  "query syntax is not interpreted",
+			query: `algo" OR "archi`,
+			want:  []string{},
+		},
+		{
+			name:  "limit",
+			query: "algorithmique",
+			opts:  libpolybase.SearchOptions{Limit: 1},
+			want:  []string{"LU3IN003/Cours/1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			courses, err := pb.SearchCourses(context.Background(), tc.query, tc.opts)
+			if err != nil {
+				t.Fatalf("failed to search courses: %v", err)
+			}
+			if got := listIDs(courses); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// The index follows the writes made to the courses
+func TestSearchFollowsWrites(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Architecture", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	assertSearch(t, pb, "archi", "LU2IN005/Cours/1")
+
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Name: stringPtr("Systèmes")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	assertSearch(t, pb, "archi")
+	assertSearch(t, pb, "systemes", "LU2IN005/Cours/1")
+
+	if err := pb.DeleteCourse(ctx, "alice", course.CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+	assertSearch(t, pb, "systemes")
+
+	if _, err := pb.RestoreCourse(ctx, "alice", course.CID()); err != nil {
+		t.Fatalf("failed to restore course: %v", err)
+	}
+	assertSearch(t, pb, "systemes", "LU2IN005/Cours/1")
+}
+
+// The teacher and the description of the courses are searched as well
+func TestSearchCourseDetails(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN003", Kind: "TD", Part: 1, Name: "Algorithmique", Quantity: 10, Total: 20, Semester: "S1",
+		Teacher: "Mme Durand", Description: "Graphes et arbres couvrants",
+	}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	assertSearch(t, pb, "durand", "LU2IN003/TD/1")
+	assertSearch(t, pb, "couvrant", "LU2IN003/TD/1")
+
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Teacher: stringPtr("M. Lefèvre")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	assertSearch(t, pb, "durand")
+	assertSearch(t, pb, "lefevre", "LU2IN003/TD/1")
+
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Description: stringPtr("Programmation dynamique")}); err != nil {
+		t.Fatalf("failed to update course: %v", err)
+	}
+	assertSearch(t, pb, "couvrant")
+	assertSearch(t, pb, "dynamique", "LU2IN003/TD/1")
+}
+
+// An empty query is refused
+func TestSearchEmptyQuery(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+
+	_, err := pb.SearchCourses(context.Background(), " - ", libpolybase.SearchOptions{})
+	var validation *libpolybase.ValidationError
+	if !errors.As(err, &validation) || validation.Field != "query" {
+		t.Errorf("got error %v, want a validation error on query", err)
+	}
+}
+
+func assertSearch(t *testing.T, pb *libpolybase.PB, query string, want ...string) {
+	t.Helper()
+
+	courses, err := pb.SearchCourses(context.Background(), query, libpolybase.SearchOptions{})
+	if err != nil {
+		t.Fatalf("failed to search %q: %v", query, err)
+	}
+	if got := listIDs(courses); !slices.Equal(got, want) {
+		t.Errorf("search %q got %v, want %v", query, got, want)
+	}
+}
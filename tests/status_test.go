@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// A course moves through its lifecycle along the allowed transitions only,
+// its visibility following its status
+func TestCourseStatus(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course, err := pb.CreateCourse(ctx, "alice", libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 50, Semester: "S1", Status: libpolybase.StatusDraft})
+	if err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if course.Status != libpolybase.StatusDraft || course.Shown {
+		t.Errorf("got %s (shown %v), want a hidden draft", course.Status, course.Shown)
+	}
+
+	course, err = pb.SetCourseStatus(ctx, "alice", course.CID(), "awaiting-print")
+	if err != nil {
+		t.Fatalf("failed to set status: %v", err)
+	}
+	if course.Status != libpolybase.StatusAwaitingPrint || course.Shown {
+		t.Errorf("got %s (shown %v), want a hidden course awaiting print", course.Status, course.Shown)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.SetCourseStatus(ctx, "alice", course.CID(), libpolybase.StatusOutOfStock); !errors.As(err, &validation) {
+		t.Errorf("got %v for a course out of stock before being available, want a validation error", err)
+	}
+	if _, err := pb.SetCourseStatus(ctx, "alice", course.CID(), "sold"); !errors.As(err, &validation) {
+		t.Errorf("got %v for an unknown status, want a validation error", err)
+	}
+
+	for _, status := range []libpolybase.CourseStatus{libpolybase.StatusAvailable, libpolybase.StatusOutOfStock} {
+		if course, err = pb.SetCourseStatus(ctx, "alice", course.CID(), status); err != nil {
+			t.Fatalf("failed to set status %s: %v", status, err)
+		}
+		if !course.Shown {
+			t.Errorf("course %s not shown", status)
+		}
+	}
+
+	status := libpolybase.StatusDiscontinued
+	course, err = pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Status: &status})
+	if err != nil {
+		t.Fatalf("failed to update status: %v", err)
+	}
+	if course.Shown {
+		t.Errorf("discontinued course shown")
+	}
+	status = libpolybase.StatusAvailable
+	if _, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Status: &status}); !errors.As(err, &validation) {
+		t.Errorf("got %v for a discontinued course made available, want a validation error", err)
+	}
+
+	action := libpolybase.ActionStatus
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Action: &action})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if len(events) != 3 {
+		t.Errorf("got %d status changes in the history, want 3", len(events))
+	}
+}
+
+// Showing and hiding a course still works, through the statuses
+func TestCourseShownCompatibility(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 0, Total: 50, Shown: true, Status: libpolybase.StatusOutOfStock, Semester: "S1"}
+	db.Insert(course)
+
+	shown, err := pb.UpdateCourseShown(ctx, "alice", course.CID(), true)
+	if err != nil {
+		t.Fatalf("failed to show course: %v", err)
+	}
+	if shown.Status != libpolybase.StatusOutOfStock || shown.Revision != 1 {
+		t.Errorf("got %s at revision %d, want the course left out of stock", shown.Status, shown.Revision)
+	}
+
+	hidden, err := pb.UpdateCourseShown(ctx, "alice", course.CID(), false)
+	if err != nil {
+		t.Fatalf("failed to hide course: %v", err)
+	}
+	if hidden.Status != libpolybase.StatusDraft || hidden.Shown {
+		t.Errorf("got %s (shown %v), want a hidden draft", hidden.Status, hidden.Shown)
+	}
+
+	visible := true
+	shown, err = pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Shown: &visible})
+	if err != nil {
+		t.Fatalf("failed to show course: %v", err)
+	}
+	if shown.Status != libpolybase.StatusAvailable || !shown.Shown {
+		t.Errorf("got %s (shown %v), want an available course", shown.Status, shown.Shown)
+	}
+}
+
+func TestListCoursesByStatus(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.InsertMany([]libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Status: libpolybase.StatusAvailable, Semester: "S1"},
+		{Code: "LU2IN003", Kind: "TD", Part: 1, Parts: 1, Name: "Web", Quantity: 0, Total: 50, Shown: true, Status: libpolybase.StatusOutOfStock, Semester: "S1"},
+		{Code: "LU2IN004", Kind: "TD", Part: 1, Parts: 1, Name: "Réseau", Quantity: 0, Total: 50, Status: libpolybase.StatusAwaitingPrint, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "TD", Part: 1, Parts: 1, Name: "Système", Quantity: 0, Total: 50, Status: libpolybase.StatusDiscontinued, Semester: "S1"},
+	})
+
+	public, err := pb.ListCourses(ctx, libpolybase.CourseFilter{})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(public.Items) != 2 {
+		t.Errorf("got %d public courses, want the available and out of stock ones", len(public.Items))
+	}
+
+	page, err := pb.ListCourses(ctx, libpolybase.CourseFilter{ShowHidden: true, Statuses: []libpolybase.CourseStatus{"out_of_stock", "awaiting_print"}})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 2 || page.Items[0].Code != "LU2IN003" || page.Items[1].Code != "LU2IN004" {
+		t.Errorf("got %+v, want the courses out of stock or awaiting print", page.Items)
+	}
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.ListCourses(ctx, libpolybase.CourseFilter{Statuses: []libpolybase.CourseStatus{"sold"}}); !errors.As(err, &validation) {
+		t.Errorf("got %v for an unknown status, want a validation error", err)
+	}
+}
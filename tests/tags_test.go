@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func courseTags(t *testing.T, pb libpolybase.Polybase, id libpolybase.CourseID) []string {
+	t.Helper()
+	course, err := pb.GetCourse(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	return course.Tags
+}
+
+// Tags are trimmed, compared regardless of case and listed in order
+func TestCourseTags(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	algo := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	web := libpolybase.Course{Code: "LU2IN003", Kind: "TD", Part: 1, Parts: 1, Name: "Web", Quantity: 10, Total: 50, Shown: false, Semester: "S1"}
+	db.InsertMany([]libpolybase.Course{algo, web})
+
+	updated, err := pb.AddCourseTags(ctx, "alice", algo.CID(), []string{" parcours  MIND ", "option", "Option"})
+	if err != nil {
+		t.Fatalf("failed to add tags: %v", err)
+	}
+	if !slices.Equal(updated.Tags, []string{"option", "parcours MIND"}) {
+		t.Errorf("got %q", updated.Tags)
+	}
+	if _, err := pb.AddCourseTags(ctx, "alice", algo.CID(), []string{"OPTION"}); err != nil {
+		t.Fatalf("failed to add a tag again: %v", err)
+	}
+	if got := courseTags(t, pb, algo.CID()); !slices.Equal(got, []string{"option", "parcours MIND"}) {
+		t.Errorf("got %q after adding a tag again", got)
+	}
+
+	if _, err := pb.AddCourseTags(ctx, "alice", web.CID(), []string{"option", "à réimprimer"}); err != nil {
+		t.Fatalf("failed to add tags: %v", err)
+	}
+
+	tags, err := pb.ListTags(ctx, true)
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	want := []libpolybase.Tag{{Name: "option", Courses: 2}, {Name: "parcours MIND", Courses: 1}, {Name: "à réimprimer", Courses: 1}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("got %+v, want %+v", tags, want)
+	}
+	if tags, _ := pb.ListTags(ctx, false); len(tags) != 2 || tags[0].Courses != 1 {
+		t.Errorf("got %+v without the hidden courses", tags)
+	}
+
+	page, err := pb.ListCourses(ctx, libpolybase.CourseFilter{ShowHidden: true, Tags: []string{"Option"}})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 2 {
+		t.Errorf("got %d courses tagged option, want 2", len(page.Items))
+	}
+	page, err = pb.ListCourses(ctx, libpolybase.CourseFilter{ShowHidden: true, Tags: []string{"option", "parcours MIND"}})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].Code != "LU2IN002" {
+		t.Errorf("got %+v, want the course holding both tags", page.Items)
+	}
+
+	if _, err := pb.RemoveCourseTags(ctx, "alice", algo.CID(), []string{"Parcours mind"}); err != nil {
+		t.Fatalf("failed to remove tag: %v", err)
+	}
+	if got := courseTags(t, pb, algo.CID()); !slices.Equal(got, []string{"option"}) {
+		t.Errorf("got %q after removing a tag", got)
+	}
+	if _, err := pb.RemoveCourseTags(ctx, "alice", algo.CID(), []string{"parcours MIND"}); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+
+	var validation *libpolybase.ValidationError
+	for _, tag := range []string{" ", "a,b"} {
+		if _, err := pb.AddCourseTags(ctx, "alice", algo.CID(), []string{tag}); !errors.As(err, &validation) {
+			t.Errorf("tag %q: got %v, want a validation error", tag, err)
+		}
+	}
+}
+
+// Renaming a tag merges it into a tag courses already hold, and deleting a
+// tag takes it off every course
+func TestRenameAndDeleteTag(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	algo := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	web := libpolybase.Course{Code: "LU2IN003", Kind: "TD", Part: 1, Parts: 1, Name: "Web", Quantity: 10, Total: 50, Shown: true, Semester: "S1"}
+	db.InsertMany([]libpolybase.Course{algo, web})
+
+	if _, err := pb.AddCourseTags(ctx, "alice", algo.CID(), []string{"opt", "option"}); err != nil {
+		t.Fatalf("failed to add tags: %v", err)
+	}
+	if _, err := pb.AddCourseTags(ctx, "alice", web.CID(), []string{"opt"}); err != nil {
+		t.Fatalf("failed to add tags: %v", err)
+	}
+
+	renamed, err := pb.RenameTag(ctx, "alice", "opt", "option")
+	if err != nil {
+		t.Fatalf("failed to rename tag: %v", err)
+	}
+	if renamed != 2 {
+		t.Errorf("renamed tag on %d courses, want 2", renamed)
+	}
+	if got := courseTags(t, pb, algo.CID()); !slices.Equal(got, []string{"option"}) {
+		t.Errorf("got %q", got)
+	}
+	if got := courseTags(t, pb, web.CID()); !slices.Equal(got, []string{"option"}) {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := pb.RenameTag(ctx, "alice", "option", "Option"); err != nil {
+		t.Fatalf("failed to change the case of a tag: %v", err)
+	}
+	if got := courseTags(t, pb, web.CID()); !slices.Equal(got, []string{"Option"}) {
+		t.Errorf("got %q", got)
+	}
+
+	deleted, err := pb.DeleteTag(ctx, "alice", "option")
+	if err != nil {
+		t.Fatalf("failed to delete tag: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("deleted tag from %d courses, want 2", deleted)
+	}
+	if tags, _ := pb.ListTags(ctx, true); len(tags) != 0 {
+		t.Errorf("got %+v", tags)
+	}
+	if _, err := pb.DeleteTag(ctx, "alice", "option"); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("got %v, want not found", err)
+	}
+}
+
+// Tags follow their course when it is renamed, come back when a change is
+// reverted and are carried over to the next year
+func TestTagsFollowCourse(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Semester: "S1", Tags: []string{"option"}}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+
+	code := "LU2IN012"
+	renamed, err := pb.UpdateCourse(ctx, "alice", course.CID(), libpolybase.PartialCourse{Code: &code})
+	if err != nil {
+		t.Fatalf("failed to rename course: %v", err)
+	}
+	if !slices.Equal(renamed.Tags, []string{"option"}) {
+		t.Errorf("got %q after renaming", renamed.Tags)
+	}
+
+	tags := []string{"option", "double licence"}
+	if _, err := pb.UpdateCourse(ctx, "alice", renamed.CID(), libpolybase.PartialCourse{Tags: &tags}); err != nil {
+		t.Fatalf("failed to update tags: %v", err)
+	}
+	if _, err := pb.RemoveCourseTags(ctx, "alice", renamed.CID(), []string{"option"}); err != nil {
+		t.Fatalf("failed to remove tag: %v", err)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); err != nil {
+		t.Fatalf("failed to revert tag removal: %v", err)
+	}
+	if got := courseTags(t, pb, renamed.CID()); !slices.Equal(got, []string{"double licence", "option"}) {
+		t.Errorf("got %q after reverting", got)
+	}
+
+	if _, err := pb.RollOverYear(ctx, "alice", pb.Year(), pb.Year()+1, libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+	if got := courseTags(t, pb.ForYear(pb.Year()+1), renamed.CID()); !slices.Equal(got, []string{"double licence", "option"}) {
+		t.Errorf("got %q in the next year", got)
+	}
+}
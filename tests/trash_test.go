@@ -0,0 +1,290 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+// Deleted courses and packs are listed in the trash and hidden elsewhere
+func TestTrashList(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+
+	if err := pb.DeletePack(ctx, "alice", 1); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if err := pb.DeleteCourse(ctx, "bob", course.CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+
+	trash, err := pb.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if len(trash.Courses) != 1 || trash.Courses[0].Course.CID() != course.CID() || trash.Courses[0].DeletedBy != "bob" {
+		t.Errorf("got trashed courses %+v", trash.Courses)
+	}
+	if len(trash.Packs) != 1 || trash.Packs[0].DeletedBy != "alice" || len(trash.Packs[0].Pack.Courses) != 1 {
+		t.Errorf("got trashed packs %+v", trash.Packs)
+	}
+
+	courses, err := pb.ListCourses(ctx, libpolybase.CourseFilter{ShowHidden: true})
+	if err != nil {
+		t.Fatalf("failed to list courses: %v", err)
+	}
+	if len(courses.Items) != 0 {
+		t.Errorf("got %d courses, want 0", len(courses.Items))
+	}
+
+	packs, err := pb.ListPacks(ctx)
+	if err != nil {
+		t.Fatalf("failed to list packs: %v", err)
+	}
+	if len(packs) != 0 {
+		t.Errorf("got %d packs, want 0", len(packs))
+	}
+}
+
+// Restoring a course puts it back in its packs
+func TestTrashRestoreCourse(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 5, Total: 20, Shown: true, Semester: "S1"},
+	}
+	db.InsertMany(courses)
+	pack := libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(courses[0].CID(), courses[1].CID())}
+	db.InsertPack(pack)
+
+	if err := pb.DeleteCourse(ctx, "alice", courses[0].CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+
+	got, err := pb.GetPack(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get pack: %v", err)
+	}
+	if len(got.Courses) != 1 {
+		t.Errorf("got pack courses %v, want only the live course", got.Courses)
+	}
+
+	// Editing the pack meanwhile keeps the trashed membership
+	if _, err := pb.UpdatePack(ctx, "alice", 1, libpolybase.PartialPack{Name: stringPtr("Pack L2 S1")}); err != nil {
+		t.Fatalf("failed to update pack: %v", err)
+	}
+
+	restored, err := pb.RestoreCourse(ctx, "bob", courses[0].CID())
+	if err != nil {
+		t.Fatalf("failed to restore course: %v", err)
+	}
+	if restored.Name != "Algo" || restored.Quantity != 10 {
+		t.Errorf("got restored course %+v", restored)
+	}
+
+	db.AssertExists(courses[0].CID())
+	db.AssertPackEqual(1, libpolybase.Pack{ID: 1, Name: "Pack L2 S1", Courses: pack.Courses})
+
+	if _, err := pb.RestoreCourse(ctx, "bob", courses[0].CID()); err == nil {
+		t.Error("expected error when restoring a live course")
+	}
+
+	change := lastChange(t, pb)
+	if change.Action != libpolybase.ActionRestore || change.Actor != "bob" {
+		t.Errorf("got change %+v, want a restore by bob", change)
+	}
+}
+
+// Restoring a pack brings back its courses
+func TestTrashRestorePack(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	pack := libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())}
+	db.InsertPack(pack)
+
+	if err := pb.DeletePack(ctx, "alice", 1); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+	if _, err := pb.UpdatePackQuantity(ctx, "alice", 1, -1, libpolybase.PolicyClamp); err == nil {
+		t.Error("expected error when distributing a trashed pack")
+	}
+
+	restored, err := pb.RestorePack(ctx, "alice", 1)
+	if err != nil {
+		t.Fatalf("failed to restore pack: %v", err)
+	}
+	if restored.Name != pack.Name || len(restored.Courses) != 1 {
+		t.Errorf("got restored pack %+v", restored)
+	}
+	db.AssertPackEqual(1, pack)
+
+	if _, err := pb.RestorePack(ctx, "alice", 1); err == nil {
+		t.Error("expected error when restoring a live pack")
+	}
+}
+
+// Purging deletes for good what was trashed before the cutoff
+func TestTrashPurge(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 5, Total: 20, Shown: true, Semester: "S1"},
+	}
+	db.InsertMany(courses)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(courses[0].CID(), courses[1].CID())})
+
+	if err := pb.DeleteCourse(ctx, "alice", courses[0].CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+	if err := pb.DeletePack(ctx, "alice", 1); err != nil {
+		t.Fatalf("failed to delete pack: %v", err)
+	}
+
+	purged, err := pb.PurgeTrash(ctx, "alice", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if purged != 0 {
+		t.Errorf("purged %d items before the cutoff, want 0", purged)
+	}
+
+	purged, err = pb.PurgeTrash(ctx, "alice", time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("purged %d items, want 2", purged)
+	}
+
+	trash, err := pb.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if len(trash.Courses) != 0 || len(trash.Packs) != 0 {
+		t.Errorf("got trash %+v, want it empty", trash)
+	}
+	if count := db.CountPackCourses(1); count != 0 {
+		t.Errorf("pack course count after purge = %d, want 0", count)
+	}
+	db.AssertExists(courses[1].CID())
+
+	if _, err := pb.RestoreCourse(ctx, "alice", courses[0].CID()); err == nil {
+		t.Error("expected error when restoring a purged course")
+	}
+	if _, err := pb.RevertChange(ctx, "alice", lastChange(t, pb).ID); err == nil {
+		t.Error("expected error when reverting a purge")
+	}
+}
+
+// Purging covers the trash of every academic year but the read-only ones
+func TestTrashPurgeYears(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+	previous := pb.ForYear(pb.Year() - 1)
+	next := pb.ForYear(pb.Year() + 1)
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Name: "Algo", Quantity: 10, Total: 20, Semester: "S1"}
+	trashCourse := func(year libpolybase.Polybase) {
+		t.Helper()
+		if _, err := year.CreateCourse(ctx, "alice", course); err != nil {
+			t.Fatalf("failed to create course: %v", err)
+		}
+		if err := year.DeleteCourse(ctx, "alice", course.CID()); err != nil {
+			t.Fatalf("failed to delete course: %v", err)
+		}
+	}
+
+	trashCourse(previous)
+	live := course
+	live.Code = "LU2IN003"
+	if _, err := previous.CreateCourse(ctx, "alice", live); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if _, err := pb.RollOverYear(ctx, "alice", previous.Year(), pb.Year(), libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+	trashCourse(pb)
+	trashCourse(next)
+
+	purged, err := previous.PurgeTrash(ctx, "alice", time.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("failed to purge trash: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("purged %d items, want the courses of the current and next years", purged)
+	}
+
+	for _, year := range []libpolybase.Polybase{previous, pb, next} {
+		trash, err := year.ListTrash(ctx)
+		if err != nil {
+			t.Fatalf("failed to list trash: %v", err)
+		}
+		want := 0
+		if year == previous {
+			want = 1
+		}
+		if len(trash.Courses) != want {
+			t.Errorf("got %d courses in the trash of %s, want %d", len(trash.Courses), year.Year(), want)
+		}
+	}
+}
+
+// Creating a course over a trashed one is refused, the trashed course keeping
+// its pack memberships until restored or purged
+func TestTrashCreateRefuses(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{
+		Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 20, Shown: true, Semester: "S1",
+	}
+	db.Insert(course)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+
+	if err := pb.DeleteCourse(ctx, "alice", course.CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+	if _, err := pb.CreateCourse(ctx, "alice", course); !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Errorf("got %v, want already exists", err)
+	}
+	if _, err := pb.CreateCourses(ctx, "alice", []libpolybase.Course{course}); !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Errorf("got %v creating several courses, want already exists", err)
+	}
+
+	trash, err := pb.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("failed to list trash: %v", err)
+	}
+	if len(trash.Courses) != 1 {
+		t.Errorf("got trashed courses %+v, want the deleted one", trash.Courses)
+	}
+
+	if _, err := pb.RestoreCourse(ctx, "alice", course.CID()); err != nil {
+		t.Fatalf("failed to restore course: %v", err)
+	}
+	db.AssertCourseInPack(1, course.CID())
+}
@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -23,6 +24,7 @@ func TestUpdateAllFields(t *testing.T) {
 		Name:     "Operating Systems",
 		Quantity: 30,
 		Total:    50,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -55,7 +57,7 @@ func TestUpdateAllFields(t *testing.T) {
 	newTotal := 60
 	newShown := false
 	newSemester := "S2"
-	newYear := 3
+	newLevel := libpolybase.LevelL3
 
 	partial := libpolybase.PartialCourse{
 		Code:     &newCode,
@@ -91,12 +93,14 @@ func TestUpdateAllFields(t *testing.T) {
 		Name:     newName,
 		Quantity: newQuantity,
 		Total:    newTotal,
+		Status:   libpolybase.StatusDraft,
 		Shown:    newShown,
 		Semester: newSemester,
-		Year:     newYear,
+		Level:    newLevel,
+		Revision: 2,
 	}
 
-	if updated != expected {
+	if !reflect.DeepEqual(updated, expected) {
 		t.Errorf("updated course mismatch:\ngot: %+v\nwant: %+v", updated, expected)
 	}
 
@@ -134,6 +138,7 @@ func TestUpdateSingleField(t *testing.T) {
 		Name:     "Operating Systems",
 		Quantity: 30,
 		Total:    50,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -156,9 +161,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 30,
 				Total:    50,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -174,9 +180,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 30,
 				Total:    50,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -192,9 +199,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Advanced OS",
 				Quantity: 30,
 				Total:    50,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -210,9 +218,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 40,
 				Total:    50,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -228,9 +237,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 30,
 				Total:    60,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -246,9 +256,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 30,
 				Total:    50,
+				Status:   libpolybase.StatusDraft,
 				Shown:    false,
 				Semester: "S1",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 		{
@@ -264,9 +275,10 @@ func TestUpdateSingleField(t *testing.T) {
 				Name:     "Operating Systems",
 				Quantity: 30,
 				Total:    50,
+				Status:   libpolybase.StatusAvailable,
 				Shown:    true,
 				Semester: "S2",
-				Year:     3,
+				Level:    libpolybase.LevelL3,
 			},
 		},
 	}
@@ -291,7 +303,9 @@ func TestUpdateSingleField(t *testing.T) {
 				t.Fatalf("failed to update course: %v", err)
 			}
 
-			if updated != tt.want {
+			// every update bumps the revision of the created course
+			tt.want.Revision = created.Revision + 1
+			if !reflect.DeepEqual(updated, tt.want) {
 				t.Errorf("updated course mismatch:\ngot: %+v\nwant: %+v", updated, tt.want)
 			}
 
@@ -347,6 +361,7 @@ func TestUpdateWithInvalidValues(t *testing.T) {
 		Name:     "Operating Systems",
 		Quantity: 30,
 		Total:    50,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -447,6 +462,7 @@ func TestUpdateToDuplicate(t *testing.T) {
 		Name:     "Operating Systems",
 		Quantity: 30,
 		Total:    50,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -460,6 +476,7 @@ func TestUpdateToDuplicate(t *testing.T) {
 		Name:     "Algorithms",
 		Quantity: 40,
 		Total:    60,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
 	}
@@ -521,9 +538,10 @@ func TestUpdateCourseWithSameInfo(t *testing.T) {
 		Name:     "Systèmes de Gestion de Bases de Données",
 		Quantity: 8,
 		Total:    60,
+		Status:   libpolybase.StatusAvailable,
 		Shown:    true,
 		Semester: "S1",
-		Year:     3,
+		Level:    libpolybase.LevelL3,
 	}
 
 	t.Log("Creating initial course...")
@@ -543,7 +561,8 @@ func TestUpdateCourseWithSameInfo(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to get course before update: %v", err)
 	}
-	if fetchedBefore != original {
+	original.Revision = 1
+	if !reflect.DeepEqual(fetchedBefore, original) {
 		t.Errorf("initial course mismatch:\ngot: %+v\nwant: %+v", fetchedBefore, original)
 	}
 	t.Log("Course exists and matches expected state")
@@ -583,8 +602,9 @@ func TestUpdateCourseWithSameInfo(t *testing.T) {
 	}
 	t.Logf("Update successful, received: %+v", updated)
 
-	// Everything should match the original
-	if updated != original {
+	// Everything should match the original but the revision
+	original.Revision = 2
+	if !reflect.DeepEqual(updated, original) {
 		t.Errorf("updated course mismatch:\ngot: %+v\nwant: %+v", updated, original)
 	}
 
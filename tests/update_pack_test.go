@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 
@@ -55,7 +56,7 @@ func TestUpdatePackNameOnly(t *testing.T) {
 		{Code: "UL1IN002", Kind: "TME", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, courseIDs)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(courseIDs...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -82,7 +83,7 @@ func TestUpdatePackNameOnly(t *testing.T) {
 	db.AssertPackEqual(created.ID, libpolybase.Pack{
 		ID:      created.ID,
 		Name:    newName,
-		Courses: courseIDs,
+		Courses: packCourses(courseIDs...),
 	})
 
 	// Verify all courses are still in the pack
@@ -154,7 +155,7 @@ func TestUpdatePackCoursesOnly(t *testing.T) {
 		{Code: "UL1IN002", Kind: "TME", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", packName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", packName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -166,7 +167,7 @@ func TestUpdatePackCoursesOnly(t *testing.T) {
 	}
 
 	updated, err := pb.UpdatePack(ctx, "testuser", created.ID, libpolybase.PartialPack{
-		Courses: &newCourses,
+		Courses: packCoursesPtr(newCourses...),
 	})
 	if err != nil {
 		t.Fatalf("failed to update pack courses: %v", err)
@@ -185,7 +186,7 @@ func TestUpdatePackCoursesOnly(t *testing.T) {
 	db.AssertPackEqual(created.ID, libpolybase.Pack{
 		ID:      created.ID,
 		Name:    packName,
-		Courses: newCourses,
+		Courses: packCourses(newCourses...),
 	})
 
 	// Verify new course membership
@@ -264,7 +265,7 @@ func TestUpdatePackNameAndCourses(t *testing.T) {
 		{Code: "UL1IN002", Kind: "TME", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -278,7 +279,7 @@ func TestUpdatePackNameAndCourses(t *testing.T) {
 
 	updated, err := pb.UpdatePack(ctx, "testuser", created.ID, libpolybase.PartialPack{
 		Name:    &newName,
-		Courses: &newCourses,
+		Courses: packCoursesPtr(newCourses...),
 	})
 	if err != nil {
 		t.Fatalf("failed to update pack: %v", err)
@@ -297,7 +298,7 @@ func TestUpdatePackNameAndCourses(t *testing.T) {
 	db.AssertPackEqual(created.ID, libpolybase.Pack{
 		ID:      created.ID,
 		Name:    newName,
-		Courses: newCourses,
+		Courses: packCourses(newCourses...),
 	})
 
 	// Verify new course membership
@@ -383,7 +384,7 @@ func TestUpdatePackWithNoCourses(t *testing.T) {
 		{Code: "UL1IN001", Kind: "Cours", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -395,13 +396,13 @@ func TestUpdatePackWithNoCourses(t *testing.T) {
 		{
 			name: "empty course slice",
 			update: libpolybase.PartialPack{
-				Courses: &[]libpolybase.CourseID{},
+				Courses: &[]libpolybase.PackCourse{},
 			},
 		},
 		{
 			name: "nil course slice",
 			update: libpolybase.PartialPack{
-				Courses: new([]libpolybase.CourseID),
+				Courses: new([]libpolybase.PackCourse),
 			},
 		},
 	}
@@ -423,7 +424,7 @@ func TestUpdatePackWithNoCourses(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    initialName,
-				Courses: initialCourses,
+				Courses: packCourses(initialCourses...),
 			})
 
 			// Verify course relationships remain intact
@@ -472,7 +473,7 @@ func TestUpdatePackWithNonExistentCourses(t *testing.T) {
 	initialName := "Programming Pack"
 	initialCourses := []libpolybase.CourseID{existingID}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -519,7 +520,7 @@ func TestUpdatePackWithNonExistentCourses(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Attempt update with non-existent courses
 			_, err := pb.UpdatePack(ctx, "testuser", created.ID, libpolybase.PartialPack{
-				Courses: &tt.updateCourses,
+				Courses: packCoursesPtr(tt.updateCourses...),
 			})
 
 			if err == nil {
@@ -534,7 +535,7 @@ func TestUpdatePackWithNonExistentCourses(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    initialName,
-				Courses: initialCourses,
+				Courses: packCourses(initialCourses...),
 			})
 
 			// Verify original course relationship remains intact
@@ -590,7 +591,7 @@ func TestUpdatePackWithDuplicateCourses(t *testing.T) {
 		{Code: "UL1IN001", Kind: "Cours", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -628,7 +629,7 @@ func TestUpdatePackWithDuplicateCourses(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			_, err := pb.UpdatePack(ctx, "testuser", created.ID, libpolybase.PartialPack{
-				Courses: &tt.updateCourses,
+				Courses: packCoursesPtr(tt.updateCourses...),
 			})
 			if err == nil {
 				t.Fatal("expected error when updating pack with duplicate courses, got nil")
@@ -637,7 +638,7 @@ func TestUpdatePackWithDuplicateCourses(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    initialName,
-				Courses: initialCourses,
+				Courses: packCourses(initialCourses...),
 			})
 
 			for _, courseID := range initialCourses {
@@ -690,9 +691,7 @@ func TestUpdateNonExistentPack(t *testing.T) {
 			name:   "zero ID",
 			packID: 0,
 			update: libpolybase.PartialPack{
-				Courses: &[]libpolybase.CourseID{
-					{Code: "UL1IN001", Kind: "Cours", Part: 1},
-				},
+				Courses: packCoursesPtr(libpolybase.NewCourseID("UL1IN001", "Cours", 1)),
 			},
 		},
 		{
@@ -700,7 +699,7 @@ func TestUpdateNonExistentPack(t *testing.T) {
 			packID: -1,
 			update: libpolybase.PartialPack{
 				Name:    stringPtr("New Name"),
-				Courses: &[]libpolybase.CourseID{{Code: "UL1IN001", Kind: "Cours", Part: 1}},
+				Courses: packCoursesPtr(libpolybase.NewCourseID("UL1IN001", "Cours", 1)),
 			},
 		},
 	}
@@ -747,7 +746,7 @@ func TestUpdatePackWithNoChanges(t *testing.T) {
 		{Code: "UL1IN001", Kind: "Cours", Part: 1},
 	}
 
-	created, err := pb.CreatePack(ctx, "testuser", initialName, initialCourses)
+	created, err := pb.CreatePack(ctx, "testuser", initialName, packCourses(initialCourses...))
 	if err != nil {
 		t.Fatalf("failed to create pack: %v", err)
 	}
@@ -765,14 +764,14 @@ func TestUpdatePackWithNoChanges(t *testing.T) {
 		{
 			name: "same courses",
 			update: libpolybase.PartialPack{
-				Courses: &initialCourses,
+				Courses: packCoursesPtr(initialCourses...),
 			},
 		},
 		{
 			name: "same name and courses",
 			update: libpolybase.PartialPack{
 				Name:    &initialName,
-				Courses: &initialCourses,
+				Courses: packCoursesPtr(initialCourses...),
 			},
 		},
 	}
@@ -791,7 +790,7 @@ func TestUpdatePackWithNoChanges(t *testing.T) {
 			db.AssertPackEqual(created.ID, libpolybase.Pack{
 				ID:      created.ID,
 				Name:    initialName,
-				Courses: initialCourses,
+				Courses: packCourses(initialCourses...),
 			})
 
 			for _, courseID := range initialCourses {
@@ -804,3 +803,66 @@ func TestUpdatePackWithNoChanges(t *testing.T) {
 		})
 	}
 }
+
+// Published packs are listed for the public with their semester and level,
+// and a revert takes the publication back
+func TestPublishPack(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 10, Total: 50, Shown: true, Status: libpolybase.StatusAvailable, Semester: "S1"}
+	db.Insert(course)
+	hidden, err := pb.CreatePack(ctx, "alice", "Brouillon", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	pack, err := pb.CreatePack(ctx, "alice", "L2 S1", packCourses(course.CID()))
+	if err != nil {
+		t.Fatalf("failed to create pack: %v", err)
+	}
+	if pack.Shown {
+		t.Errorf("new pack shown")
+	}
+
+	shown, semester, level := true, "S1", libpolybase.Level("l2")
+	published, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{Shown: &shown, Semester: &semester, Level: &level})
+	if err != nil {
+		t.Fatalf("failed to publish pack: %v", err)
+	}
+	if !published.Shown || published.Semester != "S1" || published.Level != libpolybase.LevelL2 || published.Name != pack.Name {
+		t.Errorf("got %+v", published)
+	}
+
+	packs, err := pb.ListShownPacks(ctx)
+	if err != nil {
+		t.Fatalf("failed to list shown packs: %v", err)
+	}
+	if len(packs) != 1 || packs[0].ID != pack.ID || packs[0].Level != libpolybase.LevelL2 {
+		t.Errorf("got %+v, want the published pack only", packs)
+	}
+	if all, _ := pb.ListPacks(ctx); len(all) != 2 || all[0].ID != hidden.ID {
+		t.Errorf("got %+v, want every pack", all)
+	}
+
+	bad := "S9"
+	var validation *libpolybase.ValidationError
+	if _, err := pb.UpdatePack(ctx, "alice", pack.ID, libpolybase.PartialPack{Semester: &bad}); !errors.As(err, &validation) {
+		t.Errorf("got %v for an unknown semester, want a validation error", err)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("failed to list audit events: %v", err)
+	}
+	if _, err := pb.RevertChange(ctx, "alice", events[0].ID); err != nil {
+		t.Fatalf("failed to revert publication: %v", err)
+	}
+	reverted, err := pb.GetPack(ctx, pack.ID)
+	if err != nil {
+		t.Fatalf("failed to get pack: %v", err)
+	}
+	if reverted.Shown || reverted.Semester != "" || reverted.Level != "" {
+		t.Errorf("got %+v after reverting the publication", reverted)
+	}
+}
@@ -1,5 +1,7 @@
 package tests
 
+import "github.com/alias-asso/polybase-go/libpolybase"
+
 func stringPtr(s string) *string {
 	return &s
 }
@@ -11,3 +13,8 @@ func intPtr(i int) *int {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func packCoursesPtr(ids ...libpolybase.CourseID) *[]libpolybase.PackCourse {
+	courses := packCourses(ids...)
+	return &courses
+}
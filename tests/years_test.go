@@ -0,0 +1,315 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alias-asso/polybase-go/libpolybase"
+)
+
+func TestCurrentAcademicYear(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want libpolybase.AcademicYear
+	}{
+		{time.Date(2026, time.June, 30, 23, 0, 0, 0, time.UTC), 2025},
+		{time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), 2026},
+		{time.Date(2027, time.January, 15, 12, 0, 0, 0, time.UTC), 2026},
+	}
+
+	for _, tt := range tests {
+		if got := libpolybase.CurrentAcademicYear(tt.now); got != tt.want {
+			t.Errorf("CurrentAcademicYear(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestParseAcademicYear(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    libpolybase.AcademicYear
+		wantErr bool
+	}{
+		{"2026-2027", 2026, false},
+		{"2026", 2026, false},
+		{" 2026 ", 2026, false},
+		{"2026-2028", 0, true},
+		{"26-27", 0, true},
+		{"next", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := libpolybase.ParseAcademicYear(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAcademicYear(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		var validation *libpolybase.ValidationError
+		if err != nil && (!errors.As(err, &validation) || validation.Field != "year") {
+			t.Errorf("ParseAcademicYear(%q) error = %v, want a ValidationError on year", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("ParseAcademicYear(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+		if err == nil && got.String() != "2026-2027" {
+			t.Errorf("String() = %q, want 2026-2027", got.String())
+		}
+	}
+}
+
+// Rolling over copies the live courses and packs and closes the source year
+func TestRollOverYear(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+	from := pb.Year()
+	to := from + 1
+
+	courses := []libpolybase.Course{
+		{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"},
+		{Code: "LU2IN005", Kind: "Cours", Part: 1, Parts: 1, Name: "Archi", Quantity: 0, Total: 10, Status: libpolybase.StatusDraft, Shown: false, Semester: "S1"},
+		{Code: "LU2IN006", Kind: "TME", Part: 1, Parts: 1, Name: "Struct", Quantity: 5, Total: 5, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S2"},
+	}
+	db.InsertMany(courses)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(courses[0].CID(), courses[1].CID(), courses[2].CID())})
+
+	if err := pb.DeleteCourse(ctx, "alice", courses[2].CID()); err != nil {
+		t.Fatalf("failed to delete course: %v", err)
+	}
+
+	created, err := pb.RollOverYear(ctx, "alice", from, to, libpolybase.RollOverOptions{})
+	if err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+	if created.Year != to || created.ReadOnly || created.Courses != 2 || created.Packs != 1 {
+		t.Errorf("got %+v", created)
+	}
+
+	next := pb.ForYear(to)
+	for _, course := range courses[:2] {
+		got, err := next.GetCourse(ctx, course.CID())
+		if err != nil {
+			t.Fatalf("failed to get copied course %s: %v", course.ID(), err)
+		}
+		course.Level, course.Revision = libpolybase.LevelL2, got.Revision
+		if !reflect.DeepEqual(got, course) {
+			t.Errorf("got %+v, want %+v", got, course)
+		}
+	}
+	if _, err := next.GetCourse(ctx, courses[2].CID()); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("deleted course was copied: %v", err)
+	}
+
+	packs, err := next.ListPacks(ctx)
+	if err != nil {
+		t.Fatalf("failed to list packs: %v", err)
+	}
+	if len(packs) != 1 || packs[0].Name != "L2 S1" || len(packs[0].Courses) != 2 {
+		t.Errorf("got packs %+v", packs)
+	}
+
+	reason := libpolybase.ReasonRollOver
+	movements, err := next.ListMovements(ctx, libpolybase.MovementFilter{Reason: &reason})
+	if err != nil {
+		t.Fatalf("failed to list movements: %v", err)
+	}
+	if len(movements) != 1 || movements[0].Delta != 4 {
+		t.Errorf("got movements %+v", movements)
+	}
+
+	years, err := pb.ListYears(ctx)
+	if err != nil {
+		t.Fatalf("failed to list years: %v", err)
+	}
+	want := []libpolybase.YearInfo{
+		{Year: to, Courses: 2, Packs: 1},
+		{Year: from, ReadOnly: true, Courses: 2, Packs: 1},
+	}
+	if len(years) != len(want) || years[0] != want[0] || years[1] != want[1] {
+		t.Errorf("got years %+v, want %+v", years, want)
+	}
+}
+
+func TestRollOverYearOptions(t *testing.T) {
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"}
+	empty := libpolybase.Course{Code: "LU2IN005", Kind: "TD", Part: 1, Parts: 1, Name: "Archi", Quantity: 0, Total: 10, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"}
+
+	tests := []struct {
+		name        string
+		opts        libpolybase.RollOverOptions
+		wantCourse  [2]int
+		wantEmpty   [2]int
+		wantInvalid bool
+	}{
+		{"keep", libpolybase.RollOverOptions{}, [2]int{4, 20}, [2]int{0, 10}, false},
+		{"reset quantities", libpolybase.RollOverOptions{ResetQuantities: true}, [2]int{20, 20}, [2]int{10, 10}, false},
+		{"reset totals", libpolybase.RollOverOptions{ResetTotals: true}, [2]int{4, 4}, [2]int{0, 10}, false},
+		{"both", libpolybase.RollOverOptions{ResetQuantities: true, ResetTotals: true}, [2]int{}, [2]int{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := NewDB(t)
+			pb := libpolybase.New(db.DB, "", false)
+			ctx := context.Background()
+			db.InsertMany([]libpolybase.Course{course, empty})
+
+			_, err := pb.RollOverYear(ctx, "alice", pb.Year(), pb.Year()+1, tt.opts)
+			if tt.wantInvalid {
+				var validation *libpolybase.ValidationError
+				if !errors.As(err, &validation) {
+					t.Fatalf("got error %v, want ValidationError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to roll over: %v", err)
+			}
+
+			next := pb.ForYear(pb.Year() + 1)
+			for _, c := range []struct {
+				id   libpolybase.CourseID
+				want [2]int
+			}{{course.CID(), tt.wantCourse}, {empty.CID(), tt.wantEmpty}} {
+				got, err := next.GetCourse(ctx, c.id)
+				if err != nil {
+					t.Fatalf("failed to get course: %v", err)
+				}
+				if [2]int{got.Quantity, got.Total} != c.want {
+					t.Errorf("%s: got %d/%d, want %d/%d", c.id.ID(), got.Quantity, got.Total, c.want[0], c.want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestRollOverYearErrors(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+	year := pb.Year()
+
+	if _, err := pb.RollOverYear(ctx, "alice", year, year+1, libpolybase.RollOverOptions{}); !errors.Is(err, libpolybase.ErrNotFound) {
+		t.Errorf("empty year: got %v, want ErrNotFound", err)
+	}
+
+	db.Insert(libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"})
+
+	var validation *libpolybase.ValidationError
+	if _, err := pb.RollOverYear(ctx, "alice", year, year, libpolybase.RollOverOptions{}); !errors.As(err, &validation) {
+		t.Errorf("same year: got %v, want ValidationError", err)
+	}
+	if _, err := pb.RollOverYear(ctx, "alice", year, year+1, libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+	if _, err := pb.RollOverYear(ctx, "alice", year, year+1, libpolybase.RollOverOptions{}); !errors.Is(err, libpolybase.ErrAlreadyExists) {
+		t.Errorf("second roll-over: got %v, want ErrAlreadyExists", err)
+	}
+}
+
+// A rolled over year can still be read but no longer modified
+func TestReadOnlyYear(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"}
+	db.Insert(course)
+	db.InsertPack(libpolybase.Pack{ID: 1, Name: "L2 S1", Courses: packCourses(course.CID())})
+
+	if _, err := pb.RollOverYear(ctx, "alice", pb.Year(), pb.Year()+1, libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+
+	if _, err := pb.GetCourse(ctx, course.CID()); err != nil {
+		t.Errorf("failed to get course of a read-only year: %v", err)
+	}
+
+	other := course
+	other.Part, other.Parts = 2, 2
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"create course", func() error { _, err := pb.CreateCourse(ctx, "alice", other); return err }},
+		{"delete course", func() error { return pb.DeleteCourse(ctx, "alice", course.CID()) }},
+		{"course quantity", func() error { _, err := pb.UpdateCourseQuantity(ctx, "alice", course.CID(), -1); return err }},
+		{"course visibility", func() error { _, err := pb.UpdateCourseShown(ctx, "alice", course.CID(), false); return err }},
+		{"delete pack", func() error { return pb.DeletePack(ctx, "alice", 1) }},
+	}
+
+	for _, tt := range tests {
+		if err := tt.fn(); !errors.Is(err, libpolybase.ErrConflict) {
+			t.Errorf("%s: got %v, want ErrConflict", tt.name, err)
+		}
+	}
+
+	db.AssertCourseEqual(course.CID(), course)
+
+	if _, err := pb.ForYear(pb.Year()+1).UpdateCourseQuantity(ctx, "alice", course.CID(), -1); err != nil {
+		t.Errorf("failed to update the new year: %v", err)
+	}
+}
+
+// Courses of different academic years do not see each other
+func TestYearScoping(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	next := pb.ForYear(pb.Year() + 1)
+	ctx := context.Background()
+
+	course := libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"}
+	if _, err := next.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create course: %v", err)
+	}
+	if _, err := pb.CreateCourse(ctx, "alice", course); err != nil {
+		t.Fatalf("failed to create the same course in another year: %v", err)
+	}
+
+	if _, err := next.UpdateCourseQuantity(ctx, "alice", course.CID(), -3); err != nil {
+		t.Fatalf("failed to update quantity: %v", err)
+	}
+	got, err := pb.GetCourse(ctx, course.CID())
+	if err != nil {
+		t.Fatalf("failed to get course: %v", err)
+	}
+	if got.Quantity != 4 {
+		t.Errorf("got quantity %d, want 4", got.Quantity)
+	}
+
+	events, err := pb.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("got %d events, want 1", len(events))
+	}
+}
+
+func TestRevertRollOver(t *testing.T) {
+	db := NewDB(t)
+	pb := libpolybase.New(db.DB, "", false)
+	ctx := context.Background()
+
+	db.Insert(libpolybase.Course{Code: "LU2IN002", Kind: "TD", Part: 1, Parts: 1, Name: "Algo", Quantity: 4, Total: 20, Status: libpolybase.StatusAvailable, Shown: true, Semester: "S1"})
+	if _, err := pb.RollOverYear(ctx, "alice", pb.Year(), pb.Year()+1, libpolybase.RollOverOptions{}); err != nil {
+		t.Fatalf("failed to roll over: %v", err)
+	}
+
+	next := pb.ForYear(pb.Year() + 1)
+	events, err := next.ListAuditEvents(ctx, libpolybase.AuditFilter{})
+	if err != nil {
+		t.Fatalf("failed to list events: %v", err)
+	}
+	if len(events) != 1 || events[0].Action != libpolybase.ActionRollOver {
+		t.Fatalf("got events %+v", events)
+	}
+
+	if _, err := next.RevertChange(ctx, "alice", events[0].ID); !errors.Is(err, libpolybase.ErrConflict) {
+		t.Errorf("got %v, want ErrConflict", err)
+	}
+}
@@ -1,12 +1,18 @@
 package views
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"net/url"
 	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/a-h/templ"
+
 	"github.com/alias-asso/polybase-go/libpolybase"
 )
 
@@ -14,12 +20,13 @@ import (
 type SemesterGroup struct {
 	Name    string
 	Kinds   []KindGroup
-	KindMap map[string]int
+	KindMap map[libpolybase.Level]int
 }
 
-// KindGroup represents a group of courses of the same kind
+// KindGroup represents a group of courses of the same level
 type KindGroup struct {
 	Name    string
+	Level   libpolybase.Level
 	Courses []libpolybase.Course
 }
 
@@ -41,16 +48,16 @@ func GroupCoursesBySemesterAndKind(courses []libpolybase.Course) []SemesterGroup
 		return num1 > num2
 	})
 
-	// Step 2: Get unique sorted kinds
-	kindMap := make(map[int]bool)
+	// Step 2: Get unique sorted levels
+	levelMap := make(map[libpolybase.Level]bool)
 	for _, course := range courses {
-		kindMap[course.Year] = true
+		levelMap[course.Level] = true
 	}
-	kinds := make([]int, 0, len(kindMap))
-	for kind := range kindMap {
-		kinds = append(kinds, kind)
+	levels := make([]libpolybase.Level, 0, len(levelMap))
+	for level := range levelMap {
+		levels = append(levels, level)
 	}
-	slices.Sort(kinds)
+	slices.SortFunc(levels, libpolybase.Level.Compare)
 
 	// Step 3: Create the structured result
 	result := make([]SemesterGroup, len(semesters))
@@ -59,16 +66,17 @@ func GroupCoursesBySemesterAndKind(courses []libpolybase.Course) []SemesterGroup
 	for i, semester := range semesters {
 		result[i] = SemesterGroup{
 			Name:    semester,
-			Kinds:   make([]KindGroup, len(kinds)),
-			KindMap: make(map[string]int),
+			Kinds:   make([]KindGroup, len(levels)),
+			KindMap: make(map[libpolybase.Level]int),
 		}
-		// Initialize kind groups
-		for j, kind := range kinds {
+		// Initialize level groups
+		for j, level := range levels {
 			result[i].Kinds[j] = KindGroup{
-				Name:    GetYear(kind),
+				Name:    levelLabel(level),
+				Level:   level,
 				Courses: make([]libpolybase.Course, 0),
 			}
-			result[i].KindMap[GetYear(kind)] = j
+			result[i].KindMap[level] = j
 		}
 	}
 
@@ -76,7 +84,7 @@ func GroupCoursesBySemesterAndKind(courses []libpolybase.Course) []SemesterGroup
 	for _, course := range courses {
 		semIdx := slices.IndexFunc(result, func(sg SemesterGroup) bool { return sg.Name == course.Semester })
 		if semIdx != -1 {
-			kindIdx := result[semIdx].KindMap[GetYear(course.Year)]
+			kindIdx := result[semIdx].KindMap[course.Level]
 			result[semIdx].Kinds[kindIdx].Courses = append(
 				result[semIdx].Kinds[kindIdx].Courses,
 				course,
@@ -96,6 +104,56 @@ func GroupCoursesBySemesterAndKind(courses []libpolybase.Course) []SemesterGroup
 	return result
 }
 
+type catalogueKey struct{}
+
+// WithCatalogue makes the catalogue rules available to the components rendered
+// with the returned context.
+func WithCatalogue(ctx context.Context, catalogue libpolybase.Catalogue) context.Context {
+	return context.WithValue(ctx, catalogueKey{}, catalogue)
+}
+
+func catalogue(ctx context.Context) libpolybase.Catalogue {
+	if c, ok := ctx.Value(catalogueKey{}).(libpolybase.Catalogue); ok {
+		return c
+	}
+	return libpolybase.DefaultCatalogue()
+}
+
+func kindLabel(ctx context.Context, name string) string {
+	if kind, ok := catalogue(ctx).Kind(name); ok {
+		return kind.DisplayName()
+	}
+	return name
+}
+
+// kindStyle overrides the colour of the cards of a kind when the catalogue
+// sets one. Catalogue colours are checked when compiled.
+func kindStyle(ctx context.Context, name string) templ.SafeCSS {
+	if kind, ok := catalogue(ctx).Kind(name); ok && kind.Colour != "" {
+		return templ.SafeCSS("--color-course: " + kind.Colour + ";")
+	}
+	return ""
+}
+
+// newCourseSemesters lists the semesters offered for a new course, the first
+// one first from July and the last one first before.
+// templateSummary lists the kinds of a template with their number of parts.
+func templateSummary(ctx context.Context, template libpolybase.Template) string {
+	courses := make([]string, len(template.Courses))
+	for i, course := range template.Courses {
+		courses[i] = fmt.Sprintf("%s×%d", kindLabel(ctx, course.Kind), course.Parts)
+	}
+	return strings.Join(courses, " + ")
+}
+
+func newCourseSemesters(ctx context.Context) []string {
+	semesters := slices.Clone(catalogue(ctx).Semesters)
+	if time.Now().Month() < time.July {
+		slices.Reverse(semesters)
+	}
+	return semesters
+}
+
 var niceMessages = []string{
 	"Nous espérons que tu passes une belle journée.",
 	"Nya~",
@@ -113,13 +171,274 @@ func GetRandomMessage() string {
 	return niceMessages[r.Intn(len(niceMessages))]
 }
 
-func GetYear(i int) string {
-	if i < 4 {
-		return fmt.Sprintf("L%d", i)
+// formatPrice writes a price the French way, such as 3,50 €.
+func formatPrice(price libpolybase.Price) string {
+	return strings.Replace(price.String(), ".", ",", 1) + " €"
+}
+
+// priceInput fills a price field, left empty when there is no price.
+func priceInput(price libpolybase.Price) string {
+	if price == 0 {
+		return ""
+	}
+	return strings.Replace(price.String(), ".", ",", 1)
+}
+
+// fixedPriceInput fills the fixed price field of a pack, left empty when its
+// price is computed from its courses.
+func fixedPriceInput(pricing libpolybase.PackPricing) string {
+	if pricing.Fixed == nil {
+		return ""
 	}
-	return fmt.Sprintf("M%d", i%3)
+	return strings.Replace(pricing.Fixed.String(), ".", ",", 1)
+}
+
+// packPricingLabel tells how the price of a pack is set.
+func packPricingLabel(pricing libpolybase.PackPricing) string {
+	switch {
+	case pricing.Fixed != nil:
+		return "Prix fixe"
+	case pricing.Discount > 0:
+		return fmt.Sprintf("Somme des prix des polys moins %d %%", pricing.Discount)
+	default:
+		return "Somme des prix des polys"
+	}
+}
+
+func optionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func hasDetails(course libpolybase.Course) bool {
+	return course.Pages != 0 || course.Price != 0 || course.PrintCost != 0 || course.Teacher != "" ||
+		course.Edition != "" || course.EditionDate != "" || course.Description != "" || len(course.Tags) > 0
+}
+
+// tagURL filters the grid on a tag.
+func tagURL(tag string, isAdmin bool) templ.SafeURL {
+	path := "/"
+	if isAdmin {
+		path = "/admin"
+	}
+	return templ.SafeURL(path + "?" + url.Values{"tag": {tag}}.Encode())
+}
+
+// noteEdited tells when a note was last edited, for the title of its date.
+func noteEdited(note libpolybase.Note) string {
+	if note.UpdatedAt == nil {
+		return ""
+	}
+	return "Modifiée le " + note.UpdatedAt.Local().Format("02/01/2006 15:04")
+}
+
+// fileSize formats the size of a file in the largest fitting unit.
+func fileSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f Mio", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%d Kio", size>>10)
+	default:
+		return fmt.Sprintf("%d o", size)
+	}
+}
+
+// courseSummary lists the details of a course worth showing on its card, the
+// internal ones only to admins.
+func courseSummary(course libpolybase.Course, isAdmin bool) string {
+	var parts []string
+	if course.Price > 0 {
+		parts = append(parts, formatPrice(course.Price))
+	}
+	if course.Pages > 0 {
+		parts = append(parts, fmt.Sprintf("%d p.", course.Pages))
+	}
+	if course.Teacher != "" {
+		parts = append(parts, course.Teacher)
+	}
+	if isAdmin {
+		if course.PrintCost > 0 {
+			parts = append(parts, "impression "+formatPrice(course.PrintCost))
+		}
+		if course.Edition != "" {
+			parts = append(parts, course.Edition)
+		}
+		if course.EditionDate != "" {
+			parts = append(parts, course.EditionDate)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+func levelLabel(level libpolybase.Level) string {
+	if level == libpolybase.LevelOther {
+		return "Autres"
+	}
+	return string(level)
+}
+
+// statusLabel names a status on the course cards and in the forms.
+func statusLabel(status libpolybase.CourseStatus) string {
+	switch status {
+	case libpolybase.StatusDraft:
+		return "Brouillon"
+	case libpolybase.StatusAwaitingPrint:
+		return "À imprimer"
+	case libpolybase.StatusAvailable:
+		return "Disponible"
+	case libpolybase.StatusOutOfStock:
+		return "Épuisé"
+	case libpolybase.StatusDiscontinued:
+		return "Abandonné"
+	default:
+		return string(status)
+	}
+}
+
+// statusClass colours the status badge of a course card.
+func statusClass(status libpolybase.CourseStatus) string {
+	switch status {
+	case libpolybase.StatusAvailable:
+		return "text-green-700 bg-green-100"
+	case libpolybase.StatusAwaitingPrint:
+		return "text-accent-600 bg-accent-100"
+	case libpolybase.StatusOutOfStock:
+		return "text-red-600 bg-red-100"
+	default:
+		return "text-base-600 bg-base-200"
+	}
+}
+
+// statusOptions lists the statuses a course may be moved to from its form,
+// starting with its own.
+func statusOptions(course libpolybase.Course) []libpolybase.CourseStatus {
+	return append([]libpolybase.CourseStatus{course.Status}, course.Status.Next()...)
+}
+
+// packQuantityPolicyLabel names a pack quantity policy on the pack cards.
+func packQuantityPolicyLabel(policy libpolybase.PackQuantityPolicy) string {
+	switch policy {
+	case libpolybase.PolicyStrict:
+		return "Tout ou rien"
+	case libpolybase.PolicyClamp:
+		return "Au mieux"
+	case libpolybase.PolicySkip:
+		return "Sauter les bloqués"
+	default:
+		return string(policy)
+	}
+}
+
+func packAvailabilityLabel(availability libpolybase.PackAvailability) string {
+	switch availability.Complete {
+	case 0:
+		return "Incomplet"
+	case 1:
+		return "1 complet"
+	default:
+		return fmt.Sprintf("%d complets", availability.Complete)
+	}
+}
+
+func packAvailabilityClass(availability libpolybase.PackAvailability) string {
+	switch {
+	case availability.Complete == 0:
+		return "text-red-600 bg-red-100"
+	case len(availability.Hidden) > 0:
+		return "text-base-600 bg-base-200"
+	default:
+		return "text-green-700 bg-green-100"
+	}
+}
+
+// packAvailabilityDetails names the course limiting the pack and the ones
+// out of stock or hidden.
+func packAvailabilityDetails(availability libpolybase.PackAvailability) string {
+	var lines []string
+	if availability.Limiting != nil {
+		lines = append(lines, "Limité par "+availability.Limiting.PID())
+	}
+	if len(availability.OutOfStock) > 0 {
+		lines = append(lines, "Épuisés : "+pidList(availability.OutOfStock))
+	}
+	if len(availability.Hidden) > 0 {
+		lines = append(lines, "Masqués : "+pidList(availability.Hidden))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// packCourseState tells why a course of a pack is marked on its card.
+func packCourseState(availability libpolybase.PackAvailability, course libpolybase.CourseID) string {
+	switch {
+	case contains(availability.Hidden, course):
+		return "Pas encore disponible"
+	case contains(availability.OutOfStock, course):
+		return "Épuisé"
+	default:
+		return ""
+	}
+}
+
+// packGroup describes the semester and level of a pack.
+func packGroup(pack libpolybase.Pack) string {
+	var parts []string
+	if pack.Level != "" {
+		parts = append(parts, levelLabel(pack.Level))
+	}
+	if pack.Semester != "" {
+		parts = append(parts, pack.Semester)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func pidList(courses []libpolybase.CourseID) string {
+	pids := make([]string, len(courses))
+	for i, course := range courses {
+		pids[i] = course.PID()
+	}
+	return strings.Join(pids, ", ")
 }
 
 func contains(courses []libpolybase.CourseID, id libpolybase.CourseID) bool {
 	return slices.Contains(courses, id)
 }
+
+// packCourseCount returns the number of copies of a course in a pack, zero
+// when the pack does not hold it.
+func packCourseCount(courses []libpolybase.PackCourse, id libpolybase.CourseID) int {
+	for _, course := range courses {
+		if course.CourseID == id {
+			return course.Count
+		}
+	}
+	return 0
+}
+
+// AuditEventLabel describes an audit event in a few words, for the toast shown
+// after an admin action.
+func AuditEventLabel(event libpolybase.AuditEvent) string {
+	subject := event.EntityID
+	if event.EntityType == libpolybase.EntityPack {
+		subject = "pack " + event.EntityID
+	}
+
+	switch event.Action {
+	case libpolybase.ActionCreate:
+		return "Ajout : " + subject
+	case libpolybase.ActionDelete:
+		return "Suppression : " + subject
+	case libpolybase.ActionQuantity:
+		return "Quantité modifiée : " + subject
+	case libpolybase.ActionStatus:
+		return "Statut modifié : " + subject
+	case libpolybase.ActionVisibility:
+		return "Visibilité modifiée : " + subject
+	case libpolybase.ActionUpload:
+		return "Master déposé : " + subject
+	default:
+		return "Modification : " + subject
+	}
+}